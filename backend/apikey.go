@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+)
+
+const apiKeyUsage = `usage:
+  backend apikey issue -name NAME [-org NAME] [-scopes read,write,admin] [-roles viewer,operator,engineer,admin] [-expires 720h]
+  backend apikey list
+  backend apikey revoke ID`
+
+// runAPIKeyCommand manages API keys from the command line, e.g. to issue the first admin key
+func runAPIKeyCommand(orgs service.OrganizationService, keys service.APIKeyService, args []string) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+
+	switch args[0] {
+	case "issue":
+		flags := flag.NewFlagSet("apikey issue", flag.ContinueOnError)
+		name := flags.String("name", "", "what the key is for, e.g. the client using it")
+		orgName := flags.String("org", models.DefaultOrganizationName, "the organization whose machines the key works with")
+		scopes := flags.String("scopes", "", "comma separated scopes: read, write and/or admin")
+		roles := flags.String("roles", "", "comma separated roles: viewer, operator, engineer and/or admin")
+		expires := flags.Duration("expires", 0, "how long the key is valid; 0 never expires")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		req := service.IssueKeyRequest{Name: *name, Scopes: splitList(*scopes), Roles: splitList(*roles)}
+		if *expires > 0 {
+			expiresAt := time.Now().Add(*expires)
+			req.ExpiresAt = &expiresAt
+		}
+		org, err := orgs.GetOrganizationByName(*orgName)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, *orgName)
+		}
+		issued, err := keys.ForTenant(org.ID).IssueKey(req)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Issued API key %d (%s) in organization %s with scopes [%s] and roles [%s].\n", issued.ID, issued.Name, org.Name,
+			strings.Join(issued.Scopes, ", "), strings.Join(issued.Roles, ", "))
+		fmt.Println("Store it now, it cannot be shown again:")
+		fmt.Println(issued.Key)
+		return nil
+
+	case "list":
+		all, err := keys.GetKeys()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tORG\tNAME\tPREFIX\tSCOPES\tROLES\tEXPIRES\tLAST USED\tREVOKED")
+		for _, k := range all {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.OrganizationID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), strings.Join(k.Roles, ","),
+				formatTime(k.ExpiresAt), formatTime(k.LastUsedAt), formatTime(k.RevokedAt))
+		}
+		return w.Flush()
+
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(apiKeyUsage)
+		}
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid API key ID %q", args[1])
+		}
+		if err := keys.RevokeKey(uint(id)); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked API key %d.\n", id)
+		return nil
+	}
+	return errors.New(apiKeyUsage)
+}
+
+// splitList splits a comma separated flag value, ignoring empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
@@ -0,0 +1,103 @@
+package artifact
+
+import (
+	"context"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// ObjectClient is the subset of an S3-compatible API the S3 store needs.
+// Implementations must return ErrNotFound for missing objects.
+type ObjectClient interface {
+	PutObject(ctx context.Context, bucket, key string, r io.Reader) error
+	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+	RemoveObject(ctx context.Context, bucket, key string) error
+}
+
+// S3Store keeps artifacts as objects in a bucket of an S3-compatible service
+type S3Store struct {
+	Client ObjectClient
+	Bucket string
+	Prefix string // prepended to every key, e.g. "artifacts/"
+}
+
+// NewS3Store creates a store keeping its objects in bucket
+func NewS3Store(client ObjectClient, bucket, prefix string) *S3Store {
+	return &S3Store{Client: client, Bucket: bucket, Prefix: prefix}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader) (Object, error) {
+	if err := ValidateKey(key); err != nil {
+		return Object{}, err
+	}
+	cr := newChecksumReader(r)
+	if err := s.Client.PutObject(ctx, s.Bucket, s.Prefix+key, cr); err != nil {
+		return Object{}, err
+	}
+	return cr.object(), nil
+}
+
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	return s.Client.GetObject(ctx, s.Bucket, s.Prefix+key)
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	return s.Client.RemoveObject(ctx, s.Bucket, s.Prefix+key)
+}
+
+// MinioClient implements ObjectClient for any S3-compatible service (AWS S3, MinIO, Ceph, ...)
+type MinioClient struct {
+	Client *minio.Client
+}
+
+// NewMinioClient connects to the S3-compatible service at endpoint (host:port)
+func NewMinioClient(endpoint, accessKey, secretKey, region string, useSSL bool) (*MinioClient, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MinioClient{Client: client}, nil
+}
+
+func (m *MinioClient) PutObject(ctx context.Context, bucket, key string, r io.Reader) error {
+	// An unknown size makes the client upload in parts instead of buffering the whole file
+	_, err := m.Client.PutObject(ctx, bucket, key, r, -1, minio.PutObjectOptions{})
+	return err
+}
+
+func (m *MinioClient) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	obj, err := m.Client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	// GetObject is lazy; stat it so a missing object is reported here rather than on the first read
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, notFound(err)
+	}
+	return obj, nil
+}
+
+func (m *MinioClient) RemoveObject(ctx context.Context, bucket, key string) error {
+	return m.Client.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{})
+}
+
+// notFound translates the service's missing-object error into ErrNotFound
+func notFound(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+// Package artifact stores files produced by simulation runs, such as CSV results, plots and logs.
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("artifact not found")
+
+// Store keeps artifact contents under slash-separated keys
+type Store interface {
+	// Put stores the contents of r under key, replacing any previous object
+	Put(ctx context.Context, key string, r io.Reader) (Object, error)
+	// Open streams the object stored under key; callers must close the reader
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Object describes a stored artifact
+type Object struct {
+	Size   int64
+	SHA256 string // hex-encoded checksum of the contents
+}
+
+// ValidateKey rejects keys that could escape the store's namespace
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("invalid artifact key %q", key)
+	}
+	return nil
+}
+
+// checksumReader computes the size and checksum of everything read through it
+type checksumReader struct {
+	r    io.Reader
+	hash hash.Hash
+	size int64
+}
+
+func newChecksumReader(r io.Reader) *checksumReader {
+	return &checksumReader{r: r, hash: sha256.New()}
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	c.size += int64(n)
+	return n, err
+}
+
+func (c *checksumReader) object() Object {
+	return Object{Size: c.size, SHA256: hex.EncodeToString(c.hash.Sum(nil))}
+}
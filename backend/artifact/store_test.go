@@ -0,0 +1,85 @@
+package artifact_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/artifact"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryClient is an in-memory stand-in for an S3-compatible service
+type memoryClient struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *memoryClient) PutObject(ctx context.Context, bucket, key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.objects == nil {
+		m.objects = map[string][]byte{}
+	}
+	m.objects[bucket+"/"+key] = data
+	return nil
+}
+func (m *memoryClient) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[bucket+"/"+key]
+	if !ok {
+		return nil, artifact.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+func (m *memoryClient) RemoveObject(ctx context.Context, bucket, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, bucket+"/"+key)
+	return nil
+}
+
+func TestStores(t *testing.T) {
+	local, err := artifact.NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+	client := &memoryClient{}
+	stores := map[string]artifact.Store{
+		"Local": local,
+		"S3":    artifact.NewS3Store(client, "runs", "artifacts/"),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			obj, err := store.Put(ctx, "runs/1/results.csv", strings.NewReader("a,b\n1,2\n"))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(8), obj.Size)
+			assert.Equal(t, "492d5ea496056f1a6a6592241032fab764c321596317930b4fa0e1e8bc3b7470", obj.SHA256)
+
+			r, err := store.Open(ctx, "runs/1/results.csv")
+			assert.Nil(t, err)
+			data, _ := io.ReadAll(r)
+			r.Close()
+			assert.Equal(t, "a,b\n1,2\n", string(data))
+
+			assert.Nil(t, store.Delete(ctx, "runs/1/results.csv"))
+			assert.Nil(t, store.Delete(ctx, "runs/1/results.csv"), "Deleting a missing object should not fail")
+			_, err = store.Open(ctx, "runs/1/results.csv")
+			assert.ErrorIs(t, err, artifact.ErrNotFound)
+
+			for _, key := range []string{"", "/etc/passwd", "../secret", "runs/../../secret"} {
+				_, err := store.Put(ctx, key, strings.NewReader("x"))
+				assert.NotNil(t, err, "Key %q should be rejected", key)
+			}
+		})
+	}
+	assert.Empty(t, client.objects)
+}
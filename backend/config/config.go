@@ -0,0 +1,282 @@
+// Package config reads the backend's runtime settings from environment variables.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/CBYeuler/automation-backend/backend/ratelimit"
+	"github.com/CBYeuler/automation-backend/backend/tracing"
+)
+
+// Queue drivers selectable with QUEUE_DRIVER
+const (
+	QueueDriverDB    = "db"    // jobs are stored in the application database (default)
+	QueueDriverRedis = "redis" // jobs are stored in Redis, shared by every backend instance using it
+)
+
+// Artifact stores selectable with ARTIFACT_STORE
+const (
+	ArtifactStoreLocal = "local" // artifacts are files below ARTIFACT_DIR (default)
+	ArtifactStoreS3    = "s3"    // artifacts are objects in an S3-compatible bucket
+)
+
+// Config holds the settings that can be changed without rebuilding the backend
+type Config struct {
+	LogFormat string     // LOG_FORMAT, text or json
+	LogLevel  slog.Level // LOG_LEVEL, debug, info, warn or error; adjustable at runtime over the API
+
+	TraceExporter    string  // TRACE_EXPORTER, none, otlp or stdout
+	TraceFile        string  // TRACE_FILE, where the stdout exporter writes instead of stdout
+	TraceSampleRatio float64 // TRACE_SAMPLE_RATIO, share of new traces recorded, from 0 to 1
+
+	Runners map[string][]string // RUNNERS_FILE, JSON object of the commands machines may run, by runner name
+
+	QueueDriver string // QUEUE_DRIVER
+
+	RedisAddr     string // REDIS_ADDR, host:port
+	RedisPassword string // REDIS_PASSWORD
+	RedisDB       int    // REDIS_DB
+	RedisPrefix   string // REDIS_QUEUE_PREFIX, namespace of the queue's keys
+
+	ArtifactStore     string        // ARTIFACT_STORE
+	ArtifactDir       string        // ARTIFACT_DIR, root of the local store
+	ArtifactRetention time.Duration // ARTIFACT_RETENTION, 0 keeps artifacts forever
+
+	S3Endpoint  string // S3_ENDPOINT, host:port
+	S3Bucket    string // S3_BUCKET
+	S3Prefix    string // S3_PREFIX, prepended to every object key
+	S3Region    string // S3_REGION
+	S3AccessKey string // S3_ACCESS_KEY
+	S3SecretKey string // S3_SECRET_KEY
+	S3UseSSL    bool   // S3_USE_SSL
+
+	APIAuth bool // API_AUTH, false serves the API without API keys, for local development only
+
+	MetricsAddr string // METRICS_ADDR, host:port where metrics are served apart from the API
+
+	JWKSURL       string        // JWKS_URL, key set of the identity provider; enables JWT bearer tokens
+	JWKSFile      string        // JWKS_FILE, a local key set instead of JWKS_URL, e.g. for testing offline
+	JWKSRefresh   time.Duration // JWKS_REFRESH, how long fetched keys are used before fetching them again
+	JWTIssuer     string        // JWT_ISSUER, required iss claim
+	JWTAudience   string        // JWT_AUDIENCE, required aud claim
+	JWTScopeClaim string        // JWT_SCOPE_CLAIM, claim listing the read, write and admin scopes
+	JWTRolesClaim string        // JWT_ROLES_CLAIM, claim listing the roles, such as operator
+	JWTOrgClaim   string        // JWT_ORG_CLAIM, claim naming the caller's organization
+
+	RateLimitAddress ratelimit.Rate // RATE_LIMIT_ADDRESS, per IP address before authenticating
+	RateLimitRead    ratelimit.Rate // RATE_LIMIT_READ, per client, e.g. 20/s; 0 is unlimited
+	RateLimitWrite   ratelimit.Rate // RATE_LIMIT_WRITE
+	RateLimitSubmit  ratelimit.Rate // RATE_LIMIT_SUBMIT, for runs, batches and workflow runs
+	RunQuota         int            // RUN_QUOTA, runs an organization may have pending or running; 0 is unlimited
+	TrustedProxies   []string       // TRUSTED_PROXIES, comma separated addresses or CIDRs of proxies whose X-Forwarded-For is believed
+}
+
+// JWTAuth reports whether JWT bearer tokens are accepted
+func (c Config) JWTAuth() bool {
+	return c.JWKSURL != "" || c.JWKSFile != ""
+}
+
+// Load reads the configuration from the environment, applying defaults for unset variables
+func Load() (Config, error) {
+	cfg := Config{
+		LogFormat: getenv("LOG_FORMAT", logging.FormatText),
+		LogLevel:  slog.LevelInfo,
+
+		TraceExporter:    getenv("TRACE_EXPORTER", tracing.ExporterNone),
+		TraceFile:        os.Getenv("TRACE_FILE"),
+		TraceSampleRatio: 1,
+
+		QueueDriver:   getenv("QUEUE_DRIVER", QueueDriverDB),
+		RedisAddr:     getenv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword: os.Getenv("REDIS_PASSWORD"),
+		RedisPrefix:   getenv("REDIS_QUEUE_PREFIX", "automation:jobs"),
+
+		ArtifactStore:     getenv("ARTIFACT_STORE", ArtifactStoreLocal),
+		ArtifactDir:       getenv("ARTIFACT_DIR", "../data/artifacts"),
+		ArtifactRetention: 30 * 24 * time.Hour,
+
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3Prefix:    os.Getenv("S3_PREFIX"),
+		S3Region:    os.Getenv("S3_REGION"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("S3_SECRET_KEY"),
+		S3UseSSL:    true,
+
+		APIAuth: true,
+
+		MetricsAddr: getenv("METRICS_ADDR", "localhost:9464"),
+
+		JWKSURL:       os.Getenv("JWKS_URL"),
+		JWKSFile:      os.Getenv("JWKS_FILE"),
+		JWKSRefresh:   time.Hour,
+		JWTIssuer:     os.Getenv("JWT_ISSUER"),
+		JWTAudience:   os.Getenv("JWT_AUDIENCE"),
+		JWTScopeClaim: getenv("JWT_SCOPE_CLAIM", "scope"),
+		JWTRolesClaim: getenv("JWT_ROLES_CLAIM", "roles"),
+		JWTOrgClaim:   getenv("JWT_ORG_CLAIM", "org"),
+
+		RateLimitAddress: ratelimit.Rate{Requests: 50, Per: time.Second},
+		RateLimitRead:    ratelimit.Rate{Requests: 20, Per: time.Second},
+		RateLimitWrite:   ratelimit.Rate{Requests: 10, Per: time.Second},
+		RateLimitSubmit:  ratelimit.Rate{Requests: 30, Per: time.Minute},
+		RunQuota:         100,
+	}
+
+	if cfg.LogFormat != logging.FormatText && cfg.LogFormat != logging.FormatJSON {
+		return cfg, fmt.Errorf("LOG_FORMAT must be %q or %q, got %q", logging.FormatText, logging.FormatJSON, cfg.LogFormat)
+	}
+	if raw := os.Getenv("LOG_LEVEL"); raw != "" {
+		level, err := logging.ParseLevel(raw)
+		if err != nil {
+			return cfg, fmt.Errorf("LOG_LEVEL: %w", err)
+		}
+		cfg.LogLevel = level
+	}
+
+	switch cfg.TraceExporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		return cfg, fmt.Errorf("TRACE_EXPORTER must be %q, %q or %q, got %q", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout, cfg.TraceExporter)
+	}
+	if raw := os.Getenv("TRACE_SAMPLE_RATIO"); raw != "" {
+		ratio, err := strconv.ParseFloat(raw, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return cfg, fmt.Errorf("TRACE_SAMPLE_RATIO must be a number from 0 to 1, got %q", raw)
+		}
+		cfg.TraceSampleRatio = ratio
+	}
+
+	if path := os.Getenv("RUNNERS_FILE"); path != "" {
+		runners, err := loadRunners(path)
+		if err != nil {
+			return cfg, fmt.Errorf("RUNNERS_FILE: %w", err)
+		}
+		cfg.Runners = runners
+	}
+
+	if cfg.QueueDriver != QueueDriverDB && cfg.QueueDriver != QueueDriverRedis {
+		return cfg, fmt.Errorf("QUEUE_DRIVER must be %q or %q, got %q", QueueDriverDB, QueueDriverRedis, cfg.QueueDriver)
+	}
+	if raw := os.Getenv("REDIS_DB"); raw != "" {
+		db, err := strconv.Atoi(raw)
+		if err != nil || db < 0 {
+			return cfg, fmt.Errorf("REDIS_DB must be a non-negative integer, got %q", raw)
+		}
+		cfg.RedisDB = db
+	}
+
+	switch cfg.ArtifactStore {
+	case ArtifactStoreLocal:
+	case ArtifactStoreS3:
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return cfg, fmt.Errorf("ARTIFACT_STORE=s3 requires S3_ENDPOINT and S3_BUCKET")
+		}
+	default:
+		return cfg, fmt.Errorf("ARTIFACT_STORE must be %q or %q, got %q", ArtifactStoreLocal, ArtifactStoreS3, cfg.ArtifactStore)
+	}
+	if raw := os.Getenv("ARTIFACT_RETENTION"); raw != "" {
+		retention, err := time.ParseDuration(raw)
+		if err != nil || retention < 0 {
+			return cfg, fmt.Errorf("ARTIFACT_RETENTION must be a non-negative duration, got %q", raw)
+		}
+		cfg.ArtifactRetention = retention
+	}
+	if raw := os.Getenv("S3_USE_SSL"); raw != "" {
+		useSSL, err := strconv.ParseBool(raw)
+		if err != nil {
+			return cfg, fmt.Errorf("S3_USE_SSL must be a boolean, got %q", raw)
+		}
+		cfg.S3UseSSL = useSSL
+	}
+	if raw := os.Getenv("API_AUTH"); raw != "" {
+		apiAuth, err := strconv.ParseBool(raw)
+		if err != nil {
+			return cfg, fmt.Errorf("API_AUTH must be a boolean, got %q", raw)
+		}
+		cfg.APIAuth = apiAuth
+	}
+
+	if cfg.JWKSURL != "" && cfg.JWKSFile != "" {
+		return cfg, fmt.Errorf("JWKS_URL and JWKS_FILE are mutually exclusive")
+	}
+	if cfg.JWTAuth() && (cfg.JWTIssuer == "" || cfg.JWTAudience == "") {
+		return cfg, fmt.Errorf("JWT bearer tokens require JWT_ISSUER and JWT_AUDIENCE")
+	}
+	if raw := os.Getenv("JWKS_REFRESH"); raw != "" {
+		refresh, err := time.ParseDuration(raw)
+		if err != nil || refresh < 0 {
+			return cfg, fmt.Errorf("JWKS_REFRESH must be a non-negative duration, got %q", raw)
+		}
+		cfg.JWKSRefresh = refresh
+	}
+
+	for name, rate := range map[string]*ratelimit.Rate{
+		"RATE_LIMIT_ADDRESS": &cfg.RateLimitAddress,
+		"RATE_LIMIT_READ":    &cfg.RateLimitRead,
+		"RATE_LIMIT_WRITE":   &cfg.RateLimitWrite,
+		"RATE_LIMIT_SUBMIT":  &cfg.RateLimitSubmit,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			parsed, err := ratelimit.ParseRate(raw)
+			if err != nil {
+				return cfg, fmt.Errorf("%s: %w", name, err)
+			}
+			*rate = parsed
+		}
+	}
+	if raw := os.Getenv("RUN_QUOTA"); raw != "" {
+		quota, err := strconv.Atoi(raw)
+		if err != nil || quota < 0 {
+			return cfg, fmt.Errorf("RUN_QUOTA must be a non-negative integer, got %q", raw)
+		}
+		cfg.RunQuota = quota
+	}
+	// Without trusted proxies the address of a client is the one it connects from, as anyone
+	// could claim any address in X-Forwarded-For
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return cfg, fmt.Errorf("TRUSTED_PROXIES must list IP addresses or CIDRs, got %q", proxy)
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+	}
+	return cfg, nil
+}
+
+// loadRunners reads the registry of runner commands, e.g. {"warmup": ["python3", "simulator/warmup.py"]}
+func loadRunners(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var runners map[string][]string
+	if err := json.Unmarshal(data, &runners); err != nil {
+		return nil, fmt.Errorf("must be a JSON object of commands: %w", err)
+	}
+	for name, command := range runners {
+		if name == "" || len(command) == 0 || command[0] == "" {
+			return nil, fmt.Errorf("runner %q needs a name and a command", name)
+		}
+	}
+	return runners, nil
+}
+
+// getenv returns the value of an environment variable, or fallback if it is unset or empty
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,183 @@
+package config_test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/config"
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/CBYeuler/automation-backend/backend/ratelimit"
+	"github.com/CBYeuler/automation-backend/backend/tracing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Setenv("QUEUE_DRIVER", "")
+	t.Setenv("REDIS_DB", "")
+	cfg, err := config.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, config.QueueDriverDB, cfg.QueueDriver)
+	assert.Equal(t, "localhost:6379", cfg.RedisAddr)
+
+	t.Setenv("QUEUE_DRIVER", "redis")
+	t.Setenv("REDIS_DB", "2")
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, config.QueueDriverRedis, cfg.QueueDriver)
+	assert.Equal(t, 2, cfg.RedisDB)
+
+	t.Setenv("QUEUE_DRIVER", "kafka")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+
+	t.Setenv("QUEUE_DRIVER", "")
+	t.Setenv("REDIS_DB", "-1")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+
+	t.Setenv("REDIS_DB", "")
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, config.ArtifactStoreLocal, cfg.ArtifactStore)
+	assert.Equal(t, 30*24*time.Hour, cfg.ArtifactRetention)
+	assert.True(t, cfg.S3UseSSL)
+
+	t.Setenv("ARTIFACT_STORE", "s3")
+	_, err = config.Load()
+	assert.NotNil(t, err, "The S3 store needs an endpoint and a bucket")
+
+	t.Setenv("S3_ENDPOINT", "localhost:9000")
+	t.Setenv("S3_BUCKET", "artifacts")
+	t.Setenv("S3_USE_SSL", "false")
+	t.Setenv("ARTIFACT_RETENTION", "0")
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.False(t, cfg.S3UseSSL)
+	assert.Zero(t, cfg.ArtifactRetention)
+
+	t.Setenv("ARTIFACT_RETENTION", "a week")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+	t.Setenv("ARTIFACT_RETENTION", "")
+
+	t.Setenv("API_AUTH", "")
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.True(t, cfg.APIAuth, "API keys are required unless turned off")
+	t.Setenv("API_AUTH", "false")
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.False(t, cfg.APIAuth)
+	t.Setenv("API_AUTH", "sometimes")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+	t.Setenv("API_AUTH", "")
+
+	assert.False(t, cfg.JWTAuth(), "JWT bearer tokens are off without a key set")
+	t.Setenv("JWKS_URL", "https://idp.example.com/jwks")
+	_, err = config.Load()
+	assert.NotNil(t, err, "Tokens require an issuer and audience")
+	t.Setenv("JWT_ISSUER", "https://idp.example.com")
+	t.Setenv("JWT_AUDIENCE", "automation-backend")
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.True(t, cfg.JWTAuth())
+	assert.Equal(t, "scope", cfg.JWTScopeClaim)
+	assert.Equal(t, "roles", cfg.JWTRolesClaim)
+	assert.Equal(t, "org", cfg.JWTOrgClaim)
+	assert.Equal(t, time.Hour, cfg.JWKSRefresh)
+	t.Setenv("JWKS_FILE", "jwks.json")
+	_, err = config.Load()
+	assert.NotNil(t, err, "A key set comes from a URL or a file, not both")
+	t.Setenv("JWKS_FILE", "")
+
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "20/s", cfg.RateLimitRead.String())
+	assert.Equal(t, "30/m", cfg.RateLimitSubmit.String())
+	assert.Equal(t, 100, cfg.RunQuota)
+	assert.Equal(t, "50/s", cfg.RateLimitAddress.String())
+	assert.Equal(t, "localhost:9464", cfg.MetricsAddr)
+	assert.Nil(t, cfg.TrustedProxies, "No proxy is trusted by default")
+	t.Setenv("RATE_LIMIT_READ", "0")
+	t.Setenv("RATE_LIMIT_SUBMIT", "5/10s")
+	t.Setenv("RUN_QUOTA", "0")
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.True(t, cfg.RateLimitRead.Unlimited())
+	assert.Equal(t, ratelimit.Rate{Requests: 5, Per: 10 * time.Second}, cfg.RateLimitSubmit)
+	assert.Zero(t, cfg.RunQuota)
+	t.Setenv("RATE_LIMIT_WRITE", "lots")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+	t.Setenv("RATE_LIMIT_WRITE", "")
+	t.Setenv("RUN_QUOTA", "-5")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+	t.Setenv("RUN_QUOTA", "")
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1")
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, cfg.TrustedProxies)
+	t.Setenv("TRUSTED_PROXIES", "proxy.internal")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+	t.Setenv("TRUSTED_PROXIES", "")
+
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, logging.FormatText, cfg.LogFormat)
+	assert.Equal(t, slog.LevelInfo, cfg.LogLevel)
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_LEVEL", "debug")
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, logging.FormatJSON, cfg.LogFormat)
+	assert.Equal(t, slog.LevelDebug, cfg.LogLevel)
+	t.Setenv("LOG_LEVEL", "chatty")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LOG_FORMAT", "xml")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+	t.Setenv("LOG_FORMAT", "")
+
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, tracing.ExporterNone, cfg.TraceExporter)
+	assert.Equal(t, 1.0, cfg.TraceSampleRatio)
+	t.Setenv("TRACE_EXPORTER", "stdout")
+	t.Setenv("TRACE_FILE", "/tmp/traces.json")
+	t.Setenv("TRACE_SAMPLE_RATIO", "0.25")
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, tracing.ExporterStdout, cfg.TraceExporter)
+	assert.Equal(t, "/tmp/traces.json", cfg.TraceFile)
+	assert.Equal(t, 0.25, cfg.TraceSampleRatio)
+	t.Setenv("TRACE_SAMPLE_RATIO", "2")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+	t.Setenv("TRACE_SAMPLE_RATIO", "")
+	t.Setenv("TRACE_EXPORTER", "jaeger")
+	_, err = config.Load()
+	assert.NotNil(t, err)
+	t.Setenv("TRACE_EXPORTER", "")
+
+	runners := filepath.Join(t.TempDir(), "runners.json")
+	assert.Nil(t, os.WriteFile(runners, []byte(`{"warmup": ["python3", "simulator/warmup.py"]}`), 0o600))
+	t.Setenv("RUNNERS_FILE", runners)
+	cfg, err = config.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{"warmup": {"python3", "simulator/warmup.py"}}, cfg.Runners)
+	assert.Nil(t, os.WriteFile(runners, []byte(`{"warmup": []}`), 0o600))
+	_, err = config.Load()
+	assert.NotNil(t, err)
+	t.Setenv("RUNNERS_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	_, err = config.Load()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,39 @@
+// Package callbacks registers the callbacks of GORM plugins that observe every query, such as
+// the metrics and tracing plugins.
+package callbacks
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// registerer is a point of GORM's callback chains a callback can be registered at
+type registerer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// Around registers the callbacks before and after return for an operation around each of the
+// create, query, update, delete, row and raw callbacks of db, named after the plugin
+func Around(db *gorm.DB, plugin string, before, after func(operation string) func(*gorm.DB)) error {
+	c := db.Callback()
+	chains := []struct {
+		operation     string
+		before, after registerer
+	}{
+		{"create", c.Create().Before("gorm:create"), c.Create().After("gorm:create")},
+		{"query", c.Query().Before("gorm:query"), c.Query().After("gorm:query")},
+		{"update", c.Update().Before("gorm:update"), c.Update().After("gorm:update")},
+		{"delete", c.Delete().Before("gorm:delete"), c.Delete().After("gorm:delete")},
+		{"row", c.Row().Before("gorm:row"), c.Row().After("gorm:row")},
+		{"raw", c.Raw().Before("gorm:raw"), c.Raw().After("gorm:raw")},
+	}
+	var errs []error
+	for _, chain := range chains {
+		errs = append(errs,
+			chain.before.Register(plugin+":before_"+chain.operation, before(chain.operation)),
+			chain.after.Register(plugin+":after_"+chain.operation, after(chain.operation)),
+		)
+	}
+	return errors.Join(errs...)
+}
@@ -1,32 +1,76 @@
 package database
 
 import (
-	"log"
+	"log/slog"
+	"os"
+	"time"
 
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/CBYeuler/automation-backend/backend/metrics"
 	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/tracing"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// slowQueryThreshold is how long a query may take before it is logged as slow
+const slowQueryThreshold = 200 * time.Millisecond
+
 var DB *gorm.DB
 
 func ConnectDatabase() {
 	var err error
-	DB, err = gorm.Open(sqlite.Open("../data/automation.db"), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open("../data/automation.db"), &gorm.Config{
+		Logger: logging.NewGormLogger(slowQueryThreshold),
+	})
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	if err := DB.Use(metrics.GormPlugin{}); err != nil {
+		slog.Error("Failed to instrument database", "error", err)
+		os.Exit(1)
 	}
-	log.Println("Database connection established")
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		slog.Error("Failed to instrument database", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Database connection established")
 
 	MigrateModels()
 }
 
 func MigrateModels() {
-	err := DB.AutoMigrate(&models.Machine{})
+	err := DB.AutoMigrate(
+		&models.Machine{},
+		&models.TelemetrySample{},
+		&models.AlarmRule{},
+		&models.Alarm{},
+		&models.SimulationRun{},
+		&models.Job{},
+		&models.Batch{},
+		&models.BatchPoint{},
+		&models.Workflow{},
+		&models.WorkflowRun{},
+		&models.WorkflowStepRun{},
+		&models.Artifact{},
+		&models.RunLog{},
+		&models.MachineGroup{},
+		&models.MachineLink{},
+		&models.APIKey{},
+		&models.Organization{},
+	)
 	if err != nil {
-		log.Fatal("Failed to migrate database models:", err)
+		slog.Error("Failed to migrate database models", "error", err)
+		os.Exit(1)
+	}
+	// Records from before organizations existed belong to the default organization
+	defaultOrg := models.Organization{Model: models.Model{ID: models.DefaultOrganizationID}, Name: models.DefaultOrganizationName}
+	if err := DB.FirstOrCreate(&defaultOrg, models.DefaultOrganizationID).Error; err != nil {
+		slog.Error("Failed to create the default organization", "error", err)
+		os.Exit(1)
 	}
-	log.Println("Database models migrated successfully")
+	slog.Info("Database models migrated successfully")
 }
 
 func GetDB() *gorm.DB {
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AlarmHandler contains the service interface for dependency injection
+type AlarmHandler struct {
+	Service service.AlarmService
+}
+
+// NewAlarmHandler creates a new handler instance
+func NewAlarmHandler(s service.AlarmService) *AlarmHandler {
+	return &AlarmHandler{Service: s}
+}
+
+// AcknowledgeRequest is the optional body of POST /api/v1/alarms/:id/acknowledge
+type AcknowledgeRequest struct {
+	AcknowledgedBy string `json:"acknowledged_by"`
+}
+
+// GetAlarms handles GET /api/v1/alarms, optionally filtered by ?state= and ?machine_id=
+func (h *AlarmHandler) GetAlarms(c *gin.Context) {
+	filter := repository.AlarmFilter{State: c.Query("state")}
+	filter.MachineID, _ = queryUint(c, "machine_id")
+
+	alarms, err := forRequest(c, h.Service).GetAlarms(filter)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to retrieve alarms", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alarms"})
+		return
+	}
+	c.JSON(http.StatusOK, alarms)
+}
+
+// AcknowledgeAlarm handles POST /api/v1/alarms/:id/acknowledge
+func (h *AlarmHandler) AcknowledgeAlarm(c *gin.Context) {
+	id := pathID(c, "id")
+	req := requestBody[AcknowledgeRequest](c)
+	alarm, err := forRequest(c, h.Service).AcknowledgeAlarm(id, req.AcknowledgedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAlarmNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alarm not found"})
+		case errors.Is(err, service.ErrAlarmAlreadyAcknowledged):
+			c.JSON(http.StatusConflict, gin.H{"error": "Alarm already acknowledged"})
+		default:
+			slog.ErrorContext(c.Request.Context(), "Failed to acknowledge alarm", "alarm_id", id, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge alarm"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, alarm)
+}
+
+// CreateAlarmRule handles POST /api/v1/alarm-rules
+func (h *AlarmHandler) CreateAlarmRule(c *gin.Context) {
+	createdRule, err := forRequest(c, h.Service).CreateRule(requestBody[models.AlarmRule](c))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAlarmRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to create alarm rule", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alarm rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdRule)
+}
+
+// GetAlarmRules handles GET /api/v1/alarm-rules
+func (h *AlarmHandler) GetAlarmRules(c *gin.Context) {
+	rules, err := forRequest(c, h.Service).GetRules()
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to retrieve alarm rules", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alarm rules"})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// DeleteAlarmRule handles DELETE /api/v1/alarm-rules/:id
+func (h *AlarmHandler) DeleteAlarmRule(c *gin.Context) {
+	id := pathID(c, "id")
+	if err := forRequest(c, h.Service).DeleteRule(id); err != nil {
+		if errors.Is(err, service.ErrAlarmRuleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alarm rule not found"})
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to delete alarm rule", "rule_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alarm rule"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,139 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockAlarmRepository serves a single raised alarm (ID 1) and accepts any rule
+type MockAlarmRepository struct {
+	Filter repository.AlarmFilter
+}
+
+func (m *MockAlarmRepository) WithContext(context.Context) repository.AlarmRepository { return m }
+
+func (m *MockAlarmRepository) CreateRule(rule *models.AlarmRule) error {
+	rule.ID = 1
+	return nil
+}
+func (m *MockAlarmRepository) FindRules() ([]models.AlarmRule, error) { return nil, nil }
+func (m *MockAlarmRepository) FindRuleByID(id uint) (*models.AlarmRule, error) {
+	if id != 1 {
+		return nil, errors.New("record not found")
+	}
+	return &models.AlarmRule{Model: models.Model{ID: 1}}, nil
+}
+func (m *MockAlarmRepository) DeleteRule(id uint) error            { return nil }
+func (m *MockAlarmRepository) Create(alarm *models.Alarm) error    { return nil }
+func (m *MockAlarmRepository) Update(alarm *models.Alarm) error    { return nil }
+func (m *MockAlarmRepository) FindActive() ([]models.Alarm, error) { return nil, nil }
+func (m *MockAlarmRepository) FindByID(id uint) (*models.Alarm, error) {
+	if id != 1 {
+		return nil, errors.New("record not found")
+	}
+	return &models.Alarm{Model: models.Model{ID: 1}, State: models.AlarmStateRaised, RaisedAt: time.Now()}, nil
+}
+func (m *MockAlarmRepository) Find(filter repository.AlarmFilter) ([]models.Alarm, error) {
+	m.Filter = filter
+	return []models.Alarm{}, nil
+}
+
+func setupAlarmRouter() (*gin.Engine, *MockAlarmRepository) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	repo := &MockAlarmRepository{}
+	alarmHandler := handler.NewAlarmHandler(service.NewAlarmService(repo, &MockMachineRepository{}))
+
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Alarm: alarmHandler}))
+	return router, repo
+}
+
+func TestGetAlarmsHandler(t *testing.T) {
+	router, repo := setupAlarmRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/alarms?state=Raised&machine_id=3", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Expected HTTP 200 OK")
+	assert.Equal(t, repository.AlarmFilter{MachineID: 3, State: "Raised"}, repo.Filter)
+}
+
+func TestAcknowledgeAlarmHandler(t *testing.T) {
+	router, _ := setupAlarmRouter()
+
+	// 1. Successful acknowledgement
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/alarms/1/acknowledge", bytes.NewBufferString(`{"acknowledged_by": "alice"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, "Expected HTTP 200 OK")
+		var alarm models.Alarm
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &alarm))
+		assert.Equal(t, models.AlarmStateAcknowledged, alarm.State)
+		assert.Equal(t, "alice", alarm.AcknowledgedBy)
+	})
+
+	// 2. Unknown alarm
+	t.Run("NotFound", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/alarms/2/acknowledge", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code, "Expected HTTP 404 Not Found")
+	})
+}
+
+func TestAlarmRuleHandlers(t *testing.T) {
+	router, _ := setupAlarmRouter()
+
+	// 1. Valid rule
+	t.Run("Create", func(t *testing.T) {
+		body := `{"name": "Hot", "kind": "threshold", "metric": "temperature", "operator": ">", "threshold": 80, "for_seconds": 30}`
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/alarm-rules", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code, "Expected HTTP 201 Created")
+	})
+
+	// 2. Rule the service cannot evaluate
+	t.Run("Invalid", func(t *testing.T) {
+		body := `{"name": "Hot", "kind": "threshold", "metric": "temperature", "operator": "~"}`
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/alarm-rules", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, "Expected HTTP 400 Bad Request")
+	})
+
+	// 3. Delete known and unknown rules
+	t.Run("Delete", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/api/v1/alarm-rules/1", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code, "Expected HTTP 204 No Content")
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("DELETE", "/api/v1/alarm-rules/5", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, "Expected HTTP 404 Not Found")
+	})
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler contains the service interface for dependency injection
+type APIKeyHandler struct {
+	Service service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new handler instance
+func NewAPIKeyHandler(s service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{Service: s}
+}
+
+// IssueKey handles POST /api/v1/api-keys. The response is the only time the key is shown.
+func (h *APIKeyHandler) IssueKey(c *gin.Context) {
+	issued, err := forRequest(c, h.Service).IssueKey(requestBody[service.IssueKeyRequest](c))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAPIKeyRequest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to issue API key", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue API key"})
+		return
+	}
+	c.JSON(http.StatusCreated, issued)
+}
+
+// GetKeys handles GET /api/v1/api-keys
+func (h *APIKeyHandler) GetKeys(c *gin.Context) {
+	keys, err := forRequest(c, h.Service).GetKeys()
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to retrieve API keys", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeKey handles DELETE /api/v1/api-keys/:id
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	id := pathID(c, "id")
+	if err := forRequest(c, h.Service).RevokeKey(id); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to revoke API key", "key_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
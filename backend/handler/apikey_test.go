@@ -0,0 +1,92 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockAPIKeyService accepts a key per scope, named after it, and an operator key, and knows API key 1
+type MockAPIKeyService struct{}
+
+func (m *MockAPIKeyService) ForTenant(uint) service.APIKeyService              { return m }
+func (m *MockAPIKeyService) WithContext(context.Context) service.APIKeyService { return m }
+
+func (m *MockAPIKeyService) IssueKey(req service.IssueKeyRequest) (service.IssuedKey, error) {
+	if len(req.Scopes) == 0 && len(req.Roles) == 0 {
+		return service.IssuedKey{}, fmt.Errorf("%w: at least one scope or role is required", service.ErrInvalidAPIKeyRequest)
+	}
+	if req.Name == "taken" {
+		return service.IssuedKey{}, fmt.Errorf("%w: name is taken", service.ErrInvalidAPIKeyRequest)
+	}
+	key := models.APIKey{Model: models.Model{ID: 2}, Name: req.Name, Prefix: "ak_new", Scopes: req.Scopes}
+	return service.IssuedKey{APIKey: key, Key: "ak_new-secret"}, nil
+}
+func (m *MockAPIKeyService) GetKeys() ([]models.APIKey, error) {
+	return []models.APIKey{{Model: models.Model{ID: 1}, Name: "admin", Prefix: "ak_admin", Scopes: []string{models.APIKeyScopeAdmin}}}, nil
+}
+func (m *MockAPIKeyService) RevokeKey(id uint) error {
+	if id != 1 {
+		return service.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// mockKeyIDs tells the identities of the mock keys apart
+var mockKeyIDs = map[string]uint{"ak_read": 11, "ak_write": 12, "ak_admin": 13, "ak_operator": 14, "ak_acme_admin": 15}
+
+func (m *MockAPIKeyService) Authenticate(key string) (models.APIKey, error) {
+	id := models.Model{ID: mockKeyIDs[key]}
+	switch key {
+	case "ak_read", "ak_write", "ak_admin":
+		return models.APIKey{Model: id, Name: key, OrganizationID: models.DefaultOrganizationID, Scopes: []string{key[3:]}}, nil
+	case "ak_operator":
+		return models.APIKey{Model: id, Name: key, OrganizationID: models.DefaultOrganizationID, Roles: []string{models.RoleOperator}}, nil
+	case "ak_acme_admin":
+		return models.APIKey{Model: id, Name: key, OrganizationID: 2, Scopes: []string{models.APIKeyScopeAdmin}}, nil
+	case "ak_revoked":
+		return models.APIKey{}, fmt.Errorf("%w: the key has been revoked", service.ErrInvalidAPIKey)
+	}
+	return models.APIKey{}, service.ErrInvalidAPIKey
+}
+
+func TestAPIKeyHandlers(t *testing.T) {
+	router := setupAuthRouter()
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	cases := []struct {
+		method, path, body string
+		code               int
+	}{
+		{"POST", "/api/v1/api-keys", `{"name": "ci", "scopes": ["read", "write"], "expires_at": "` + expires + `"}`, http.StatusCreated},
+		{"POST", "/api/v1/api-keys", `{"name": "ci", "scopes": ["root"]}`, http.StatusBadRequest},
+		{"POST", "/api/v1/api-keys", `{"name": "panel", "roles": ["operator"]}`, http.StatusCreated},
+		{"POST", "/api/v1/api-keys", `{"name": "panel", "roles": ["superuser"]}`, http.StatusBadRequest},
+		{"POST", "/api/v1/api-keys", `{"name": "ci"}`, http.StatusBadRequest},
+		{"POST", "/api/v1/api-keys", `{"name": "taken", "scopes": ["read"]}`, http.StatusBadRequest},
+		{"GET", "/api/v1/api-keys", "", http.StatusOK},
+		{"DELETE", "/api/v1/api-keys/1", "", http.StatusNoContent},
+		{"DELETE", "/api/v1/api-keys/2", "", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Authorization", "Bearer ak_admin")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, "Unexpected status for %s %s: %s", tc.method, tc.path, w.Body.String())
+		if tc.code == http.StatusCreated {
+			assert.Contains(t, w.Body.String(), `"key":"ak_new-secret"`, "The key is shown when issued")
+		}
+		if tc.method == "GET" {
+			assert.NotContains(t, w.Body.String(), "hash", "Hashes are never returned")
+		}
+	}
+}
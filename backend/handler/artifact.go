@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ArtifactHandler contains the service interface for dependency injection
+type ArtifactHandler struct {
+	Service service.ArtifactService
+}
+
+// NewArtifactHandler creates a new handler instance
+func NewArtifactHandler(s service.ArtifactService) *ArtifactHandler {
+	return &ArtifactHandler{Service: s}
+}
+
+// GetRunArtifacts handles GET /api/v1/runs/:id/artifacts
+func (h *ArtifactHandler) GetRunArtifacts(c *gin.Context) {
+	id := pathID(c, "id")
+	artifacts, err := forRequest(c, h.Service).GetRunArtifacts(id)
+	if errors.Is(err, service.ErrRunNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Run not found"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to retrieve artifacts", "run_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve artifacts"})
+		return
+	}
+	c.JSON(http.StatusOK, artifacts)
+}
+
+// DownloadArtifact handles GET /api/v1/runs/:id/artifacts/:artifactId, streaming the contents.
+// The SHA-256 checksum is sent as ETag and X-Checksum-SHA256 so clients can verify the download.
+func (h *ArtifactHandler) DownloadArtifact(c *gin.Context) {
+	runID, artifactID := pathID(c, "id"), pathID(c, "artifactId")
+	a, r, err := forRequest(c, h.Service).OpenArtifact(runID, artifactID)
+	if errors.Is(err, service.ErrArtifactNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artifact not found"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to open artifact", "artifact_id", artifactID, "run_id", runID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open artifact"})
+		return
+	}
+	defer r.Close()
+
+	etag := fmt.Sprintf("%q", a.SHA256)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.DataFromReader(http.StatusOK, a.Size, a.ContentType, r, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(a.Name)}),
+		"ETag":                etag,
+		"X-Checksum-SHA256":   a.SHA256,
+	})
+}
@@ -0,0 +1,90 @@
+package handler_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockArtifactService serves artifact 1 of run 1
+type MockArtifactService struct{}
+
+var testArtifact = models.Artifact{
+	Model:       models.Model{ID: 1},
+	RunID:       1,
+	Name:        "plots/results.csv",
+	Size:        8,
+	SHA256:      "0e0a1b2c",
+	ContentType: "text/csv",
+}
+
+func (m *MockArtifactService) ForTenant(uint) service.ArtifactService              { return m }
+func (m *MockArtifactService) WithContext(context.Context) service.ArtifactService { return m }
+
+func (m *MockArtifactService) Collect(ctx context.Context, runID uint, dir string) error { return nil }
+func (m *MockArtifactService) GetRunArtifacts(runID uint) ([]models.Artifact, error) {
+	if runID != 1 {
+		return nil, service.ErrRunNotFound
+	}
+	return []models.Artifact{testArtifact}, nil
+}
+func (m *MockArtifactService) OpenArtifact(runID, artifactID uint) (models.Artifact, io.ReadCloser, error) {
+	if runID != 1 || artifactID != 1 {
+		return models.Artifact{}, nil, service.ErrArtifactNotFound
+	}
+	return testArtifact, io.NopCloser(strings.NewReader("a,b\n1,2\n")), nil
+}
+func (m *MockArtifactService) PurgeOlderThan(maxAge time.Duration) (int64, error) { return 0, nil }
+func (m *MockArtifactService) StartRetention(maxAge, interval time.Duration)      {}
+
+func TestArtifactHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	artifactHandler := handler.NewArtifactHandler(&MockArtifactService{})
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Artifact: artifactHandler}))
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{"/api/v1/runs/1/artifacts", http.StatusOK},
+		{"/api/v1/runs/2/artifacts", http.StatusNotFound},
+		{"/api/v1/runs/abc/artifacts", http.StatusBadRequest},
+		{"/api/v1/runs/1/artifacts/2", http.StatusNotFound},
+		{"/api/v1/runs/2/artifacts/1", http.StatusNotFound},
+		{"/api/v1/runs/1/artifacts/abc", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tc.path, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, "Unexpected status for %s", tc.path)
+	}
+
+	t.Run("Download", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/runs/1/artifacts/1", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "a,b\n1,2\n", w.Body.String())
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		assert.Equal(t, "8", w.Header().Get("Content-Length"))
+		assert.Equal(t, "attachment; filename=results.csv", w.Header().Get("Content-Disposition"))
+		assert.Equal(t, testArtifact.SHA256, w.Header().Get("X-Checksum-SHA256"))
+
+		w = httptest.NewRecorder()
+		req.Header.Set("If-None-Match", `"`+testArtifact.SHA256+`"`)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	})
+}
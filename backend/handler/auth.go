@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// identityKey is the context key of the identity a request was authenticated as
+const identityKey = "handler.identity"
+
+// RequireAuth admits requests to routes with a scope only with credentials granting it, sent
+// as "Authorization: Bearer <credential>": an API key, or a JWT from the identity provider if
+// tokens is not nil. Requests without valid credentials get 401, requests whose credentials
+// lack the scope or the permission of the route 403. Requests that may change state are written to the audit log.
+func RequireAuth(keys service.APIKeyService, tokens service.TokenService) RouteMiddleware {
+	return func(r Route) gin.HandlerFunc {
+		if r.Scope == "" {
+			return nil
+		}
+		return func(c *gin.Context) {
+			raw, ok := bearerToken(c.GetHeader("Authorization"))
+			if !ok {
+				c.Header("WWW-Authenticate", `Bearer realm="automation-backend"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key or token required"})
+				return
+			}
+			identity, err := authenticate(c, keys, tokens, raw)
+			if errors.Is(err, service.ErrInvalidAPIKey) || errors.Is(err, service.ErrInvalidToken) {
+				c.Header("WWW-Authenticate", `Bearer realm="automation-backend", error="invalid_token"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				slog.ErrorContext(c.Request.Context(), "Failed to authenticate request", "error", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+				return
+			}
+			if !identity.HasScope(r.Scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "requires the " + r.Scope + " scope"})
+				return
+			}
+			if r.Permission != "" && !identity.Can(r.Permission) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "requires the " + r.Permission + " permission"})
+				return
+			}
+			c.Set(identityKey, identity)
+			c.Next()
+
+			if c.Request.Method != http.MethodGet {
+				slog.InfoContext(c.Request.Context(), "Audit", "method", c.Request.Method, "path", c.Request.URL.Path,
+					"action", r.Summary, "identity", identity.String(), "status", c.Writer.Status())
+			}
+		}
+	}
+}
+
+// CurrentIdentity returns who made a request, if it was authenticated
+func CurrentIdentity(c *gin.Context) (service.Identity, bool) {
+	identity, ok := c.Get(identityKey)
+	if !ok {
+		return service.Identity{}, false
+	}
+	return identity.(service.Identity), true
+}
+
+// tenant returns the organization a request works with, or 0 for every organization when
+// requests are not authenticated
+func tenant(c *gin.Context) uint {
+	identity, ok := CurrentIdentity(c)
+	if !ok {
+		return 0
+	}
+	if identity.OrganizationID == 0 {
+		return models.DefaultOrganizationID
+	}
+	return identity.OrganizationID
+}
+
+// requestScoped is a service that can be limited to an organization and bound to a request
+type requestScoped[S any] interface {
+	ForTenant(organizationID uint) S
+	WithContext(ctx context.Context) S
+}
+
+// forRequest returns a service limited to the organization of a request, logging with its
+// request ID
+func forRequest[S requestScoped[S]](c *gin.Context, s S) S {
+	return s.ForTenant(tenant(c)).WithContext(c.Request.Context())
+}
+
+// requirePlatformAdmin answers 403 unless the caller administers the whole backend or
+// requests are not authenticated, reporting whether the request may go on
+func requirePlatformAdmin(c *gin.Context) bool {
+	if !platformAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires an admin of the " + models.DefaultOrganizationName + " organization"})
+		return false
+	}
+	return true
+}
+
+// platformAdmin reports whether the caller administers the whole backend or requests are not
+// authenticated
+func platformAdmin(c *gin.Context) bool {
+	identity, ok := CurrentIdentity(c)
+	return !ok || identity.PlatformAdmin()
+}
+
+// authenticate tells API keys from JWTs by shape: JWTs are three dot separated parts, and
+// API keys contain no dots
+func authenticate(c *gin.Context, keys service.APIKeyService, tokens service.TokenService, raw string) (service.Identity, error) {
+	if strings.Count(raw, ".") == 2 {
+		if tokens == nil {
+			return service.Identity{}, service.ErrInvalidToken
+		}
+		return tokens.Authenticate(c.Request.Context(), raw)
+	}
+	key, err := keys.WithContext(c.Request.Context()).Authenticate(raw)
+	if err != nil {
+		return service.Identity{}, err
+	}
+	return service.APIKeyIdentity(key), nil
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,200 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockTokenService accepts one token, of a reader
+type MockTokenService struct{}
+
+func (m *MockTokenService) Authenticate(ctx context.Context, raw string) (service.Identity, error) {
+	if raw != "reader.token.sig" {
+		return service.Identity{}, service.ErrInvalidToken
+	}
+	return service.Identity{Subject: "alice", Method: service.AuthMethodToken, Scopes: []string{"read"}}, nil
+}
+
+func setupAuthRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	keys := &MockAPIKeyService{}
+	handlers := handler.Handlers{
+		Machine: handler.NewMachineHandler(service.NewMachineService(&MockMachineRepository{})),
+		APIKey:  handler.NewAPIKeyHandler(keys),
+
+		Organization: handler.NewOrganizationHandler(&MockOrganizationService{}),
+	}
+	handler.RegisterRoutes(router, handler.Routes(handlers), handler.RequireAuth(keys, &MockTokenService{}))
+	return router
+}
+
+func TestRequireAuth(t *testing.T) {
+	router := setupAuthRouter()
+
+	cases := []struct {
+		name, method, path, auth string
+		code                     int
+	}{
+		{"PublicRoute", "GET", "/health", "", http.StatusOK},
+		{"MissingKey", "GET", "/api/v1/machines", "", http.StatusUnauthorized},
+		{"WrongScheme", "GET", "/api/v1/machines", "Basic ak_read", http.StatusUnauthorized},
+		{"UnknownKey", "GET", "/api/v1/machines", "Bearer ak_guess", http.StatusUnauthorized},
+		{"RevokedKey", "GET", "/api/v1/machines", "Bearer ak_revoked", http.StatusUnauthorized},
+		{"ReadKeyReads", "GET", "/api/v1/machines/1", "Bearer ak_read", http.StatusOK},
+		{"ReadKeyCannotWrite", "DELETE", "/api/v1/machines/1", "Bearer ak_read", http.StatusForbidden},
+		{"WriteKeyWrites", "DELETE", "/api/v1/machines/1", "bearer ak_write", http.StatusNoContent},
+		{"WriteKeyCannotManageKeys", "GET", "/api/v1/api-keys", "Bearer ak_write", http.StatusForbidden},
+		{"AdminKeyManagesKeys", "GET", "/api/v1/api-keys", "Bearer ak_admin", http.StatusOK},
+		{"AuthenticatedBeforeValidated", "GET", "/api/v1/machines/abc", "", http.StatusUnauthorized},
+		{"TokenReads", "GET", "/api/v1/machines/1", "Bearer reader.token.sig", http.StatusOK},
+		{"TokenCannotWrite", "DELETE", "/api/v1/machines/1", "Bearer reader.token.sig", http.StatusForbidden},
+		{"InvalidToken", "GET", "/api/v1/machines/1", "Bearer forged.token.sig", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.code, w.Code, w.Body.String())
+			if tc.code == http.StatusUnauthorized {
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestCurrentIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	route := handler.Route{}
+	route.Scope = "read"
+	var seen service.Identity
+	router.GET("/whoami", handler.RequireAuth(&MockAPIKeyService{}, nil)(route), func(c *gin.Context) {
+		seen, _ = handler.CurrentIdentity(c)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer ak_write")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "ak_write", seen.Name)
+	assert.Equal(t, service.AuthMethodAPIKey, seen.Method)
+
+	w = httptest.NewRecorder()
+	req.Header.Set("Authorization", "Bearer reader.token.sig")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "Tokens are rejected without a token service")
+}
+
+func TestMachineRoles(t *testing.T) {
+	router := setupAuthRouter()
+
+	cases := []struct {
+		name, method, path, body string
+		code                     int
+	}{
+		{"OperatorStarts", "PUT", "/api/v1/machines/1", `{"name": "TestMachine", "status": "Running"}`, http.StatusOK},
+		{"OperatorRunsCommands", "POST", "/api/v1/machines/commands", `{"command": "stop", "ids": [1]}`, http.StatusOK},
+		{"OperatorCannotConfigure", "PUT", "/api/v1/machines/1", `{"name": "TestMachine", "status": "Idle", "config_json": "{}"}`, http.StatusForbidden},
+		{"OperatorCannotCreate", "POST", "/api/v1/machines", `{"name": "New"}`, http.StatusForbidden},
+		{"OperatorCannotDelete", "DELETE", "/api/v1/machines/1", "", http.StatusForbidden},
+		{"OperatorCannotSubmitRuns", "POST", "/api/v1/machines/1/runs", `{"params": {"speed": 2}}`, http.StatusForbidden},
+		{"OperatorCannotSubmitBatches", "POST", "/api/v1/machines/1/batches", `{}`, http.StatusForbidden},
+		{"OperatorCannotInjectFaults", "POST", "/api/v1/machines/1/faults", `{"type": "fail_runs"}`, http.StatusForbidden},
+		{"OperatorCannotClearFaults", "DELETE", "/api/v1/machines/1/faults", "", http.StatusForbidden},
+		{"OperatorCannotMoveMachines", "PUT", "/api/v1/machines/1/group", `{"group_id": 1}`, http.StatusForbidden},
+		{"OperatorCannotCreateWorkflows", "POST", "/api/v1/workflows", `{"name": "w"}`, http.StatusForbidden},
+		{"OperatorCannotStartWorkflows", "POST", "/api/v1/workflows/1/runs", "", http.StatusForbidden},
+		{"OperatorCannotCreateAlarmRules", "POST", "/api/v1/alarm-rules", `{"metric": "temperature"}`, http.StatusForbidden},
+		{"OperatorCannotDeleteAlarmRules", "DELETE", "/api/v1/alarm-rules/1", "", http.StatusForbidden},
+		{"OperatorCannotCreateGroups", "POST", "/api/v1/groups", `{"name": "Site"}`, http.StatusForbidden},
+		{"OperatorCannotDeleteGroups", "DELETE", "/api/v1/groups/1", "", http.StatusForbidden},
+		{"OperatorCannotLinkMachines", "POST", "/api/v1/links", `{"from_id": 1, "to_id": 2}`, http.StatusForbidden},
+		{"OperatorCannotUnlinkMachines", "DELETE", "/api/v1/links/1", "", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer ak_operator")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.code, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestOrganizationIsolation(t *testing.T) {
+	router := setupAuthRouter()
+
+	cases := []struct {
+		name, method, path, auth, body string
+		code                           int
+	}{
+		{"OwnMachine", "GET", "/api/v1/machines/1", "Bearer ak_read", "", http.StatusOK},
+		{"OtherOrganizationsMachine", "GET", "/api/v1/machines/1", "Bearer ak_acme_admin", "", http.StatusNotFound},
+		{"MissingMachine", "PUT", "/api/v1/machines/99", "Bearer ak_admin", `{"name": "Press"}`, http.StatusNotFound},
+		{"CannotUpdateOtherOrganizationsMachine", "PUT", "/api/v1/machines/1", "Bearer ak_acme_admin", `{"name": "Press"}`, http.StatusNotFound},
+		{"CannotDeleteOtherOrganizationsMachine", "DELETE", "/api/v1/machines/1", "Bearer ak_acme_admin", "", http.StatusNotFound},
+		{"OrganizationAdminCannotPauseSimulator", "POST", "/api/v1/simulator/pause", "Bearer ak_acme_admin", "", http.StatusForbidden},
+		{"OrganizationAdminCannotCreateOrganizations", "GET", "/api/v1/organizations", "Bearer ak_acme_admin", "", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", tc.auth)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.code, w.Code, w.Body.String())
+		})
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/machines", nil)
+	req.Header.Set("Authorization", "Bearer ak_acme_admin")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "[]", w.Body.String(), "Organizations only list their own machines")
+}
+
+func TestSimulatorStatusIsolation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	keys := &MockAPIKeyService{}
+	sim := simulation.NewMachineSimulator(&MockMachineRepository{}, nil, nil)
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Simulator: handler.NewSimulatorHandler(sim)}), handler.RequireAuth(keys, &MockTokenService{}))
+	// Starts a worker for the Idle mock machine, which belongs to the default organization
+	sim.Reconcile()
+
+	status := func(key string) (simulation.Status, string) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/simulator", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result simulation.Status
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result, w.Body.String()
+	}
+
+	result, _ := status("ak_admin")
+	assert.Len(t, result.Workers, 1, "Platform admins see every worker")
+
+	result, body := status("ak_acme_admin")
+	assert.Empty(t, result.Workers, "Other organizations do not see the workers of the default organization's machines")
+	assert.NotContains(t, body, "machine_id")
+	assert.Equal(t, 1, result.ActiveWorkers, "The worker count is all other organizations see")
+}
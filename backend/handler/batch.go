@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// BatchHandler contains the service interface for dependency injection
+type BatchHandler struct {
+	Service service.BatchService
+}
+
+// NewBatchHandler creates a new handler instance
+func NewBatchHandler(s service.BatchService) *BatchHandler {
+	return &BatchHandler{Service: s}
+}
+
+// SubmitBatch handles POST /api/v1/machines/:id/batches
+func (h *BatchHandler) SubmitBatch(c *gin.Context) {
+	id := pathID(c, "id")
+	batch, err := forRequest(c, h.Service).SubmitBatch(id, requestBody[service.BatchRequest](c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrMachineNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Machine not found"})
+		case errors.Is(err, service.ErrInvalidBatch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrQuotaExceeded):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			slog.ErrorContext(c.Request.Context(), "Failed to submit batch", "machine_id", id, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit batch"})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, batch)
+}
+
+// GetBatch handles GET /api/v1/batches/:id, including progress and per-point results
+func (h *BatchHandler) GetBatch(c *gin.Context) {
+	id := pathID(c, "id")
+	batch, err := forRequest(c, h.Service).GetBatch(id)
+	if err != nil {
+		if errors.Is(err, service.ErrBatchNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to retrieve batch", "batch_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve batch"})
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
@@ -0,0 +1,66 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockBatchService serves batch 1 and accepts every submission for machines other than 99
+type MockBatchService struct{}
+
+func (m *MockBatchService) ForTenant(uint) service.BatchService              { return m }
+func (m *MockBatchService) WithContext(context.Context) service.BatchService { return m }
+
+func (m *MockBatchService) SubmitBatch(machineID uint, req service.BatchRequest) (models.Batch, error) {
+	if machineID == 99 {
+		return models.Batch{}, service.ErrMachineNotFound
+	}
+	if req.Mode == "spiral" {
+		return models.Batch{}, service.ErrInvalidBatch
+	}
+	return models.Batch{Model: models.Model{ID: 1}, MachineID: machineID, Mode: req.Mode, Status: models.BatchStatusRunning}, nil
+}
+func (m *MockBatchService) GetBatch(id uint) (service.BatchResult, error) {
+	if id != 1 {
+		return service.BatchResult{}, service.ErrBatchNotFound
+	}
+	return service.BatchResult{Batch: models.Batch{Model: models.Model{ID: 1}}}, nil
+}
+func (m *MockBatchService) ResumeBatches() error { return nil }
+
+func TestBatchHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	batchHandler := handler.NewBatchHandler(&MockBatchService{})
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Batch: batchHandler}))
+
+	cases := []struct {
+		method, path, body string
+		code               int
+	}{
+		{"POST", "/api/v1/machines/1/batches", `{"mode": "grid", "parameters": {"load": [0.5, 1]}}`, http.StatusAccepted},
+		{"POST", "/api/v1/machines/1/batches", `{"mode": "spiral"}`, http.StatusBadRequest},
+		{"POST", "/api/v1/machines/1/batches", `not json`, http.StatusBadRequest},
+		{"POST", "/api/v1/machines/99/batches", `{"mode": "list"}`, http.StatusNotFound},
+		{"POST", "/api/v1/machines/abc/batches", `{}`, http.StatusBadRequest},
+		{"GET", "/api/v1/batches/1", "", http.StatusOK},
+		{"GET", "/api/v1/batches/2", "", http.StatusNotFound},
+		{"GET", "/api/v1/batches/abc", "", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, "Unexpected status for %s %s", tc.method, tc.path)
+	}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/openapi"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// docsPage loads the embedded Swagger UI and points it at the OpenAPI document
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>automation-backend API</title>
+  <link rel="stylesheet" type="text/css" href="/docs/swagger-ui.css">
+  <link rel="icon" type="image/png" href="/docs/favicon-32x32.png" sizes="32x32">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/swagger-ui-bundle.js" charset="UTF-8"></script>
+  <script src="/docs/swagger-ui-standalone-preset.js" charset="UTF-8"></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({
+        url: "/openapi.json",
+        dom_id: "#swagger-ui",
+        deepLinking: true,
+        persistAuthorization: true,
+        presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+        layout: "StandaloneLayout"
+      });
+    };
+  </script>
+</body>
+</html>
+`
+
+// DocsHandler serves the OpenAPI document and a Swagger UI to browse it
+type DocsHandler struct {
+	Spec []byte
+}
+
+// NewDocsHandler creates a new handler instance serving the given document
+func NewDocsHandler(doc *openapi.Document) (*DocsHandler, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &DocsHandler{Spec: spec}, nil
+}
+
+// Register serves GET /openapi.json, GET /docs and the Swagger UI assets under /docs/
+func (h *DocsHandler) Register(router gin.IRoutes) {
+	router.GET("/openapi.json", h.GetSpec)
+	router.GET("/docs", h.GetDocs)
+	router.GET("/docs/*filepath", h.GetAsset)
+}
+
+// GetSpec handles GET /openapi.json
+func (h *DocsHandler) GetSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.Spec)
+}
+
+// GetDocs handles GET /docs
+func (h *DocsHandler) GetDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+// GetAsset handles GET /docs/*filepath, serving the files of the Swagger UI
+func (h *DocsHandler) GetAsset(c *gin.Context) {
+	if c.Param("filepath") == "/" {
+		h.GetDocs(c)
+		return
+	}
+	c.FileFromFS(c.Param("filepath"), http.FS(swaggerFiles.FS))
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/gin-gonic/gin"
+)
+
+// FaultHandler exposes the simulator's fault injection for chaos testing
+type FaultHandler struct {
+	Simulator *simulation.MachineSimulator
+	Machines  service.MachineService
+}
+
+// NewFaultHandler creates a new handler instance
+func NewFaultHandler(sim *simulation.MachineSimulator, machines service.MachineService) *FaultHandler {
+	return &FaultHandler{Simulator: sim, Machines: machines}
+}
+
+// machineID returns the :id path parameter if the machine exists, writing the error response if not
+func (h *FaultHandler) machineID(c *gin.Context) (uint, bool) {
+	id := pathID(c, "id")
+	if _, err := forRequest(c, h.Machines).GetMachineByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Machine not found"})
+		return 0, false
+	}
+	return id, true
+}
+
+// InjectFault handles POST /api/v1/machines/:id/faults
+func (h *FaultHandler) InjectFault(c *gin.Context) {
+	id, ok := h.machineID(c)
+	if !ok {
+		return
+	}
+
+	fault, err := h.Simulator.InjectFault(id, requestBody[simulation.FaultSpec](c))
+	if err != nil {
+		if errors.Is(err, simulation.ErrInvalidFault) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to inject fault", "machine_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to inject fault"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, fault)
+}
+
+// GetFaults handles GET /api/v1/machines/:id/faults
+func (h *FaultHandler) GetFaults(c *gin.Context) {
+	id, ok := h.machineID(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.Simulator.Faults.Faults(id))
+}
+
+// ClearFaults handles DELETE /api/v1/machines/:id/faults
+func (h *FaultHandler) ClearFaults(c *gin.Context) {
+	id, ok := h.machineID(c)
+	if !ok {
+		return
+	}
+	h.Simulator.Faults.Clear(id)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// RemoveFault handles DELETE /api/v1/machines/:id/faults/:faultId
+func (h *FaultHandler) RemoveFault(c *gin.Context) {
+	id, ok := h.machineID(c)
+	if !ok {
+		return
+	}
+	if !h.Simulator.Faults.Remove(id, pathID(c, "faultId")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fault not found"})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// GetChaos handles GET /api/v1/simulator/chaos
+func (h *FaultHandler) GetChaos(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Simulator.Faults.Chaos())
+}
+
+// SetChaos handles PUT /api/v1/simulator/chaos
+func (h *FaultHandler) SetChaos(c *gin.Context) {
+	if !requirePlatformAdmin(c) {
+		return
+	}
+	profile := requestBody[simulation.ChaosProfile](c)
+	if err := h.Simulator.Faults.SetChaos(profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "Chaos profile updated", "profile", profile)
+	c.JSON(http.StatusOK, profile)
+}
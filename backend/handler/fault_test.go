@@ -0,0 +1,98 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupFaultRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	mockRepo := &MockMachineRepository{}
+	sim := simulation.NewMachineSimulator(mockRepo, nil, nil)
+	faultHandler := handler.NewFaultHandler(sim, service.NewMachineService(mockRepo))
+
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Fault: faultHandler}))
+	return router
+}
+
+func TestFaultHandlers(t *testing.T) {
+	router := setupFaultRouter()
+
+	// 1. Inject, list and remove a fault
+	t.Run("Lifecycle", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/machines/1/faults", bytes.NewBufferString(`{"type": "slow", "factor": 4, "duration": "5m"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code, "Expected HTTP 201 Created")
+
+		var fault simulation.Fault
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &fault))
+		assert.Equal(t, 4.0, fault.Factor)
+		assert.NotNil(t, fault.ExpiresAt)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/v1/machines/1/faults", nil)
+		router.ServeHTTP(w, req)
+		var faults []simulation.Fault
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &faults))
+		assert.Len(t, faults, 1)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("DELETE", "/api/v1/machines/1/faults/1", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code, "Expected HTTP 204 No Content")
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("DELETE", "/api/v1/machines/1/faults/1", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, "Expected HTTP 404 Not Found")
+	})
+
+	// 2. Invalid specs and unknown machines
+	t.Run("Invalid", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/machines/1/faults", bytes.NewBufferString(`{"type": "fail_runs"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "Expected HTTP 400 Bad Request")
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/api/v1/machines/99/faults", bytes.NewBufferString(`{"type": "hang"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, "Expected HTTP 404 Not Found")
+	})
+
+	// 3. Chaos profile
+	t.Run("Chaos", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/api/v1/simulator/chaos", bytes.NewBufferString(`{"error_rate": 0.5, "slow_rate": 0.1, "slow_factor": 3}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, "Expected HTTP 200 OK")
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/v1/simulator/chaos", nil)
+		router.ServeHTTP(w, req)
+		var profile simulation.ChaosProfile
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &profile))
+		assert.Equal(t, 0.5, profile.ErrorRate)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("PUT", "/api/v1/simulator/chaos", bytes.NewBufferString(`{"error_rate": 2}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "Expected HTTP 400 Bad Request")
+	})
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// JobHandler contains the service interface for dependency injection
+type JobHandler struct {
+	Service service.JobService
+}
+
+// NewJobHandler creates a new handler instance
+func NewJobHandler(s service.JobService) *JobHandler {
+	return &JobHandler{Service: s}
+}
+
+// SubmitRun handles POST /api/v1/machines/:id/runs
+func (h *JobHandler) SubmitRun(c *gin.Context) {
+	id := pathID(c, "id")
+	// An empty body submits a run with the machine's own config
+	job, err := forRequest(c, h.Service).SubmitRun(id, requestBody[service.RunRequest](c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrMachineNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Machine not found"})
+		case errors.Is(err, service.ErrInvalidJob):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrQuotaExceeded):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			slog.ErrorContext(c.Request.Context(), "Failed to submit run", "machine_id", id, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit run"})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetJob handles GET /api/v1/jobs/:id
+func (h *JobHandler) GetJob(c *gin.Context) {
+	id := pathID(c, "id")
+	job, err := forRequest(c, h.Service).GetJob(id)
+	if err != nil {
+		if errors.Is(err, service.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to retrieve job", "job_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve job"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// GetJobs handles GET /api/v1/jobs with optional ?machine_id=, ?status= and ?limit= filters
+func (h *JobHandler) GetJobs(c *gin.Context) {
+	filter := queue.Filter{Status: c.Query("status")}
+	filter.MachineID, _ = queryUint(c, "machine_id")
+	limit, _ := queryUint(c, "limit")
+	filter.Limit = int(limit)
+
+	jobs, err := forRequest(c, h.Service).ListJobs(filter)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to list jobs", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve jobs"})
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
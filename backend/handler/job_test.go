@@ -0,0 +1,131 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockQueue stores enqueued jobs in memory
+type MockQueue struct {
+	Jobs   []models.Job
+	Filter queue.Filter
+}
+
+func (m *MockQueue) Enqueue(job *models.Job) error {
+	job.ID = uint(len(m.Jobs) + 1)
+	job.Status = models.JobStatusPending
+	m.Jobs = append(m.Jobs, *job)
+	return nil
+}
+func (m *MockQueue) Lease(owner string, visibility time.Duration) (*models.Job, error) {
+	return nil, queue.ErrEmpty
+}
+func (m *MockQueue) Ack(jobID uint, owner string, runID *uint) error    { return nil }
+func (m *MockQueue) Nack(jobID uint, owner string, reason string) error { return nil }
+func (m *MockQueue) Get(jobID uint) (*models.Job, error) {
+	if jobID == 0 || int(jobID) > len(m.Jobs) {
+		return nil, queue.ErrJobNotFound
+	}
+	return &m.Jobs[jobID-1], nil
+}
+func (m *MockQueue) List(filter queue.Filter) ([]models.Job, error) {
+	m.Filter = filter
+	var jobs []models.Job
+	for _, job := range m.Jobs {
+		if filter.Status == "" || job.Status == filter.Status {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+func (m *MockQueue) Depth() (int64, error) { return int64(len(m.Jobs)), nil }
+
+func TestJobHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	q := &MockQueue{}
+	jobHandler := handler.NewJobHandler(service.NewJobService(q, &MockMachineRepository{}, 0))
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Job: jobHandler}))
+
+	submit := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := submit("/api/v1/machines/1/runs", `{"params": {"load": 0.8}, "max_attempts": 5}`)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var job models.Job
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, models.JobStatusPending, job.Status)
+	assert.JSONEq(t, `{"load": 0.8}`, job.Params)
+	assert.Equal(t, 5, job.MaxAttempts)
+
+	assert.Equal(t, http.StatusAccepted, submit("/api/v1/machines/1/runs", "").Code, "An empty body should run with the machine's config")
+	assert.Equal(t, http.StatusBadRequest, submit("/api/v1/machines/1/runs", `{"params": [1]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, submit("/api/v1/machines/1/runs", `{"max_attempts": 100}`).Code)
+	for _, params := range []string{`{"runner": "run"}`, `{"timeout": "1h"}`, `{"command": ["sh"]}`} {
+		w := submit("/api/v1/machines/1/runs", `{"params": `+params+`}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code, "Runs cannot change what they execute: %s", params)
+		assert.Contains(t, w.Body.String(), "params cannot override")
+	}
+	assert.Equal(t, http.StatusNotFound, submit("/api/v1/machines/99/runs", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, submit("/api/v1/machines/abc/runs", `{}`).Code)
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{"/api/v1/jobs/1", http.StatusOK},
+		{"/api/v1/jobs/9", http.StatusNotFound},
+		{"/api/v1/jobs/abc", http.StatusBadRequest},
+		{"/api/v1/jobs?machine_id=1&status=Dead&limit=5", http.StatusOK},
+		{"/api/v1/jobs?status=Unknown", http.StatusBadRequest},
+		{"/api/v1/jobs?machine_id=abc", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tc.path, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, "Unexpected status for %s", tc.path)
+	}
+	assert.Equal(t, queue.Filter{MachineID: 1, Status: models.JobStatusDead, Limit: 5}, q.Filter)
+}
+
+func TestSubmitRunQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	q := &MockQueue{}
+	jobHandler := handler.NewJobHandler(service.NewJobService(q, &MockMachineRepository{}, 2))
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Job: jobHandler}))
+
+	submit := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/machines/1/runs", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusAccepted, submit().Code)
+	assert.Equal(t, http.StatusAccepted, submit().Code)
+	w := submit()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "The quota caps the runs waiting at once")
+	assert.Contains(t, w.Body.String(), "at most 2 runs")
+	assert.Len(t, q.Jobs, 2)
+
+	q.Jobs[0].Status = models.JobStatusSucceeded
+	assert.Equal(t, http.StatusAccepted, submit().Code, "Finished runs free up the quota")
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID of a request, chosen by the client or generated
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs chosen by clients
+const maxRequestIDLength = 64
+
+// RequestID gives every request an ID, taken from its X-Request-ID header when the client
+// sent a usable one, and echoes it in the response. Records logged with the request's
+// context are tagged with it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// validRequestID accepts IDs that are safe to log: short, and made of letters, digits and
+// a few separators
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	return strings.IndexFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.:", r))
+	}) < 0
+}
+
+// AccessLog logs every request once it was answered, at error level if it failed on the
+// server. It must come after RequestID so that records carry the request's ID.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"status", status,
+			"duration", time.Since(start),
+			"client_ip", c.ClientIP(),
+		}
+		if identity, ok := CurrentIdentity(c); ok {
+			attrs = append(attrs, "identity", identity.Subject)
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(c.Request.Context(), level, "Request", attrs...)
+	}
+}
+
+// LogSettings is the body of GET and PUT /api/v1/logging
+type LogSettings struct {
+	Level string `json:"level" binding:"required" enum:"debug,info,warn,error"`
+}
+
+// LoggingHandler exposes the level of the backend's logs, which platform admins may change
+// while it runs, e.g. to see every query while debugging
+type LoggingHandler struct{}
+
+// NewLoggingHandler creates a new handler instance
+func NewLoggingHandler() *LoggingHandler {
+	return &LoggingHandler{}
+}
+
+// GetSettings handles GET /api/v1/logging
+func (h *LoggingHandler) GetSettings(c *gin.Context) {
+	if !requirePlatformAdmin(c) {
+		return
+	}
+	c.JSON(http.StatusOK, currentLogSettings())
+}
+
+// UpdateSettings handles PUT /api/v1/logging
+func (h *LoggingHandler) UpdateSettings(c *gin.Context) {
+	if !requirePlatformAdmin(c) {
+		return
+	}
+	l, err := logging.ParseLevel(requestBody[LogSettings](c).Level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logging.SetLevel(l)
+	c.JSON(http.StatusOK, currentLogSettings())
+}
+
+func currentLogSettings() LogSettings {
+	return LogSettings{Level: strings.ToLower(logging.Level().String())}
+}
@@ -0,0 +1,92 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	var buf bytes.Buffer
+	assert.Nil(t, logging.Setup(&buf, logging.FormatJSON, slog.LevelInfo))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.RequestID(), handler.AccessLog())
+	handlers := handler.Handlers{
+		Machine: handler.NewMachineHandler(service.NewMachineService(&MockMachineRepository{})),
+	}
+	handler.RegisterRoutes(router, handler.Routes(handlers))
+
+	request := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/machines/1", nil)
+		if id != "" {
+			req.Header.Set(handler.RequestIDHeader, id)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("client-42")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "client-42", w.Header().Get(handler.RequestIDHeader), "Request IDs of clients are kept")
+	var record map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "Request", record["msg"])
+	assert.Equal(t, "client-42", record[logging.RequestIDKey])
+	assert.Equal(t, "/api/v1/machines/:id", record["route"])
+	assert.Equal(t, float64(http.StatusOK), record["status"])
+
+	generated := request("").Header().Get(handler.RequestIDHeader)
+	assert.Len(t, generated, 16, "Requests without an ID get one")
+	unsafe := request("a b\nforged=1").Header().Get(handler.RequestIDHeader)
+	assert.Len(t, unsafe, 16, "Unsafe request IDs are replaced")
+	assert.Len(t, request(strings.Repeat("a", 65)).Header().Get(handler.RequestIDHeader), 16)
+}
+
+func TestLoggingHandler(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	var buf bytes.Buffer
+	assert.Nil(t, logging.Setup(&buf, logging.FormatText, slog.LevelInfo))
+	defer logging.SetLevel(slog.LevelInfo)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Logging: handler.NewLoggingHandler()}))
+
+	settings := func(method, body string) (int, handler.LogSettings) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/api/v1/logging", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		var result handler.LogSettings
+		_ = json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+
+	code, result := settings("GET", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "info", result.Level)
+
+	code, result = settings("PUT", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "debug", result.Level)
+	assert.Equal(t, slog.LevelDebug, logging.Level())
+
+	code, _ = settings("PUT", `{"level":"loud"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, slog.LevelDebug, logging.Level())
+}
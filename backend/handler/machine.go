@@ -1,9 +1,9 @@
 package handler
 
 import (
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
-	"strconv"
 
 	"github.com/CBYeuler/automation-backend/backend/models"
 	"github.com/CBYeuler/automation-backend/backend/service"
@@ -20,18 +20,33 @@ func NewMachineHandler(s service.MachineService) *MachineHandler {
 	return &MachineHandler{Service: s}
 }
 
-// CreateMachine handles POST /api/v1/machines
-func (h *MachineHandler) CreateMachine(c *gin.Context) {
-	var machine models.Machine
-	// Bind the incoming JSON request body to the Machine struct
-	if err := c.ShouldBindJSON(&machine); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+// machines returns the machine service limited to the caller's organization and to what the
+// caller may do, or the service itself when requests are not authenticated
+func (h *MachineHandler) machines(c *gin.Context) service.MachineService {
+	if identity, ok := CurrentIdentity(c); ok {
+		return service.NewAuthorizedMachineService(forRequest(c, h.Service), identity)
 	}
+	return h.Service.WithContext(c.Request.Context())
+}
 
-	createdMachine, err := h.Service.CreateMachine(machine)
+// forbidden answers 403 if err is service.ErrForbidden, reporting whether it did
+func forbidden(c *gin.Context, err error) bool {
+	if !errors.Is(err, service.ErrForbidden) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	return true
+}
+
+// CreateMachine handles POST /api/v1/machines
+func (h *MachineHandler) CreateMachine(c *gin.Context) {
+	// The body has been validated against the Machine schema by the validation middleware
+	createdMachine, err := h.machines(c).CreateMachine(requestBody[models.Machine](c))
 	if err != nil {
-		log.Printf("Error creating machine: %v", err)
+		if forbidden(c, err) {
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to create machine", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create machine"})
 		return
 	}
@@ -41,9 +56,12 @@ func (h *MachineHandler) CreateMachine(c *gin.Context) {
 
 // GetMachines handles GET /api/v1/machines
 func (h *MachineHandler) GetMachines(c *gin.Context) {
-	machines, err := h.Service.GetAllMachines()
+	machines, err := h.machines(c).GetAllMachines()
 	if err != nil {
-		log.Printf("Error retrieving machines: %v", err)
+		if forbidden(c, err) {
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to retrieve machines", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve machines"})
 		return
 	}
@@ -52,14 +70,11 @@ func (h *MachineHandler) GetMachines(c *gin.Context) {
 
 // GetMachineByID handles GET /api/v1/machines/:id
 func (h *MachineHandler) GetMachineByID(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid machine ID"})
-		return
-	}
-
-	machine, err := h.Service.GetMachineByID(uint(id))
+	machine, err := h.machines(c).GetMachineByID(pathID(c, "id"))
 	if err != nil {
+		if forbidden(c, err) {
+			return
+		}
 		// Check for gorm.ErrRecordNotFound or similar custom error from service
 		c.JSON(http.StatusNotFound, gin.H{"error": "Machine not found"})
 		return
@@ -70,22 +85,18 @@ func (h *MachineHandler) GetMachineByID(c *gin.Context) {
 
 // UpdateMachine handles PUT /api/v1/machines/:id
 func (h *MachineHandler) UpdateMachine(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	id := pathID(c, "id")
+	updatedMachine, err := h.machines(c).UpdateMachine(id, requestBody[models.Machine](c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid machine ID"})
-		return
-	}
-
-	var machine models.Machine
-	if err := c.ShouldBindJSON(&machine); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	updatedMachine, err := h.Service.UpdateMachine(uint(id), machine)
-	if err != nil {
-		log.Printf("Error updating machine ID %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update machine or machine not found"})
+		if forbidden(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrMachineNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Machine not found"})
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to update machine", "machine_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update machine"})
 		return
 	}
 
@@ -94,15 +105,16 @@ func (h *MachineHandler) UpdateMachine(c *gin.Context) {
 
 // DeleteMachine handles DELETE /api/v1/machines/:id
 func (h *MachineHandler) DeleteMachine(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid machine ID"})
-		return
-	}
-
-	err = h.Service.DeleteMachine(uint(id))
-	if err != nil {
-		log.Printf("Error deleting machine ID %d: %v", id, err)
+	id := pathID(c, "id")
+	if err := h.machines(c).DeleteMachine(id); err != nil {
+		if forbidden(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrMachineNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Machine not found"})
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to delete machine", "machine_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete machine"})
 		return
 	}
@@ -110,3 +122,25 @@ func (h *MachineHandler) DeleteMachine(c *gin.Context) {
 	// Use StatusNoContent for a successful DELETE operation with no body
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// RunCommand handles POST /api/v1/machines/commands, starting, stopping or resetting every
+// machine matching the selector in the body
+func (h *MachineHandler) RunCommand(c *gin.Context) {
+	cmd := requestBody[service.BulkCommand](c)
+	result, err := h.machines(c).RunCommand(cmd)
+	switch {
+	case forbidden(c, err):
+		return
+	case errors.Is(err, service.ErrInvalidCommand):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrGroupNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group not found"})
+		return
+	case err != nil:
+		slog.ErrorContext(c.Request.Context(), "Failed to run command", "command", cmd.Command, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run command"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -2,6 +2,7 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,32 +10,58 @@ import (
 
 	"github.com/CBYeuler/automation-backend/backend/handler"
 	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
 	"github.com/CBYeuler/automation-backend/backend/service"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
 )
 
-// MockMachineRepository is a simple mock for testing the handler/service interaction
-type MockMachineRepository struct{}
+// MockMachineRepository is a simple mock for testing the handler/service interaction. Its
+// machines belong to the default organization, so other organizations see none of them.
+type MockMachineRepository struct {
+	organizationID uint
+}
+
+func (m *MockMachineRepository) ForTenant(organizationID uint) repository.MachineRepository {
+	return &MockMachineRepository{organizationID: organizationID}
+}
+func (m *MockMachineRepository) WithContext(context.Context) repository.MachineRepository { return m }
+
+func (m *MockMachineRepository) foreign() bool {
+	return m.organizationID != 0 && m.organizationID != models.DefaultOrganizationID
+}
 
 func (m *MockMachineRepository) Create(machine *models.Machine) error {
 	machine.ID = 1
 	return nil
 }
 func (m *MockMachineRepository) FindAll() ([]models.Machine, error) {
+	if m.foreign() {
+		return []models.Machine{}, nil
+	}
 	return []models.Machine{
 		{Model: models.Model{ID: 1}, Name: "TestMachine", Status: "Idle"},
 	}, nil
 }
+func (m *MockMachineRepository) CountByStatus() (map[string]int64, error) {
+	return map[string]int64{"Idle": 1}, nil
+}
 func (m *MockMachineRepository) FindByID(id uint) (*models.Machine, error) {
-	if id == 99 {
+	if id == 99 || m.foreign() {
 		return nil, gorm.ErrRecordNotFound // Use gorm error for not found check
 	}
 	return &models.Machine{Model: models.Model{ID: id}, Name: "TestMachine", Status: "Idle"}, nil
 }
-func (m *MockMachineRepository) Update(machine *models.Machine) error { return nil }
-func (m *MockMachineRepository) Delete(id uint) error                 { return nil }
+func (m *MockMachineRepository) Update(machine *models.Machine) error         { return nil }
+func (m *MockMachineRepository) UpdateStatus(ids []uint, status string) error { return nil }
+func (m *MockMachineRepository) Transaction(fn func(repository.MachineRepository) error) error {
+	return fn(m)
+}
+func (m *MockMachineRepository) SetStatusIf(id uint, status string, from ...string) (bool, error) {
+	return true, nil
+}
+func (m *MockMachineRepository) Delete(id uint) error { return nil }
 
 // setupRouter creates a test router with the handler initialized
 func setupRouter() (*gin.Engine, *handler.MachineHandler) {
@@ -44,15 +71,8 @@ func setupRouter() (*gin.Engine, *handler.MachineHandler) {
 	machineService := service.NewMachineService(mockRepo)
 	machineHandler := handler.NewMachineHandler(machineService)
 
-	// Set up the routes the handler tests will hit
-	api := router.Group("/api/v1")
-	{
-		api.POST("/machines", machineHandler.CreateMachine)
-		api.GET("/machines", machineHandler.GetMachines)
-		api.GET("/machines/:id", machineHandler.GetMachineByID)
-		api.PUT("/machines/:id", machineHandler.UpdateMachine)
-		api.DELETE("/machines/:id", machineHandler.DeleteMachine)
-	}
+	// Serve the routes the handler tests will hit behind the validation middleware
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Machine: machineHandler}))
 	return router, machineHandler
 }
 
@@ -117,4 +137,35 @@ func TestGetMachineByIDHandler(t *testing.T) {
 	})
 }
 
+func TestRunCommandHandler(t *testing.T) {
+	router, _ := setupRouter()
+
+	cases := []struct {
+		body string
+		code int
+	}{
+		{`{"command": "stop", "status": "Idle"}`, http.StatusOK},
+		{`{"command": "stop", "ids": [1, 99]}`, http.StatusOK},
+		{`{"command": "stop"}`, http.StatusBadRequest},
+		{`{"command": "explode", "ids": [1]}`, http.StatusBadRequest},
+		{`{"ids": [1]}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/machines/commands", bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, "Unexpected status for %s", tc.body)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/machines/commands", bytes.NewBufferString(`{"command": "stop", "ids": [1]}`))
+	router.ServeHTTP(w, req)
+	var result service.BulkCommandResult
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, "Offline", result.Results[0].Status)
+}
+
 // Further tests for GET (All), PUT, and DELETE handlers would follow this pattern.
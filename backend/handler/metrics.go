@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests to paths no route serves, so that scanners probing random
+// paths cannot blow up the number of series
+const unmatchedRoute = "unmatched"
+
+// Metrics counts every request once it was answered and observes how long it took, by
+// method, route and status
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsHandler serves the backend's metrics to Prometheus. They cover every organization,
+// so they are served on a listener of their own rather than next to the API.
+type MetricsHandler struct {
+	Handler http.Handler
+}
+
+// NewMetricsHandler creates a new handler instance serving the metrics of g
+func NewMetricsHandler(g prometheus.Gatherer) *MetricsHandler {
+	return &MetricsHandler{Handler: promhttp.HandlerFor(g, promhttp.HandlerOpts{})}
+}
+
+// Register serves the metrics on the router
+func (h *MetricsHandler) Register(router gin.IRoutes) {
+	router.GET("/metrics", h.GetMetrics)
+}
+
+// GetMetrics handles GET /metrics
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	h.Handler.ServeHTTP(c.Writer, c.Request)
+}
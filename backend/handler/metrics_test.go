@@ -0,0 +1,58 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/metrics"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.Metrics())
+	handlers := handler.Handlers{
+		Machine: handler.NewMachineHandler(service.NewMachineService(&MockMachineRepository{})),
+	}
+	handler.RegisterRoutes(router, handler.Routes(handlers))
+	metricsRouter := gin.New()
+	handler.NewMetricsHandler(metrics.Registry).Register(metricsRouter)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	scrape := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/metrics", nil)
+		metricsRouter.ServeHTTP(w, req)
+		return w
+	}
+	counted := func(route, status string) float64 {
+		return testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", route, status))
+	}
+
+	found, missing, unmatched := counted("/api/v1/machines/:id", "200"), counted("/api/v1/machines/:id", "404"), counted("unmatched", "404")
+	request("/api/v1/machines/1")
+	request("/api/v1/machines/99")
+	request("/api/v1/machines/2")
+	request("/wp-login.php")
+	assert.Equal(t, found+2, counted("/api/v1/machines/:id", "200"), "Requests are counted by route, not path")
+	assert.Equal(t, missing+1, counted("/api/v1/machines/:id", "404"))
+	assert.Equal(t, unmatched+1, counted("unmatched", "404"))
+
+	assert.Equal(t, http.StatusNotFound, request("/metrics").Code, "Metrics are not served next to the API")
+	w := scrape()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, w.Body.String(), `automation_http_requests_total{method="GET",route="/api/v1/machines/:id",status="200"}`)
+	assert.Contains(t, w.Body.String(), "automation_http_request_duration_seconds_bucket")
+}
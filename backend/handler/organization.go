@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationHandler contains the service interface for dependency injection. Organizations
+// are managed by platform admins only.
+type OrganizationHandler struct {
+	Service service.OrganizationService
+}
+
+// NewOrganizationHandler creates a new handler instance
+func NewOrganizationHandler(s service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{Service: s}
+}
+
+// CreateOrganization handles POST /api/v1/organizations
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	if !requirePlatformAdmin(c) {
+		return
+	}
+	created, err := h.Service.WithContext(c.Request.Context()).CreateOrganization(requestBody[models.Organization](c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidOrganization):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrOrganizationExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			slog.ErrorContext(c.Request.Context(), "Failed to create organization", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetOrganizations handles GET /api/v1/organizations
+func (h *OrganizationHandler) GetOrganizations(c *gin.Context) {
+	if !requirePlatformAdmin(c) {
+		return
+	}
+	orgs, err := h.Service.WithContext(c.Request.Context()).GetOrganizations()
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to retrieve organizations", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organizations"})
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
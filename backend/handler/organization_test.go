@@ -0,0 +1,60 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockOrganizationService knows the default organization and creates any other
+type MockOrganizationService struct{}
+
+func (m *MockOrganizationService) CreateOrganization(org models.Organization) (models.Organization, error) {
+	switch org.Name {
+	case models.DefaultOrganizationName:
+		return models.Organization{}, service.ErrOrganizationExists
+	case "acme corp":
+		return models.Organization{}, service.ErrInvalidOrganization
+	}
+	org.ID = 2
+	return org, nil
+}
+func (m *MockOrganizationService) GetOrganizations() ([]models.Organization, error) {
+	return []models.Organization{{Model: models.Model{ID: models.DefaultOrganizationID}, Name: models.DefaultOrganizationName}}, nil
+}
+func (m *MockOrganizationService) GetOrganizationByName(name string) (models.Organization, error) {
+	return models.Organization{}, service.ErrOrganizationNotFound
+}
+func (m *MockOrganizationService) WithContext(context.Context) service.OrganizationService {
+	return m
+}
+
+func TestOrganizationHandlers(t *testing.T) {
+	router := setupAuthRouter()
+
+	cases := []struct {
+		method, path, body, auth string
+		code                     int
+	}{
+		{"POST", "/api/v1/organizations", `{"name": "acme"}`, "ak_admin", http.StatusCreated},
+		{"POST", "/api/v1/organizations", `{"name": "default"}`, "ak_admin", http.StatusConflict},
+		{"POST", "/api/v1/organizations", `{"name": "acme corp"}`, "ak_admin", http.StatusBadRequest},
+		{"POST", "/api/v1/organizations", `{}`, "ak_admin", http.StatusBadRequest},
+		{"GET", "/api/v1/organizations", "", "ak_admin", http.StatusOK},
+		{"GET", "/api/v1/organizations", "", "ak_write", http.StatusForbidden},
+		{"POST", "/api/v1/organizations", `{"name": "globex"}`, "ak_acme_admin", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Authorization", "Bearer "+tc.auth)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, "Unexpected status for %s %s as %s: %s", tc.method, tc.path, tc.auth, w.Body.String())
+	}
+}
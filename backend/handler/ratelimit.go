@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// Rate limit classes of routes; every API route counts against one
+const (
+	RateLimitRead   = "read"   // GET requests
+	RateLimitWrite  = "write"  // requests that change state
+	RateLimitSubmit = "submit" // submissions of runs, batches and workflow runs, which cost simulator time
+)
+
+// RateLimitAddresses admits requests to rate limited routes at one rate per IP address,
+// whatever their credentials. It must come before RequireAuth, so that clients guessing
+// credentials are limited too; RateLimit then gives each identity buckets of its own.
+func RateLimitAddresses(limiter *ratelimit.Limiter) RouteMiddleware {
+	return func(r Route) gin.HandlerFunc {
+		if r.RateLimit == "" || limiter.Rate.Unlimited() {
+			return nil
+		}
+		return func(c *gin.Context) {
+			if allowed, wait := limiter.Allow(c.ClientIP()); !allowed {
+				tooManyRequests(c, wait, fmt.Sprintf("rate limit of %s requests per address exceeded", limiter.Rate))
+				return
+			}
+			c.Next()
+		}
+	}
+}
+
+// RateLimit admits requests to routes at the rate of their class, counted per client: per
+// identity for authenticated requests, per IP address otherwise. Requests over the limit get
+// 429 with a Retry-After header. Routes whose class has no limiter are not limited. It must
+// come after RequireAuth so that requests are counted against their identity.
+func RateLimit(limiters map[string]*ratelimit.Limiter) RouteMiddleware {
+	return func(r Route) gin.HandlerFunc {
+		limiter, ok := limiters[r.RateLimit]
+		if !ok || limiter.Rate.Unlimited() {
+			return nil
+		}
+		return func(c *gin.Context) {
+			client := "ip:" + c.ClientIP()
+			if identity, ok := CurrentIdentity(c); ok {
+				client = identity.Subject
+			}
+			if allowed, wait := limiter.Allow(client); !allowed {
+				tooManyRequests(c, wait, fmt.Sprintf("rate limit of %s %s requests exceeded", limiter.Rate, r.RateLimit))
+				return
+			}
+			c.Next()
+		}
+	}
+}
+
+func tooManyRequests(c *gin.Context, wait time.Duration, message string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": message})
+}
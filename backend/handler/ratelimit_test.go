@@ -0,0 +1,100 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/ratelimit"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	keys := &MockAPIKeyService{}
+	handlers := handler.Handlers{
+		Machine: handler.NewMachineHandler(service.NewMachineService(&MockMachineRepository{})),
+	}
+	limiters := map[string]*ratelimit.Limiter{
+		handler.RateLimitRead:  ratelimit.NewLimiter(ratelimit.Rate{Requests: 2, Per: time.Minute}),
+		handler.RateLimitWrite: ratelimit.NewLimiter(ratelimit.Rate{}),
+	}
+	handler.RegisterRoutes(router, handler.Routes(handlers), handler.RequireAuth(keys, nil), handler.RateLimit(limiters))
+
+	request := func(method, path, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/machines", "ak_read").Code)
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/machines/1", "ak_read").Code)
+	w := request("GET", "/api/v1/machines", "ak_read")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "Reads of a client share one bucket")
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "2/m read requests")
+
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/machines", "ak_write").Code, "Other clients are not affected")
+	assert.Equal(t, http.StatusNoContent, request("DELETE", "/api/v1/machines/2", "ak_write").Code, "Classes without a limit are not limited")
+	assert.Equal(t, http.StatusOK, request("GET", "/health", "").Code, "Routes without a class are not limited")
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/api/v1/machines", "").Code, "Requests are authenticated first")
+}
+
+func TestRateLimitByAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handlers := handler.Handlers{
+		Machine: handler.NewMachineHandler(service.NewMachineService(&MockMachineRepository{})),
+	}
+	limiters := map[string]*ratelimit.Limiter{
+		handler.RateLimitRead: ratelimit.NewLimiter(ratelimit.Rate{Requests: 1, Per: time.Second}),
+	}
+	handler.RegisterRoutes(router, handler.Routes(handlers), handler.RateLimit(limiters))
+
+	request := func(addr string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/machines", nil)
+		req.RemoteAddr = addr
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:5678"), "Unauthenticated clients are told apart by address")
+	assert.Equal(t, http.StatusOK, request("10.0.0.2:1234"))
+}
+
+func TestRateLimitAddresses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	assert.Nil(t, router.SetTrustedProxies(nil))
+	handlers := handler.Handlers{
+		Machine: handler.NewMachineHandler(service.NewMachineService(&MockMachineRepository{})),
+	}
+	limiter := ratelimit.NewLimiter(ratelimit.Rate{Requests: 1, Per: time.Minute})
+	handler.RegisterRoutes(router, handler.Routes(handlers), handler.RateLimitAddresses(limiter), handler.RequireAuth(&MockAPIKeyService{}, nil))
+
+	request := func(addr, forwardedFor string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/machines", nil)
+		req.RemoteAddr = addr
+		req.Header.Set("Authorization", "Bearer ak_guess")
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, request("10.0.0.1:1234", ""))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:5678", ""), "Requests with bad credentials are limited too")
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:5678", "10.9.9.9"), "X-Forwarded-For of untrusted proxies is ignored")
+	assert.Equal(t, http.StatusUnauthorized, request("10.0.0.2:1234", ""))
+}
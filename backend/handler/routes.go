@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/openapi"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers bundles the handlers serving the API
+type Handlers struct {
+	Machine   *MachineHandler
+	Topology  *TopologyHandler
+	Telemetry *TelemetryHandler
+	Run       *RunHandler
+	RunLog    *RunLogHandler
+	Artifact  *ArtifactHandler
+	Job       *JobHandler
+	Batch     *BatchHandler
+	Workflow  *WorkflowHandler
+	Fault     *FaultHandler
+	Simulator *SimulatorHandler
+	Alarm     *AlarmHandler
+	APIKey    *APIKeyHandler
+	Logging   *LoggingHandler
+
+	Organization *OrganizationHandler
+}
+
+// Route is a documented API route and the handler serving it
+type Route struct {
+	openapi.Route
+	Handle gin.HandlerFunc
+}
+
+// RouteMiddleware returns the middleware to run before the handler of a route, or nil for none
+type RouteMiddleware func(r Route) gin.HandlerFunc
+
+// HealthStatus is the body of GET /health
+type HealthStatus struct {
+	Status string `json:"status"`
+}
+
+// Health handles GET /health
+func Health(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthStatus{Status: "OK"})
+}
+
+var (
+	badRequest      = []int{http.StatusBadRequest}
+	notFound        = []int{http.StatusBadRequest, http.StatusNotFound}
+	failed          = []int{http.StatusInternalServerError}
+	invalidOrFailed = []int{http.StatusBadRequest, http.StatusInternalServerError}
+	anyError        = []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}
+	duplicate       = []int{http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError}
+	conflict        = []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}
+
+	idQuery     = &openapi.Schema{Type: "integer", Minimum: new(float64)}
+	limitQuery  = openapi.Query("limit", &openapi.Schema{Type: "integer", Minimum: new(float64)}, "Maximum number of results; 0 for the default")
+	stringQuery = &openapi.Schema{Type: "string"}
+
+	jobStatusQuery  = &openapi.Schema{Type: "string", Enum: []string{models.JobStatusPending, models.JobStatusLeased, models.JobStatusSucceeded, models.JobStatusDead}}
+	alarmStateQuery = &openapi.Schema{Type: "string", Enum: []string{models.AlarmStateRaised, models.AlarmStateAcknowledged, models.AlarmStateCleared}}
+)
+
+// Routes lists every route of the API. It is the single source of both the router and the OpenAPI document.
+func Routes(h Handlers) []Route {
+	routes := []Route{
+		{openapi.Route{Method: http.MethodGet, Path: "/health", Summary: "Check that the server is up", Tag: "health",
+			Status: http.StatusOK, Response: HealthStatus{}}, Health},
+
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/machines", Summary: "Create a machine", Tag: "machines",
+			Body: models.Machine{}, Status: http.StatusCreated, Response: models.Machine{}, Errors: invalidOrFailed}, h.Machine.CreateMachine},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/machines", Summary: "List machines", Tag: "machines",
+			Status: http.StatusOK, Response: []models.Machine{}, Errors: failed}, h.Machine.GetMachines},
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/machines/commands", Summary: "Start, stop or reset the selected machines", Tag: "machines", Permission: models.PermissionMachineCommand,
+			Body: service.BulkCommand{}, Status: http.StatusOK, Response: service.BulkCommandResult{}, Errors: invalidOrFailed}, h.Machine.RunCommand},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/machines/:id", Summary: "Get a machine", Tag: "machines",
+			Status: http.StatusOK, Response: models.Machine{}, Errors: notFound}, h.Machine.GetMachineByID},
+		{openapi.Route{Method: http.MethodPut, Path: "/api/v1/machines/:id", Summary: "Update a machine", Tag: "machines", Permission: models.PermissionMachineCommand,
+			Body: models.Machine{}, Status: http.StatusOK, Response: models.Machine{}, Errors: anyError}, h.Machine.UpdateMachine},
+		{openapi.Route{Method: http.MethodDelete, Path: "/api/v1/machines/:id", Summary: "Delete a machine", Tag: "machines", Permission: models.PermissionMachineDelete,
+			Status: http.StatusNoContent, Errors: anyError}, h.Machine.DeleteMachine},
+		{openapi.Route{Method: http.MethodPut, Path: "/api/v1/machines/:id/group", Summary: "Move a machine into a group, or out of any", Tag: "topology",
+			Body: machineGroupRequest{}, Status: http.StatusOK, Response: models.Machine{}, Errors: anyError}, h.Topology.SetMachineGroup},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/machines/:id/telemetry", Summary: "Get aggregated telemetry of a machine", Tag: "telemetry",
+			Query: []openapi.Parameter{
+				openapi.Query("metric", stringQuery, "Metrics to return, repeatable or comma separated"),
+				openapi.Query("from", &openapi.Schema{Type: "string", Format: "date-time"}, "Start of the range"),
+				openapi.Query("to", &openapi.Schema{Type: "string", Format: "date-time"}, "End of the range"),
+				openapi.Query("bucket", stringQuery, "Aggregation interval such as 1m; chosen automatically when omitted"),
+			},
+			Status: http.StatusOK, Response: service.TelemetryResult{}, Errors: anyError}, h.Telemetry.GetTelemetry},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/machines/:id/runs", Summary: "List the runs of a machine, newest first", Tag: "runs",
+			Query:  []openapi.Parameter{limitQuery},
+			Status: http.StatusOK, Response: []models.SimulationRun{}, Errors: anyError}, h.Run.GetMachineRuns},
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/machines/:id/runs", Summary: "Submit an ad-hoc run", Tag: "jobs", RateLimit: RateLimitSubmit,
+			Body: service.RunRequest{}, OptionalBody: true, Status: http.StatusAccepted, Response: models.Job{}, Errors: anyError}, h.Job.SubmitRun},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/runs/:id", Summary: "Get a run", Tag: "runs",
+			Status: http.StatusOK, Response: models.SimulationRun{}, Errors: notFound}, h.Run.GetRun},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/runs/:id/logs", Summary: "Get the log of a run, or follow it as server-sent events", Tag: "runs",
+			Query:  []openapi.Parameter{openapi.Query("follow", &openapi.Schema{Type: "boolean"}, "Stream the log as server-sent events until the run ends")},
+			Status: http.StatusOK, ContentType: "text/plain", Errors: notFound}, h.RunLog.GetRunLogs},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/runs/:id/artifacts", Summary: "List the artifacts of a run", Tag: "runs",
+			Status: http.StatusOK, Response: []models.Artifact{}, Errors: anyError}, h.Artifact.GetRunArtifacts},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/runs/:id/artifacts/:artifactId", Summary: "Download an artifact", Tag: "runs",
+			Status: http.StatusOK, ContentType: "application/octet-stream", Errors: anyError}, h.Artifact.DownloadArtifact},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/jobs", Summary: "List jobs", Tag: "jobs",
+			Query: []openapi.Parameter{
+				openapi.Query("machine_id", idQuery, "Only jobs of this machine"),
+				openapi.Query("status", jobStatusQuery, "Only jobs in this status"),
+				limitQuery,
+			},
+			Status: http.StatusOK, Response: []models.Job{}, Errors: invalidOrFailed}, h.Job.GetJobs},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/jobs/:id", Summary: "Get a job", Tag: "jobs",
+			Status: http.StatusOK, Response: models.Job{}, Errors: anyError}, h.Job.GetJob},
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/machines/:id/batches", Summary: "Submit a parameter sweep", Tag: "batches", RateLimit: RateLimitSubmit,
+			Body: service.BatchRequest{}, Status: http.StatusAccepted, Response: models.Batch{}, Errors: anyError}, h.Batch.SubmitBatch},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/batches/:id", Summary: "Get a batch with the results of its points", Tag: "batches",
+			Status: http.StatusOK, Response: service.BatchResult{}, Errors: anyError}, h.Batch.GetBatch},
+
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/workflows", Summary: "Create a workflow", Tag: "workflows",
+			Body: models.Workflow{}, Status: http.StatusCreated, Response: models.Workflow{}, Errors: duplicate}, h.Workflow.CreateWorkflow},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/workflows", Summary: "List workflows", Tag: "workflows",
+			Status: http.StatusOK, Response: []models.Workflow{}, Errors: failed}, h.Workflow.GetWorkflows},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/workflows/:id", Summary: "Get a workflow", Tag: "workflows",
+			Status: http.StatusOK, Response: models.Workflow{}, Errors: anyError}, h.Workflow.GetWorkflowByID},
+		{openapi.Route{Method: http.MethodDelete, Path: "/api/v1/workflows/:id", Summary: "Delete a workflow", Tag: "workflows",
+			Status: http.StatusNoContent, Errors: anyError}, h.Workflow.DeleteWorkflow},
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/workflows/:id/runs", Summary: "Start a workflow run", Tag: "workflows", RateLimit: RateLimitSubmit,
+			Body: service.WorkflowRunRequest{}, OptionalBody: true, Status: http.StatusAccepted, Response: models.WorkflowRun{}, Errors: conflict}, h.Workflow.StartRun},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/workflow-runs/:id", Summary: "Get a workflow run with the state of its steps", Tag: "workflows",
+			Status: http.StatusOK, Response: service.WorkflowRunResult{}, Errors: anyError}, h.Workflow.GetRun},
+
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/groups", Summary: "Create a site, line or cell", Tag: "topology",
+			Body: models.MachineGroup{}, Status: http.StatusCreated, Response: models.MachineGroup{}, Errors: invalidOrFailed}, h.Topology.CreateGroup},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/groups", Summary: "List groups", Tag: "topology",
+			Status: http.StatusOK, Response: []models.MachineGroup{}, Errors: failed}, h.Topology.GetGroups},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/groups/:id", Summary: "Get a group", Tag: "topology",
+			Status: http.StatusOK, Response: models.MachineGroup{}, Errors: anyError}, h.Topology.GetGroupByID},
+		{openapi.Route{Method: http.MethodDelete, Path: "/api/v1/groups/:id", Summary: "Delete an empty group", Tag: "topology",
+			Status: http.StatusNoContent, Errors: conflict}, h.Topology.DeleteGroup},
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/links", Summary: "Link a machine to the machine it feeds", Tag: "topology",
+			Body: models.MachineLink{}, Status: http.StatusCreated, Response: models.MachineLink{}, Errors: duplicate}, h.Topology.CreateLink},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/links", Summary: "List machine links", Tag: "topology",
+			Status: http.StatusOK, Response: []models.MachineLink{}, Errors: failed}, h.Topology.GetLinks},
+		{openapi.Route{Method: http.MethodDelete, Path: "/api/v1/links/:id", Summary: "Delete a machine link", Tag: "topology",
+			Status: http.StatusNoContent, Errors: anyError}, h.Topology.DeleteLink},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/topology", Summary: "Get the plant hierarchy with machines and links", Tag: "topology",
+			Query:  []openapi.Parameter{openapi.Query("group_id", idQuery, "Only this group and everything below it")},
+			Status: http.StatusOK, Response: service.TopologyGraph{}, Errors: anyError}, h.Topology.GetTopology},
+
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/machines/:id/faults", Summary: "Inject a fault", Tag: "simulator",
+			Body: simulation.FaultSpec{}, Status: http.StatusCreated, Response: simulation.Fault{}, Errors: anyError}, h.Fault.InjectFault},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/machines/:id/faults", Summary: "List the active faults of a machine", Tag: "simulator",
+			Status: http.StatusOK, Response: []simulation.Fault{}, Errors: notFound}, h.Fault.GetFaults},
+		{openapi.Route{Method: http.MethodDelete, Path: "/api/v1/machines/:id/faults", Summary: "Clear the faults of a machine", Tag: "simulator",
+			Status: http.StatusNoContent, Errors: notFound}, h.Fault.ClearFaults},
+		{openapi.Route{Method: http.MethodDelete, Path: "/api/v1/machines/:id/faults/:faultId", Summary: "Remove a fault", Tag: "simulator",
+			Status: http.StatusNoContent, Errors: notFound}, h.Fault.RemoveFault},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/simulator/chaos", Summary: "Get the chaos profile", Tag: "simulator",
+			Status: http.StatusOK, Response: simulation.ChaosProfile{}}, h.Fault.GetChaos},
+		{openapi.Route{Method: http.MethodPut, Path: "/api/v1/simulator/chaos", Summary: "Set the chaos profile", Tag: "simulator", Scope: models.APIKeyScopeAdmin,
+			Body: simulation.ChaosProfile{}, Status: http.StatusOK, Response: simulation.ChaosProfile{}, Errors: badRequest}, h.Fault.SetChaos},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/simulator", Summary: "Get the simulator status", Tag: "simulator",
+			Status: http.StatusOK, Response: simulation.Status{}}, h.Simulator.GetStatus},
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/simulator/pause", Summary: "Pause the simulation", Tag: "simulator", Scope: models.APIKeyScopeAdmin,
+			Status: http.StatusOK, Response: simulation.Status{}}, h.Simulator.Pause},
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/simulator/resume", Summary: "Resume the simulation", Tag: "simulator", Scope: models.APIKeyScopeAdmin,
+			Status: http.StatusOK, Response: simulation.Status{}}, h.Simulator.Resume},
+		{openapi.Route{Method: http.MethodPut, Path: "/api/v1/simulator/config", Summary: "Change simulator settings", Tag: "simulator", Scope: models.APIKeyScopeAdmin,
+			Body: simulation.ConfigUpdate{}, Status: http.StatusOK, Response: simulation.Status{}, Errors: badRequest}, h.Simulator.UpdateConfig},
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/simulator/reconcile", Summary: "Run a monitor pass now", Tag: "simulator", Scope: models.APIKeyScopeAdmin,
+			Status: http.StatusOK, Response: simulation.Status{}}, h.Simulator.Reconcile},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/simulator/workers", Summary: "List simulation workers", Tag: "simulator", Scope: models.APIKeyScopeAdmin,
+			Status: http.StatusOK, Response: []simulation.WorkerInfo{}}, h.Simulator.GetWorkers},
+
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/alarms", Summary: "List alarms", Tag: "alarms",
+			Query: []openapi.Parameter{
+				openapi.Query("state", alarmStateQuery, "Only alarms in this state"),
+				openapi.Query("machine_id", idQuery, "Only alarms of this machine"),
+			},
+			Status: http.StatusOK, Response: []models.Alarm{}, Errors: invalidOrFailed}, h.Alarm.GetAlarms},
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/alarms/:id/acknowledge", Summary: "Acknowledge an alarm", Tag: "alarms", Permission: models.PermissionMachineCommand,
+			Body: AcknowledgeRequest{}, OptionalBody: true, Status: http.StatusOK, Response: models.Alarm{}, Errors: conflict}, h.Alarm.AcknowledgeAlarm},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/alarm-rules", Summary: "List alarm rules", Tag: "alarms",
+			Status: http.StatusOK, Response: []models.AlarmRule{}, Errors: failed}, h.Alarm.GetAlarmRules},
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/alarm-rules", Summary: "Create an alarm rule", Tag: "alarms",
+			Body: models.AlarmRule{}, Status: http.StatusCreated, Response: models.AlarmRule{}, Errors: invalidOrFailed}, h.Alarm.CreateAlarmRule},
+		{openapi.Route{Method: http.MethodDelete, Path: "/api/v1/alarm-rules/:id", Summary: "Delete an alarm rule", Tag: "alarms",
+			Status: http.StatusNoContent, Errors: anyError}, h.Alarm.DeleteAlarmRule},
+
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/api-keys", Summary: "Issue an API key", Tag: "api-keys", Scope: models.APIKeyScopeAdmin,
+			Body: service.IssueKeyRequest{}, Status: http.StatusCreated, Response: service.IssuedKey{}, Errors: invalidOrFailed}, h.APIKey.IssueKey},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/api-keys", Summary: "List API keys", Tag: "api-keys", Scope: models.APIKeyScopeAdmin,
+			Status: http.StatusOK, Response: []models.APIKey{}, Errors: failed}, h.APIKey.GetKeys},
+		{openapi.Route{Method: http.MethodDelete, Path: "/api/v1/api-keys/:id", Summary: "Revoke an API key", Tag: "api-keys", Scope: models.APIKeyScopeAdmin,
+			Status: http.StatusNoContent, Errors: anyError}, h.APIKey.RevokeKey},
+
+		{openapi.Route{Method: http.MethodPost, Path: "/api/v1/organizations", Summary: "Create an organization", Tag: "organizations", Scope: models.APIKeyScopeAdmin,
+			Body: models.Organization{}, Status: http.StatusCreated, Response: models.Organization{}, Errors: duplicate}, h.Organization.CreateOrganization},
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/organizations", Summary: "List organizations", Tag: "organizations", Scope: models.APIKeyScopeAdmin,
+			Status: http.StatusOK, Response: []models.Organization{}, Errors: failed}, h.Organization.GetOrganizations},
+
+		{openapi.Route{Method: http.MethodGet, Path: "/api/v1/logging", Summary: "Get the log level", Tag: "logging", Scope: models.APIKeyScopeAdmin,
+			Status: http.StatusOK, Response: LogSettings{}}, h.Logging.GetSettings},
+		{openapi.Route{Method: http.MethodPut, Path: "/api/v1/logging", Summary: "Change the log level", Tag: "logging", Scope: models.APIKeyScopeAdmin,
+			Body: LogSettings{}, Status: http.StatusOK, Response: LogSettings{}, Errors: badRequest}, h.Logging.UpdateSettings},
+	}
+
+	// Every other API route needs a key: reading needs the read scope, anything else write.
+	// Writes also need the permission to configure machines unless they name a lesser one, as
+	// runs, faults, workflows, rules and the topology all change what machines do.
+	// API routes are rate limited the same way, reads and writes in classes of their own.
+	for i := range routes {
+		r := &routes[i].Route
+		if !strings.HasPrefix(r.Path, "/api/") {
+			continue
+		}
+		if r.Scope == "" {
+			r.Scope = models.APIKeyScopeWrite
+			if r.Method == http.MethodGet {
+				r.Scope = models.APIKeyScopeRead
+			}
+		}
+		if r.Scope == models.APIKeyScopeWrite && r.Permission == "" {
+			r.Permission = models.PermissionMachineConfigWrite
+		}
+		if r.RateLimit == "" {
+			r.RateLimit = RateLimitWrite
+			if r.Method == http.MethodGet {
+				r.RateLimit = RateLimitRead
+			}
+		}
+	}
+	return routes
+}
+
+// RegisterRoutes serves every API route on the router behind the given middleware, validating
+// requests against the OpenAPI document of the routes, which it returns
+func RegisterRoutes(router gin.IRoutes, routes []Route, middleware ...RouteMiddleware) *openapi.Document {
+	doc := OpenAPISpec(routes)
+	for _, r := range routes {
+		var handlers []gin.HandlerFunc
+		for _, m := range middleware {
+			if h := m(r); h != nil {
+				handlers = append(handlers, h)
+			}
+		}
+		handlers = append(handlers, Validate(doc, r.Route), r.Handle)
+		router.Handle(r.Method, r.Path, handlers...)
+	}
+	return doc
+}
+
+// OpenAPISpec documents the routes
+func OpenAPISpec(routes []Route) *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "automation-backend",
+		Version:     "1.0.0",
+		Description: "Orchestrates simulated machines and their runs, workflows and telemetry.",
+	})
+	for _, r := range routes {
+		doc.Add(r.Route)
+	}
+	return doc
+}
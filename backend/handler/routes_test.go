@@ -0,0 +1,123 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDocsRouter(t *testing.T) (*gin.Engine, *openapi.Document) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	doc := handler.RegisterRoutes(router, handler.Routes(handler.Handlers{}))
+	docsHandler, err := handler.NewDocsHandler(doc)
+	assert.Nil(t, err)
+	docsHandler.Register(router)
+	return router, doc
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	router, doc := setupDocsRouter(t)
+
+	var registered []string
+	for _, r := range router.Routes() {
+		if r.Path == "/openapi.json" || strings.HasPrefix(r.Path, "/docs") {
+			continue
+		}
+		registered = append(registered, r.Method+" "+r.Path)
+	}
+	sort.Strings(registered)
+	assert.Equal(t, registered, doc.Operations(), "Every registered route should be documented, and nothing else")
+
+	for path, item := range doc.Paths {
+		for method, op := range *item {
+			var params []string
+			for _, p := range op.Parameters {
+				if p.In == "path" {
+					params = append(params, "{"+p.Name+"}")
+				}
+			}
+			for _, segment := range strings.Split(path, "/") {
+				if strings.HasPrefix(segment, "{") {
+					assert.Contains(t, params, segment, "%s %s should describe its path parameters", method, path)
+				}
+			}
+			assert.NotEmpty(t, op.Responses, "%s %s should have responses", method, path)
+		}
+	}
+}
+
+func TestOpenAPISchemasMatchHandlerTypes(t *testing.T) {
+	_, doc := setupDocsRouter(t)
+	schemas := doc.Components.Schemas
+
+	// Every reference must resolve
+	raw, err := json.Marshal(doc)
+	assert.Nil(t, err)
+	for _, ref := range strings.Split(string(raw), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		assert.Contains(t, schemas, name, "Dangling reference to %s", name)
+	}
+
+	machine := schemas["Machine"]
+	if assert.NotNil(t, machine) {
+		for _, field := range []string{"ID", "CreatedAt", "name", "status", "config_json", "group_id", "tags", "simulated_runs"} {
+			assert.Contains(t, machine.Properties, field)
+		}
+		assert.Equal(t, []string{"name"}, machine.Required)
+		assert.True(t, machine.Properties["group_id"].Nullable)
+		assert.Equal(t, "array", machine.Properties["tags"].Type)
+	}
+
+	// Embedded structs are inlined like encoding/json does
+	command := schemas["BulkCommand"]
+	if assert.NotNil(t, command) {
+		for _, field := range []string{"command", "ids", "group_id", "tags", "status"} {
+			assert.Contains(t, command.Properties, field)
+		}
+	}
+
+	op := doc.Lookup(http.MethodPost, "/api/v1/machines/:id/runs")
+	if assert.NotNil(t, op) && assert.NotNil(t, op.RequestBody) {
+		assert.False(t, op.RequestBody.Required, "An empty body submits a run with the machine's own config")
+		assert.Contains(t, op.Responses, "202")
+		assert.Contains(t, op.Responses, "404")
+	}
+}
+
+func TestDocsHandler(t *testing.T) {
+	router, _ := setupDocsRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var spec map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, openapi.Version, spec["openapi"])
+
+	for path, contentType := range map[string]string{
+		"/docs":                      "text/html",
+		"/docs/":                     "text/html",
+		"/docs/swagger-ui-bundle.js": "javascript",
+		"/docs/swagger-ui.css":       "text/css",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Contains(t, w.Header().Get("Content-Type"), contentType, path)
+	}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/docs/missing.js", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
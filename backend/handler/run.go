@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RunHandler contains the service interface for dependency injection
+type RunHandler struct {
+	Service service.RunService
+}
+
+// NewRunHandler creates a new handler instance
+func NewRunHandler(s service.RunService) *RunHandler {
+	return &RunHandler{Service: s}
+}
+
+// GetRun handles GET /api/v1/runs/:id
+func (h *RunHandler) GetRun(c *gin.Context) {
+	run, err := forRequest(c, h.Service).GetRun(pathID(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Run not found"})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// GetMachineRuns handles GET /api/v1/machines/:id/runs, newest first, with an optional ?limit=
+func (h *RunHandler) GetMachineRuns(c *gin.Context) {
+	id := pathID(c, "id")
+	limit, _ := queryUint(c, "limit")
+	runs, err := forRequest(c, h.Service).GetMachineRuns(id, int(limit))
+	if err != nil {
+		if errors.Is(err, service.ErrMachineNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Machine not found"})
+			return
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to retrieve runs", "machine_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve runs"})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
@@ -0,0 +1,63 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockRunRepository serves a single cancelled run (ID 1) and records the requested limit
+type MockRunRepository struct {
+	Limit int
+}
+
+func (m *MockRunRepository) WithContext(context.Context) repository.RunRepository { return m }
+
+func (m *MockRunRepository) Create(run *models.SimulationRun) error { return nil }
+func (m *MockRunRepository) Update(run *models.SimulationRun) error { return nil }
+func (m *MockRunRepository) FindByID(id uint) (*models.SimulationRun, error) {
+	if id != 1 {
+		return nil, errors.New("record not found")
+	}
+	return &models.SimulationRun{Model: models.Model{ID: 1}, MachineID: 1, Status: models.RunStatusCancelled}, nil
+}
+func (m *MockRunRepository) FindByMachine(machineID uint, limit int) ([]models.SimulationRun, error) {
+	m.Limit = limit
+	return []models.SimulationRun{}, nil
+}
+
+func TestRunHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	repo := &MockRunRepository{}
+	runHandler := handler.NewRunHandler(service.NewRunService(repo, &MockMachineRepository{}))
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Run: runHandler}))
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{"/api/v1/runs/1", http.StatusOK},
+		{"/api/v1/runs/2", http.StatusNotFound},
+		{"/api/v1/runs/abc", http.StatusBadRequest},
+		{"/api/v1/machines/1/runs?limit=5", http.StatusOK},
+		{"/api/v1/machines/1/runs?limit=-5", http.StatusBadRequest},
+		{"/api/v1/machines/99/runs", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", tc.path, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, "Unexpected status for %s", tc.path)
+	}
+	assert.Equal(t, 5, repo.Limit)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/gin-gonic/gin"
+)
+
+// RunLogHandler serves the captured output of simulation runs
+type RunLogHandler struct {
+	Runs service.RunService
+	Logs *simulation.LogHub
+}
+
+// NewRunLogHandler creates a new handler instance
+func NewRunLogHandler(runs service.RunService, logs *simulation.LogHub) *RunLogHandler {
+	return &RunLogHandler{Runs: runs, Logs: logs}
+}
+
+// GetRunLogs handles GET /api/v1/runs/:id/logs. By default it returns the log captured so far
+// as plain text; with ?follow=true it streams the log as server-sent "log" events until the run
+// finishes, followed by an "end" event carrying the run's final state.
+func (h *RunLogHandler) GetRunLogs(c *gin.Context) {
+	id := pathID(c, "id")
+	follow, _ := strconv.ParseBool(c.DefaultQuery("follow", "false"))
+	if _, err := forRequest(c, h.Runs).GetRun(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Run not found"})
+		return
+	}
+
+	if !follow {
+		runLog, err := h.Logs.Get(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Run log not found"})
+			return
+		}
+		if runLog.Truncated {
+			c.Header("X-Log-Truncated", "true")
+		}
+		c.String(http.StatusOK, runLog.Content)
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // keep reverse proxies from buffering the stream
+	err := h.Logs.Follow(c.Request.Context(), id, func(chunk []byte) {
+		c.SSEvent("log", string(chunk))
+		c.Writer.Flush()
+	})
+	if errors.Is(err, simulation.ErrRunLogNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Run log not found"})
+		return
+	}
+	if err != nil {
+		return // the client went away
+	}
+
+	run, err := forRequest(c, h.Runs).GetRun(id)
+	if err != nil {
+		return
+	}
+	c.SSEvent("end", gin.H{"status": run.Status, "error": run.Error})
+	c.Writer.Flush()
+}
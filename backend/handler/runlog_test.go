@@ -0,0 +1,73 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockRunLogRepository serves the stored logs it holds
+type MockRunLogRepository struct {
+	Logs map[uint]models.RunLog
+}
+
+func (m *MockRunLogRepository) WithContext(context.Context) repository.RunLogRepository { return m }
+
+func (m *MockRunLogRepository) Create(log *models.RunLog) error {
+	m.Logs[log.RunID] = *log
+	return nil
+}
+func (m *MockRunLogRepository) FindByRun(runID uint) (*models.RunLog, error) {
+	log, ok := m.Logs[runID]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &log, nil
+}
+
+func TestRunLogHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	logs := &MockRunLogRepository{Logs: map[uint]models.RunLog{
+		1: {RunID: 1, Content: "starting\ndone\n", Truncated: true},
+	}}
+	runLogHandler := handler.NewRunLogHandler(service.NewRunService(&MockRunRepository{}, &MockMachineRepository{}), simulation.NewLogHub(logs))
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{RunLog: runLogHandler}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/runs/1/logs")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "starting\ndone\n", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("X-Log-Truncated"))
+
+	w = get("/api/v1/runs/1/logs?follow=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/event-stream")
+	assert.True(t, strings.HasPrefix(w.Body.String(), "event:log\ndata:starting\ndata:done\n"), w.Body.String())
+	assert.Contains(t, w.Body.String(), "event:end\ndata:{\"error\":\"\",\"status\":\"Cancelled\"}")
+
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/runs/abc/logs").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/runs/1/logs?follow=maybe").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/runs/2/logs").Code)
+
+	delete(logs.Logs, 1)
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/runs/1/logs").Code, "Runs recorded without a log have none")
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/runs/1/logs?follow=true").Code)
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/gin-gonic/gin"
+)
+
+// SimulatorHandler exposes the state of the running machine simulator. The simulator runs the
+// machines of every organization, so only platform admins may see its workers or change it;
+// everyone else only sees its state, settings and worker count.
+type SimulatorHandler struct {
+	Simulator *simulation.MachineSimulator
+}
+
+// NewSimulatorHandler creates a new handler instance
+func NewSimulatorHandler(sim *simulation.MachineSimulator) *SimulatorHandler {
+	return &SimulatorHandler{Simulator: sim}
+}
+
+// GetWorkers handles GET /api/v1/simulator/workers
+func (h *SimulatorHandler) GetWorkers(c *gin.Context) {
+	if !requirePlatformAdmin(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.Simulator.Workers())
+}
+
+// GetStatus handles GET /api/v1/simulator
+func (h *SimulatorHandler) GetStatus(c *gin.Context) {
+	status := h.Simulator.Status()
+	if !platformAdmin(c) {
+		status.Workers = nil // they are the workers of every organization's machines
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Pause handles POST /api/v1/simulator/pause
+func (h *SimulatorHandler) Pause(c *gin.Context) {
+	if !requirePlatformAdmin(c) {
+		return
+	}
+	h.Simulator.Pause()
+	c.JSON(http.StatusOK, h.Simulator.Status())
+}
+
+// Resume handles POST /api/v1/simulator/resume
+func (h *SimulatorHandler) Resume(c *gin.Context) {
+	if !requirePlatformAdmin(c) {
+		return
+	}
+	h.Simulator.Resume()
+	c.JSON(http.StatusOK, h.Simulator.Status())
+}
+
+// UpdateConfig handles PUT /api/v1/simulator/config
+func (h *SimulatorHandler) UpdateConfig(c *gin.Context) {
+	if !requirePlatformAdmin(c) {
+		return
+	}
+	if err := h.Simulator.Configure(requestBody[simulation.ConfigUpdate](c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.Simulator.Status())
+}
+
+// Reconcile handles POST /api/v1/simulator/reconcile, running a monitor pass without waiting for the next tick
+func (h *SimulatorHandler) Reconcile(c *gin.Context) {
+	if !requirePlatformAdmin(c) {
+		return
+	}
+	h.Simulator.Reconcile()
+	c.JSON(http.StatusOK, h.Simulator.Status())
+}
@@ -0,0 +1,82 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSimulatorRouter() (*gin.Engine, *simulation.MachineSimulator) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	sim := simulation.NewMachineSimulator(&MockMachineRepository{}, nil, nil)
+	simulatorHandler := handler.NewSimulatorHandler(sim)
+
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Simulator: simulatorHandler}))
+	return router, sim
+}
+
+func TestGetWorkersHandler(t *testing.T) {
+	router, _ := setupSimulatorRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/simulator/workers", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Expected HTTP 200 OK")
+	var workers []simulation.WorkerInfo
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &workers))
+	assert.Len(t, workers, 0, "No workers run before the first reconciliation")
+}
+
+func TestSimulatorControlHandlers(t *testing.T) {
+	router, sim := setupSimulatorRouter()
+
+	status := func(method, path, body string) (int, simulation.Status) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		var result simulation.Status
+		_ = json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+
+	// 1. Status before the monitor is started
+	code, result := status("GET", "/api/v1/simulator", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, simulation.SimulatorStopped, result.State)
+	assert.Equal(t, "5s", result.TickInterval)
+
+	// 2. Pause and resume
+	_, result = status("POST", "/api/v1/simulator/pause", "")
+	assert.Equal(t, simulation.SimulatorPaused, result.State)
+	_, result = status("POST", "/api/v1/simulator/resume", "")
+	assert.Equal(t, simulation.SimulatorStopped, result.State, "Resuming an unstarted simulator leaves it stopped")
+
+	// 3. Runtime config
+	code, result = status("PUT", "/api/v1/simulator/config", `{"tick_interval": "1s", "error_rate": 0.1}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1s", result.TickInterval)
+	assert.Equal(t, 0.1, result.ErrorRate)
+	assert.Equal(t, 0.1, sim.Faults.Chaos().ErrorRate, "Error rate is shared with the chaos profile")
+
+	code, _ = status("PUT", "/api/v1/simulator/config", `{"tick_interval": "1ms"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = status("PUT", "/api/v1/simulator/config", `{"error_rate": -1}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 4. Immediate reconciliation starts workers for the Idle mock machine
+	code, result = status("POST", "/api/v1/simulator/reconcile", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotNil(t, result.LastReconcile)
+	assert.Equal(t, 1, result.ActiveWorkers)
+}
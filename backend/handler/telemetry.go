@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TelemetryHandler contains the service interface for dependency injection
+type TelemetryHandler struct {
+	Service service.TelemetryService
+}
+
+// NewTelemetryHandler creates a new handler instance
+func NewTelemetryHandler(s service.TelemetryService) *TelemetryHandler {
+	return &TelemetryHandler{Service: s}
+}
+
+// GetTelemetry handles GET /api/v1/machines/:id/telemetry
+//
+// Query parameters: metric (repeatable or comma separated), from and to (RFC 3339),
+// and bucket (a duration such as "1m"; chosen automatically when omitted).
+func (h *TelemetryHandler) GetTelemetry(c *gin.Context) {
+	id := pathID(c, "id")
+	var query service.TelemetryQuery
+	for _, value := range c.QueryArray("metric") {
+		for _, metric := range strings.Split(value, ",") {
+			if metric = strings.TrimSpace(metric); metric != "" {
+				query.Metrics = append(query.Metrics, metric)
+			}
+		}
+	}
+	query.From = timeParam(c, "from")
+	query.To = timeParam(c, "to")
+	if bucket := c.Query("bucket"); bucket != "" {
+		var err error
+		if query.Bucket, err = time.ParseDuration(bucket); err != nil || query.Bucket <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bucket, expected a positive duration such as 1m"})
+			return
+		}
+	}
+
+	result, err := forRequest(c, h.Service).GetTelemetry(id, query)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrMachineNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Machine not found"})
+		case errors.Is(err, service.ErrInvalidTelemetryQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			slog.ErrorContext(c.Request.Context(), "Failed to retrieve telemetry", "machine_id", id, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve telemetry"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// timeParam reads an optional query parameter the validation middleware has checked to be
+// an RFC 3339 time, returning the zero time when it is absent
+func timeParam(c *gin.Context, name string) time.Time {
+	t, _ := time.Parse(time.RFC3339, c.Query(name))
+	return t
+}
@@ -0,0 +1,85 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockTelemetryRepository records the metrics it was queried with
+type MockTelemetryRepository struct {
+	Metrics []string
+}
+
+func (m *MockTelemetryRepository) WithContext(context.Context) repository.TelemetryRepository {
+	return m
+}
+
+func (m *MockTelemetryRepository) Append(samples []models.TelemetrySample) error { return nil }
+func (m *MockTelemetryRepository) Query(machineID uint, metrics []string, from, to time.Time) ([]models.TelemetrySample, error) {
+	m.Metrics = metrics
+	return []models.TelemetrySample{{MachineID: machineID, Metric: "temperature", Value: 70, Timestamp: from}}, nil
+}
+func (m *MockTelemetryRepository) Aggregate(machineID uint, metrics []string, from, to time.Time, bucket time.Duration) ([]repository.TelemetryBucket, error) {
+	m.Metrics = metrics
+	return []repository.TelemetryBucket{{Metric: "temperature", Start: from.UnixMilli(), Min: 70, Max: 70, Avg: 70, Count: 1}}, nil
+}
+func (m *MockTelemetryRepository) DeleteOlderThan(cutoff time.Time) (int64, error) { return 0, nil }
+
+func setupTelemetryRouter() (*gin.Engine, *MockTelemetryRepository) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	repo := &MockTelemetryRepository{}
+	telemetryHandler := handler.NewTelemetryHandler(service.NewTelemetryService(repo, &MockMachineRepository{}))
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Telemetry: telemetryHandler}))
+	return router, repo
+}
+
+func TestGetTelemetryHandler(t *testing.T) {
+	router, repo := setupTelemetryRouter()
+
+	// 1. Successful query with metric selection and explicit bucket
+	t.Run("Success", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/machines/1/telemetry?metric=temperature,power&metric=vibration&from=2025-01-01T00:00:00Z&to=2025-01-01T01:00:00Z&bucket=5m", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, "Expected HTTP 200 OK")
+		assert.Equal(t, []string{"temperature", "power", "vibration"}, repo.Metrics)
+
+		var result service.TelemetryResult
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, "5m0s", result.Bucket)
+		assert.Len(t, result.Series, 1)
+	})
+
+	// 2. Invalid parameters
+	t.Run("InvalidParams", func(t *testing.T) {
+		for _, query := range []string{"from=yesterday", "to=now", "bucket=fast", "bucket=-1m", "from=2025-01-02T00:00:00Z&to=2025-01-01T00:00:00Z"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/machines/1/telemetry?"+query, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, "Expected HTTP 400 Bad Request for %s", query)
+		}
+	})
+
+	// 3. Unknown machine
+	t.Run("NotFound", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/machines/99/telemetry", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code, "Expected HTTP 404 Not Found")
+	})
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TopologyHandler contains the service interface for dependency injection
+type TopologyHandler struct {
+	Service service.TopologyService
+}
+
+// NewTopologyHandler creates a new handler instance
+func NewTopologyHandler(s service.TopologyService) *TopologyHandler {
+	return &TopologyHandler{Service: s}
+}
+
+// topologyError writes the response for an error returned by the topology service
+func topologyError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, service.ErrGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+	case errors.Is(err, service.ErrLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+	case errors.Is(err, service.ErrMachineNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Machine not found"})
+	case errors.Is(err, service.ErrGroupNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": "Group still contains groups or machines"})
+	case errors.Is(err, service.ErrLinkExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Machines are already linked"})
+	case errors.Is(err, service.ErrInvalidTopology):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		slog.ErrorContext(c.Request.Context(), "Failed to "+action, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action})
+	}
+}
+
+// CreateGroup handles POST /api/v1/groups
+func (h *TopologyHandler) CreateGroup(c *gin.Context) {
+	created, err := forRequest(c, h.Service).CreateGroup(requestBody[models.MachineGroup](c))
+	if err != nil {
+		topologyError(c, err, "create group")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetGroups handles GET /api/v1/groups
+func (h *TopologyHandler) GetGroups(c *gin.Context) {
+	groups, err := forRequest(c, h.Service).GetGroups()
+	if err != nil {
+		topologyError(c, err, "retrieve groups")
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+// GetGroupByID handles GET /api/v1/groups/:id
+func (h *TopologyHandler) GetGroupByID(c *gin.Context) {
+	group, err := forRequest(c, h.Service).GetGroupByID(pathID(c, "id"))
+	if err != nil {
+		topologyError(c, err, "retrieve group")
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// DeleteGroup handles DELETE /api/v1/groups/:id
+func (h *TopologyHandler) DeleteGroup(c *gin.Context) {
+	if err := forRequest(c, h.Service).DeleteGroup(pathID(c, "id")); err != nil {
+		topologyError(c, err, "delete group")
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// machineGroupRequest is the body of PUT /api/v1/machines/:id/group; a null group_id ungroups the machine
+type machineGroupRequest struct {
+	GroupID *uint `json:"group_id"`
+}
+
+// SetMachineGroup handles PUT /api/v1/machines/:id/group
+func (h *TopologyHandler) SetMachineGroup(c *gin.Context) {
+	req := requestBody[machineGroupRequest](c)
+	machine, err := forRequest(c, h.Service).SetMachineGroup(pathID(c, "id"), req.GroupID)
+	if errors.Is(err, service.ErrGroupNotFound) {
+		// The machine exists; it is the requested group that does not
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group not found"})
+		return
+	}
+	if err != nil {
+		topologyError(c, err, "update machine group")
+		return
+	}
+	c.JSON(http.StatusOK, machine)
+}
+
+// CreateLink handles POST /api/v1/links
+func (h *TopologyHandler) CreateLink(c *gin.Context) {
+	created, err := forRequest(c, h.Service).CreateLink(requestBody[models.MachineLink](c))
+	if err != nil {
+		topologyError(c, err, "create link")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetLinks handles GET /api/v1/links
+func (h *TopologyHandler) GetLinks(c *gin.Context) {
+	links, err := forRequest(c, h.Service).GetLinks()
+	if err != nil {
+		topologyError(c, err, "retrieve links")
+		return
+	}
+	c.JSON(http.StatusOK, links)
+}
+
+// DeleteLink handles DELETE /api/v1/links/:id
+func (h *TopologyHandler) DeleteLink(c *gin.Context) {
+	if err := forRequest(c, h.Service).DeleteLink(pathID(c, "id")); err != nil {
+		topologyError(c, err, "delete link")
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// GetTopology handles GET /api/v1/topology, optionally limited to one group with ?group_id=
+func (h *TopologyHandler) GetTopology(c *gin.Context) {
+	var groupID *uint
+	if id, ok := queryUint(c, "group_id"); ok {
+		groupID = &id
+	}
+
+	graph, err := forRequest(c, h.Service).GetTopology(groupID)
+	if err != nil {
+		topologyError(c, err, "retrieve topology")
+		return
+	}
+	c.JSON(http.StatusOK, graph)
+}
@@ -0,0 +1,126 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockTopologyService serves group 1, which is not empty, group 2, which is, and link 1
+type MockTopologyService struct{}
+
+func (m *MockTopologyService) ForTenant(uint) service.TopologyService              { return m }
+func (m *MockTopologyService) WithContext(context.Context) service.TopologyService { return m }
+
+func (m *MockTopologyService) CreateGroup(group models.MachineGroup) (models.MachineGroup, error) {
+	if group.Kind != models.GroupKindSite {
+		return models.MachineGroup{}, fmt.Errorf("%w: a %s needs a parent", service.ErrInvalidTopology, group.Kind)
+	}
+	group.ID = 3
+	return group, nil
+}
+func (m *MockTopologyService) GetGroups() ([]models.MachineGroup, error) { return nil, nil }
+func (m *MockTopologyService) GetGroupByID(id uint) (models.MachineGroup, error) {
+	if id > 2 {
+		return models.MachineGroup{}, service.ErrGroupNotFound
+	}
+	return models.MachineGroup{Model: models.Model{ID: id}, Name: "Line", Kind: models.GroupKindLine}, nil
+}
+func (m *MockTopologyService) DeleteGroup(id uint) error {
+	switch {
+	case id == 1:
+		return service.ErrGroupNotEmpty
+	case id > 2:
+		return service.ErrGroupNotFound
+	}
+	return nil
+}
+func (m *MockTopologyService) SetMachineGroup(machineID uint, groupID *uint) (models.Machine, error) {
+	if machineID == 99 {
+		return models.Machine{}, service.ErrMachineNotFound
+	}
+	if groupID != nil && *groupID > 2 {
+		return models.Machine{}, service.ErrGroupNotFound
+	}
+	return models.Machine{Model: models.Model{ID: machineID}, Name: "TestMachine", Status: "Idle", GroupID: groupID}, nil
+}
+func (m *MockTopologyService) CreateLink(link models.MachineLink) (models.MachineLink, error) {
+	switch {
+	case link.FromMachineID == link.ToMachineID:
+		return models.MachineLink{}, fmt.Errorf("%w: a machine cannot feed itself", service.ErrInvalidTopology)
+	case link.FromMachineID == 1 && link.ToMachineID == 2:
+		return models.MachineLink{}, service.ErrLinkExists
+	}
+	link.ID = 2
+	return link, nil
+}
+func (m *MockTopologyService) GetLinks() ([]models.MachineLink, error) { return nil, nil }
+func (m *MockTopologyService) DeleteLink(id uint) error {
+	if id != 1 {
+		return service.ErrLinkNotFound
+	}
+	return nil
+}
+func (m *MockTopologyService) GetTopology(groupID *uint) (service.TopologyGraph, error) {
+	if groupID != nil && *groupID > 2 {
+		return service.TopologyGraph{}, service.ErrGroupNotFound
+	}
+	return service.TopologyGraph{}, nil
+}
+func (m *MockTopologyService) MachineUpdated(machine models.Machine) {}
+func (m *MockTopologyService) MachineDeleted(id uint)                {}
+
+func TestTopologyHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	topologyHandler := handler.NewTopologyHandler(&MockTopologyService{})
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Topology: topologyHandler}))
+
+	cases := []struct {
+		method, path, body string
+		code               int
+	}{
+		{"POST", "/api/v1/groups", `{"name": "Plant A", "kind": "site"}`, http.StatusCreated},
+		{"POST", "/api/v1/groups", `{"name": "Line 1", "kind": "line"}`, http.StatusBadRequest},
+		{"POST", "/api/v1/groups", `{"name": "Plant A"}`, http.StatusBadRequest},
+		{"GET", "/api/v1/groups", "", http.StatusOK},
+		{"GET", "/api/v1/groups/1", "", http.StatusOK},
+		{"GET", "/api/v1/groups/3", "", http.StatusNotFound},
+		{"GET", "/api/v1/groups/abc", "", http.StatusBadRequest},
+		{"DELETE", "/api/v1/groups/1", "", http.StatusConflict},
+		{"DELETE", "/api/v1/groups/2", "", http.StatusNoContent},
+		{"DELETE", "/api/v1/groups/3", "", http.StatusNotFound},
+		{"PUT", "/api/v1/machines/1/group", `{"group_id": 2}`, http.StatusOK},
+		{"PUT", "/api/v1/machines/1/group", `{"group_id": null}`, http.StatusOK},
+		{"PUT", "/api/v1/machines/1/group", `{"group_id": 3}`, http.StatusBadRequest},
+		{"PUT", "/api/v1/machines/99/group", `{"group_id": 2}`, http.StatusNotFound},
+		{"PUT", "/api/v1/machines/abc/group", `{"group_id": 2}`, http.StatusBadRequest},
+		{"POST", "/api/v1/links", `{"from_machine_id": 2, "to_machine_id": 3}`, http.StatusCreated},
+		{"POST", "/api/v1/links", `{"from_machine_id": 1, "to_machine_id": 2}`, http.StatusConflict},
+		{"POST", "/api/v1/links", `{"from_machine_id": 2, "to_machine_id": 2}`, http.StatusBadRequest},
+		{"POST", "/api/v1/links", `not json`, http.StatusBadRequest},
+		{"GET", "/api/v1/links", "", http.StatusOK},
+		{"DELETE", "/api/v1/links/1", "", http.StatusNoContent},
+		{"DELETE", "/api/v1/links/2", "", http.StatusNotFound},
+		{"GET", "/api/v1/topology", "", http.StatusOK},
+		{"GET", "/api/v1/topology?group_id=1", "", http.StatusOK},
+		{"GET", "/api/v1/topology?group_id=3", "", http.StatusNotFound},
+		{"GET", "/api/v1/topology?group_id=abc", "", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, "Unexpected status for %s %s", tc.method, tc.path)
+	}
+}
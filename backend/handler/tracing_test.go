@@ -0,0 +1,41 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracing(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	var buf bytes.Buffer
+	assert.Nil(t, logging.Setup(&buf, logging.FormatJSON, slog.LevelInfo))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.RequestID(), handler.Tracing(), handler.AccessLog())
+	handlers := handler.Handlers{
+		Machine: handler.NewMachineHandler(service.NewMachineService(&MockMachineRepository{})),
+	}
+	handler.RegisterRoutes(router, handler.Routes(handlers))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/machines/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var record map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", record[logging.TraceIDKey], "Requests continue the trace of their caller")
+	assert.NotEmpty(t, record[logging.SpanIDKey])
+}
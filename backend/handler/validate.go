@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/CBYeuler/automation-backend/backend/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	// bodyKey is the context key of the request body decoded by the validation middleware
+	bodyKey = "handler.requestBody"
+	// MaxRequestBodyBytes caps the size of request bodies, enough for the largest batch
+	MaxRequestBodyBytes = 4 << 20
+)
+
+// Validate returns a middleware that checks requests against the documented operation of a
+// route before they reach its handler: path and query parameters and the JSON body must match
+// their schemas, or the request is rejected with 400. The body is then decoded into the route's
+// body type for requestBody; bodies over MaxRequestBodyBytes are rejected with 413. In gin's test mode, responses are checked against the documented
+// responses too, and a mismatch turns the response into a 500.
+func Validate(doc *openapi.Document, r openapi.Route) gin.HandlerFunc {
+	op := doc.Lookup(r.Method, r.Path)
+	var bodyType reflect.Type
+	if r.Body != nil {
+		bodyType = reflect.TypeOf(r.Body)
+	}
+
+	return func(c *gin.Context) {
+		if op == nil {
+			c.Next()
+			return
+		}
+		if err := validateRequest(c, doc, op, bodyType); err != nil {
+			status := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		if gin.Mode() != gin.TestMode {
+			c.Next()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer, status: c.Writer.Status()}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if err := validateResponse(doc, op, w); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Response does not match the OpenAPI document", "method", r.Method, "route", r.Path, "error", err)
+			c.Writer.Header().Del("Content-Type")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Response does not match the OpenAPI document: " + err.Error()})
+			return
+		}
+		w.flush()
+	}
+}
+
+func validateRequest(c *gin.Context, doc *openapi.Document, op *openapi.Operation, bodyType reflect.Type) error {
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			if err := doc.ValidateParameter(p, c.Param(p.Name)); err != nil {
+				return fmt.Errorf("invalid path parameter: %w", err)
+			}
+		case "query":
+			for _, raw := range c.QueryArray(p.Name) {
+				if err := doc.ValidateParameter(p, raw); err != nil {
+					return fmt.Errorf("invalid query parameter: %w", err)
+				}
+			}
+		}
+	}
+
+	if op.RequestBody == nil || bodyType == nil {
+		return nil
+	}
+	var raw []byte
+	if c.Request.Body != nil {
+		var err error
+		if raw, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxRequestBodyBytes)); err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if op.RequestBody.Required {
+			return errors.New("request body is required")
+		}
+		return nil
+	}
+
+	value, err := openapi.DecodeJSON(raw)
+	if err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	if err := doc.Validate(op.RequestBody.Content["application/json"].Schema, value); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	body := reflect.New(bodyType).Interface()
+	if err := json.Unmarshal(raw, body); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	// binding tags can be stricter than the schema, e.g. required also rejects empty values
+	if binding.Validator != nil {
+		if err := binding.Validator.ValidateStruct(body); err != nil {
+			return err
+		}
+	}
+	c.Set(bodyKey, body)
+	return nil
+}
+
+func validateResponse(doc *openapi.Document, op *openapi.Operation, w *recordingWriter) error {
+	if w.status >= 300 && w.status < 400 {
+		return nil // redirects and Not Modified carry no body
+	}
+	res, ok := op.Responses[strconv.Itoa(w.status)]
+	if !ok {
+		return fmt.Errorf("status %d is not documented", w.status)
+	}
+	media, ok := res.Content["application/json"]
+	if !ok || w.body.Len() == 0 || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return nil
+	}
+	value, err := openapi.DecodeJSON(w.body.Bytes())
+	if err != nil {
+		return err
+	}
+	return doc.Validate(media.Schema, value)
+}
+
+// recordingWriter holds back a response until it has been validated
+type recordingWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *recordingWriter) WriteHeaderNow()                   {}
+func (w *recordingWriter) Write(data []byte) (int, error)    { return w.body.Write(data) }
+func (w *recordingWriter) WriteString(s string) (int, error) { return w.body.WriteString(s) }
+func (w *recordingWriter) Status() int                       { return w.status }
+func (w *recordingWriter) Size() int                         { return w.body.Len() }
+func (w *recordingWriter) Written() bool                     { return w.body.Len() > 0 }
+func (w *recordingWriter) Flush()                            {}
+
+func (w *recordingWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	if w.body.Len() == 0 {
+		return
+	}
+	if _, err := w.ResponseWriter.Write(w.body.Bytes()); err != nil {
+		slog.Error("Failed to write response", "error", err)
+	}
+}
+
+// requestBody returns the body decoded by the validation middleware, or the zero value
+// when an optional body was omitted
+func requestBody[T any](c *gin.Context) T {
+	if body, ok := c.Get(bodyKey); ok {
+		if v, ok := body.(*T); ok {
+			return *v
+		}
+	}
+	var zero T
+	return zero
+}
+
+// pathID returns a path parameter the validation middleware has checked to be an unsigned integer
+func pathID(c *gin.Context, name string) uint {
+	id, _ := strconv.ParseUint(c.Param(name), 10, 64)
+	return uint(id)
+}
+
+// queryUint returns an optional query parameter the validation middleware has checked to be an
+// unsigned integer, and whether it was given
+func queryUint(c *gin.Context, name string) (uint, bool) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return 0, false
+	}
+	v, _ := strconv.ParseUint(raw, 10, 64)
+	return uint(v), true
+}
@@ -0,0 +1,105 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRequests(t *testing.T) {
+	router, _ := setupRouter()
+
+	cases := []struct {
+		name, method, path, body string
+		code                     int
+		err                      string
+	}{
+		{"ValidBody", "PUT", "/api/v1/machines/1", `{"name": "Press", "status": "Idle", "tags": ["line-1"]}`, http.StatusOK, ""},
+		{"UnknownPropertiesAllowed", "POST", "/api/v1/machines", `{"name": "Press", "color": "red"}`, http.StatusCreated, ""},
+		{"PathNotAnInteger", "GET", "/api/v1/machines/abc", "", http.StatusBadRequest, "invalid path parameter: id must be an integer"},
+		{"PathNegative", "DELETE", "/api/v1/machines/-1", "", http.StatusBadRequest, "invalid path parameter: id must be at least 0"},
+		{"MissingBody", "POST", "/api/v1/machines", "", http.StatusBadRequest, "request body is required"},
+		{"MalformedBody", "POST", "/api/v1/machines", `{"name": `, http.StatusBadRequest, "invalid JSON body: unexpected EOF"},
+		{"MissingRequiredField", "POST", "/api/v1/machines", `{"status": "Idle"}`, http.StatusBadRequest, "invalid request body: name is required"},
+		{"WrongType", "PUT", "/api/v1/machines/1", `{"name": "Press", "tags": "line-1"}`, http.StatusBadRequest, "invalid request body: tags must be an array"},
+		{"StatusEnum", "PUT", "/api/v1/machines/1", `{"name": "Press", "status": "Broken"}`, http.StatusBadRequest, "invalid request body: status must be one of Offline, Idle, Running, Error"},
+		{"CommandEnum", "POST", "/api/v1/machines/commands", `{"command": "explode", "ids": [1]}`, http.StatusBadRequest, "invalid request body: command must be one of start, stop, reset"},
+		{"SelectorEnum", "POST", "/api/v1/machines/commands", `{"command": "stop", "status": "idle"}`, http.StatusBadRequest, "invalid request body: status must be one of Offline, Idle, Running, Error"},
+		{"BindingStricterThanSchema", "POST", "/api/v1/machines", `{"name": ""}`, http.StatusBadRequest, "'required' tag"},
+		{"BodyTooLarge", "POST", "/api/v1/machines", `{"name": "` + strings.Repeat("x", handler.MaxRequestBodyBytes) + `"}`, http.StatusRequestEntityTooLarge, "request body too large"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.code, w.Code, w.Body.String())
+			if tc.err != "" {
+				var body openapi.Error
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Contains(t, body.Error, tc.err)
+			}
+		})
+	}
+
+	// The validated body reaches the handler decoded
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/v1/machines/7", bytes.NewBufferString(`{"name": "Press", "status": "Running"}`))
+	router.ServeHTTP(w, req)
+	var machine models.Machine
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &machine))
+	assert.Equal(t, uint(7), machine.ID)
+	assert.Equal(t, "Running", machine.Status)
+}
+
+func TestValidateResponsesInTestMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler.RegisterRoutes(router, []handler.Route{
+		{openapi.Route{Method: http.MethodGet, Path: "/machines/:id", Status: http.StatusOK, Response: models.Machine{}, Errors: []int{http.StatusNotFound}},
+			func(c *gin.Context) {
+				switch c.Param("id") {
+				case "1":
+					c.JSON(http.StatusOK, models.Machine{Name: "Press", Status: "Idle"})
+				case "2":
+					c.JSON(http.StatusOK, models.Machine{Name: "Press", Status: "Exploded"})
+				case "3":
+					c.JSON(http.StatusTeapot, gin.H{"error": "I'm a teapot"})
+				default:
+					c.JSON(http.StatusNotFound, gin.H{"message": "Machine not found"})
+				}
+			}},
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/machines/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `"name":"Press"`))
+
+	for path, want := range map[string]string{
+		"/machines/2": "status must be one of Offline, Idle, Running, Error",
+		"/machines/3": "status 418 is not documented",
+		"/machines/4": "error is required",
+	} {
+		w := get(path)
+		assert.Equal(t, http.StatusInternalServerError, w.Code, path)
+		assert.Contains(t, w.Body.String(), want, path)
+	}
+}
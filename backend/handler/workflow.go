@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// WorkflowHandler contains the service interface for dependency injection
+type WorkflowHandler struct {
+	Service service.WorkflowService
+}
+
+// NewWorkflowHandler creates a new handler instance
+func NewWorkflowHandler(s service.WorkflowService) *WorkflowHandler {
+	return &WorkflowHandler{Service: s}
+}
+
+// workflowError writes the response for an error returned by the workflow service
+func workflowError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, service.ErrWorkflowNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+	case errors.Is(err, service.ErrWorkflowRunNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow run not found"})
+	case errors.Is(err, service.ErrWorkflowExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Workflow already exists"})
+	case errors.Is(err, service.ErrInvalidWorkflow):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrQuotaExceeded):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		slog.ErrorContext(c.Request.Context(), "Failed to "+action, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action})
+	}
+}
+
+// CreateWorkflow handles POST /api/v1/workflows
+func (h *WorkflowHandler) CreateWorkflow(c *gin.Context) {
+	created, err := forRequest(c, h.Service).CreateWorkflow(requestBody[models.Workflow](c))
+	if err != nil {
+		workflowError(c, err, "create workflow")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetWorkflows handles GET /api/v1/workflows
+func (h *WorkflowHandler) GetWorkflows(c *gin.Context) {
+	workflows, err := forRequest(c, h.Service).GetWorkflows()
+	if err != nil {
+		workflowError(c, err, "retrieve workflows")
+		return
+	}
+	c.JSON(http.StatusOK, workflows)
+}
+
+// GetWorkflowByID handles GET /api/v1/workflows/:id
+func (h *WorkflowHandler) GetWorkflowByID(c *gin.Context) {
+	wf, err := forRequest(c, h.Service).GetWorkflowByID(pathID(c, "id"))
+	if err != nil {
+		workflowError(c, err, "retrieve workflow")
+		return
+	}
+	c.JSON(http.StatusOK, wf)
+}
+
+// DeleteWorkflow handles DELETE /api/v1/workflows/:id
+func (h *WorkflowHandler) DeleteWorkflow(c *gin.Context) {
+	if err := forRequest(c, h.Service).DeleteWorkflow(pathID(c, "id")); err != nil {
+		workflowError(c, err, "delete workflow")
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// StartRun handles POST /api/v1/workflows/:id/runs
+func (h *WorkflowHandler) StartRun(c *gin.Context) {
+	// An empty body starts a run without inputs
+	run, err := forRequest(c, h.Service).StartRun(pathID(c, "id"), requestBody[service.WorkflowRunRequest](c))
+	if err != nil {
+		workflowError(c, err, "start workflow run")
+		return
+	}
+	c.JSON(http.StatusAccepted, run)
+}
+
+// GetRun handles GET /api/v1/workflow-runs/:id, including the state of every step
+func (h *WorkflowHandler) GetRun(c *gin.Context) {
+	run, err := forRequest(c, h.Service).GetRun(pathID(c, "id"))
+	if err != nil {
+		workflowError(c, err, "retrieve workflow run")
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
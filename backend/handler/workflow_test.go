@@ -0,0 +1,97 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockWorkflowService serves workflow 1 and workflow run 1
+type MockWorkflowService struct{}
+
+func (m *MockWorkflowService) ForTenant(uint) service.WorkflowService              { return m }
+func (m *MockWorkflowService) WithContext(context.Context) service.WorkflowService { return m }
+
+func (m *MockWorkflowService) CreateWorkflow(wf models.Workflow) (models.Workflow, error) {
+	switch {
+	case wf.Name == "exists":
+		return models.Workflow{}, service.ErrWorkflowExists
+	case len(wf.Steps) == 0:
+		return models.Workflow{}, fmt.Errorf("%w: a workflow needs at least one step", service.ErrInvalidWorkflow)
+	}
+	wf.ID = 1
+	return wf, nil
+}
+func (m *MockWorkflowService) GetWorkflows() ([]models.Workflow, error) {
+	return []models.Workflow{{Model: models.Model{ID: 1}}}, nil
+}
+func (m *MockWorkflowService) GetWorkflowByID(id uint) (models.Workflow, error) {
+	if id != 1 {
+		return models.Workflow{}, service.ErrWorkflowNotFound
+	}
+	return models.Workflow{Model: models.Model{ID: 1}}, nil
+}
+func (m *MockWorkflowService) DeleteWorkflow(id uint) error {
+	if id != 1 {
+		return service.ErrWorkflowNotFound
+	}
+	return nil
+}
+func (m *MockWorkflowService) StartRun(workflowID uint, req service.WorkflowRunRequest) (models.WorkflowRun, error) {
+	if workflowID != 1 {
+		return models.WorkflowRun{}, service.ErrWorkflowNotFound
+	}
+	return models.WorkflowRun{Model: models.Model{ID: 1}, WorkflowID: 1, Status: models.WorkflowStatusRunning}, nil
+}
+func (m *MockWorkflowService) GetRun(id uint) (service.WorkflowRunResult, error) {
+	if id != 1 {
+		return service.WorkflowRunResult{}, service.ErrWorkflowRunNotFound
+	}
+	return service.WorkflowRunResult{WorkflowRun: models.WorkflowRun{Model: models.Model{ID: 1}}}, nil
+}
+
+func TestWorkflowHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	workflowHandler := handler.NewWorkflowHandler(&MockWorkflowService{})
+	handler.RegisterRoutes(router, handler.Routes(handler.Handlers{Workflow: workflowHandler}))
+
+	cases := []struct {
+		method, path, body string
+		code               int
+	}{
+		{"POST", "/api/v1/workflows", `{"name": "qualify", "steps": [{"name": "a", "machine_id": 1}]}`, http.StatusCreated},
+		{"POST", "/api/v1/workflows", `{"name": "qualify"}`, http.StatusBadRequest},
+		{"POST", "/api/v1/workflows", `{"name": "exists", "steps": [{"name": "a", "machine_id": 1}]}`, http.StatusConflict},
+		{"POST", "/api/v1/workflows", `not json`, http.StatusBadRequest},
+		{"GET", "/api/v1/workflows", "", http.StatusOK},
+		{"GET", "/api/v1/workflows/1", "", http.StatusOK},
+		{"GET", "/api/v1/workflows/2", "", http.StatusNotFound},
+		{"GET", "/api/v1/workflows/abc", "", http.StatusBadRequest},
+		{"DELETE", "/api/v1/workflows/1", "", http.StatusNoContent},
+		{"DELETE", "/api/v1/workflows/2", "", http.StatusNotFound},
+		{"POST", "/api/v1/workflows/1/runs", "", http.StatusAccepted},
+		{"POST", "/api/v1/workflows/1/runs", `{"inputs": {"load": 0.8}}`, http.StatusAccepted},
+		{"POST", "/api/v1/workflows/1/runs", `not json`, http.StatusBadRequest},
+		{"POST", "/api/v1/workflows/2/runs", "", http.StatusNotFound},
+		{"GET", "/api/v1/workflow-runs/1", "", http.StatusOK},
+		{"GET", "/api/v1/workflow-runs/2", "", http.StatusNotFound},
+		{"GET", "/api/v1/workflow-runs/abc", "", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Code, "Unexpected status for %s %s", tc.method, tc.path)
+	}
+}
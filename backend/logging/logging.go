@@ -0,0 +1,125 @@
+// Package logging sets up the backend's structured logs: log/slog records written as text or
+// JSON, at a level that can be changed while the backend runs, and tagged with the ID of the
+// request and the trace span they were written for.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Log formats selectable with LOG_FORMAT
+const (
+	FormatText = "text"
+	FormatJSON = "json" // one JSON object per line, for log shippers
+)
+
+// RequestIDKey is the attribute naming the request a record was written for
+const RequestIDKey = "request_id"
+
+// TraceIDKey and SpanIDKey are the attributes naming the trace span a record was written in
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// level is the minimum level of records written, shared by every logger Setup creates
+var level = new(slog.LevelVar)
+
+type requestIDKey struct{}
+
+// Setup makes slog's default logger, which the standard log package writes through too,
+// write records in the format to w
+func Setup(w io.Writer, format string, minLevel slog.Level) error {
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch format {
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("log format must be %q or %q, got %q", FormatText, FormatJSON, format)
+	}
+	level.Set(minLevel)
+	slog.SetDefault(slog.New(ContextHandler{h}))
+	return nil
+}
+
+// Level returns the minimum level of records written
+func Level() slog.Level {
+	return level.Level()
+}
+
+// SetLevel changes the minimum level of records written, effective immediately
+func SetLevel(l slog.Level) {
+	level.Set(l)
+	slog.Info("Log level changed", "level", l)
+}
+
+// ParseLevel reads a level name such as debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("log level must be debug, info, warn or error, got %q", s)
+	}
+	return l, nil
+}
+
+// NewRequestID returns a random request ID
+func NewRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a context carrying a request ID, which records logged with it are
+// tagged with
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by a context, or ""
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ContextHandler tags records with the request ID and trace span of the context they are
+// logged with
+type ContextHandler struct {
+	slog.Handler
+}
+
+func (h ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	if ctx != nil {
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			r.AddAttrs(slog.String(TraceIDKey, span.TraceID().String()), slog.String(SpanIDKey, span.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ContextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h ContextHandler) WithGroup(name string) slog.Handler {
+	return ContextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,104 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &record), line)
+		out = append(out, record)
+	}
+	buf.Reset()
+	return out
+}
+
+func TestSetup(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	var buf bytes.Buffer
+	assert.NotNil(t, logging.Setup(&buf, "xml", slog.LevelInfo))
+	assert.Nil(t, logging.Setup(&buf, logging.FormatJSON, slog.LevelInfo))
+
+	ctx := logging.WithRequestID(context.Background(), "abc123")
+	slog.InfoContext(ctx, "Machine created", "machine_id", 7)
+	slog.Debug("Hidden")
+	log.Printf("From the standard logger")
+	got := records(t, &buf)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "Machine created", got[0]["msg"])
+		assert.Equal(t, "abc123", got[0][logging.RequestIDKey])
+		assert.Equal(t, float64(7), got[0]["machine_id"])
+		assert.Equal(t, "From the standard logger", got[1]["msg"])
+		assert.NotContains(t, got[1], logging.RequestIDKey)
+	}
+
+	logging.SetLevel(slog.LevelDebug)
+	assert.Equal(t, slog.LevelDebug, logging.Level())
+	slog.With("machine_id", 3).DebugContext(ctx, "Shown")
+	got = records(t, &buf)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "Log level changed", got[0]["msg"])
+		assert.Equal(t, "Shown", got[1]["msg"])
+		assert.Equal(t, "abc123", got[1][logging.RequestIDKey], "Loggers with attributes keep tagging records")
+	}
+	logging.SetLevel(slog.LevelInfo)
+}
+
+func TestParseLevel(t *testing.T) {
+	for raw, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, " error ": slog.LevelError} {
+		l, err := logging.ParseLevel(raw)
+		assert.Nil(t, err, raw)
+		assert.Equal(t, want, l)
+	}
+	_, err := logging.ParseLevel("loud")
+	assert.NotNil(t, err)
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Equal(t, "", logging.RequestID(context.Background()))
+	var none context.Context
+	assert.Equal(t, "", logging.RequestID(none), "Code outside of requests may log without a context")
+	assert.Len(t, logging.NewRequestID(), 16)
+	assert.NotEqual(t, logging.NewRequestID(), logging.NewRequestID())
+}
+
+func TestGormLogger(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	var buf bytes.Buffer
+	assert.Nil(t, logging.Setup(&buf, logging.FormatJSON, slog.LevelInfo))
+	ctx := logging.WithRequestID(context.Background(), "abc123")
+	gl := logging.NewGormLogger(100 * time.Millisecond)
+	query := func() (string, int64) { return "SELECT * FROM machines", 3 }
+
+	gl.Trace(ctx, time.Now(), query, nil)
+	gl.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+	assert.Empty(t, records(t, &buf), "Fast queries are only logged at debug level")
+
+	gl.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+	gl.Trace(ctx, time.Now(), query, errors.New("database is locked"))
+	got := records(t, &buf)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "Slow query", got[0]["msg"])
+		assert.Equal(t, "WARN", got[0]["level"])
+		assert.Equal(t, "abc123", got[0][logging.RequestIDKey])
+		assert.Equal(t, "Query failed", got[1]["msg"])
+		assert.Equal(t, "database is locked", got[1]["error"])
+	}
+}
@@ -1,56 +1,309 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/CBYeuler/automation-backend/backend/artifact"
+	"github.com/CBYeuler/automation-backend/backend/config"
 	"github.com/CBYeuler/automation-backend/backend/database"
 	"github.com/CBYeuler/automation-backend/backend/handler"
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/CBYeuler/automation-backend/backend/metrics"
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/CBYeuler/automation-backend/backend/ratelimit"
 	"github.com/CBYeuler/automation-backend/backend/repository"
 	"github.com/CBYeuler/automation-backend/backend/service"
 	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/CBYeuler/automation-backend/backend/token"
+	"github.com/CBYeuler/automation-backend/backend/tracing"
+	"github.com/CBYeuler/automation-backend/backend/workflow"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// telemetryRetention is how long simulated telemetry is kept before being purged
+	telemetryRetention = 7 * 24 * time.Hour
+	// telemetryPurgeInterval is how often expired telemetry is purged
+	telemetryPurgeInterval = time.Hour
+	// artifactPurgeInterval is how often expired artifacts are purged
+	artifactPurgeInterval = time.Hour
+	// jobConsumers is how many ad-hoc runs from the job queue execute concurrently
+	jobConsumers = 2
+	// shutdownTimeout is how long requests being served may take to finish on shutdown
+	shutdownTimeout = 10 * time.Second
 )
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
+	// Logs are structured from here on, records written while serving a request carrying its ID
+	if err := logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel); err != nil {
+		log.Fatal(err)
+	}
+	// Spans of requests, service calls, queries and runs are exported from here on
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TraceExporter, cfg.TraceFile, cfg.TraceSampleRatio)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+	slog.Info("Tracing", "exporter", cfg.TraceExporter, "sample_ratio", cfg.TraceSampleRatio)
+	// Background work, such as workflow runs, is interrupted once the process is asked to stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize the database connection
 	database.ConnectDatabase()
 
 	db := database.GetDB()
 
+	// Organizations keep the machines of different teams, and everything about them, apart
+	organizationRepo := repository.NewOrganizationRepository(db)
+	organizationService := service.NewOrganizationService(organizationRepo)
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(db))
+	if len(os.Args) > 1 && (os.Args[1] == "apikey" || os.Args[1] == "org") {
+		var err error
+		if os.Args[1] == "org" {
+			err = runOrganizationCommand(organizationService, os.Args[2:])
+		} else {
+			err = runAPIKeyCommand(organizationService, apiKeyService, os.Args[2:])
+		}
+		if err != nil {
+			fatal("Command failed", err)
+		}
+		return
+	}
+	organizationHandler := handler.NewOrganizationHandler(organizationService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+
 	machineRepo := repository.NewMachineRepository(db)
-	machineService := service.NewMachineService(machineRepo)
+	telemetryRepo := repository.NewTelemetryRepository(db)
+	runRepo := repository.NewRunRepository(db)
+
+	// The simulator listens to machine changes so stop/delete commands cancel runs immediately
+	machineSimulator := simulation.NewMachineSimulator(machineRepo, telemetryRepo, runRepo)
+	// Machines only name the runner of their runs, the commands behind the names are the server's
+	machineSimulator.Runner = &simulation.DefaultRunner{Commands: cfg.Runners}
+
+	// Links between machines let an upstream machine in Error starve the machines it feeds
+	topologyRepo := repository.NewTopologyRepository(db)
+	machineSimulator.Topology = topologyRepo
+	topologyService := service.NewTopologyService(topologyRepo, machineRepo, machineSimulator, machineSimulator)
+	topologyHandler := handler.NewTopologyHandler(topologyService)
+
+	// Services record a span for every call made while serving a traced request
+	machineService := &service.MachineServiceImpl{Repo: machineRepo, Groups: topologyRepo, Listeners: []service.MachineListener{machineSimulator, topologyService}}
 	machineHandler := handler.NewMachineHandler(machineService)
 
-	machineSimulator := simulation.NewMachineSimulator(machineRepo)
+	telemetryService := service.NewTelemetryService(telemetryRepo, machineRepo)
+	telemetryHandler := handler.NewTelemetryHandler(telemetryService)
+	telemetryService.StartRetention(telemetryRetention, telemetryPurgeInterval)
+
+	alarmRepo := repository.NewAlarmRepository(db)
+	alarmService := service.NewAlarmService(alarmRepo, machineRepo)
+	alarmHandler := handler.NewAlarmHandler(alarmService)
+
+	runService := service.NewRunService(runRepo, machineRepo)
+	runHandler := handler.NewRunHandler(runService)
+
+	// Each recorded run captures its own output, including its command's stdout and stderr
+	runLogs := simulation.NewLogHub(repository.NewRunLogRepository(db))
+	machineSimulator.Logs = runLogs
+	runLogHandler := handler.NewRunLogHandler(runService, runLogs)
+
+	// Files left by runs in $RUN_ARTIFACTS are kept in the artifact store
+	artifactStore, err := newArtifactStore(cfg)
+	if err != nil {
+		fatal("Failed to open artifact store", err)
+	}
+	artifactService := service.NewArtifactService(repository.NewArtifactRepository(db), runRepo, artifactStore)
+	artifactHandler := handler.NewArtifactHandler(artifactService)
+	machineSimulator.Artifacts = artifactService
+	if cfg.ArtifactRetention > 0 {
+		artifactService.StartRetention(cfg.ArtifactRetention, artifactPurgeInterval)
+	}
+
+	// Ad-hoc runs go through a durable queue so submissions survive restarts
+	jobQueue, err := newJobQueue(cfg, db)
+	if err != nil {
+		fatal("Failed to connect to job queue", err)
+	}
+	jobService := service.NewJobService(jobQueue, machineRepo, cfg.RunQuota)
+	jobHandler := handler.NewJobHandler(jobService)
+
+	// Parameter sweeps queue their points as jobs; unfinished ones resume after a restart
+	batchRepo := repository.NewBatchRepository(db)
+	batchService := service.NewBatchService(batchRepo, machineRepo, jobQueue, cfg.RunQuota)
+	batchHandler := handler.NewBatchHandler(batchService)
+	if err := batchService.ResumeBatches(); err != nil {
+		slog.Error("Failed to resume batches", "error", err)
+	}
+
+	// Workflows chain ad-hoc runs into DAGs; unfinished runs resume after a restart
+	workflowRepo := repository.NewWorkflowRepository(db)
+	workflowEngine := workflow.NewEngine(ctx, workflowRepo, machineSimulator)
+	workflowService := service.NewWorkflowService(workflowRepo, machineRepo, workflowEngine, cfg.RunQuota)
+	workflowHandler := handler.NewWorkflowHandler(workflowService)
+	if err := workflowEngine.Resume(); err != nil {
+		slog.Error("Failed to resume workflow runs", "error", err)
+	}
+
+	machineSimulator.AddObserver(alarmService)
 	machineSimulator.StartGlobalSimulation()
+	machineSimulator.StartJobConsumers(jobQueue, jobConsumers, queue.DefaultVisibilityTimeout)
+	// Machines by status, simulation goroutines and the queue depth are sampled at every scrape
+	metrics.Registry.MustRegister(metrics.NewStateCollector(machineRepo, machineSimulator, jobQueue))
+	faultHandler := handler.NewFaultHandler(machineSimulator, machineService)
+	simulatorHandler := handler.NewSimulatorHandler(machineSimulator)
 
-	router := gin.Default()
+	routes := handler.Routes(handler.Handlers{
+		Machine:   machineHandler,
+		Topology:  topologyHandler,
+		Telemetry: telemetryHandler,
+		Run:       runHandler,
+		RunLog:    runLogHandler,
+		Artifact:  artifactHandler,
+		Job:       jobHandler,
+		Batch:     batchHandler,
+		Workflow:  workflowHandler,
+		Fault:     faultHandler,
+		Simulator: simulatorHandler,
+		Alarm:     alarmHandler,
+		APIKey:    apiKeyHandler,
+		Logging:   handler.NewLoggingHandler(),
 
-	// Define a simple health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "OK",
-		})
+		Organization: organizationHandler,
 	})
-	api := router.Group("/api/v1")
-	{
-		// TODO: Add machine routes (CRUD) here in the next step
+	// Addresses are rate limited before authenticating, so that guessing credentials is slow
+	middleware := []handler.RouteMiddleware{handler.RateLimitAddresses(ratelimit.NewLimiter(cfg.RateLimitAddress))}
+	if cfg.APIAuth {
+		var tokenService service.TokenService
+		if cfg.JWTAuth() {
+			keys := token.NewFileKeySet(cfg.JWKSFile)
+			if cfg.JWKSURL != "" {
+				keys = token.NewRemoteKeySet(cfg.JWKSURL, nil, cfg.JWKSRefresh)
+			}
+			verifier := &token.Verifier{Keys: keys, Issuer: cfg.JWTIssuer, Audience: cfg.JWTAudience, Leeway: time.Minute}
+			claims := service.TokenClaims{Scope: cfg.JWTScopeClaim, Roles: cfg.JWTRolesClaim, Organization: cfg.JWTOrgClaim}
+			tokenService = service.NewTokenService(verifier, claims, organizationRepo)
+			slog.Info("Accepting JWT bearer tokens", "issuer", cfg.JWTIssuer, "keys", keys.Source)
+		}
+		middleware = append(middleware, handler.RequireAuth(apiKeyService, tokenService))
+	} else {
+		slog.Warn("API_AUTH is off, anyone who can reach the server can use the API")
+	}
+	// Clients are rate limited after authenticating, so each identity gets buckets of its own
+	middleware = append(middleware, handler.RateLimit(map[string]*ratelimit.Limiter{
+		handler.RateLimitRead:   ratelimit.NewLimiter(cfg.RateLimitRead),
+		handler.RateLimitWrite:  ratelimit.NewLimiter(cfg.RateLimitWrite),
+		handler.RateLimitSubmit: ratelimit.NewLimiter(cfg.RateLimitSubmit),
+	}))
+	slog.Info("Rate limits per client", "address", cfg.RateLimitAddress.String(), "read", cfg.RateLimitRead.String(), "write", cfg.RateLimitWrite.String(), "submit", cfg.RateLimitSubmit.String(), "run_quota", cfg.RunQuota)
+
+	// Every request gets an ID and a span before anything logs about it, and is logged and
+	// measured once answered
+	router := gin.New()
+	// Client addresses, which rate limits are counted by, are only taken from X-Forwarded-For
+	// when set by a trusted proxy
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		fatal("Invalid TRUSTED_PROXIES", err)
+	}
+	router.Use(gin.Recovery(), handler.RequestID(), handler.Tracing(), handler.AccessLog(), handler.Metrics())
+	// The OpenAPI document is generated from the same routes, so it always matches them,
+	// and every request is validated against it before reaching its handler
+	doc := handler.RegisterRoutes(router, routes, middleware...)
+	docsHandler, err := handler.NewDocsHandler(doc)
+	if err != nil {
+		fatal("Failed to generate OpenAPI document", err)
+	}
+	docsHandler.Register(router)
 
-		api.POST("/machines", machineHandler.CreateMachine)
-		api.GET("/machines", machineHandler.GetMachines)
-		api.GET("/machines/:id", machineHandler.GetMachineByID)
-		api.PUT("/machines/:id", machineHandler.UpdateMachine)
-		api.DELETE("/machines/:id", machineHandler.DeleteMachine)
+	// Metrics cover every organization, so they are served on a listener of their own that
+	// only Prometheus should reach, not next to the API
+	metricsRouter := gin.New()
+	metricsRouter.Use(gin.Recovery())
+	handler.NewMetricsHandler(metrics.Registry).Register(metricsRouter)
+	metricsServer := &http.Server{Addr: cfg.MetricsAddr, Handler: metricsRouter}
+	go func() {
+		slog.Info("Serving metrics", "addr", metricsServer.Addr)
+		if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Failed to serve metrics", "error", err)
+		}
+	}()
 
-		// Placeholder route to verify server is running
-		// api.GET("/machines", func(c *gin.Context) {
-		//	 c.JSON(200, gin.H{"message": "Machine list placeholder"})
-		// })
+	server := &http.Server{Addr: ":8080", Handler: router}
+	served := make(chan error, 1)
+	go func() {
+		slog.Info("Starting API server", "addr", server.Addr)
+		served <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-served:
+		_ = shutdownTracing(context.Background())
+		fatal("Failed to start server", err)
+	case <-ctx.Done():
 	}
 
-	log.Println("Starting API Server on :8080...")
-	err := router.Run(":8080")
+	// A second signal kills the process right away. Requests being served get to finish and
+	// workflow runs to record their interruption before the last spans are exported.
+	stop()
+	slog.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shut down API server", "error", err)
+	}
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shut down metrics server", "error", err)
+	}
+	workflowEngine.Wait()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to export remaining spans", "error", err)
+	}
+}
+
+// newJobQueue opens the job queue selected by QUEUE_DRIVER
+func newJobQueue(cfg config.Config, db *gorm.DB) (queue.Queue, error) {
+	if cfg.QueueDriver != config.QueueDriverRedis {
+		slog.Info("Using database job queue")
+		return queue.NewDBQueue(db), nil
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr, Password: cfg.RedisPassword, DB: cfg.RedisDB})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+	slog.Info("Using Redis job queue", "addr", cfg.RedisAddr)
+	return queue.NewRedisQueue(client, cfg.RedisPrefix), nil
+}
+
+// newArtifactStore opens the artifact store selected by ARTIFACT_STORE
+func newArtifactStore(cfg config.Config) (artifact.Store, error) {
+	if cfg.ArtifactStore != config.ArtifactStoreS3 {
+		slog.Info("Storing artifacts locally", "dir", cfg.ArtifactDir)
+		return artifact.NewLocalStore(cfg.ArtifactDir)
+	}
+
+	client, err := artifact.NewMinioClient(cfg.S3Endpoint, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3Region, cfg.S3UseSSL)
 	if err != nil {
-		log.Fatal("Failed to start server:", err)
+		return nil, err
 	}
+	slog.Info("Storing artifacts in S3", "bucket", cfg.S3Bucket, "endpoint", cfg.S3Endpoint)
+	return artifact.NewS3Store(client, cfg.S3Bucket, cfg.S3Prefix), nil
+}
+
+// fatal logs an error the backend cannot start or go on without, and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
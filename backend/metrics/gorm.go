@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/database/callbacks"
+	"gorm.io/gorm"
+)
+
+// startKey is where GormPlugin keeps the start time of a query on its statement
+const startKey = "metrics:start"
+
+// GormPlugin observes the duration of every query GORM runs in DBQueryDuration
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize times the create, query, update, delete, row and raw callbacks of db
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	return callbacks.Around(db, "metrics", start, observe)
+}
+
+func start(string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		db.InstanceSet(startKey, time.Now())
+	}
+}
+
+func observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		DBQueryDuration.WithLabelValues(operation, db.Statement.Table).Observe(time.Since(v.(time.Time)).Seconds())
+	}
+}
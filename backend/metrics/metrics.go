@@ -0,0 +1,79 @@
+// Package metrics defines the backend's Prometheus metrics: the throughput and latency of the
+// API and the database, what the simulator is doing and how deep the job queue is. They are
+// registered with Registry, which GET /metrics serves.
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "automation"
+
+// Registry holds every metric of the backend, along with the Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts answered API requests by method, route and status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "API requests answered, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes how long API requests took to answer
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to answer API requests, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// DBQueryDuration observes how long database queries took
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time taken by database queries, by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// RunsCompleted counts finished simulation runs by machine and outcome
+	RunsCompleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "simulation_runs_total",
+		Help:      "Simulation runs finished, by machine and status.",
+	}, []string{"machine_id", "status"})
+
+	// MachineErrors counts how often machines entered the Error status
+	MachineErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "simulation_machine_errors_total",
+		Help:      "Times a machine entered the Error status.",
+	}, []string{"machine_id"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		DBQueryDuration,
+		RunsCompleted,
+		MachineErrors,
+	)
+}
+
+// MachineLabel is the machine_id label value of a machine
+func MachineLabel(machineID uint) string {
+	return strconv.FormatUint(uint64(machineID), 10)
+}
+
+// ForgetMachine drops the series of a deleted machine, so they stop being exported
+func ForgetMachine(machineID uint) {
+	labels := prometheus.Labels{"machine_id": MachineLabel(machineID)}
+	RunsCompleted.DeletePartialMatch(labels)
+	MachineErrors.DeletePartialMatch(labels)
+}
@@ -0,0 +1,80 @@
+package metrics_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/metrics"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type machineCounts map[string]int64
+
+func (m machineCounts) CountByStatus() (map[string]int64, error) {
+	if m == nil {
+		return nil, errors.New("database is locked")
+	}
+	return m, nil
+}
+
+type workers int
+
+func (w workers) ActiveWorkers() int { return int(w) }
+
+type depth int64
+
+func (d depth) Depth() (int64, error) { return int64(d), nil }
+
+func TestStateCollector(t *testing.T) {
+	collector := metrics.NewStateCollector(machineCounts{"Running": 2, "Error": 1}, workers(2), depth(5))
+	expected := `
+# HELP automation_machines Machines by status.
+# TYPE automation_machines gauge
+automation_machines{status="Error"} 1
+automation_machines{status="Running"} 2
+# HELP automation_queue_depth Jobs waiting or in progress in the job queue.
+# TYPE automation_queue_depth gauge
+automation_queue_depth 5
+# HELP automation_simulation_active_workers Simulation goroutines running machines.
+# TYPE automation_simulation_active_workers gauge
+automation_simulation_active_workers 2
+`
+	assert.Nil(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	failing := metrics.NewStateCollector(machineCounts(nil), nil, depth(0))
+	assert.Equal(t, 1, testutil.CollectAndCount(failing), "Sources that fail or are missing are left out")
+}
+
+func TestGormPlugin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:TestGormPlugin?mode=memory&cache=shared"), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Use(metrics.GormPlugin{}))
+	assert.Nil(t, db.AutoMigrate(&models.Organization{}))
+
+	before := testutil.CollectAndCount(metrics.DBQueryDuration)
+	assert.Nil(t, db.Create(&models.Organization{Name: "acme"}).Error)
+	var orgs []models.Organization
+	assert.Nil(t, db.Find(&orgs).Error)
+
+	assert.Equal(t, before+2, testutil.CollectAndCount(metrics.DBQueryDuration))
+	observer, err := metrics.DBQueryDuration.GetMetricWithLabelValues("query", "organizations")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, testutil.CollectAndCount(observer.(prometheus.Collector)))
+}
+
+func TestForgetMachine(t *testing.T) {
+	metrics.RunsCompleted.WithLabelValues(metrics.MachineLabel(7), models.RunStatusSucceeded).Inc()
+	metrics.RunsCompleted.WithLabelValues(metrics.MachineLabel(7), models.RunStatusFailed).Inc()
+	metrics.MachineErrors.WithLabelValues(metrics.MachineLabel(7)).Inc()
+	metrics.RunsCompleted.WithLabelValues(metrics.MachineLabel(8), models.RunStatusSucceeded).Inc()
+
+	metrics.ForgetMachine(7)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.MachineErrors.WithLabelValues(metrics.MachineLabel(7))))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.RunsCompleted.WithLabelValues(metrics.MachineLabel(8), models.RunStatusSucceeded)))
+}
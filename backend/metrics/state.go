@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MachineCounter counts machines by status, such as repository.MachineRepository
+type MachineCounter interface {
+	CountByStatus() (map[string]int64, error)
+}
+
+// WorkerCounter counts running simulation goroutines, such as simulation.MachineSimulator
+type WorkerCounter interface {
+	ActiveWorkers() int
+}
+
+// QueueDepth reports how many jobs wait in a queue, such as queue.Queue
+type QueueDepth interface {
+	Depth() (int64, error)
+}
+
+var (
+	machinesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "machines"),
+		"Machines by status.", []string{"status"}, nil)
+	workersDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "simulation", "active_workers"),
+		"Simulation goroutines running machines.", nil, nil)
+	queueDepthDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "queue", "depth"),
+		"Jobs waiting or in progress in the job queue.", nil, nil)
+)
+
+// StateCollector samples the state of machines, the simulator and the job queue whenever the
+// metrics are scraped. Sources that fail are left out of the scrape.
+type StateCollector struct {
+	Machines MachineCounter
+	Workers  WorkerCounter
+	Queue    QueueDepth
+}
+
+// NewStateCollector returns a collector of the given sources; any may be nil
+func NewStateCollector(machines MachineCounter, workers WorkerCounter, queue QueueDepth) *StateCollector {
+	return &StateCollector{Machines: machines, Workers: workers, Queue: queue}
+}
+
+func (c *StateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- machinesDesc
+	ch <- workersDesc
+	ch <- queueDepthDesc
+}
+
+func (c *StateCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.Machines != nil {
+		counts, err := c.Machines.CountByStatus()
+		if err != nil {
+			slog.Error("Failed to count machines for metrics", "error", err)
+		}
+		for status, n := range counts {
+			ch <- prometheus.MustNewConstMetric(machinesDesc, prometheus.GaugeValue, float64(n), status)
+		}
+	}
+	if c.Workers != nil {
+		ch <- prometheus.MustNewConstMetric(workersDesc, prometheus.GaugeValue, float64(c.Workers.ActiveWorkers()))
+	}
+	if c.Queue != nil {
+		depth, err := c.Queue.Depth()
+		if err != nil {
+			slog.Error("Failed to measure job queue depth for metrics", "error", err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(depth))
+		}
+	}
+}
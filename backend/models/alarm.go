@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Alarm rule kinds
+const (
+	// AlarmRuleThreshold fires when a telemetry metric crosses a threshold for a minimum duration
+	AlarmRuleThreshold = "threshold"
+	// AlarmRuleStatusCount fires when a machine enters a status a number of times within a time window
+	AlarmRuleStatusCount = "status_count"
+)
+
+// Alarm lifecycle states
+const (
+	AlarmStateRaised       = "Raised"
+	AlarmStateAcknowledged = "Acknowledged"
+	AlarmStateCleared      = "Cleared"
+)
+
+// AlarmRule describes a condition on simulator output that raises an Alarm,
+// e.g. "temperature > 80 for 30s" or "3 Errors within 10 minutes".
+type AlarmRule struct {
+	Model
+	OrganizationID uint   `gorm:"not null;default:1;index" json:"organization_id"`
+	Name           string `gorm:"not null" json:"name" binding:"required"`
+	Kind           string `gorm:"not null" json:"kind" binding:"required" enum:"threshold,status_count"`
+	MachineID      *uint  `gorm:"index" json:"machine_id"` // nil applies the rule to every machine of the organization
+	Severity       string `gorm:"default:'warning'" json:"severity"`
+	Disabled       bool   `json:"disabled"`
+
+	// Threshold rules
+	Metric     string  `json:"metric"`
+	Operator   string  `json:"operator"` // one of >, >=, <, <=
+	Threshold  float64 `json:"threshold"`
+	ForSeconds int     `json:"for_seconds"`
+
+	// Status count rules
+	Status        string `json:"status"`
+	Count         int    `json:"count"`
+	WindowSeconds int    `json:"window_seconds"`
+}
+
+// TableName overrides the default table name for better organization
+func (AlarmRule) TableName() string {
+	return "alarm_rules"
+}
+
+// Alarm is a single occurrence of an AlarmRule firing for a machine.
+// It moves from Raised to Acknowledged (by an operator) and to Cleared (once the condition no longer holds).
+type Alarm struct {
+	Model
+	OrganizationID uint       `gorm:"not null;default:1;index" json:"organization_id"`
+	RuleID         uint       `gorm:"index;not null" json:"rule_id"`
+	MachineID      uint       `gorm:"index;not null" json:"machine_id"`
+	State          string     `gorm:"index;not null" json:"state"`
+	Severity       string     `json:"severity"`
+	Message        string     `json:"message"`
+	Value          float64    `json:"value"`
+	RaisedAt       time.Time  `json:"raised_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy string     `json:"acknowledged_by"`
+	ClearedAt      *time.Time `json:"cleared_at"`
+}
+
+// TableName overrides the default table name for better organization
+func (Alarm) TableName() string {
+	return "alarms"
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// API key scopes. Each scope includes the ones before it: admin keys may also write, and
+// write keys may also read.
+const (
+	APIKeyScopeRead  = "read"  // GET routes
+	APIKeyScopeWrite = "write" // every other route of the API
+	APIKeyScopeAdmin = "admin" // managing API keys
+)
+
+// APIKey grants a client access to the REST API. Only a hash of the key is stored; the key
+// itself is shown once, when it is issued.
+type APIKey struct {
+	Model
+	OrganizationID uint       `gorm:"not null;default:1;index" json:"organization_id"`
+	Name           string     `gorm:"not null" json:"name" binding:"required"`
+	Prefix         string     `gorm:"uniqueIndex;not null" json:"prefix"` // the start of the key, to tell keys apart
+	Hash           string     `gorm:"not null" json:"-"`                  // hex SHA-256 of the key
+	Scopes         []string   `gorm:"serializer:json" json:"scopes"`
+	Roles          []string   `gorm:"serializer:json" json:"roles"` // what the key may do to machines; see Identity.Can
+	ExpiresAt      *time.Time `json:"expires_at"`                   // nil never expires
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+}
+
+// TableName overrides the default table name for better organization
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// HasScope reports whether the key grants a scope, directly or through a broader one
+func (k APIKey) HasScope(scope string) bool {
+	return GrantsScope(k.Scopes, scope)
+}
+
+// GrantsScope reports whether a list of scopes grants a scope, directly or through a broader one
+func GrantsScope(scopes []string, scope string) bool {
+	rank := map[string]int{APIKeyScopeRead: 1, APIKeyScopeWrite: 2, APIKeyScopeAdmin: 3}
+	for _, s := range scopes {
+		if rank[s] >= rank[scope] && rank[scope] > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package models
+
+// Artifact is a file produced by a simulation run, e.g. a CSV of results, a plot or a log.
+// The contents live in the artifact store under Key.
+type Artifact struct {
+	Model
+	RunID       uint   `gorm:"uniqueIndex:idx_artifacts_run_name;not null" json:"run_id"`
+	Name        string `gorm:"uniqueIndex:idx_artifacts_run_name;not null" json:"name"` // path relative to the run's artifact directory
+	Key         string `gorm:"not null" json:"-"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type"`
+}
+
+// TableName overrides the default table name for better organization
+func (Artifact) TableName() string {
+	return "artifacts"
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Batch lifecycle states
+const (
+	BatchStatusRunning   = "Running"
+	BatchStatusCompleted = "Completed" // every point has finished, successfully or not
+)
+
+// Batch point states
+const (
+	PointStatusPending   = "Pending"
+	PointStatusRunning   = "Running"
+	PointStatusSucceeded = "Succeeded"
+	PointStatusFailed    = "Failed"
+)
+
+// Batch is a parameter sweep: one submission expanded into many runs of a machine,
+// each with a different set of overrides of its ConfigJSON.
+type Batch struct {
+	Model
+	OrganizationID uint       `gorm:"not null;default:1;index" json:"organization_id"`
+	MachineID      uint       `gorm:"index;not null" json:"machine_id"`
+	Mode           string     `gorm:"not null" json:"mode"` // grid, list or random
+	Spec           string     `json:"spec"`                 // the sweep definition, as submitted (with the random seed filled in)
+	Concurrency    int        `json:"concurrency"`
+	Status         string     `gorm:"index;not null" json:"status"`
+	Total          int        `json:"total"`
+	FinishedAt     *time.Time `json:"finished_at"`
+}
+
+// TableName overrides the default table name for better organization
+func (Batch) TableName() string {
+	return "batches"
+}
+
+// BatchPoint is a single parameter combination of a batch and the outcome of its run
+type BatchPoint struct {
+	Model
+	BatchID    uint       `gorm:"index;not null" json:"batch_id"`
+	Position   int        `json:"position"` // order of the point within the sweep
+	Params     string     `json:"params"`
+	Status     string     `gorm:"not null" json:"status"`
+	JobID      *uint      `json:"job_id"` // the job running the point, once it is queued
+	RunID      *uint      `json:"run_id"`
+	Error      string     `json:"error"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// TableName overrides the default table name for better organization
+func (BatchPoint) TableName() string {
+	return "batch_points"
+}
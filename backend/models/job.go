@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Job lifecycle states
+const (
+	JobStatusPending   = "Pending"   // waiting to be leased (possibly after a retry backoff)
+	JobStatusLeased    = "Leased"    // held by a consumer until its lease expires
+	JobStatusSucceeded = "Succeeded" // acknowledged by the consumer
+	JobStatusDead      = "Dead"      // exhausted its attempts and was moved to the dead letters
+)
+
+// Job is a queued request to run a simulation of a machine, optionally with parameters
+// that override keys of the machine's ConfigJSON for that run only.
+type Job struct {
+	Model
+	OrganizationID uint       `gorm:"not null;default:1;index" json:"organization_id"`
+	MachineID      uint       `gorm:"index;not null" json:"machine_id"`
+	Params         string     `json:"params"`
+	Status         string     `gorm:"index;not null" json:"status"`
+	Attempts       int        `json:"attempts"`
+	MaxAttempts    int        `json:"max_attempts"`
+	AvailableAt    time.Time  `gorm:"index" json:"available_at"`
+	LeaseOwner     string     `json:"lease_owner"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at"`
+	LastError      string     `json:"last_error"`
+	RunID          *uint      `json:"run_id"`
+	CompletedAt    *time.Time `json:"completed_at"`
+	RequestID      string     `json:"request_id,omitempty"` // of the API request that submitted the job, for tracing it in the logs
+	TraceParent    string     `json:"-"`                    // W3C trace context of the submitting request, which the run continues
+}
+
+// TableName overrides the default table name for better organization
+func (Job) TableName() string {
+	return "jobs"
+}
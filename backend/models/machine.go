@@ -10,12 +10,21 @@ import (
 // This ensures gorm.Model fields (ID, CreatedAt, etc.) are visible outside the models package.
 type Model gorm.Model
 
+// StoredTime returns t as timestamps are stored and compared in queries. SQLite compares
+// timestamps as text, so every stored timestamp must be in the same zone.
+func StoredTime(t time.Time) time.Time {
+	return t.UTC()
+}
+
 // Machine represents a single piece of equipment/machine configuration.
 type Machine struct {
-	Model             // ⬅️ Use the new exported base model
-	Name       string `gorm:"unique;not null" json:"name" binding:"required"`
-	Status     string `gorm:"default:'Offline'" json:"status"`
-	ConfigJSON string `gorm:"type:jsonb" json:"config_json"`
+	Model                   // ⬅️ Use the new exported base model
+	OrganizationID uint     `gorm:"not null;default:1;uniqueIndex:idx_machines_organization_name,priority:1" json:"organization_id"`
+	Name           string   `gorm:"not null;uniqueIndex:idx_machines_organization_name,priority:2" json:"name" binding:"required"` // unique within the organization
+	Status         string   `gorm:"default:'Offline'" json:"status" enum:"Offline,Idle,Running,Error"`
+	ConfigJSON     string   `gorm:"type:jsonb" json:"config_json"`
+	GroupID        *uint    `gorm:"index" json:"group_id"` // the site, line or cell the machine belongs to
+	Tags           []string `gorm:"serializer:json" json:"tags"`
 
 	// Simulation-specific fields
 	LastSimulated time.Time `json:"last_simulated"`
@@ -0,0 +1,21 @@
+package models
+
+// DefaultOrganizationID is the organization that owns records created before organizations
+// existed and records created while requests are not authenticated. Its admins administer
+// the whole backend, e.g. create organizations and pause the simulator.
+const DefaultOrganizationID = 1
+
+// DefaultOrganizationName is the name of the default organization
+const DefaultOrganizationName = "default"
+
+// Organization is a tenant: a team whose machines, and everything recorded about them, only
+// its own API keys and users can see and control.
+type Organization struct {
+	Model
+	Name string `gorm:"uniqueIndex;not null" json:"name" binding:"required"` // also the value of the organization claim of JWTs
+}
+
+// TableName overrides the default table name for better organization
+func (Organization) TableName() string {
+	return "organizations"
+}
@@ -0,0 +1,73 @@
+package models
+
+// Roles bundle the permissions of a kind of user. They are granted to API keys and to JWTs
+// through a roles claim.
+const (
+	RoleViewer   = "viewer"   // sees machines
+	RoleOperator = "operator" // also starts, stops and resets them
+	RoleEngineer = "engineer" // also creates, configures and deletes them
+	RoleAdmin    = "admin"    // also manages API keys
+)
+
+// Permissions on machines
+const (
+	PermissionMachineRead        = "machine:read"
+	PermissionMachineCommand     = "machine:command"      // change a machine's status, e.g. start or stop it
+	PermissionMachineConfigWrite = "machine:config-write" // create and configure machines and what they run, e.g. runs, faults or workflows
+	PermissionMachineDelete      = "machine:delete"
+)
+
+// Roles lists the known roles from the least to the most privileged
+var Roles = []string{RoleViewer, RoleOperator, RoleEngineer, RoleAdmin}
+
+var rolePermissions = map[string][]string{
+	RoleViewer:   {PermissionMachineRead},
+	RoleOperator: {PermissionMachineRead, PermissionMachineCommand},
+	RoleEngineer: {PermissionMachineRead, PermissionMachineCommand, PermissionMachineConfigWrite, PermissionMachineDelete},
+	RoleAdmin:    {PermissionMachineRead, PermissionMachineCommand, PermissionMachineConfigWrite, PermissionMachineDelete},
+}
+
+// roleScopes is the API key scope each role needs to reach the routes of its permissions.
+// Scopes other routes check; roles narrow down what may be done to machines.
+var roleScopes = map[string]string{
+	RoleViewer:   APIKeyScopeRead,
+	RoleOperator: APIKeyScopeWrite,
+	RoleEngineer: APIKeyScopeWrite,
+	RoleAdmin:    APIKeyScopeAdmin,
+}
+
+// scopeRoles is the role of credentials with a scope but no roles, which keeps what they
+// could do before roles existed
+var scopeRoles = map[string]string{
+	APIKeyScopeRead:  RoleViewer,
+	APIKeyScopeWrite: RoleEngineer,
+	APIKeyScopeAdmin: RoleAdmin,
+}
+
+// IsRole reports whether a role is known
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleScope returns the scope a role implies, or "" for unknown roles
+func RoleScope(role string) string {
+	return roleScopes[role]
+}
+
+// ScopeRole returns the role credentials with only a scope act as, or "" for unknown scopes
+func ScopeRole(scope string) string {
+	return scopeRoles[scope]
+}
+
+// GrantsPermission reports whether any of the roles grants a permission
+func GrantsPermission(roles []string, permission string) bool {
+	for _, role := range roles {
+		for _, p := range rolePermissions[role] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
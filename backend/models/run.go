@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Simulation run outcomes
+const (
+	RunStatusRunning   = "Running"
+	RunStatusSucceeded = "Succeeded"
+	RunStatusFailed    = "Failed"
+	RunStatusCancelled = "Cancelled"
+)
+
+// SimulationRun records a single simulation run of a machine and how it ended.
+type SimulationRun struct {
+	Model
+	OrganizationID uint       `gorm:"not null;default:1;index" json:"organization_id"`
+	MachineID      uint       `gorm:"index;not null" json:"machine_id"`
+	Status         string     `gorm:"index;not null" json:"status"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+	Error          string     `json:"error"`
+	JobID          *uint      `gorm:"index" json:"job_id"` // set for ad-hoc runs submitted through the job queue
+	Params         string     `json:"params"`              // parameter overrides the run was executed with
+	Outputs        string     `json:"outputs"`             // JSON object of named results, for ad-hoc runs including the telemetry sampled
+}
+
+// TableName overrides the default table name for better organization
+func (SimulationRun) TableName() string {
+	return "simulation_runs"
+}
@@ -0,0 +1,15 @@
+package models
+
+// RunLog is the captured output of a simulation run: simulator messages about the run and
+// anything its command wrote to stdout or stderr.
+type RunLog struct {
+	Model
+	RunID     uint   `gorm:"uniqueIndex;not null" json:"run_id"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated"` // the run produced more output than the log keeps
+}
+
+// TableName overrides the default table name for better organization
+func (RunLog) TableName() string {
+	return "run_logs"
+}
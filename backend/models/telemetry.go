@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// TelemetrySample is a single simulated sensor reading (temperature, vibration, ...) for a machine.
+// Samples are append-only, so they skip gorm.Model and its soft-delete bookkeeping.
+type TelemetrySample struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	OrganizationID uint      `gorm:"not null;default:1" json:"organization_id"` // of the machine, so alarm rules of other organizations ignore the sample
+	MachineID      uint      `gorm:"not null;index:idx_telemetry_machine_metric_time,priority:1" json:"machine_id"`
+	Metric         string    `gorm:"not null;index:idx_telemetry_machine_metric_time,priority:2" json:"metric"`
+	Value          float64   `json:"value"`
+	Timestamp      time.Time `gorm:"not null;index:idx_telemetry_machine_metric_time,priority:3" json:"timestamp"`
+}
+
+// TableName overrides the default table name for better organization
+func (TelemetrySample) TableName() string {
+	return "telemetry_samples"
+}
@@ -0,0 +1,36 @@
+package models
+
+// Group kinds, from the top of the hierarchy down. A line belongs to a site and a cell to a line;
+// machines can be placed in a group of any kind.
+const (
+	GroupKindSite = "site"
+	GroupKindLine = "line"
+	GroupKindCell = "cell"
+)
+
+// MachineGroup is a node of the plant hierarchy: a site, a production line or a cell.
+type MachineGroup struct {
+	Model
+	OrganizationID uint   `gorm:"not null;default:1;index" json:"organization_id"`
+	Name           string `gorm:"not null" json:"name" binding:"required"`
+	Kind           string `gorm:"not null" json:"kind" binding:"required" enum:"site,line,cell"`
+	ParentID       *uint  `gorm:"index" json:"parent_id"` // nil for sites
+}
+
+// TableName overrides the default table name for better organization
+func (MachineGroup) TableName() string {
+	return "machine_groups"
+}
+
+// MachineLink is a directed material flow between two machines: the output of From feeds To.
+type MachineLink struct {
+	Model
+	OrganizationID uint `gorm:"not null;default:1;index" json:"organization_id"`
+	FromMachineID  uint `gorm:"uniqueIndex:idx_machine_links_pair;not null" json:"from_machine_id" binding:"required"`
+	ToMachineID    uint `gorm:"uniqueIndex:idx_machine_links_pair;index;not null" json:"to_machine_id" binding:"required"`
+}
+
+// TableName overrides the default table name for better organization
+func (MachineLink) TableName() string {
+	return "machine_links"
+}
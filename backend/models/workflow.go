@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Workflow run and step run states
+const (
+	WorkflowStatusRunning   = "Running"
+	WorkflowStatusSucceeded = "Succeeded"
+	WorkflowStatusFailed    = "Failed" // at least one step failed
+
+	StepStatusPending   = "Pending"
+	StepStatusRunning   = "Running"
+	StepStatusSucceeded = "Succeeded"
+	StepStatusFailed    = "Failed"
+	StepStatusSkipped   = "Skipped" // its condition was not met
+)
+
+// Step conditions, evaluated once every dependency has finished
+const (
+	StepWhenSuccess = "success" // all dependencies succeeded (the default)
+	StepWhenFailure = "failure" // at least one dependency failed, e.g. for cleanup or rollback
+	StepWhenAlways  = "always"
+)
+
+// WorkflowStep is one node of a workflow DAG: a simulation run of a machine, optionally with
+// a runner registered on the server instead of the machine's own.
+// String values in Params may reference ${inputs.<key>}, ${steps.<name>.outputs.<key>}
+// and ${steps.<name>.status} of the steps it depends on.
+type WorkflowStep struct {
+	Name      string                     `json:"name"`
+	MachineID uint                       `json:"machine_id"`
+	Runner    string                     `json:"runner,omitempty"`
+	Params    map[string]json.RawMessage `json:"params,omitempty"`
+	DependsOn []string                   `json:"depends_on,omitempty"`
+	When      string                     `json:"when,omitempty"`
+	Retries   int                        `json:"retries,omitempty"`
+}
+
+// Workflow is a named DAG of simulation steps
+type Workflow struct {
+	Model
+	OrganizationID uint           `gorm:"not null;default:1;uniqueIndex:idx_workflows_organization_name,priority:1" json:"organization_id"`
+	Name           string         `gorm:"not null;uniqueIndex:idx_workflows_organization_name,priority:2" json:"name"` // unique within the organization
+	Description    string         `json:"description"`
+	Steps          []WorkflowStep `gorm:"serializer:json" json:"steps"`
+}
+
+// TableName overrides the default table name for better organization
+func (Workflow) TableName() string {
+	return "workflows"
+}
+
+// WorkflowRun is one execution of a workflow. It keeps a copy of the steps it was started with,
+// so deleting the workflow does not affect it.
+type WorkflowRun struct {
+	Model
+	OrganizationID uint           `gorm:"not null;default:1;index" json:"organization_id"`
+	WorkflowID     uint           `gorm:"index;not null" json:"workflow_id"`
+	Status         string         `gorm:"index;not null" json:"status"`
+	Inputs         string         `json:"inputs"`
+	Steps          []WorkflowStep `gorm:"serializer:json" json:"-"`
+	Error          string         `json:"error"`
+	FinishedAt     *time.Time     `json:"finished_at"`
+}
+
+// TableName overrides the default table name for better organization
+func (WorkflowRun) TableName() string {
+	return "workflow_runs"
+}
+
+// WorkflowStepRun is the state of one step within a workflow run
+type WorkflowStepRun struct {
+	Model
+	WorkflowRunID uint       `gorm:"index;not null" json:"workflow_run_id"`
+	Step          string     `gorm:"not null" json:"step"`
+	Status        string     `gorm:"not null" json:"status"`
+	Attempts      int        `json:"attempts"`
+	RunID         *uint      `json:"run_id"` // the simulation run of the last attempt
+	Params        string     `json:"params"` // params after resolving references to inputs and outputs
+	Outputs       string     `json:"outputs"`
+	Error         string     `json:"error"`
+	StartedAt     *time.Time `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+}
+
+// TableName overrides the default table name for better organization
+func (WorkflowStepRun) TableName() string {
+	return "workflow_step_runs"
+}
@@ -0,0 +1,287 @@
+// Package openapi builds an OpenAPI 3 document for the REST API. Request and response
+// schemas are generated from the Go types the handlers bind and return, so the document
+// cannot drift from the code.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version of the generated documents
+const Version = "3.0.3"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	schemas *schemaGenerator
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path, keyed by lowercase HTTP method
+type PathItem map[string]*Operation
+
+// Operation is a single API operation
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of a request
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header describes a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType holds the schema of a request or response body
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components holds the schemas and security schemes referenced from operations
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how clients authenticate
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// APIKeyScheme is the name of the security scheme of routes with a scope
+const APIKeyScheme = "apiKey"
+
+// Schema is a JSON schema as used by OpenAPI 3.0. The zero Schema accepts any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Route documents a route of the API
+type Route struct {
+	Method  string // e.g. http.MethodGet
+	Path    string // in gin syntax, e.g. /api/v1/machines/:id; path parameters are unsigned integers
+	Summary string
+	Tag     string
+	Query   []Parameter
+
+	Body         interface{} // a value of the request body type; nil for none
+	OptionalBody bool        // the body may be omitted
+
+	Status      int         // the status of a successful response
+	Response    interface{} // a value of the response type; nil for none
+	ContentType string      // of the response, when it is not JSON
+	Errors      []int       // statuses of error responses, which carry an Error
+
+	Scope      string // the API key scope the route requires; empty for public routes
+	Permission string // the role permission the route requires besides its scope; empty for none
+	RateLimit  string // the rate limit class the route counts against; empty for unlimited routes
+}
+
+// Error is the body of every error response
+type Error struct {
+	Error string `json:"error" binding:"required"`
+}
+
+// New returns an empty document
+func New(info Info) *Document {
+	d := &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      map[string]*PathItem{},
+		Components: Components{Schemas: map[string]*Schema{}},
+	}
+	d.schemas = newSchemaGenerator(d.Components.Schemas)
+	return d
+}
+
+// Query returns a description of an optional query parameter
+func Query(name string, schema *Schema, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// Add documents a route
+func (d *Document) Add(r Route) {
+	path, params := convertPath(r.Path)
+	op := &Operation{
+		OperationID: operationID(r.Method, path),
+		Summary:     r.Summary,
+		Parameters:  append(params, r.Query...),
+		Responses:   map[string]*Response{},
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+	if r.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: !r.OptionalBody,
+			Content:  map[string]MediaType{"application/json": {Schema: d.Schema(r.Body)}},
+		}
+	}
+
+	success := &Response{Description: http.StatusText(r.Status)}
+	switch {
+	case r.ContentType != "":
+		success.Content = map[string]MediaType{r.ContentType: {Schema: &Schema{Type: "string"}}}
+	case r.Response != nil:
+		success.Content = map[string]MediaType{"application/json": {Schema: d.Schema(r.Response)}}
+	}
+	op.Responses[strconv.Itoa(r.Status)] = success
+	errors := r.Errors
+	if r.Scope != "" {
+		op.Description = fmt.Sprintf("Requires an API key or token with the %q scope.", r.Scope)
+		if r.Permission != "" {
+			op.Description = fmt.Sprintf("Requires an API key or token with the %q scope and the %q permission.", r.Scope, r.Permission)
+		}
+		op.Security = []map[string][]string{{APIKeyScheme: {}}}
+		if d.Components.SecuritySchemes == nil {
+			d.Components.SecuritySchemes = map[string]*SecurityScheme{}
+		}
+		d.Components.SecuritySchemes[APIKeyScheme] = &SecurityScheme{
+			Type: "http", Scheme: "bearer", Description: "An API key, or a JWT from the identity provider, sent as \"Authorization: Bearer <credential>\"",
+		}
+		errors = append([]int{http.StatusUnauthorized, http.StatusForbidden}, errors...)
+	}
+	if r.RateLimit != "" {
+		errors = append(errors, http.StatusTooManyRequests)
+	}
+	for _, status := range errors {
+		op.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{"application/json": {Schema: d.Schema(Error{})}},
+		}
+	}
+	if res, ok := op.Responses[strconv.Itoa(http.StatusTooManyRequests)]; ok {
+		res.Headers = map[string]*Header{
+			"Retry-After": {Description: "Seconds until the request may be retried", Schema: &Schema{Type: "integer"}},
+		}
+	}
+
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	(*item)[strings.ToLower(r.Method)] = op
+}
+
+// Schema returns the schema of the type of v, registering the named types it uses as components
+func (d *Document) Schema(v interface{}) *Schema {
+	return d.schemas.schema(reflect.TypeOf(v))
+}
+
+// Operations returns the method and gin-style path of every documented operation, sorted
+func (d *Document) Operations() []string {
+	var ops []string
+	for path, item := range d.Paths {
+		for method := range *item {
+			ops = append(ops, strings.ToUpper(method)+" "+ginPath(path))
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// Lookup returns the operation for a method and a gin-style path, or nil
+func (d *Document) Lookup(method, path string) *Operation {
+	converted, _ := convertPath(path)
+	item, ok := d.Paths[converted]
+	if !ok {
+		return nil
+	}
+	return (*item)[strings.ToLower(method)]
+}
+
+// convertPath turns /machines/:id into /machines/{id} and describes its parameters
+func convertPath(path string) (string, []Parameter) {
+	var params []Parameter
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			name := segment[1:]
+			segments[i] = "{" + name + "}"
+			params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "integer", Minimum: new(float64)}})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// ginPath turns /machines/{id} back into /machines/:id
+func ginPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationID derives a stable identifier such as getApiV1MachinesById
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") {
+			segment = "by-" + strings.Trim(segment, "{}")
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
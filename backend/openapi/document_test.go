@@ -0,0 +1,109 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/openapi"
+	"github.com/stretchr/testify/assert"
+)
+
+type base struct {
+	ID uint
+}
+
+type node struct {
+	base
+	Name     string            `json:"name" binding:"required"`
+	Kind     string            `json:"kind" enum:"leaf,branch"`
+	Parent   *uint             `json:"parent_id"`
+	Children []node            `json:"children"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Seen     time.Time         `json:"seen"`
+	Extra    json.RawMessage   `json:"extra"`
+	Secret   string            `json:"-"`
+	internal int
+}
+
+func TestSchemaFollowsJSONEncoding(t *testing.T) {
+	doc := openapi.New(openapi.Info{Title: "test", Version: "1"})
+
+	assert.Equal(t, "#/components/schemas/Node", doc.Schema(node{}).Ref)
+	assert.Equal(t, &openapi.Schema{Type: "array", Items: &openapi.Schema{Ref: "#/components/schemas/Node"}, Nullable: true}, doc.Schema([]node{}),
+		"nil slices encode as null")
+
+	s := doc.Components.Schemas["Node"]
+	if assert.NotNil(t, s) {
+		assert.ElementsMatch(t, []string{"ID", "name", "kind", "parent_id", "children", "labels", "seen", "extra"}, keys(s.Properties),
+			"Embedded fields are inlined; ignored and unexported fields are left out")
+		assert.Equal(t, []string{"name"}, s.Required)
+		assert.Equal(t, []string{"leaf", "branch"}, s.Properties["kind"].Enum)
+		assert.True(t, s.Properties["parent_id"].Nullable)
+		assert.Equal(t, "#/components/schemas/Node", s.Properties["children"].Items.Ref, "Recursive types refer to themselves")
+		assert.Equal(t, "string", s.Properties["labels"].AdditionalProperties.Type)
+		assert.Equal(t, "date-time", s.Properties["seen"].Format)
+		assert.Equal(t, &openapi.Schema{}, s.Properties["extra"], "Raw JSON may hold any value")
+	}
+}
+
+func TestAddRoute(t *testing.T) {
+	doc := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/api/v1/nodes/:id/children/:childId", Summary: "Get a child", Tag: "nodes",
+		Query:  []openapi.Parameter{openapi.Query("depth", &openapi.Schema{Type: "integer"}, "")},
+		Status: http.StatusOK, Response: node{}, Errors: []int{http.StatusNotFound},
+	})
+	doc.Add(openapi.Route{Method: http.MethodDelete, Path: "/api/v1/nodes/:id", Status: http.StatusNoContent})
+
+	assert.Equal(t, []string{"DELETE /api/v1/nodes/:id", "GET /api/v1/nodes/:id/children/:childId"}, doc.Operations())
+	op := doc.Lookup(http.MethodGet, "/api/v1/nodes/:id/children/:childId")
+	if assert.NotNil(t, op) {
+		assert.Equal(t, "getApiV1NodesByIdChildrenByChildId", op.OperationID)
+		assert.Len(t, op.Parameters, 3)
+		assert.Equal(t, "path", op.Parameters[1].In)
+		assert.True(t, op.Parameters[1].Required)
+		assert.Equal(t, "#/components/schemas/Error", op.Responses["404"].Content["application/json"].Schema.Ref)
+	}
+	assert.Empty(t, doc.Lookup(http.MethodDelete, "/api/v1/nodes/:id").Responses["204"].Content)
+	assert.Nil(t, doc.Lookup(http.MethodPost, "/api/v1/nodes/:id"))
+}
+
+func TestAddRouteWithScope(t *testing.T) {
+	doc := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	doc.Add(openapi.Route{Method: http.MethodPost, Path: "/api/v1/nodes", Scope: "write", Status: http.StatusCreated, Response: node{}})
+	doc.Add(openapi.Route{Method: http.MethodGet, Path: "/health", Status: http.StatusOK})
+
+	op := doc.Lookup(http.MethodPost, "/api/v1/nodes")
+	if assert.NotNil(t, op) {
+		assert.Equal(t, []map[string][]string{{openapi.APIKeyScheme: {}}}, op.Security)
+		assert.Contains(t, op.Description, `"write" scope`)
+		assert.Contains(t, op.Responses, "401")
+		assert.Contains(t, op.Responses, "403")
+	}
+	assert.Empty(t, doc.Lookup(http.MethodGet, "/health").Security, "Routes without a scope are public")
+	if assert.Contains(t, doc.Components.SecuritySchemes, openapi.APIKeyScheme) {
+		assert.Equal(t, "bearer", doc.Components.SecuritySchemes[openapi.APIKeyScheme].Scheme)
+	}
+}
+
+func TestAddRouteWithRateLimit(t *testing.T) {
+	doc := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	doc.Add(openapi.Route{Method: http.MethodGet, Path: "/api/v1/nodes", RateLimit: "read", Status: http.StatusOK, Response: []node{}})
+	doc.Add(openapi.Route{Method: http.MethodGet, Path: "/health", Status: http.StatusOK})
+
+	if res, ok := doc.Lookup(http.MethodGet, "/api/v1/nodes").Responses["429"]; assert.True(t, ok) {
+		assert.Contains(t, res.Headers, "Retry-After")
+		assert.Equal(t, "#/components/schemas/Error", res.Content["application/json"].Schema.Ref)
+	}
+	assert.NotContains(t, doc.Lookup(http.MethodGet, "/health").Responses, "429")
+}
+
+func keys(m map[string]*openapi.Schema) []string {
+	var k []string
+	for key := range m {
+		k = append(k, key)
+	}
+	return k
+}
@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// componentPrefix starts every reference to a schema component
+const componentPrefix = "#/components/schemas/"
+
+// knownTypes are types whose JSON encoding is not derived from their Go structure
+var knownTypes = map[reflect.Type]Schema{
+	reflect.TypeOf(time.Time{}):          {Type: "string", Format: "date-time"},
+	reflect.TypeOf(gorm.DeletedAt{}):     {Type: "string", Format: "date-time", Nullable: true},
+	reflect.TypeOf(json.RawMessage{}):    {},
+	reflect.TypeOf([]byte{}):             {Type: "string", Format: "byte", Nullable: true},
+	reflect.TypeOf((*error)(nil)).Elem(): {Type: "string"},
+}
+
+// schemaGenerator derives schemas from Go types the way encoding/json encodes them.
+// Named struct types become components; binding:"required" fields are required, and an
+// enum:"a,b" tag lists the values a string field, or the items of a string slice, accept.
+type schemaGenerator struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaGenerator(components map[string]*Schema) *schemaGenerator {
+	return &schemaGenerator{components: components, names: map[reflect.Type]string{}}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if known, ok := knownTypes[t]; ok {
+		return &known
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := g.schema(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: new(float64)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		// nil slices and maps encode as null
+		return &Schema{Type: "array", Items: g.schema(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.component(t)
+	}
+	// Interfaces and anything else may hold any value
+	return &Schema{}
+}
+
+// component registers a named struct type and returns a reference to it
+func (g *schemaGenerator) component(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.componentName(t)
+		g.names[t] = name
+		// Registered before its properties are generated, so recursive types terminate
+		g.components[name] = &Schema{}
+		*g.components[name] = *g.object(t)
+	}
+	return &Schema{Ref: componentPrefix + name}
+}
+
+// componentName is the exported type name, qualified by its package if another type took it
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	name := exported(t.Name())
+	if _, taken := g.components[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	return exported(pkg[strings.LastIndex(pkg, "/")+1:]) + name
+}
+
+func exported(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// object generates the properties of a struct, inlining embedded structs like encoding/json
+func (g *schemaGenerator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(s, t)
+	if len(s.Properties) == 0 {
+		s.Properties = nil
+	}
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			if _, known := knownTypes[ft]; !known {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = g.schema(f.Type)
+		if enum := f.Tag.Get("enum"); enum != "" {
+			// of the items, for a slice
+			target := s.Properties[name]
+			if target.Type == "array" {
+				target = target.Items
+			}
+			target.Enum = strings.Split(enum, ",")
+		}
+		for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+			if rule == "required" {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError reports a value that does not match its schema
+type ValidationError struct {
+	Path    string // of the offending value, e.g. steps[0].machine_id; empty for the value itself
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + " " + e.Message
+}
+
+func invalid(path, format string, args ...interface{}) error {
+	return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// DecodeJSON decodes a JSON document for Validate, keeping numbers as json.Number
+func DecodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// Resolve follows the references of a schema to the component it names
+func (d *Document) Resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, componentPrefix)]
+	}
+	return s
+}
+
+// Validate checks a value decoded by DecodeJSON against a schema. Properties without a schema
+// are accepted, as OpenAPI allows additional properties by default.
+func (d *Document) Validate(s *Schema, value interface{}) error {
+	return d.validate(s, value, "")
+}
+
+// ValidateParameter checks the raw value of a path or query parameter against its schema
+func (d *Document) ValidateParameter(p Parameter, raw string) error {
+	var value interface{} = raw
+	switch d.Resolve(p.Schema).Type {
+	case "integer", "number":
+		value = json.Number(raw)
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalid(p.Name, "must be a boolean")
+		}
+		value = b
+	}
+	return d.validate(p.Schema, value, p.Name)
+}
+
+func (d *Document) validate(s *Schema, value interface{}, path string) error {
+	s = d.Resolve(s)
+	if s == nil || s.Type == "" {
+		return nil
+	}
+	if value == nil {
+		if s.Nullable {
+			return nil
+		}
+		return invalid(path, "must not be null")
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid(path, "must be an object")
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				return invalid(property(path, name), "is required")
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names) // report the same error every time
+		for _, name := range names {
+			ps, ok := s.Properties[name]
+			if !ok {
+				ps = s.AdditionalProperties
+			}
+			if err := d.validate(ps, object[name], property(path, name)); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return invalid(path, "must be an array")
+		}
+		for i, item := range items {
+			if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return invalid(path, "must be a string")
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return invalid(path, "must be an RFC 3339 date-time")
+			}
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			return invalid(path, "must be one of %s", strings.Join(s.Enum, ", "))
+		}
+	case "integer", "number":
+		kind := "a number"
+		if s.Type == "integer" {
+			kind = "an integer"
+		}
+		n, ok := value.(json.Number)
+		if !ok {
+			return invalid(path, "must be %s", kind)
+		}
+		f, err := n.Float64()
+		if err != nil || (s.Type == "integer" && !isInteger(n)) {
+			return invalid(path, "must be %s", kind)
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return invalid(path, "must be at least %v", *s.Minimum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid(path, "must be a boolean")
+		}
+	}
+	return nil
+}
+
+// isInteger reports whether a number is written the way encoding/json accepts for integer types
+func isInteger(n json.Number) bool {
+	if _, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseUint(n.String(), 10, 64)
+	return err == nil
+}
+
+func property(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
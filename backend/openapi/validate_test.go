@@ -0,0 +1,60 @@
+package openapi_test
+
+import (
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/openapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	doc := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	schema := doc.Schema(node{})
+
+	cases := []struct {
+		body string
+		err  string
+	}{
+		{`{"name": "root", "kind": "branch", "parent_id": null, "children": [{"name": "a", "ID": 2}]}`, ""},
+		{`{"name": "root", "children": null, "labels": null, "unknown": [1, "x"]}`, ""},
+		{`{"name": "root", "seen": "2025-01-01T00:00:00Z", "extra": {"any": ["thing"]}}`, ""},
+		{`{"kind": "leaf"}`, "name is required"},
+		{`{"name": 7}`, "name must be a string"},
+		{`{"name": "root", "kind": "trunk"}`, "kind must be one of leaf, branch"},
+		{`{"name": "root", "ID": -1}`, "ID must be at least 0"},
+		{`{"name": "root", "ID": 1.5}`, "ID must be an integer"},
+		{`{"name": "root", "children": [{"name": "a"}, {"name": null}]}`, "children[1].name must not be null"},
+		{`{"name": "root", "labels": {"zone": 3}}`, "labels.zone must be a string"},
+		{`{"name": "root", "seen": "yesterday"}`, "seen must be an RFC 3339 date-time"},
+		{`["root"]`, "must be an object"},
+		{`null`, "must not be null"},
+	}
+	for _, tc := range cases {
+		value, err := openapi.DecodeJSON([]byte(tc.body))
+		assert.Nil(t, err, tc.body)
+		err = doc.Validate(schema, value)
+		if tc.err == "" {
+			assert.Nil(t, err, tc.body)
+		} else if assert.Error(t, err, tc.body) {
+			assert.Equal(t, tc.err, err.Error(), tc.body)
+		}
+	}
+
+	_, err := openapi.DecodeJSON([]byte(`{"name": "a"} {"name": "b"}`))
+	assert.Error(t, err, "Trailing data is rejected")
+}
+
+func TestValidateParameter(t *testing.T) {
+	doc := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	limit := openapi.Query("limit", &openapi.Schema{Type: "integer", Minimum: new(float64)}, "")
+	follow := openapi.Query("follow", &openapi.Schema{Type: "boolean"}, "")
+	state := openapi.Query("state", &openapi.Schema{Type: "string", Enum: []string{"Raised", "Cleared"}}, "")
+
+	assert.Nil(t, doc.ValidateParameter(limit, "10"))
+	assert.EqualError(t, doc.ValidateParameter(limit, "-1"), "limit must be at least 0")
+	assert.EqualError(t, doc.ValidateParameter(limit, "ten"), "limit must be an integer")
+	assert.Nil(t, doc.ValidateParameter(follow, "true"))
+	assert.EqualError(t, doc.ValidateParameter(follow, "maybe"), "follow must be a boolean")
+	assert.Nil(t, doc.ValidateParameter(state, "Raised"))
+	assert.EqualError(t, doc.ValidateParameter(state, "Open"), "state must be one of Raised, Cleared")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+)
+
+const organizationUsage = `usage:
+  backend org create NAME
+  backend org list`
+
+// runOrganizationCommand manages organizations from the command line, e.g. to create an
+// organization before issuing its first admin key
+func runOrganizationCommand(orgs service.OrganizationService, args []string) error {
+	if len(args) == 0 {
+		return errors.New(organizationUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) != 2 {
+			return errors.New(organizationUsage)
+		}
+		org, err := orgs.CreateOrganization(models.Organization{Name: args[1]})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created organization %d (%s).\n", org.ID, org.Name)
+		return nil
+
+	case "list":
+		all, err := orgs.GetOrganizations()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tCREATED")
+		for _, org := range all {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", org.ID, org.Name, formatTime(&org.CreatedAt))
+		}
+		return w.Flush()
+	}
+	return errors.New(organizationUsage)
+}
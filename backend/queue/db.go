@@ -0,0 +1,196 @@
+package queue
+
+import (
+	"errors"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// defaultListLimit caps the number of jobs returned by List when no limit is given
+const defaultListLimit = 100
+
+// leaseRetries bounds how often Lease retries after losing a race for a job to another consumer
+const leaseRetries = 5
+
+// DBQueue is a Queue stored in the application database, so jobs survive restarts.
+// Leases are claimed with conditional updates, which keeps it safe for several consumers.
+type DBQueue struct {
+	DB *gorm.DB
+}
+
+// NewDBQueue creates a new instance of the database-backed Queue
+func NewDBQueue(db *gorm.DB) Queue {
+	return &DBQueue{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+
+// Enqueue stores a new pending job. The job is durable once Enqueue returns.
+func (q *DBQueue) Enqueue(job *models.Job) error {
+	job.Status = models.JobStatusPending
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if job.AvailableAt.IsZero() {
+		job.AvailableAt = time.Now()
+	}
+	job.AvailableAt = models.StoredTime(job.AvailableAt)
+	return q.DB.Create(job).Error
+}
+
+// Lease hands out the oldest ready job (pending, or leased with an expired lease) to owner for
+// the visibility timeout. Jobs whose lease expired on their last attempt are dead-lettered instead.
+func (q *DBQueue) Lease(owner string, visibility time.Duration) (*models.Job, error) {
+	for i := 0; i < leaseRetries; i++ {
+		now := models.StoredTime(time.Now())
+
+		// Find instead of First: an empty queue is the normal case and should not be logged as an error
+		var ready []models.Job
+		err := q.DB.
+			Where("(status = ? AND available_at <= ?) OR (status = ? AND lease_expires_at <= ?)",
+				models.JobStatusPending, now, models.JobStatusLeased, now).
+			Order("available_at, id").
+			Limit(1).
+			Find(&ready).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(ready) == 0 {
+			return nil, ErrEmpty
+		}
+		job := ready[0]
+
+		// Attempts only changes when a job is leased, so it doubles as an optimistic lock
+		claim := q.DB.Model(&models.Job{}).Where("id = ? AND status = ? AND attempts = ?", job.ID, job.Status, job.Attempts)
+
+		if job.Status == models.JobStatusLeased && job.Attempts >= job.MaxAttempts {
+			err := claim.Updates(map[string]interface{}{
+				"status":       models.JobStatusDead,
+				"last_error":   "lease expired on the last attempt",
+				"completed_at": now,
+			}).Error
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		result := claim.Updates(map[string]interface{}{
+			"status":           models.JobStatusLeased,
+			"attempts":         job.Attempts + 1,
+			"lease_owner":      owner,
+			"lease_expires_at": now.Add(visibility),
+		})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return q.Get(job.ID)
+		}
+		// Another consumer claimed the job first; look for the next one
+	}
+	return nil, ErrEmpty
+}
+
+// Ack marks a leased job as succeeded, linking the run that completed it
+func (q *DBQueue) Ack(jobID uint, owner string, runID *uint) error {
+	result := q.DB.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND lease_owner = ?", jobID, models.JobStatusLeased, owner).
+		Updates(map[string]interface{}{
+			"status":       models.JobStatusSucceeded,
+			"run_id":       runID,
+			"completed_at": models.StoredTime(time.Now()),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return q.leaseError(jobID)
+	}
+	return nil
+}
+
+// Nack rejects a leased job: it is retried after a backoff, or dead-lettered once out of attempts
+func (q *DBQueue) Nack(jobID uint, owner string, reason string) error {
+	var job models.Job
+	err := q.DB.Where("id = ? AND status = ? AND lease_owner = ?", jobID, models.JobStatusLeased, owner).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return q.leaseError(jobID)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := models.StoredTime(time.Now())
+	updates := map[string]interface{}{"last_error": reason}
+	if job.Attempts >= job.MaxAttempts {
+		updates["status"] = models.JobStatusDead
+		updates["completed_at"] = now
+	} else {
+		updates["status"] = models.JobStatusPending
+		updates["available_at"] = now.Add(retryDelay(job.Attempts))
+		updates["lease_expires_at"] = nil
+	}
+
+	result := q.DB.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, models.JobStatusLeased, job.Attempts).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// leaseError tells a missing job apart from one whose lease has moved on
+func (q *DBQueue) leaseError(jobID uint) error {
+	if _, err := q.Get(jobID); err != nil {
+		return err
+	}
+	return ErrLeaseLost
+}
+
+func (q *DBQueue) Get(jobID uint) (*models.Job, error) {
+	var job models.Job
+	err := q.DB.First(&job, jobID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List returns jobs matching the filter, newest first
+func (q *DBQueue) List(filter Filter) ([]models.Job, error) {
+	var jobs []models.Job
+	query := q.DB.Order("id DESC")
+	if filter.OrganizationID != 0 {
+		query = query.Where("organization_id = ?", filter.OrganizationID)
+	}
+	if filter.MachineID != 0 {
+		query = query.Where("machine_id = ?", filter.MachineID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	err := query.Limit(filter.Limit).Find(&jobs).Error
+	return jobs, err
+}
+
+// Depth counts the jobs that are waiting or in progress
+func (q *DBQueue) Depth() (int64, error) {
+	var count int64
+	err := q.DB.Model(&models.Job{}).
+		Where("status IN ?", []string{models.JobStatusPending, models.JobStatusLeased}).
+		Count(&count).Error
+	return count, err
+}
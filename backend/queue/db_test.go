@@ -0,0 +1,153 @@
+package queue_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDB initializes an in-memory SQLite database for testing
+func setupTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open in-memory DB: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Job{}); err != nil {
+		t.Fatalf("Failed to migrate schema: %v", err)
+	}
+	return db
+}
+
+func TestDBQueueLeaseAndAck(t *testing.T) {
+	q := queue.NewDBQueue(setupTestDB(t))
+
+	job := &models.Job{MachineID: 1, Params: `{"load": 0.8}`}
+	assert.Nil(t, q.Enqueue(job))
+	assert.Equal(t, models.JobStatusPending, job.Status)
+	assert.Equal(t, queue.DefaultMaxAttempts, job.MaxAttempts)
+
+	depth, err := q.Depth()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), depth)
+
+	leased, err := q.Lease("worker-a", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, job.ID, leased.ID)
+	assert.Equal(t, models.JobStatusLeased, leased.Status)
+	assert.Equal(t, 1, leased.Attempts)
+	assert.Equal(t, "worker-a", leased.LeaseOwner)
+
+	// A leased job is invisible to other consumers
+	_, err = q.Lease("worker-b", time.Minute)
+	assert.ErrorIs(t, err, queue.ErrEmpty)
+
+	assert.ErrorIs(t, q.Ack(job.ID, "worker-b", nil), queue.ErrLeaseLost)
+	assert.ErrorIs(t, q.Ack(42, "worker-a", nil), queue.ErrJobNotFound)
+
+	runID := uint(7)
+	assert.Nil(t, q.Ack(job.ID, "worker-a", &runID))
+	done, err := q.Get(job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, models.JobStatusSucceeded, done.Status)
+	assert.Equal(t, runID, *done.RunID)
+	assert.NotNil(t, done.CompletedAt)
+
+	depth, _ = q.Depth()
+	assert.Equal(t, int64(0), depth)
+}
+
+func TestDBQueueExpiredLeaseIsRedelivered(t *testing.T) {
+	q := queue.NewDBQueue(setupTestDB(t))
+	job := &models.Job{MachineID: 1, MaxAttempts: 2}
+	assert.Nil(t, q.Enqueue(job))
+
+	// A negative visibility timeout expires the lease immediately, as if the consumer had crashed
+	_, err := q.Lease("crashed", -time.Second)
+	assert.Nil(t, err)
+
+	leased, err := q.Lease("worker-b", -time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, leased.Attempts)
+	assert.ErrorIs(t, q.Ack(job.ID, "crashed", nil), queue.ErrLeaseLost)
+
+	// The second expiry used the last attempt, so the job is dead-lettered instead of redelivered
+	_, err = q.Lease("worker-c", time.Minute)
+	assert.ErrorIs(t, err, queue.ErrEmpty)
+	dead, _ := q.Get(job.ID)
+	assert.Equal(t, models.JobStatusDead, dead.Status)
+}
+
+func TestDBQueueNackRetriesThenDeadLetters(t *testing.T) {
+	q := queue.NewDBQueue(setupTestDB(t))
+	job := &models.Job{MachineID: 1, MaxAttempts: 2}
+	assert.Nil(t, q.Enqueue(job))
+
+	_, err := q.Lease("worker", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, q.Nack(job.ID, "worker", "boom"))
+
+	retry, _ := q.Get(job.ID)
+	assert.Equal(t, models.JobStatusPending, retry.Status)
+	assert.Equal(t, "boom", retry.LastError)
+	assert.True(t, retry.AvailableAt.After(time.Now()), "A failed job should back off before it is retried")
+
+	// Skip the backoff
+	assert.Nil(t, q.(*queue.DBQueue).DB.Model(&models.Job{}).Where("id = ?", job.ID).Update("available_at", time.Now().UTC()).Error)
+	_, err = q.Lease("worker", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, q.Nack(job.ID, "worker", "boom again"))
+
+	dead, _ := q.Get(job.ID)
+	assert.Equal(t, models.JobStatusDead, dead.Status)
+	assert.Equal(t, 2, dead.Attempts)
+
+	jobs, err := q.List(queue.Filter{Status: models.JobStatusDead})
+	assert.Nil(t, err)
+	assert.Len(t, jobs, 1)
+
+	assert.Nil(t, q.Enqueue(&models.Job{OrganizationID: 2, MachineID: 1}))
+	jobs, _ = q.List(queue.Filter{OrganizationID: 2})
+	assert.Len(t, jobs, 1)
+	jobs, _ = q.List(queue.Filter{OrganizationID: models.DefaultOrganizationID})
+	assert.Len(t, jobs, 1, "Jobs without an organization belong to the default one")
+}
+
+func TestDBQueueConcurrentConsumersLeaseEachJobOnce(t *testing.T) {
+	q := queue.NewDBQueue(setupTestDB(t))
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, q.Enqueue(&models.Job{MachineID: 1}))
+	}
+
+	var mu sync.Mutex
+	leased := map[uint]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(owner string) {
+			defer wg.Done()
+			for {
+				job, err := q.Lease(owner, time.Minute)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				leased[job.ID]++
+				mu.Unlock()
+			}
+		}(fmt.Sprintf("worker-%d", i))
+	}
+	wg.Wait()
+
+	assert.Len(t, leased, 10)
+	for id, count := range leased {
+		assert.Equal(t, 1, count, "Job %d was leased more than once", id)
+	}
+}
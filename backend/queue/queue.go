@@ -0,0 +1,62 @@
+// Package queue provides the durable job queue that feeds ad-hoc simulation runs to the simulator.
+package queue
+
+import (
+	"errors"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+)
+
+const (
+	// DefaultMaxAttempts is used for jobs enqueued without an explicit attempt limit
+	DefaultMaxAttempts = 3
+	// DefaultVisibilityTimeout is how long a leased job stays invisible to other consumers
+	DefaultVisibilityTimeout = 10 * time.Minute
+
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 5 * time.Minute
+)
+
+var (
+	// ErrEmpty is returned by Lease when no job is ready
+	ErrEmpty = errors.New("queue is empty")
+	// ErrJobNotFound is returned when a job ID does not exist
+	ErrJobNotFound = errors.New("job not found")
+	// ErrLeaseLost is returned when acknowledging a job whose lease expired or was taken by another consumer
+	ErrLeaseLost = errors.New("job lease lost")
+)
+
+// Filter narrows down the jobs returned by Queue.List; zero values match everything
+type Filter struct {
+	OrganizationID uint
+	MachineID      uint
+	Status         string
+	Limit          int
+}
+
+// Queue is a durable, at-least-once job queue. A leased job that is neither acknowledged nor
+// rejected before its visibility timeout becomes available to other consumers again, and a job
+// that fails MaxAttempts times is dead-lettered.
+type Queue interface {
+	Enqueue(job *models.Job) error
+	Lease(owner string, visibility time.Duration) (*models.Job, error)
+	Ack(jobID uint, owner string, runID *uint) error
+	Nack(jobID uint, owner string, reason string) error
+
+	Get(jobID uint) (*models.Job, error)
+	List(filter Filter) ([]models.Job, error)
+	Depth() (int64, error)
+}
+
+// retryDelay is the exponential backoff applied before a failed job becomes available again
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
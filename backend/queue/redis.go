@@ -0,0 +1,473 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeLayout is how timestamps are stored in job hashes
+const redisTimeLayout = time.RFC3339Nano
+
+// redisListBatch is how many job IDs List reads per round trip while filtering
+const redisListBatch = 100
+
+// DefaultRedisRetention is how long finished jobs are kept when RedisQueue.Retention is not set
+const DefaultRedisRetention = 7 * 24 * time.Hour
+
+// luaHelpers is prepended to every script. Scripts only touch the keys passed in KEYS, so all
+// of them hash to the same Redis Cluster slot; job statuses match the models.JobStatus* values.
+const luaHelpers = `
+local function move(from, to, id)
+  redis.call('ZREM', from, id)
+  redis.call('ZADD', to, id, id)
+end
+-- finish expires a job after the retention and records which index entries to purge with it
+local function finish(job, done, id, status, now, retention_ms, purge_at)
+  redis.call('HSET', job, 'status', status, 'updated_at', now, 'completed_at', now)
+  redis.call('PEXPIRE', job, retention_ms)
+  local machine = redis.call('HGET', job, 'machine_id')
+  redis.call('ZADD', done, purge_at, id .. ':' .. machine .. ':' .. status)
+end
+`
+
+// reclaimScript returns a job whose lease expired to the ready set, or dead-letters it on its last attempt.
+// KEYS: leased, ready, job, status:Leased, status:Pending, status:Dead, done
+// ARGV: job ID, now (ms), now, retention (ms), purge at (ms)
+var reclaimScript = redis.NewScript(luaHelpers + `
+local expires = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not expires or tonumber(expires) > tonumber(ARGV[2]) then return 0 end
+redis.call('ZREM', KEYS[1], ARGV[1])
+local fields = redis.call('HMGET', KEYS[3], 'attempts', 'max_attempts')
+if tonumber(fields[1]) >= tonumber(fields[2]) then
+  move(KEYS[4], KEYS[6], ARGV[1])
+  redis.call('HSET', KEYS[3], 'last_error', 'lease expired on the last attempt')
+  finish(KEYS[3], KEYS[7], ARGV[1], 'Dead', ARGV[3], ARGV[4], ARGV[5])
+else
+  move(KEYS[4], KEYS[5], ARGV[1])
+  redis.call('HSET', KEYS[3], 'status', 'Pending', 'updated_at', ARGV[3])
+  redis.call('HDEL', KEYS[3], 'lease_expires_at')
+  redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+end
+return 1
+`)
+
+// claimScript moves a ready job to the leased set, unless another consumer claimed it first.
+// KEYS: ready, leased, job, status:Pending, status:Leased
+// ARGV: job ID, now (ms), now, owner, lease expiry (ms), lease expiry
+var claimScript = redis.NewScript(luaHelpers + `
+local available = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not available or tonumber(available) > tonumber(ARGV[2]) then return 0 end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZADD', KEYS[2], ARGV[5], ARGV[1])
+redis.call('HINCRBY', KEYS[3], 'attempts', 1)
+move(KEYS[4], KEYS[5], ARGV[1])
+redis.call('HSET', KEYS[3], 'status', 'Leased', 'updated_at', ARGV[3], 'lease_owner', ARGV[4], 'lease_expires_at', ARGV[6])
+return 1
+`)
+
+// ackScript marks a job leased by owner as succeeded.
+// KEYS: leased, job, status:Leased, status:Succeeded, done
+// ARGV: job ID, owner, run ID (empty for none), now, retention (ms), purge at (ms)
+var ackScript = redis.NewScript(luaHelpers + `
+if redis.call('EXISTS', KEYS[2]) == 0 then return 'not_found' end
+local fields = redis.call('HMGET', KEYS[2], 'status', 'lease_owner')
+if fields[1] ~= 'Leased' or fields[2] ~= ARGV[2] then return 'lease_lost' end
+redis.call('ZREM', KEYS[1], ARGV[1])
+move(KEYS[3], KEYS[4], ARGV[1])
+if ARGV[3] ~= '' then redis.call('HSET', KEYS[2], 'run_id', ARGV[3]) end
+finish(KEYS[2], KEYS[5], ARGV[1], 'Succeeded', ARGV[4], ARGV[5], ARGV[6])
+return 'ok'
+`)
+
+// nackScript schedules a retry of a job leased by owner, or dead-letters it once out of attempts.
+// The expected attempt count guards against the lease having been reclaimed and handed out again.
+// KEYS: leased, ready, job, status:Leased, status:Pending, status:Dead, done
+// ARGV: job ID, owner, reason, now, attempts, retry at (ms), retry at, retention (ms), purge at (ms)
+var nackScript = redis.NewScript(luaHelpers + `
+if redis.call('EXISTS', KEYS[3]) == 0 then return 'not_found' end
+local fields = redis.call('HMGET', KEYS[3], 'status', 'lease_owner', 'attempts', 'max_attempts')
+if fields[1] ~= 'Leased' or fields[2] ~= ARGV[2] or fields[3] ~= ARGV[5] then return 'lease_lost' end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[3], 'last_error', ARGV[3])
+if tonumber(fields[3]) >= tonumber(fields[4]) then
+  move(KEYS[4], KEYS[6], ARGV[1])
+  finish(KEYS[3], KEYS[7], ARGV[1], 'Dead', ARGV[4], ARGV[8], ARGV[9])
+else
+  move(KEYS[4], KEYS[5], ARGV[1])
+  redis.call('HSET', KEYS[3], 'status', 'Pending', 'updated_at', ARGV[4], 'available_at', ARGV[7])
+  redis.call('HDEL', KEYS[3], 'lease_expires_at')
+  redis.call('ZADD', KEYS[2], ARGV[6], ARGV[1])
+end
+return 'ok'
+`)
+
+// RedisQueue is a Queue stored in Redis, so several backend instances can share one queue.
+// Each job is a hash; sorted sets index ready jobs by availability, leased jobs by lease expiry,
+// and all jobs by machine and status. State changes run as Lua scripts, so they are atomic.
+// Every key shares the hash tag of the prefix, which keeps the queue in one Redis Cluster slot.
+// Finished jobs expire after the retention. Durability depends on the Redis persistence
+// settings (AOF is recommended).
+type RedisQueue struct {
+	Client    redis.UniversalClient
+	Prefix    string
+	Retention time.Duration // how long finished jobs are kept; DefaultRedisRetention if 0
+}
+
+// NewRedisQueue creates a new instance of the Redis-backed Queue with keys under prefix
+func NewRedisQueue(client redis.UniversalClient, prefix string) Queue {
+	return &RedisQueue{Client: client, Prefix: prefix}
+}
+
+func (q *RedisQueue) key(parts ...interface{}) string {
+	key := "{" + q.Prefix + "}"
+	for _, part := range parts {
+		key += fmt.Sprintf(":%v", part)
+	}
+	return key
+}
+
+// retention returns the retention and the time finished jobs are purged from the indexes, in ms
+func (q *RedisQueue) retention(now time.Time) (int64, int64) {
+	retention := q.Retention
+	if retention <= 0 {
+		retention = DefaultRedisRetention
+	}
+	return retention.Milliseconds(), now.Add(retention).UnixMilli()
+}
+
+// --- Implementation of the Interface Methods ---
+
+// Enqueue stores a new pending job. The job is durable once Enqueue returns.
+func (q *RedisQueue) Enqueue(job *models.Job) error {
+	ctx := context.Background()
+	id, err := q.Client.Incr(ctx, q.key("seq")).Result()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	job.ID = uint(id)
+	job.CreatedAt, job.UpdatedAt = now, now
+	job.Status = models.JobStatusPending
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if job.AvailableAt.IsZero() {
+		job.AvailableAt = now
+	}
+	job.AvailableAt = job.AvailableAt.UTC()
+
+	member := strconv.FormatUint(uint64(job.ID), 10)
+	_, err = q.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.key("job", job.ID), encodeJob(job))
+		pipe.ZAdd(ctx, q.key("ready"), redis.Z{Score: float64(job.AvailableAt.UnixMilli()), Member: member})
+		pipe.ZAdd(ctx, q.key("all"), redis.Z{Score: float64(job.ID), Member: member})
+		pipe.ZAdd(ctx, q.key("machine", job.MachineID), redis.Z{Score: float64(job.ID), Member: member})
+		pipe.ZAdd(ctx, q.key("status", job.Status), redis.Z{Score: float64(job.ID), Member: member})
+		return nil
+	})
+	return err
+}
+
+// Lease first reclaims expired leases (or dead-letters them on their last attempt) and purges
+// expired jobs, then hands out the oldest ready job to owner for the visibility timeout
+func (q *RedisQueue) Lease(owner string, visibility time.Duration) (*models.Job, error) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if err := q.reclaim(ctx, now); err != nil {
+		return nil, err
+	}
+	if err := q.purge(ctx, now); err != nil {
+		return nil, err
+	}
+
+	expires := now.Add(visibility)
+	for i := 0; i < leaseRetries; i++ {
+		ids, err := q.Client.ZRangeByScore(ctx, q.key("ready"), &redis.ZRangeBy{
+			Min: "-inf", Max: strconv.FormatInt(now.UnixMilli(), 10), Count: 1,
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, ErrEmpty
+		}
+
+		keys := []string{q.key("ready"), q.key("leased"), q.key("job", ids[0]),
+			q.key("status", models.JobStatusPending), q.key("status", models.JobStatusLeased)}
+		claimed, err := claimScript.Run(ctx, q.Client, keys,
+			ids[0], now.UnixMilli(), now.Format(redisTimeLayout), owner, expires.UnixMilli(), expires.Format(redisTimeLayout)).Int()
+		if err != nil {
+			return nil, err
+		}
+		if claimed == 1 {
+			jobID, err := strconv.ParseUint(ids[0], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			return q.Get(uint(jobID))
+		}
+		// Another consumer claimed the job first; look for the next one
+	}
+	return nil, ErrEmpty
+}
+
+// reclaim returns the jobs whose lease expired by now to the queue
+func (q *RedisQueue) reclaim(ctx context.Context, now time.Time) error {
+	ids, err := q.Client.ZRangeByScore(ctx, q.key("leased"), &redis.ZRangeBy{
+		Min: "-inf", Max: strconv.FormatInt(now.UnixMilli(), 10), Count: redisListBatch,
+	}).Result()
+	if err != nil {
+		return err
+	}
+	retention, purgeAt := q.retention(now)
+	for _, id := range ids {
+		keys := []string{q.key("leased"), q.key("ready"), q.key("job", id), q.key("status", models.JobStatusLeased),
+			q.key("status", models.JobStatusPending), q.key("status", models.JobStatusDead), q.key("done")}
+		err := reclaimScript.Run(ctx, q.Client, keys, id, now.UnixMilli(), now.Format(redisTimeLayout), retention, purgeAt).Err()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purge removes the jobs that expired by now from the indexes. Their hashes expire by themselves.
+func (q *RedisQueue) purge(ctx context.Context, now time.Time) error {
+	members, err := q.Client.ZRangeByScore(ctx, q.key("done"), &redis.ZRangeBy{
+		Min: "-inf", Max: strconv.FormatInt(now.UnixMilli(), 10), Count: redisListBatch,
+	}).Result()
+	if err != nil || len(members) == 0 {
+		return err
+	}
+	_, err = q.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, member := range members {
+			// Members are "<job ID>:<machine ID>:<status>", naming the job's index entries
+			parts := strings.SplitN(member, ":", 3)
+			if len(parts) == 3 {
+				id, machineID, status := parts[0], parts[1], parts[2]
+				pipe.Del(ctx, q.key("job", id))
+				pipe.ZRem(ctx, q.key("all"), id)
+				pipe.ZRem(ctx, q.key("machine", machineID), id)
+				pipe.ZRem(ctx, q.key("status", status), id)
+			}
+			pipe.ZRem(ctx, q.key("done"), member)
+		}
+		return nil
+	})
+	return err
+}
+
+// Ack marks a leased job as succeeded, linking the run that completed it
+func (q *RedisQueue) Ack(jobID uint, owner string, runID *uint) error {
+	run := ""
+	if runID != nil {
+		run = strconv.FormatUint(uint64(*runID), 10)
+	}
+	now := time.Now().UTC()
+	retention, purgeAt := q.retention(now)
+	keys := []string{q.key("leased"), q.key("job", jobID), q.key("status", models.JobStatusLeased),
+		q.key("status", models.JobStatusSucceeded), q.key("done")}
+	result, err := ackScript.Run(context.Background(), q.Client, keys,
+		jobID, owner, run, now.Format(redisTimeLayout), retention, purgeAt).Text()
+	if err != nil {
+		return err
+	}
+	return scriptError(result)
+}
+
+// Nack rejects a leased job: it is retried after a backoff, or dead-lettered once out of attempts
+func (q *RedisQueue) Nack(jobID uint, owner string, reason string) error {
+	job, err := q.Get(jobID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	retryAt := now.Add(retryDelay(job.Attempts))
+	retention, purgeAt := q.retention(now)
+	keys := []string{q.key("leased"), q.key("ready"), q.key("job", jobID), q.key("status", models.JobStatusLeased),
+		q.key("status", models.JobStatusPending), q.key("status", models.JobStatusDead), q.key("done")}
+	result, err := nackScript.Run(context.Background(), q.Client, keys,
+		jobID, owner, reason, now.Format(redisTimeLayout), job.Attempts, retryAt.UnixMilli(), retryAt.Format(redisTimeLayout),
+		retention, purgeAt).Text()
+	if err != nil {
+		return err
+	}
+	return scriptError(result)
+}
+
+// scriptError maps the status returned by the ack and nack scripts to an error
+func scriptError(result string) error {
+	switch result {
+	case "not_found":
+		return ErrJobNotFound
+	case "lease_lost":
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (q *RedisQueue) Get(jobID uint) (*models.Job, error) {
+	fields, err := q.Client.HGetAll(context.Background(), q.key("job", jobID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return decodeJob(fields)
+}
+
+// List returns jobs matching the filter, newest first
+func (q *RedisQueue) List(filter Filter) ([]models.Job, error) {
+	ctx := context.Background()
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+
+	// Walk the narrowest index and check the remaining criteria on each job
+	index := q.key("all")
+	switch {
+	case filter.MachineID != 0:
+		index = q.key("machine", filter.MachineID)
+	case filter.Status != "":
+		index = q.key("status", filter.Status)
+	}
+
+	jobs := []models.Job{}
+	for start := int64(0); len(jobs) < filter.Limit; start += redisListBatch {
+		ids, err := q.Client.ZRevRange(ctx, index, start, start+redisListBatch-1).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		cmds := make([]*redis.MapStringStringCmd, len(ids))
+		_, err = q.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, id := range ids {
+				cmds[i] = pipe.HGetAll(ctx, q.key("job", id))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cmd := range cmds {
+			if len(cmd.Val()) == 0 {
+				continue
+			}
+			job, err := decodeJob(cmd.Val())
+			if err != nil {
+				return nil, err
+			}
+			if filter.Status != "" && job.Status != filter.Status {
+				continue
+			}
+			if filter.OrganizationID != 0 && job.OrganizationID != filter.OrganizationID {
+				continue
+			}
+			jobs = append(jobs, *job)
+			if len(jobs) == filter.Limit {
+				break
+			}
+		}
+	}
+	return jobs, nil
+}
+
+// Depth counts the jobs that are waiting or in progress
+func (q *RedisQueue) Depth() (int64, error) {
+	ctx := context.Background()
+	var ready, leased *redis.IntCmd
+	_, err := q.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		ready = pipe.ZCard(ctx, q.key("ready"))
+		leased = pipe.ZCard(ctx, q.key("leased"))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return ready.Val() + leased.Val(), nil
+}
+
+// encodeJob converts a new job to the fields of its hash
+func encodeJob(job *models.Job) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              job.ID,
+		"organization_id": job.OrganizationID,
+		"machine_id":      job.MachineID,
+		"params":          job.Params,
+		"request_id":      job.RequestID,
+		"trace_parent":    job.TraceParent,
+		"status":          job.Status,
+		"attempts":        job.Attempts,
+		"max_attempts":    job.MaxAttempts,
+		"available_at":    job.AvailableAt.Format(redisTimeLayout),
+		"created_at":      job.CreatedAt.Format(redisTimeLayout),
+		"updated_at":      job.UpdatedAt.Format(redisTimeLayout),
+	}
+}
+
+// decodeJob converts the fields of a job hash back to a job
+func decodeJob(fields map[string]string) (*models.Job, error) {
+	job := &models.Job{
+		Params:      fields["params"],
+		Status:      fields["status"],
+		LeaseOwner:  fields["lease_owner"],
+		LastError:   fields["last_error"],
+		RequestID:   fields["request_id"],
+		TraceParent: fields["trace_parent"],
+	}
+
+	var err error
+	parseUint := func(name string) uint {
+		value, e := strconv.ParseUint(fields[name], 10, 64)
+		if e != nil && err == nil {
+			err = fmt.Errorf("invalid job field %s: %w", name, e)
+		}
+		return uint(value)
+	}
+	parseTime := func(name string) time.Time {
+		value, e := time.Parse(redisTimeLayout, fields[name])
+		if e != nil && err == nil {
+			err = fmt.Errorf("invalid job field %s: %w", name, e)
+		}
+		return value
+	}
+
+	job.ID = parseUint("id")
+	job.MachineID = parseUint("machine_id")
+	// Jobs enqueued before organizations existed belong to the default organization
+	job.OrganizationID = models.DefaultOrganizationID
+	if fields["organization_id"] != "" {
+		job.OrganizationID = parseUint("organization_id")
+	}
+	job.Attempts = int(parseUint("attempts"))
+	job.MaxAttempts = int(parseUint("max_attempts"))
+	job.AvailableAt = parseTime("available_at")
+	job.CreatedAt = parseTime("created_at")
+	job.UpdatedAt = parseTime("updated_at")
+	if fields["lease_expires_at"] != "" {
+		leaseExpiresAt := parseTime("lease_expires_at")
+		job.LeaseExpiresAt = &leaseExpiresAt
+	}
+	if fields["completed_at"] != "" {
+		completedAt := parseTime("completed_at")
+		job.CompletedAt = &completedAt
+	}
+	if fields["run_id"] != "" {
+		runID := parseUint("run_id")
+		job.RunID = &runID
+	}
+	return job, err
+}
@@ -0,0 +1,223 @@
+package queue_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupRedisQueue starts an in-process Redis stand-in and returns a queue backed by it
+func setupRedisQueue(t *testing.T) (queue.Queue, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return queue.NewRedisQueue(client, "test:jobs"), client
+}
+
+func TestRedisQueueLeaseAndAck(t *testing.T) {
+	q, _ := setupRedisQueue(t)
+
+	job := &models.Job{MachineID: 1, Params: `{"load": 0.8}`, RequestID: "abc123", TraceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}
+	assert.Nil(t, q.Enqueue(job))
+	assert.Equal(t, uint(1), job.ID)
+	assert.Equal(t, queue.DefaultMaxAttempts, job.MaxAttempts)
+
+	depth, err := q.Depth()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), depth)
+
+	leased, err := q.Lease("worker-a", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, job.ID, leased.ID)
+	assert.Equal(t, models.JobStatusLeased, leased.Status)
+	assert.Equal(t, 1, leased.Attempts)
+	assert.Equal(t, `{"load": 0.8}`, leased.Params)
+	assert.Equal(t, "abc123", leased.RequestID, "The submitting request is kept for the logs")
+	assert.Equal(t, job.TraceParent, leased.TraceParent, "The run continues the trace of the submitting request")
+	assert.NotNil(t, leased.LeaseExpiresAt)
+
+	_, err = q.Lease("worker-b", time.Minute)
+	assert.ErrorIs(t, err, queue.ErrEmpty)
+
+	assert.ErrorIs(t, q.Ack(job.ID, "worker-b", nil), queue.ErrLeaseLost)
+	assert.ErrorIs(t, q.Ack(42, "worker-a", nil), queue.ErrJobNotFound)
+
+	runID := uint(7)
+	assert.Nil(t, q.Ack(job.ID, "worker-a", &runID))
+	done, err := q.Get(job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, models.JobStatusSucceeded, done.Status)
+	assert.Equal(t, runID, *done.RunID)
+	assert.NotNil(t, done.CompletedAt)
+
+	depth, _ = q.Depth()
+	assert.Equal(t, int64(0), depth)
+}
+
+func TestRedisQueueExpiredLeaseIsRedelivered(t *testing.T) {
+	q, _ := setupRedisQueue(t)
+	job := &models.Job{MachineID: 1, MaxAttempts: 2}
+	assert.Nil(t, q.Enqueue(job))
+
+	_, err := q.Lease("crashed", -time.Second)
+	assert.Nil(t, err)
+
+	leased, err := q.Lease("worker-b", -time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, leased.Attempts)
+	assert.ErrorIs(t, q.Ack(job.ID, "crashed", nil), queue.ErrLeaseLost)
+
+	_, err = q.Lease("worker-c", time.Minute)
+	assert.ErrorIs(t, err, queue.ErrEmpty)
+	dead, _ := q.Get(job.ID)
+	assert.Equal(t, models.JobStatusDead, dead.Status)
+}
+
+func TestRedisQueueNackRetriesThenDeadLetters(t *testing.T) {
+	q, client := setupRedisQueue(t)
+	job := &models.Job{MachineID: 1, MaxAttempts: 2}
+	assert.Nil(t, q.Enqueue(job))
+
+	_, err := q.Lease("worker", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, q.Nack(job.ID, "worker", "boom"))
+
+	retry, _ := q.Get(job.ID)
+	assert.Equal(t, models.JobStatusPending, retry.Status)
+	assert.Equal(t, "boom", retry.LastError)
+	assert.True(t, retry.AvailableAt.After(time.Now()), "A failed job should back off before it is retried")
+	_, err = q.Lease("worker", time.Minute)
+	assert.ErrorIs(t, err, queue.ErrEmpty)
+
+	// Skip the backoff
+	assert.Nil(t, client.ZAdd(context.Background(), "{test:jobs}:ready", redis.Z{Score: 0, Member: job.ID}).Err())
+	_, err = q.Lease("worker", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, q.Nack(job.ID, "worker", "boom again"))
+
+	dead, _ := q.Get(job.ID)
+	assert.Equal(t, models.JobStatusDead, dead.Status)
+	assert.Equal(t, 2, dead.Attempts)
+}
+
+func TestRedisQueueExpiresFinishedJobs(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	q := &queue.RedisQueue{Client: client, Prefix: "test:jobs", Retention: 50 * time.Millisecond}
+	ctx := context.Background()
+
+	finished, pending := &models.Job{MachineID: 1}, &models.Job{MachineID: 1}
+	assert.Nil(t, q.Enqueue(finished))
+	_, err := q.Lease("worker", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, q.Ack(finished.ID, "worker", nil))
+	assert.Nil(t, q.Enqueue(pending))
+	assert.Greater(t, server.TTL("{test:jobs}:job:1"), time.Duration(0), "Finished jobs expire")
+	assert.Equal(t, time.Duration(0), server.TTL("{test:jobs}:job:2"), "Unfinished jobs are kept")
+
+	time.Sleep(60 * time.Millisecond)
+	server.FastForward(60 * time.Millisecond)
+	leased, err := q.Lease("worker", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, pending.ID, leased.ID)
+
+	_, err = q.Get(finished.ID)
+	assert.ErrorIs(t, err, queue.ErrJobNotFound)
+	for _, index := range []string{"{test:jobs}:all", "{test:jobs}:machine:1"} {
+		ids, _ := client.ZRange(ctx, index, 0, -1).Result()
+		assert.Equal(t, []string{"2"}, ids, "Expired jobs are purged from %s", index)
+	}
+	assert.Equal(t, int64(0), client.ZCard(ctx, "{test:jobs}:status:Succeeded").Val())
+	assert.Equal(t, int64(0), client.ZCard(ctx, "{test:jobs}:done").Val())
+}
+
+func TestRedisQueueList(t *testing.T) {
+	q, _ := setupRedisQueue(t)
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, q.Enqueue(&models.Job{MachineID: uint(1 + i%2)}))
+	}
+	_, err := q.Lease("worker", time.Minute)
+	assert.Nil(t, err)
+
+	jobs, err := q.List(queue.Filter{})
+	assert.Nil(t, err)
+	assert.Len(t, jobs, 5)
+	assert.Equal(t, uint(5), jobs[0].ID, "Jobs should be listed newest first")
+
+	jobs, _ = q.List(queue.Filter{MachineID: 1})
+	assert.Len(t, jobs, 3)
+
+	jobs, _ = q.List(queue.Filter{MachineID: 1, Status: models.JobStatusLeased})
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, uint(1), jobs[0].ID)
+
+	jobs, _ = q.List(queue.Filter{Status: models.JobStatusPending, Limit: 2})
+	assert.Len(t, jobs, 2)
+}
+
+func TestRedisQueueOrganizations(t *testing.T) {
+	q, client := setupRedisQueue(t)
+	assert.Nil(t, q.Enqueue(&models.Job{OrganizationID: models.DefaultOrganizationID, MachineID: 1}))
+	assert.Nil(t, q.Enqueue(&models.Job{OrganizationID: 2, MachineID: 2}))
+
+	jobs, _ := q.List(queue.Filter{OrganizationID: 2})
+	if assert.Len(t, jobs, 1) {
+		assert.Equal(t, uint(2), jobs[0].MachineID)
+	}
+
+	// Jobs enqueued before organizations existed have no organization field
+	assert.Nil(t, client.HDel(context.Background(), "{test:jobs}:job:1", "organization_id").Err())
+	job, err := q.Get(1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(models.DefaultOrganizationID), job.OrganizationID)
+}
+
+func TestRedisQueueSharedByConsumers(t *testing.T) {
+	server := miniredis.RunT(t)
+	// Each consumer has its own client, as separate backend instances would
+	newQueue := func() queue.Queue {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return queue.NewRedisQueue(client, "shared:jobs")
+	}
+
+	producer := newQueue()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, producer.Enqueue(&models.Job{MachineID: 1}))
+	}
+
+	var mu sync.Mutex
+	leased := map[uint]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(q queue.Queue, owner string) {
+			defer wg.Done()
+			for {
+				job, err := q.Lease(owner, time.Minute)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				leased[job.ID]++
+				mu.Unlock()
+				assert.Nil(t, q.Ack(job.ID, owner, nil))
+			}
+		}(newQueue(), fmt.Sprintf("instance-%d", i))
+	}
+	wg.Wait()
+
+	assert.Len(t, leased, 10)
+	for id, count := range leased {
+		assert.Equal(t, 1, count, "Job %d was leased more than once", id)
+	}
+}
@@ -0,0 +1,161 @@
+// Package ratelimit keeps clients from overloading the backend with token buckets: each
+// client may make a burst of requests, after which requests are admitted at a steady rate.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate is how many requests a client may make per period. A client that made none for a
+// period may make all of them at once.
+type Rate struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Unlimited reports whether the rate admits every request
+func (r Rate) Unlimited() bool {
+	return r.Requests <= 0 || r.Per <= 0
+}
+
+// String formats the rate the way ParseRate reads it, e.g. "20/s"
+func (r Rate) String() string {
+	if r.Unlimited() {
+		return "0"
+	}
+	unit := r.Per.String()
+	switch r.Per {
+	case time.Second:
+		unit = "s"
+	case time.Minute:
+		unit = "m"
+	case time.Hour:
+		unit = "h"
+	}
+	return fmt.Sprintf("%d/%s", r.Requests, unit)
+}
+
+// ParseRate reads a rate such as "20/s", "600/m", "1000/h" or "5/10s"; "0" is unlimited
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "0" {
+		return Rate{}, nil
+	}
+	count, unit, ok := strings.Cut(s, "/")
+	requests, err := strconv.Atoi(count)
+	if !ok || err != nil || requests <= 0 {
+		return Rate{}, fmt.Errorf("rate must look like 20/s, got %q", s)
+	}
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		per, err = time.ParseDuration(unit)
+		if err != nil || per <= 0 {
+			return Rate{}, fmt.Errorf("rate period must be s, m, h or a duration, got %q", unit)
+		}
+	}
+	return Rate{Requests: requests, Per: per}, nil
+}
+
+// Limiter holds a token bucket per client. Buckets of clients that stayed away long enough
+// to fill up again are dropped, so the limiter does not grow with every client ever seen.
+type Limiter struct {
+	Rate Rate
+	Now  func() time.Time // nil for time.Now
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewLimiter returns a limiter admitting rate requests per client
+func NewLimiter(rate Rate) *Limiter {
+	return &Limiter{Rate: rate, buckets: map[string]*bucket{}}
+}
+
+// Allow takes a token from the client's bucket. When the bucket is empty it reports false
+// and how long until the next token.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	if l.Rate.Unlimited() {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock()
+	l.sweep(now)
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(l.Rate.Requests), updated: now}
+		l.buckets[client] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration(math.Ceil((1 - b.tokens) * float64(l.interval())))
+	return false, wait
+}
+
+// Remaining returns how many requests the client may make right now
+func (l *Limiter) Remaining(client string) int {
+	if l.Rate.Unlimited() {
+		return math.MaxInt
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[client]
+	if !ok {
+		return l.Rate.Requests
+	}
+	return int(l.refill(b, l.clock()))
+}
+
+func (l *Limiter) clock() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// interval is the time it takes to earn one token
+func (l *Limiter) interval() time.Duration {
+	return l.Rate.Per / time.Duration(l.Rate.Requests)
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	earned := float64(now.Sub(b.updated)) / float64(l.interval())
+	return math.Min(float64(l.Rate.Requests), b.tokens+earned)
+}
+
+// sweep drops full buckets at most once per period
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.Rate.Per {
+		return
+	}
+	l.lastSweep = now
+	for client, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.Rate.Requests) {
+			delete(l.buckets, client)
+		}
+	}
+}
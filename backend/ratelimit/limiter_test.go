@@ -0,0 +1,67 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRate(t *testing.T) {
+	for raw, want := range map[string]ratelimit.Rate{
+		"20/s":   {Requests: 20, Per: time.Second},
+		"600/m":  {Requests: 600, Per: time.Minute},
+		"1000/h": {Requests: 1000, Per: time.Hour},
+		"5/10s":  {Requests: 5, Per: 10 * time.Second},
+		"0":      {},
+	} {
+		rate, err := ratelimit.ParseRate(raw)
+		assert.Nil(t, err, raw)
+		assert.Equal(t, want, rate, raw)
+		assert.Equal(t, raw, rate.String())
+	}
+
+	for _, raw := range []string{"", "fast", "20", "-1/s", "20/fortnight", "20/-1s"} {
+		_, err := ratelimit.ParseRate(raw)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := ratelimit.NewLimiter(ratelimit.Rate{Requests: 3, Per: 3 * time.Second})
+	limiter.Now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.Allow("a")
+		assert.True(t, ok, "The burst is admitted")
+	}
+	ok, wait := limiter.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait, "One token is earned per second")
+	assert.Equal(t, 0, limiter.Remaining("a"))
+
+	ok, _ = limiter.Allow("b")
+	assert.True(t, ok, "Clients have buckets of their own")
+
+	now = now.Add(500 * time.Millisecond)
+	ok, wait = limiter.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.Allow("a")
+	assert.True(t, ok)
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, 3, limiter.Remaining("a"), "Buckets fill up to the burst, not beyond")
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.Rate{})
+	for i := 0; i < 1000; i++ {
+		ok, _ := limiter.Allow("a")
+		assert.True(t, ok)
+	}
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// AlarmFilter narrows down the alarms returned by AlarmRepository.Find; zero values match everything
+type AlarmFilter struct {
+	OrganizationID uint
+	MachineID      uint
+	State          string
+}
+
+// AlarmRepository defines the interface for alarm and alarm rule data operations
+type AlarmRepository interface {
+	CreateRule(rule *models.AlarmRule) error
+	FindRules() ([]models.AlarmRule, error)
+	FindRuleByID(id uint) (*models.AlarmRule, error)
+	DeleteRule(id uint) error
+
+	Create(alarm *models.Alarm) error
+	Update(alarm *models.Alarm) error
+	FindByID(id uint) (*models.Alarm, error)
+	Find(filter AlarmFilter) ([]models.Alarm, error)
+	FindActive() ([]models.Alarm, error)
+	WithContext(ctx context.Context) AlarmRepository
+}
+
+// AlarmRepositoryImpl is the concrete implementation of AlarmRepository
+type AlarmRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewAlarmRepository creates a new instance of AlarmRepository
+func NewAlarmRepository(db *gorm.DB) AlarmRepository {
+	return &AlarmRepositoryImpl{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+func (r *AlarmRepositoryImpl) CreateRule(rule *models.AlarmRule) error {
+	return r.DB.Create(rule).Error
+}
+
+func (r *AlarmRepositoryImpl) FindRules() ([]models.AlarmRule, error) {
+	var rules []models.AlarmRule
+	err := r.DB.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func (r *AlarmRepositoryImpl) FindRuleByID(id uint) (*models.AlarmRule, error) {
+	var rule models.AlarmRule
+	err := r.DB.First(&rule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *AlarmRepositoryImpl) DeleteRule(id uint) error {
+	return r.DB.Delete(&models.AlarmRule{}, id).Error
+}
+
+func (r *AlarmRepositoryImpl) Create(alarm *models.Alarm) error {
+	return r.DB.Create(alarm).Error
+}
+
+func (r *AlarmRepositoryImpl) Update(alarm *models.Alarm) error {
+	return r.DB.Save(alarm).Error
+}
+
+func (r *AlarmRepositoryImpl) FindByID(id uint) (*models.Alarm, error) {
+	var alarm models.Alarm
+	err := r.DB.First(&alarm, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &alarm, nil
+}
+
+// Find returns alarms matching the filter, most recent first
+func (r *AlarmRepositoryImpl) Find(filter AlarmFilter) ([]models.Alarm, error) {
+	var alarms []models.Alarm
+	query := tenantDB(r.DB, filter.OrganizationID).Order("raised_at DESC, id DESC")
+	if filter.MachineID != 0 {
+		query = query.Where("machine_id = ?", filter.MachineID)
+	}
+	if filter.State != "" {
+		query = query.Where("state = ?", filter.State)
+	}
+	err := query.Find(&alarms).Error
+	return alarms, err
+}
+
+// FindActive returns every alarm that has not been cleared yet
+func (r *AlarmRepositoryImpl) FindActive() ([]models.Alarm, error) {
+	var alarms []models.Alarm
+	err := r.DB.Where("state <> ?", models.AlarmStateCleared).Find(&alarms).Error
+	return alarms, err
+}
+
+func (r *AlarmRepositoryImpl) WithContext(ctx context.Context) AlarmRepository {
+	return &AlarmRepositoryImpl{DB: r.DB.WithContext(ctx)}
+}
@@ -0,0 +1,57 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlarmRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewAlarmRepository(db)
+
+	// --- 1. Rules ---
+	t.Run("Rules", func(t *testing.T) {
+		rule := models.AlarmRule{Name: "Hot", Kind: models.AlarmRuleThreshold, Metric: "temperature", Operator: ">", Threshold: 80}
+		assert.Nil(t, repo.CreateRule(&rule))
+		assert.Greater(t, rule.ID, uint(0))
+
+		rules, err := repo.FindRules()
+		assert.Nil(t, err)
+		assert.Len(t, rules, 1)
+		assert.Equal(t, "warning", rules[0].Severity, "Severity should default to warning")
+
+		assert.Nil(t, repo.DeleteRule(rule.ID))
+		_, err = repo.FindRuleByID(rule.ID)
+		assert.NotNil(t, err, "Rule should be gone after delete")
+	})
+
+	// --- 2. Alarms ---
+	t.Run("Alarms", func(t *testing.T) {
+		now := time.Now()
+		raised := models.Alarm{RuleID: 1, MachineID: 1, State: models.AlarmStateRaised, RaisedAt: now}
+		cleared := models.Alarm{RuleID: 1, MachineID: 2, State: models.AlarmStateCleared, RaisedAt: now.Add(-time.Hour)}
+		assert.Nil(t, repo.Create(&raised))
+		assert.Nil(t, repo.Create(&cleared))
+
+		active, err := repo.FindActive()
+		assert.Nil(t, err)
+		assert.Len(t, active, 1, "Only uncleared alarms are active")
+		assert.Equal(t, raised.ID, active[0].ID)
+
+		all, _ := repo.Find(repository.AlarmFilter{})
+		assert.Len(t, all, 2)
+		assert.Equal(t, raised.ID, all[0].ID, "Most recent alarms come first")
+
+		byMachine, _ := repo.Find(repository.AlarmFilter{MachineID: 2})
+		assert.Len(t, byMachine, 1)
+
+		raised.State = models.AlarmStateAcknowledged
+		assert.Nil(t, repo.Update(&raised))
+		byState, _ := repo.Find(repository.AlarmFilter{State: models.AlarmStateAcknowledged})
+		assert.Len(t, byState, 1)
+	})
+}
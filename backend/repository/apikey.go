@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository defines the interface for API key data operations
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	FindAll() ([]models.APIKey, error)
+	FindByID(id uint) (*models.APIKey, error)
+	FindByPrefix(prefix string) (*models.APIKey, error)
+	Revoke(id uint, at time.Time) error
+	TouchLastUsed(id uint, at time.Time) error
+	WithContext(ctx context.Context) APIKeyRepository
+}
+
+// APIKeyRepositoryImpl is the concrete implementation of APIKeyRepository
+type APIKeyRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new instance of APIKeyRepository
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &APIKeyRepositoryImpl{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+func (r *APIKeyRepositoryImpl) Create(key *models.APIKey) error {
+	return r.DB.Create(key).Error
+}
+
+func (r *APIKeyRepositoryImpl) FindAll() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.DB.Order("id").Find(&keys).Error
+	return keys, err
+}
+
+func (r *APIKeyRepositoryImpl) FindByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.DB.First(&key, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepositoryImpl) FindByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.DB.Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Revoke marks a key as revoked; revoked keys are kept so their use can still be audited
+func (r *APIKeyRepositoryImpl) Revoke(id uint, at time.Time) error {
+	return r.DB.Model(&models.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at).Error
+}
+
+// TouchLastUsed records when a key was last used, without touching its other columns
+func (r *APIKeyRepositoryImpl) TouchLastUsed(id uint, at time.Time) error {
+	return r.DB.Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
+
+func (r *APIKeyRepositoryImpl) WithContext(ctx context.Context) APIKeyRepository {
+	return &APIKeyRepositoryImpl{DB: r.DB.WithContext(ctx)}
+}
@@ -0,0 +1,45 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewAPIKeyRepository(db)
+
+	key := models.APIKey{Name: "ci", Prefix: "ak_abcdefgh", Hash: "hash", Scopes: []string{models.APIKeyScopeRead}}
+	assert.Nil(t, repo.Create(&key))
+	assert.NotNil(t, repo.Create(&models.APIKey{Name: "other", Prefix: "ak_abcdefgh", Hash: "hash"}), "Prefixes are unique")
+
+	found, err := repo.FindByPrefix("ak_abcdefgh")
+	if assert.Nil(t, err) {
+		assert.Equal(t, key.ID, found.ID)
+		assert.Equal(t, []string{models.APIKeyScopeRead}, found.Scopes)
+		assert.Nil(t, found.LastUsedAt)
+	}
+	_, err = repo.FindByPrefix("ak_missing")
+	assert.NotNil(t, err)
+
+	used := time.Now().Truncate(time.Second)
+	assert.Nil(t, repo.TouchLastUsed(key.ID, used))
+	revoked := used.Add(time.Minute)
+	assert.Nil(t, repo.Revoke(key.ID, revoked))
+	assert.Nil(t, repo.Revoke(key.ID, revoked.Add(time.Hour)), "Revoking again is a no-op")
+
+	found, err = repo.FindByID(key.ID)
+	if assert.Nil(t, err) && assert.NotNil(t, found.LastUsedAt) && assert.NotNil(t, found.RevokedAt) {
+		assert.True(t, used.Equal(*found.LastUsedAt))
+		assert.True(t, revoked.Equal(*found.RevokedAt), "The first revocation is kept")
+		assert.Equal(t, "hash", found.Hash)
+	}
+
+	keys, err := repo.FindAll()
+	assert.Nil(t, err)
+	assert.Len(t, keys, 1)
+}
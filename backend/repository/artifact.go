@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// ArtifactRepository defines the interface for run artifact metadata operations
+type ArtifactRepository interface {
+	Create(artifact *models.Artifact) error
+	FindByID(id uint) (*models.Artifact, error)
+	FindByRun(runID uint) ([]models.Artifact, error)
+	FindOlderThan(cutoff time.Time, limit int) ([]models.Artifact, error)
+	Delete(id uint) error
+	WithContext(ctx context.Context) ArtifactRepository
+}
+
+// ArtifactRepositoryImpl is the concrete implementation of ArtifactRepository
+type ArtifactRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewArtifactRepository creates a new instance of ArtifactRepository
+func NewArtifactRepository(db *gorm.DB) ArtifactRepository {
+	return &ArtifactRepositoryImpl{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+func (r *ArtifactRepositoryImpl) Create(artifact *models.Artifact) error {
+	return r.DB.Create(artifact).Error
+}
+
+func (r *ArtifactRepositoryImpl) FindByID(id uint) (*models.Artifact, error) {
+	var artifact models.Artifact
+	err := r.DB.First(&artifact, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &artifact, nil
+}
+
+// FindByRun returns the artifacts of a run ordered by name
+func (r *ArtifactRepositoryImpl) FindByRun(runID uint) ([]models.Artifact, error) {
+	var artifacts []models.Artifact
+	err := r.DB.Where("run_id = ?", runID).Order("name").Find(&artifacts).Error
+	return artifacts, err
+}
+
+// FindOlderThan returns up to limit artifacts created before cutoff, oldest first
+func (r *ArtifactRepositoryImpl) FindOlderThan(cutoff time.Time, limit int) ([]models.Artifact, error) {
+	var artifacts []models.Artifact
+	err := r.DB.Where("created_at < ?", models.StoredTime(cutoff)).Order("created_at").Limit(limit).Find(&artifacts).Error
+	return artifacts, err
+}
+
+// Delete removes an artifact's metadata for good; its contents must be deleted from the store separately
+func (r *ArtifactRepositoryImpl) Delete(id uint) error {
+	return r.DB.Unscoped().Delete(&models.Artifact{}, id).Error
+}
+
+func (r *ArtifactRepositoryImpl) WithContext(ctx context.Context) ArtifactRepository {
+	return &ArtifactRepositoryImpl{DB: r.DB.WithContext(ctx)}
+}
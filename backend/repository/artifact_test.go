@@ -0,0 +1,41 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtifactRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewArtifactRepository(db)
+
+	old := &models.Artifact{RunID: 1, Name: "plot.png", Key: "runs/1/plot.png"}
+	old.CreatedAt = time.Now().UTC().Add(-48 * time.Hour)
+	assert.Nil(t, repo.Create(old))
+	recent := &models.Artifact{RunID: 1, Name: "data/results.csv", Key: "runs/1/data/results.csv", Size: 8}
+	recent.CreatedAt = time.Now().UTC()
+	assert.Nil(t, repo.Create(recent))
+	assert.NotNil(t, repo.Create(&models.Artifact{RunID: 1, Name: "plot.png", Key: "runs/1/plot.png"}), "Names should be unique per run")
+	assert.Nil(t, repo.Create(&models.Artifact{RunID: 2, Name: "plot.png", Key: "runs/2/plot.png"}))
+
+	artifacts, err := repo.FindByRun(1)
+	assert.Nil(t, err)
+	if assert.Len(t, artifacts, 2) {
+		assert.Equal(t, "data/results.csv", artifacts[0].Name, "Artifacts should be ordered by name")
+	}
+
+	expired, err := repo.FindOlderThan(time.Now().Add(-24*time.Hour), 10)
+	assert.Nil(t, err)
+	if assert.Len(t, expired, 1) {
+		assert.Equal(t, old.ID, expired[0].ID)
+	}
+
+	assert.Nil(t, repo.Delete(old.ID))
+	_, err = repo.FindByID(old.ID)
+	assert.NotNil(t, err)
+	assert.Nil(t, repo.Create(&models.Artifact{RunID: 1, Name: "plot.png", Key: "runs/1/plot.png"}), "A deleted artifact's name can be reused")
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// BatchRepository defines the interface for parameter sweep data operations
+type BatchRepository interface {
+	Create(batch *models.Batch, points []models.BatchPoint) error
+	Update(batch *models.Batch) error
+	FindByID(id uint) (*models.Batch, error)
+	FindByStatus(status string) ([]models.Batch, error)
+
+	FindPoints(batchID uint) ([]models.BatchPoint, error)
+	UpdatePoint(point *models.BatchPoint) error
+	WithContext(ctx context.Context) BatchRepository
+}
+
+// BatchRepositoryImpl is the concrete implementation of BatchRepository
+type BatchRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewBatchRepository creates a new instance of BatchRepository
+func NewBatchRepository(db *gorm.DB) BatchRepository {
+	return &BatchRepositoryImpl{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+
+// Create stores a batch together with all of its points, or nothing at all
+func (r *BatchRepositoryImpl) Create(batch *models.Batch, points []models.BatchPoint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		for i := range points {
+			points[i].BatchID = batch.ID
+		}
+		return tx.CreateInBatches(points, 100).Error
+	})
+}
+
+func (r *BatchRepositoryImpl) Update(batch *models.Batch) error {
+	return r.DB.Save(batch).Error
+}
+
+func (r *BatchRepositoryImpl) FindByID(id uint) (*models.Batch, error) {
+	var batch models.Batch
+	err := r.DB.First(&batch, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *BatchRepositoryImpl) FindByStatus(status string) ([]models.Batch, error) {
+	var batches []models.Batch
+	err := r.DB.Where("status = ?", status).Order("id").Find(&batches).Error
+	return batches, err
+}
+
+// FindPoints returns the points of a batch in sweep order
+func (r *BatchRepositoryImpl) FindPoints(batchID uint) ([]models.BatchPoint, error) {
+	var points []models.BatchPoint
+	err := r.DB.Where("batch_id = ?", batchID).Order("position").Find(&points).Error
+	return points, err
+}
+
+func (r *BatchRepositoryImpl) UpdatePoint(point *models.BatchPoint) error {
+	return r.DB.Save(point).Error
+}
+
+func (r *BatchRepositoryImpl) WithContext(ctx context.Context) BatchRepository {
+	return &BatchRepositoryImpl{DB: r.DB.WithContext(ctx)}
+}
@@ -0,0 +1,46 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewBatchRepository(db)
+
+	batch := &models.Batch{MachineID: 1, Mode: "list", Concurrency: 2, Status: models.BatchStatusRunning, Total: 3}
+	points := []models.BatchPoint{
+		{Position: 2, Params: `{"load": 3}`, Status: models.PointStatusPending},
+		{Position: 0, Params: `{"load": 1}`, Status: models.PointStatusPending},
+		{Position: 1, Params: `{"load": 2}`, Status: models.PointStatusPending},
+	}
+	assert.Nil(t, repo.Create(batch, points))
+	assert.NotZero(t, batch.ID)
+
+	found, err := repo.FindPoints(batch.ID)
+	assert.Nil(t, err)
+	assert.Len(t, found, 3)
+	assert.Equal(t, `{"load": 1}`, found[0].Params, "Points should be returned in sweep order")
+	assert.Equal(t, batch.ID, found[0].BatchID)
+
+	found[0].Status = models.PointStatusSucceeded
+	assert.Nil(t, repo.UpdatePoint(&found[0]))
+	found, _ = repo.FindPoints(batch.ID)
+	assert.Equal(t, models.PointStatusSucceeded, found[0].Status)
+
+	running, err := repo.FindByStatus(models.BatchStatusRunning)
+	assert.Nil(t, err)
+	assert.Len(t, running, 1)
+
+	batch.Status = models.BatchStatusCompleted
+	assert.Nil(t, repo.Update(batch))
+	running, _ = repo.FindByStatus(models.BatchStatusRunning)
+	assert.Empty(t, running)
+
+	_, err = repo.FindByID(batch.ID + 1)
+	assert.NotNil(t, err)
+}
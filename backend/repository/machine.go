@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/CBYeuler/automation-backend/backend/models"
 	"gorm.io/gorm"
 )
@@ -11,12 +14,29 @@ type MachineRepository interface {
 	FindAll() ([]models.Machine, error)
 	FindByID(id uint) (*models.Machine, error)
 	Update(machine *models.Machine) error
+	UpdateStatus(ids []uint, status string) error
+	// SetStatusIf sets the status of a machine only while it is in one of the from statuses,
+	// reporting whether it was changed
+	SetStatusIf(id uint, status string, from ...string) (bool, error)
 	Delete(id uint) error
+	// CountByStatus counts the machines in each status
+	CountByStatus() (map[string]int64, error)
+	// Transaction runs fn with a repository whose queries are part of one transaction, committed
+	// if fn returns nil and rolled back otherwise
+	Transaction(fn func(repo MachineRepository) error) error
+
+	// ForTenant returns the repository limited to the machines of one organization, which
+	// new machines are created in; 0 is every organization
+	ForTenant(organizationID uint) MachineRepository
+	// WithContext returns the repository running its queries with the context of a request,
+	// so that they are logged with its request ID
+	WithContext(ctx context.Context) MachineRepository
 }
 
 // MachineRepositoryImpl is the concrete implementation of MachineRepository
 type MachineRepositoryImpl struct {
-	DB *gorm.DB
+	DB             *gorm.DB
+	OrganizationID uint // every query is limited to this organization unless 0
 }
 
 // NewMachineRepository creates a new instance of MachineRepository
@@ -26,28 +46,88 @@ func NewMachineRepository(db *gorm.DB) MachineRepository {
 
 // --- Implementation of the Interface Methods ---
 func (r *MachineRepositoryImpl) Create(machine *models.Machine) error {
+	machine.OrganizationID = owner(r.OrganizationID)
 	return r.DB.Create(machine).Error
 }
 
 func (r *MachineRepositoryImpl) FindAll() ([]models.Machine, error) {
 	var machines []models.Machine
-	err := r.DB.Find(&machines).Error
+	err := r.db().Find(&machines).Error
 	return machines, err
 }
 
 func (r *MachineRepositoryImpl) FindByID(id uint) (*models.Machine, error) {
 	var machine models.Machine
-	err := r.DB.First(&machine, id).Error
+	err := r.db().First(&machine, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &machine, nil
 }
 
+// Update saves a machine found through the repository; machines of other organizations
+// are not found, and cannot be moved to another organization
 func (r *MachineRepositoryImpl) Update(machine *models.Machine) error {
+	if r.OrganizationID != 0 && machine.OrganizationID != r.OrganizationID {
+		return gorm.ErrRecordNotFound
+	}
 	return r.DB.Save(machine).Error
 }
 
+// UpdateStatus sets the status of several machines at once. Either all of them are
+// updated or, if any no longer exists, none is.
+func (r *MachineRepositoryImpl) UpdateStatus(ids []uint, status string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tenantDB(tx, r.OrganizationID).Model(&models.Machine{}).Where("id IN ?", ids).Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(ids)) {
+			return fmt.Errorf("%w: only %d of %d machines exist", gorm.ErrRecordNotFound, result.RowsAffected, len(ids))
+		}
+		return nil
+	})
+}
+
+func (r *MachineRepositoryImpl) SetStatusIf(id uint, status string, from ...string) (bool, error) {
+	result := r.db().Model(&models.Machine{}).Where("id = ? AND status IN ?", id, from).Update("status", status)
+	return result.RowsAffected == 1, result.Error
+}
+
 func (r *MachineRepositoryImpl) Delete(id uint) error {
-	return r.DB.Delete(&models.Machine{}, id).Error
+	return r.db().Delete(&models.Machine{}, id).Error
+}
+
+func (r *MachineRepositoryImpl) CountByStatus() (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db().Model(&models.Machine{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *MachineRepositoryImpl) Transaction(fn func(repo MachineRepository) error) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(&MachineRepositoryImpl{DB: tx, OrganizationID: r.OrganizationID})
+	})
+}
+
+func (r *MachineRepositoryImpl) ForTenant(organizationID uint) MachineRepository {
+	return &MachineRepositoryImpl{DB: r.DB, OrganizationID: organizationID}
+}
+
+func (r *MachineRepositoryImpl) WithContext(ctx context.Context) MachineRepository {
+	return &MachineRepositoryImpl{DB: r.DB.WithContext(ctx), OrganizationID: r.OrganizationID}
+}
+
+func (r *MachineRepositoryImpl) db() *gorm.DB {
+	return tenantDB(r.DB, r.OrganizationID)
 }
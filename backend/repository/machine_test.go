@@ -1,6 +1,7 @@
 package repository_test
 
 import (
+	"fmt"
 	"log"
 	"testing"
 
@@ -13,14 +14,33 @@ import (
 
 // setupTestDB initializes an in-memory SQLite database for testing
 func setupTestDB(t *testing.T) *gorm.DB {
-	// Use an in-memory database connection
-	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	// Use an in-memory database connection, named after the test so tests don't share state
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Failed to open in-memory DB: %v", err)
 	}
 
 	// Migrate the schema (create the table)
-	err = db.AutoMigrate(&models.Machine{})
+	err = db.AutoMigrate(
+		&models.Machine{},
+		&models.TelemetrySample{},
+		&models.AlarmRule{},
+		&models.Alarm{},
+		&models.SimulationRun{},
+		&models.Job{},
+		&models.Batch{},
+		&models.BatchPoint{},
+		&models.Workflow{},
+		&models.WorkflowRun{},
+		&models.WorkflowStepRun{},
+		&models.Artifact{},
+		&models.RunLog{},
+		&models.MachineGroup{},
+		&models.MachineLink{},
+		&models.APIKey{},
+		&models.Organization{},
+	)
 	if err != nil {
 		log.Fatalf("Failed to migrate schema: %v", err)
 	}
@@ -86,3 +106,84 @@ func TestMachineRepositoryCRUD(t *testing.T) {
 		assert.NotNil(t, err, "Machine should be considered not found after soft delete")
 	})
 }
+
+func TestMachineRepositoryUpdateStatus(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewMachineRepository(db)
+
+	press := models.Machine{Name: "Press", Status: "Error", Tags: []string{"line-3", "hydraulic"}}
+	lathe := models.Machine{Name: "Lathe", Status: "Running"}
+	assert.Nil(t, repo.Create(&press))
+	assert.Nil(t, repo.Create(&lathe))
+
+	found, _ := repo.FindByID(press.ID)
+	assert.Equal(t, []string{"line-3", "hydraulic"}, found.Tags, "Tags should round-trip")
+
+	assert.Nil(t, repo.UpdateStatus([]uint{press.ID, lathe.ID}, "Offline"))
+	machines, _ := repo.FindAll()
+	for _, m := range machines {
+		assert.Equal(t, "Offline", m.Status)
+	}
+
+	// A missing machine rolls back the whole update
+	assert.NotNil(t, repo.UpdateStatus([]uint{press.ID, 42}, "Idle"))
+	found, _ = repo.FindByID(press.ID)
+	assert.Equal(t, "Offline", found.Status)
+
+	changed, err := repo.SetStatusIf(press.ID, "Running", "Idle")
+	assert.Nil(t, err)
+	assert.False(t, changed, "Only machines in one of the given statuses change")
+	changed, err = repo.SetStatusIf(press.ID, "Idle", "Running", "Offline")
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	found, _ = repo.FindByID(press.ID)
+	assert.Equal(t, "Idle", found.Status)
+}
+
+func TestMachineRepositoryCountByStatus(t *testing.T) {
+	db := setupTestDB(t)
+	all := repository.NewMachineRepository(db)
+	acme := all.ForTenant(2)
+
+	assert.Nil(t, all.Create(&models.Machine{Name: "Press", Status: "Error"}))
+	assert.Nil(t, all.Create(&models.Machine{Name: "Lathe", Status: "Running"}))
+	assert.Nil(t, acme.Create(&models.Machine{Name: "Drill", Status: "Running"}))
+
+	counts, err := all.CountByStatus()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int64{"Error": 1, "Running": 2}, counts)
+	counts, _ = acme.CountByStatus()
+	assert.Equal(t, map[string]int64{"Running": 1}, counts, "Counts are limited to the repository's organization")
+}
+
+func TestMachineRepositoryForTenant(t *testing.T) {
+	db := setupTestDB(t)
+	all := repository.NewMachineRepository(db)
+	acme, globex := all.ForTenant(2), all.ForTenant(3)
+
+	press := models.Machine{Name: "Press", Status: "Idle"}
+	assert.Nil(t, acme.Create(&press))
+	assert.Equal(t, uint(2), press.OrganizationID, "Machines are created in the repository's organization")
+	other := models.Machine{Name: "Press", Status: "Idle"}
+	assert.Nil(t, globex.Create(&other), "Names are unique per organization")
+	legacy := models.Machine{Name: "Press", Status: "Idle"}
+	assert.Nil(t, all.Create(&legacy))
+	assert.Equal(t, uint(models.DefaultOrganizationID), legacy.OrganizationID)
+	assert.NotNil(t, acme.Create(&models.Machine{Name: "Press"}))
+
+	machines, _ := acme.FindAll()
+	assert.Len(t, machines, 1)
+	machines, _ = all.FindAll()
+	assert.Len(t, machines, 3, "Repositories of no organization see every machine")
+
+	_, err := globex.FindByID(press.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.NotNil(t, globex.UpdateStatus([]uint{press.ID}, "Running"))
+	press.Status = "Running"
+	assert.ErrorIs(t, globex.Update(&press), gorm.ErrRecordNotFound)
+	assert.Nil(t, globex.Delete(press.ID))
+
+	found, err := acme.FindByID(press.ID)
+	assert.Nil(t, err, "Other organizations cannot delete the machine")
+	assert.Equal(t, "Idle", found.Status)
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// OrganizationRepository defines the interface for organization data operations
+type OrganizationRepository interface {
+	Create(org *models.Organization) error
+	FindAll() ([]models.Organization, error)
+	FindByName(name string) (*models.Organization, error)
+	WithContext(ctx context.Context) OrganizationRepository
+}
+
+// OrganizationRepositoryImpl is the concrete implementation of OrganizationRepository
+type OrganizationRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewOrganizationRepository creates a new instance of OrganizationRepository
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &OrganizationRepositoryImpl{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+func (r *OrganizationRepositoryImpl) Create(org *models.Organization) error {
+	return r.DB.Create(org).Error
+}
+
+func (r *OrganizationRepositoryImpl) FindAll() ([]models.Organization, error) {
+	var orgs []models.Organization
+	err := r.DB.Order("id").Find(&orgs).Error
+	return orgs, err
+}
+
+func (r *OrganizationRepositoryImpl) FindByName(name string) (*models.Organization, error) {
+	var org models.Organization
+	err := r.DB.Where("name = ?", name).First(&org).Error
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *OrganizationRepositoryImpl) WithContext(ctx context.Context) OrganizationRepository {
+	return &OrganizationRepositoryImpl{DB: r.DB.WithContext(ctx)}
+}
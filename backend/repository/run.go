@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// RunRepository defines the interface for simulation run data operations
+type RunRepository interface {
+	Create(run *models.SimulationRun) error
+	Update(run *models.SimulationRun) error
+	FindByID(id uint) (*models.SimulationRun, error)
+	FindByMachine(machineID uint, limit int) ([]models.SimulationRun, error)
+	WithContext(ctx context.Context) RunRepository
+}
+
+// RunRepositoryImpl is the concrete implementation of RunRepository
+type RunRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewRunRepository creates a new instance of RunRepository
+func NewRunRepository(db *gorm.DB) RunRepository {
+	return &RunRepositoryImpl{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+func (r *RunRepositoryImpl) Create(run *models.SimulationRun) error {
+	return r.DB.Create(run).Error
+}
+
+func (r *RunRepositoryImpl) Update(run *models.SimulationRun) error {
+	return r.DB.Save(run).Error
+}
+
+func (r *RunRepositoryImpl) FindByID(id uint) (*models.SimulationRun, error) {
+	var run models.SimulationRun
+	err := r.DB.First(&run, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// FindByMachine returns the most recent runs of a machine, newest first
+func (r *RunRepositoryImpl) FindByMachine(machineID uint, limit int) ([]models.SimulationRun, error) {
+	var runs []models.SimulationRun
+	err := r.DB.Where("machine_id = ?", machineID).Order("id DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+func (r *RunRepositoryImpl) WithContext(ctx context.Context) RunRepository {
+	return &RunRepositoryImpl{DB: r.DB.WithContext(ctx)}
+}
@@ -0,0 +1,38 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewRunRepository(db)
+
+	for i := 0; i < 3; i++ {
+		run := models.SimulationRun{MachineID: 1, Status: models.RunStatusRunning, StartedAt: time.Now()}
+		assert.Nil(t, repo.Create(&run))
+	}
+	other := models.SimulationRun{MachineID: 2, Status: models.RunStatusRunning, StartedAt: time.Now()}
+	assert.Nil(t, repo.Create(&other))
+
+	run, err := repo.FindByID(1)
+	assert.Nil(t, err)
+	finishedAt := time.Now()
+	run.Status = models.RunStatusCancelled
+	run.FinishedAt = &finishedAt
+	assert.Nil(t, repo.Update(run))
+
+	updated, _ := repo.FindByID(1)
+	assert.Equal(t, models.RunStatusCancelled, updated.Status)
+	assert.NotNil(t, updated.FinishedAt)
+
+	runs, err := repo.FindByMachine(1, 2)
+	assert.Nil(t, err)
+	assert.Len(t, runs, 2, "The limit should be applied")
+	assert.Equal(t, uint(3), runs[0].ID, "Newest runs come first")
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// RunLogRepository defines the interface for run log data operations
+type RunLogRepository interface {
+	Create(log *models.RunLog) error
+	FindByRun(runID uint) (*models.RunLog, error)
+	WithContext(ctx context.Context) RunLogRepository
+}
+
+// RunLogRepositoryImpl is the concrete implementation of RunLogRepository
+type RunLogRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewRunLogRepository creates a new instance of RunLogRepository
+func NewRunLogRepository(db *gorm.DB) RunLogRepository {
+	return &RunLogRepositoryImpl{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+func (r *RunLogRepositoryImpl) Create(log *models.RunLog) error {
+	return r.DB.Create(log).Error
+}
+
+// FindByRun returns the log of a run; runs recorded without log capture have none
+func (r *RunLogRepositoryImpl) FindByRun(runID uint) (*models.RunLog, error) {
+	var logs []models.RunLog
+	err := r.DB.Where("run_id = ?", runID).Limit(1).Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &logs[0], nil
+}
+
+func (r *RunLogRepositoryImpl) WithContext(ctx context.Context) RunLogRepository {
+	return &RunLogRepositoryImpl{DB: r.DB.WithContext(ctx)}
+}
@@ -0,0 +1,25 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunLogRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewRunLogRepository(db)
+
+	assert.Nil(t, repo.Create(&models.RunLog{RunID: 1, Content: "line 1\nline 2\n", Truncated: true}))
+	assert.NotNil(t, repo.Create(&models.RunLog{RunID: 1}), "A run has a single log")
+
+	log, err := repo.FindByRun(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "line 1\nline 2\n", log.Content)
+	assert.True(t, log.Truncated)
+
+	_, err = repo.FindByRun(2)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// telemetryBatchSize bounds the number of rows sent in a single INSERT statement
+const telemetryBatchSize = 100
+
+// telemetryMillis is the time of a sample in milliseconds since the Unix epoch, as SQLite
+// computes it from the stored timestamp
+const telemetryMillis = "(CAST(strftime('%s', timestamp) AS INTEGER) * 1000 + CAST(strftime('%f', timestamp) * 1000 AS INTEGER) % 1000)"
+
+// TelemetryBucket aggregates the samples of one metric that fall into one time bucket
+type TelemetryBucket struct {
+	Metric string
+	Start  int64 // milliseconds since the Unix epoch
+	Min    float64
+	Max    float64
+	Avg    float64
+	Count  int
+}
+
+// TelemetryRepository defines the interface for telemetry data operations
+type TelemetryRepository interface {
+	Append(samples []models.TelemetrySample) error
+	Query(machineID uint, metrics []string, from, to time.Time) ([]models.TelemetrySample, error)
+	Aggregate(machineID uint, metrics []string, from, to time.Time, bucket time.Duration) ([]TelemetryBucket, error)
+	DeleteOlderThan(cutoff time.Time) (int64, error)
+	WithContext(ctx context.Context) TelemetryRepository
+}
+
+// TelemetryRepositoryImpl is the concrete implementation of TelemetryRepository
+type TelemetryRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewTelemetryRepository creates a new instance of TelemetryRepository
+func NewTelemetryRepository(db *gorm.DB) TelemetryRepository {
+	return &TelemetryRepositoryImpl{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+func (r *TelemetryRepositoryImpl) Append(samples []models.TelemetrySample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	for i := range samples {
+		samples[i].Timestamp = models.StoredTime(samples[i].Timestamp)
+	}
+	return r.DB.CreateInBatches(samples, telemetryBatchSize).Error
+}
+
+// Query returns the samples of a machine in [from, to), ordered by metric and time.
+// An empty metrics slice selects every metric.
+func (r *TelemetryRepositoryImpl) Query(machineID uint, metrics []string, from, to time.Time) ([]models.TelemetrySample, error) {
+	var samples []models.TelemetrySample
+	err := r.samples(machineID, metrics, from, to).Order("metric, timestamp").Find(&samples).Error
+	return samples, err
+}
+
+// Aggregate returns min, max, avg and count of the samples of a machine in [from, to) per metric
+// and bucket, ordered by metric and time. Buckets are aligned to the epoch and computed by the
+// database, so a wide range does not load every sample in it; bucket must be at least 1ms.
+func (r *TelemetryRepositoryImpl) Aggregate(machineID uint, metrics []string, from, to time.Time, bucket time.Duration) ([]TelemetryBucket, error) {
+	var buckets []TelemetryBucket
+	ms := bucket.Milliseconds()
+	err := r.samples(machineID, metrics, from, to).
+		Select("metric, "+telemetryMillis+" / ? * ? AS start, MIN(value) AS min, MAX(value) AS max, AVG(value) AS avg, COUNT(*) AS count", ms, ms).
+		Group("metric, start").
+		Order("metric, start").
+		Scan(&buckets).Error
+	return buckets, err
+}
+
+// samples selects the samples of a machine in [from, to); an empty metrics slice selects every metric
+func (r *TelemetryRepositoryImpl) samples(machineID uint, metrics []string, from, to time.Time) *gorm.DB {
+	query := r.DB.Model(&models.TelemetrySample{}).Where("machine_id = ? AND timestamp >= ? AND timestamp < ?", machineID, models.StoredTime(from), models.StoredTime(to))
+	if len(metrics) > 0 {
+		query = query.Where("metric IN ?", metrics)
+	}
+	return query
+}
+
+// DeleteOlderThan removes every sample recorded before cutoff and reports how many were deleted
+func (r *TelemetryRepositoryImpl) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	result := r.DB.Where("timestamp < ?", models.StoredTime(cutoff)).Delete(&models.TelemetrySample{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *TelemetryRepositoryImpl) WithContext(ctx context.Context) TelemetryRepository {
+	return &TelemetryRepositoryImpl{DB: r.DB.WithContext(ctx)}
+}
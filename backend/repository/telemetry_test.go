@@ -0,0 +1,103 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelemetryRepositoryAppend(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewTelemetryRepository(db)
+
+	now := time.Now()
+	samples := []models.TelemetrySample{
+		{MachineID: 1, Metric: "temperature", Value: 61.5, Timestamp: now},
+		{MachineID: 1, Metric: "power", Value: 4.5, Timestamp: now},
+	}
+
+	err := repo.Append(samples)
+	assert.Nil(t, err, "Append should not return an error")
+	assert.Greater(t, samples[0].ID, uint(0), "Sample ID should be set after insert")
+
+	var count int64
+	db.Model(&models.TelemetrySample{}).Where("machine_id = ?", 1).Count(&count)
+	assert.Equal(t, int64(2), count, "Both samples should be stored")
+
+	// Empty batches are a no-op rather than an error
+	assert.Nil(t, repo.Append(nil))
+}
+
+func TestTelemetryRepositoryQueryAndRetention(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewTelemetryRepository(db)
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var samples []models.TelemetrySample
+	for i := 0; i < 10; i++ {
+		ts := base.Add(time.Duration(i) * time.Minute)
+		samples = append(samples,
+			models.TelemetrySample{MachineID: 1, Metric: "temperature", Value: float64(i), Timestamp: ts},
+			models.TelemetrySample{MachineID: 1, Metric: "power", Value: float64(i), Timestamp: ts},
+			models.TelemetrySample{MachineID: 2, Metric: "temperature", Value: float64(i), Timestamp: ts},
+		)
+	}
+	assert.Nil(t, repo.Append(samples))
+
+	t.Run("TimeRange", func(t *testing.T) {
+		result, err := repo.Query(1, nil, base.Add(2*time.Minute), base.Add(5*time.Minute))
+		assert.Nil(t, err)
+		assert.Len(t, result, 6, "Three minutes of two metrics, 'to' is exclusive")
+		assert.Equal(t, "power", result[0].Metric, "Results should be ordered by metric")
+	})
+
+	t.Run("MetricSelection", func(t *testing.T) {
+		result, err := repo.Query(1, []string{"temperature"}, base, base.Add(time.Hour))
+		assert.Nil(t, err)
+		assert.Len(t, result, 10)
+		for _, sample := range result {
+			assert.Equal(t, uint(1), sample.MachineID)
+			assert.Equal(t, "temperature", sample.Metric)
+		}
+	})
+
+	t.Run("DeleteOlderThan", func(t *testing.T) {
+		deleted, err := repo.DeleteOlderThan(base.Add(5 * time.Minute))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(15), deleted, "Five minutes of three series should be purged")
+
+		result, _ := repo.Query(1, nil, base, base.Add(time.Hour))
+		assert.Len(t, result, 10)
+	})
+}
+
+func TestTelemetryRepositoryAggregate(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewTelemetryRepository(db)
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Nil(t, repo.Append([]models.TelemetrySample{
+		{MachineID: 1, Metric: "power", Value: 1, Timestamp: base},
+		{MachineID: 1, Metric: "power", Value: 3, Timestamp: base.Add(59*time.Second + 999*time.Millisecond)},
+		{MachineID: 1, Metric: "power", Value: 8, Timestamp: base.Add(70 * time.Second)},
+		{MachineID: 1, Metric: "temperature", Value: 60, Timestamp: base.Add(10 * time.Second)},
+		{MachineID: 2, Metric: "power", Value: 100, Timestamp: base},
+	}))
+
+	buckets, err := repo.Aggregate(1, nil, base, base.Add(5*time.Minute), time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, []repository.TelemetryBucket{
+		{Metric: "power", Start: base.UnixMilli(), Min: 1, Max: 3, Avg: 2, Count: 2},
+		{Metric: "power", Start: base.Add(time.Minute).UnixMilli(), Min: 8, Max: 8, Avg: 8, Count: 1},
+		{Metric: "temperature", Start: base.UnixMilli(), Min: 60, Max: 60, Avg: 60, Count: 1},
+	}, buckets, "Samples are grouped per metric into buckets aligned to the epoch")
+
+	buckets, err = repo.Aggregate(1, []string{"power"}, base, base.Add(time.Minute), 500*time.Millisecond)
+	assert.Nil(t, err)
+	if assert.Len(t, buckets, 2) {
+		assert.Equal(t, base.Add(59*time.Second+500*time.Millisecond).UnixMilli(), buckets[1].Start, "Buckets may be shorter than a second")
+	}
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// tenantDB limits queries to the records of one organization. An organizationID of 0 means
+// every organization, for background work such as the simulator.
+func tenantDB(db *gorm.DB, organizationID uint) *gorm.DB {
+	if organizationID == 0 {
+		return db
+	}
+	return db.Where("organization_id = ?", organizationID)
+}
+
+// owner is the organization new records of a repository bound to organizationID belong to
+func owner(organizationID uint) uint {
+	if organizationID == 0 {
+		return models.DefaultOrganizationID
+	}
+	return organizationID
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// TopologyRepository defines the interface for machine group and link data operations
+type TopologyRepository interface {
+	CreateGroup(group *models.MachineGroup) error
+	FindGroups() ([]models.MachineGroup, error)
+	FindGroupByID(id uint) (*models.MachineGroup, error)
+	DeleteGroup(id uint) error
+	SetMachineGroup(machineID uint, groupID *uint) error
+
+	CreateLink(link *models.MachineLink) error
+	FindLinks() ([]models.MachineLink, error)
+	FindLinkByID(id uint) (*models.MachineLink, error)
+	DeleteLink(id uint) error
+	DeleteMachineLinks(machineID uint) error
+
+	// ForTenant returns the repository limited to the groups, links and machines of one
+	// organization, which new groups and links are created in; 0 is every organization
+	ForTenant(organizationID uint) TopologyRepository
+	WithContext(ctx context.Context) TopologyRepository
+}
+
+// TopologyRepositoryImpl is the concrete implementation of TopologyRepository
+type TopologyRepositoryImpl struct {
+	DB             *gorm.DB
+	OrganizationID uint // every query is limited to this organization unless 0
+}
+
+// NewTopologyRepository creates a new instance of TopologyRepository
+func NewTopologyRepository(db *gorm.DB) TopologyRepository {
+	return &TopologyRepositoryImpl{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+func (r *TopologyRepositoryImpl) CreateGroup(group *models.MachineGroup) error {
+	group.OrganizationID = owner(r.OrganizationID)
+	return r.DB.Create(group).Error
+}
+
+func (r *TopologyRepositoryImpl) FindGroups() ([]models.MachineGroup, error) {
+	var groups []models.MachineGroup
+	err := r.db().Order("id").Find(&groups).Error
+	return groups, err
+}
+
+func (r *TopologyRepositoryImpl) FindGroupByID(id uint) (*models.MachineGroup, error) {
+	var group models.MachineGroup
+	err := r.db().First(&group, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// DeleteGroup removes a group for good, so its name can be reused
+func (r *TopologyRepositoryImpl) DeleteGroup(id uint) error {
+	return r.db().Unscoped().Delete(&models.MachineGroup{}, id).Error
+}
+
+// SetMachineGroup moves a machine into a group, or out of any group for a nil groupID.
+// Only the group column is written, so concurrent status updates are not overwritten.
+func (r *TopologyRepositoryImpl) SetMachineGroup(machineID uint, groupID *uint) error {
+	return r.db().Model(&models.Machine{}).Where("id = ?", machineID).Update("group_id", groupID).Error
+}
+
+func (r *TopologyRepositoryImpl) CreateLink(link *models.MachineLink) error {
+	link.OrganizationID = owner(r.OrganizationID)
+	return r.DB.Create(link).Error
+}
+
+func (r *TopologyRepositoryImpl) FindLinks() ([]models.MachineLink, error) {
+	var links []models.MachineLink
+	err := r.db().Order("id").Find(&links).Error
+	return links, err
+}
+
+func (r *TopologyRepositoryImpl) FindLinkByID(id uint) (*models.MachineLink, error) {
+	var link models.MachineLink
+	err := r.db().First(&link, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// DeleteLink removes a link for good, so the same machines can be linked again
+func (r *TopologyRepositoryImpl) DeleteLink(id uint) error {
+	return r.db().Unscoped().Delete(&models.MachineLink{}, id).Error
+}
+
+// DeleteMachineLinks removes every link from or to a machine
+func (r *TopologyRepositoryImpl) DeleteMachineLinks(machineID uint) error {
+	return r.db().Unscoped().Where("from_machine_id = ? OR to_machine_id = ?", machineID, machineID).Delete(&models.MachineLink{}).Error
+}
+
+func (r *TopologyRepositoryImpl) ForTenant(organizationID uint) TopologyRepository {
+	return &TopologyRepositoryImpl{DB: r.DB, OrganizationID: organizationID}
+}
+
+func (r *TopologyRepositoryImpl) WithContext(ctx context.Context) TopologyRepository {
+	return &TopologyRepositoryImpl{DB: r.DB.WithContext(ctx), OrganizationID: r.OrganizationID}
+}
+
+func (r *TopologyRepositoryImpl) db() *gorm.DB {
+	return tenantDB(r.DB, r.OrganizationID)
+}
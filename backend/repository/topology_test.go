@@ -0,0 +1,58 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopologyRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewTopologyRepository(db)
+	machines := repository.NewMachineRepository(db)
+
+	site := models.MachineGroup{Name: "Plant A", Kind: models.GroupKindSite}
+	assert.Nil(t, repo.CreateGroup(&site))
+	line := models.MachineGroup{Name: "Line 1", Kind: models.GroupKindLine, ParentID: &site.ID}
+	assert.Nil(t, repo.CreateGroup(&line))
+	groups, err := repo.FindGroups()
+	assert.Nil(t, err)
+	assert.Len(t, groups, 2)
+
+	press := models.Machine{Name: "Press", Status: "Running"}
+	packer := models.Machine{Name: "Packer", Status: "Idle"}
+	assert.Nil(t, machines.Create(&press))
+	assert.Nil(t, machines.Create(&packer))
+
+	assert.Nil(t, repo.SetMachineGroup(press.ID, &line.ID))
+	found, _ := machines.FindByID(press.ID)
+	if assert.NotNil(t, found.GroupID) {
+		assert.Equal(t, line.ID, *found.GroupID)
+	}
+	assert.Equal(t, "Running", found.Status, "Only the group should change")
+	assert.Nil(t, repo.SetMachineGroup(press.ID, nil))
+	found, _ = machines.FindByID(press.ID)
+	assert.Nil(t, found.GroupID)
+
+	link := models.MachineLink{FromMachineID: press.ID, ToMachineID: packer.ID}
+	assert.Nil(t, repo.CreateLink(&link))
+	assert.NotNil(t, repo.CreateLink(&models.MachineLink{FromMachineID: press.ID, ToMachineID: packer.ID}), "Machines can be linked only once")
+
+	// Deleted links are gone for good, so the machines can be linked again
+	assert.Nil(t, repo.DeleteLink(link.ID))
+	_, err = repo.FindLinkByID(link.ID)
+	assert.NotNil(t, err)
+	assert.Nil(t, repo.CreateLink(&models.MachineLink{FromMachineID: press.ID, ToMachineID: packer.ID}))
+	assert.Nil(t, repo.CreateLink(&models.MachineLink{FromMachineID: packer.ID, ToMachineID: 42}))
+
+	assert.Nil(t, repo.DeleteMachineLinks(packer.ID))
+	links, err := repo.FindLinks()
+	assert.Nil(t, err)
+	assert.Empty(t, links)
+
+	assert.Nil(t, repo.DeleteGroup(line.ID))
+	_, err = repo.FindGroupByID(line.ID)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"gorm.io/gorm"
+)
+
+// WorkflowRepository defines the interface for workflow and workflow run data operations
+type WorkflowRepository interface {
+	Create(workflow *models.Workflow) error
+	FindAll() ([]models.Workflow, error)
+	FindByID(id uint) (*models.Workflow, error)
+	FindByName(organizationID uint, name string) (*models.Workflow, error)
+	Delete(id uint) error
+
+	CreateRun(run *models.WorkflowRun, steps []models.WorkflowStepRun) error
+	UpdateRun(run *models.WorkflowRun) error
+	FindRunByID(id uint) (*models.WorkflowRun, error)
+	FindRunsByStatus(status string) ([]models.WorkflowRun, error)
+	FindStepRuns(runID uint) ([]models.WorkflowStepRun, error)
+	UpdateStepRun(step *models.WorkflowStepRun) error
+	WithContext(ctx context.Context) WorkflowRepository
+}
+
+// WorkflowRepositoryImpl is the concrete implementation of WorkflowRepository
+type WorkflowRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewWorkflowRepository creates a new instance of WorkflowRepository
+func NewWorkflowRepository(db *gorm.DB) WorkflowRepository {
+	return &WorkflowRepositoryImpl{DB: db}
+}
+
+// --- Implementation of the Interface Methods ---
+func (r *WorkflowRepositoryImpl) Create(workflow *models.Workflow) error {
+	return r.DB.Create(workflow).Error
+}
+
+func (r *WorkflowRepositoryImpl) FindAll() ([]models.Workflow, error) {
+	var workflows []models.Workflow
+	err := r.DB.Order("id").Find(&workflows).Error
+	return workflows, err
+}
+
+func (r *WorkflowRepositoryImpl) FindByID(id uint) (*models.Workflow, error) {
+	var workflow models.Workflow
+	err := r.DB.First(&workflow, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// FindByName finds a workflow by its name, which is unique within an organization
+func (r *WorkflowRepositoryImpl) FindByName(organizationID uint, name string) (*models.Workflow, error) {
+	var workflow models.Workflow
+	err := r.DB.Where("organization_id = ? AND name = ?", organizationID, name).First(&workflow).Error
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// Delete removes a workflow for good, so its name can be reused. Its runs are kept.
+func (r *WorkflowRepositoryImpl) Delete(id uint) error {
+	return r.DB.Unscoped().Delete(&models.Workflow{}, id).Error
+}
+
+// CreateRun stores a workflow run together with the state of each of its steps
+func (r *WorkflowRepositoryImpl) CreateRun(run *models.WorkflowRun, steps []models.WorkflowStepRun) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(run).Error; err != nil {
+			return err
+		}
+		for i := range steps {
+			steps[i].WorkflowRunID = run.ID
+		}
+		return tx.Create(&steps).Error
+	})
+}
+
+func (r *WorkflowRepositoryImpl) UpdateRun(run *models.WorkflowRun) error {
+	return r.DB.Save(run).Error
+}
+
+func (r *WorkflowRepositoryImpl) FindRunByID(id uint) (*models.WorkflowRun, error) {
+	var run models.WorkflowRun
+	err := r.DB.First(&run, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *WorkflowRepositoryImpl) FindRunsByStatus(status string) ([]models.WorkflowRun, error) {
+	var runs []models.WorkflowRun
+	err := r.DB.Where("status = ?", status).Order("id").Find(&runs).Error
+	return runs, err
+}
+
+// FindStepRuns returns the step states of a run in definition order
+func (r *WorkflowRepositoryImpl) FindStepRuns(runID uint) ([]models.WorkflowStepRun, error) {
+	var steps []models.WorkflowStepRun
+	err := r.DB.Where("workflow_run_id = ?", runID).Order("id").Find(&steps).Error
+	return steps, err
+}
+
+func (r *WorkflowRepositoryImpl) UpdateStepRun(step *models.WorkflowStepRun) error {
+	return r.DB.Save(step).Error
+}
+
+func (r *WorkflowRepositoryImpl) WithContext(ctx context.Context) WorkflowRepository {
+	return &WorkflowRepositoryImpl{DB: r.DB.WithContext(ctx)}
+}
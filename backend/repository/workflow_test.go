@@ -0,0 +1,60 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflowRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := repository.NewWorkflowRepository(db)
+
+	wf := &models.Workflow{Name: "qualify", Steps: []models.WorkflowStep{
+		{Name: "warmup", MachineID: 1, Runner: "warmup"},
+		{Name: "run", MachineID: 1, DependsOn: []string{"warmup"}, Retries: 2},
+	}}
+	assert.Nil(t, repo.Create(wf))
+
+	found, err := repo.FindByName(models.DefaultOrganizationID, "qualify")
+	assert.Nil(t, err)
+	assert.Equal(t, wf.Steps, found.Steps, "Steps should round-trip through JSON")
+
+	run := &models.WorkflowRun{WorkflowID: wf.ID, Status: models.WorkflowStatusRunning, Steps: wf.Steps}
+	assert.Nil(t, repo.CreateRun(run, []models.WorkflowStepRun{
+		{Step: "warmup", Status: models.StepStatusPending},
+		{Step: "run", Status: models.StepStatusPending},
+	}))
+
+	steps, err := repo.FindStepRuns(run.ID)
+	assert.Nil(t, err)
+	assert.Len(t, steps, 2)
+	assert.Equal(t, "warmup", steps[0].Step)
+	assert.Equal(t, run.ID, steps[0].WorkflowRunID)
+
+	steps[0].Status = models.StepStatusSucceeded
+	assert.Nil(t, repo.UpdateStepRun(&steps[0]))
+	steps, _ = repo.FindStepRuns(run.ID)
+	assert.Equal(t, models.StepStatusSucceeded, steps[0].Status)
+
+	running, err := repo.FindRunsByStatus(models.WorkflowStatusRunning)
+	assert.Nil(t, err)
+	assert.Len(t, running, 1)
+	assert.Equal(t, wf.Steps, running[0].Steps, "A run keeps its own copy of the steps")
+
+	run.Status = models.WorkflowStatusSucceeded
+	assert.Nil(t, repo.UpdateRun(run))
+	running, _ = repo.FindRunsByStatus(models.WorkflowStatusRunning)
+	assert.Empty(t, running)
+
+	assert.Nil(t, repo.Delete(wf.ID))
+	_, err = repo.FindByID(wf.ID)
+	assert.NotNil(t, err)
+	_, err = repo.FindRunByID(run.ID)
+	assert.Nil(t, err, "Runs should outlive their workflow")
+
+	// The name is free again once the workflow is deleted
+	assert.Nil(t, repo.Create(&models.Workflow{Name: "qualify"}))
+}
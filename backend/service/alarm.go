@@ -0,0 +1,415 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+)
+
+var (
+	// ErrAlarmNotFound is returned when an alarm ID does not exist
+	ErrAlarmNotFound = errors.New("alarm not found")
+	// ErrAlarmRuleNotFound is returned when an alarm rule ID does not exist
+	ErrAlarmRuleNotFound = errors.New("alarm rule not found")
+	// ErrInvalidAlarmRule is returned when a rule definition cannot be evaluated
+	ErrInvalidAlarmRule = errors.New("invalid alarm rule")
+	// ErrAlarmAlreadyAcknowledged is returned when acknowledging an alarm twice
+	ErrAlarmAlreadyAcknowledged = errors.New("alarm already acknowledged")
+)
+
+type AlarmService interface {
+	CreateRule(rule models.AlarmRule) (models.AlarmRule, error)
+	GetRules() ([]models.AlarmRule, error)
+	DeleteRule(id uint) error
+
+	GetAlarms(filter repository.AlarmFilter) ([]models.Alarm, error)
+	AcknowledgeAlarm(id uint, by string) (models.Alarm, error)
+
+	// ObserveTelemetry and ObserveStatus feed simulator output into rule evaluation
+	ObserveTelemetry(samples []models.TelemetrySample)
+	ObserveStatus(machine models.Machine, at time.Time)
+
+	// ForTenant returns the service limited to the rules and alarms of one organization, which
+	// new rules are created in; 0 is every organization
+	ForTenant(organizationID uint) AlarmService
+	WithContext(ctx context.Context) AlarmService
+}
+
+// alarmKey identifies the (at most one) active alarm of a rule for a machine
+type alarmKey struct {
+	ruleID    uint
+	machineID uint
+}
+
+type statusEvent struct {
+	status string
+	at     time.Time
+}
+
+// AlarmServiceImpl evaluates rules in memory and persists alarm lifecycle changes through the repository.
+// Rules only apply to the machines of their own organization.
+type AlarmServiceImpl struct {
+	Repo     repository.AlarmRepository
+	Machines repository.MachineRepository // finds the machine a rule is limited to
+	Tenant   uint                         // the organization whose rules and alarms are visible, 0 for all
+	Ctx      context.Context              // the request the service works for; nil outside of requests
+
+	*alarmState // shared with the services ForTenant returns
+}
+
+// alarmState is what rule evaluation keeps in memory
+type alarmState struct {
+	mu           sync.Mutex
+	loaded       bool
+	rules        []models.AlarmRule
+	active       map[alarmKey]*models.Alarm
+	breaches     map[alarmKey]time.Time // when a threshold rule first started breaching
+	statusEvents map[uint][]statusEvent
+}
+
+func NewAlarmService(repo repository.AlarmRepository, machines repository.MachineRepository) AlarmService {
+	return &AlarmServiceImpl{
+		Repo:     repo,
+		Machines: machines,
+		alarmState: &alarmState{
+			breaches:     make(map[alarmKey]time.Time),
+			statusEvents: make(map[uint][]statusEvent),
+		},
+	}
+}
+
+// --- Implementation of the Interface Methods ---
+
+// CreateRule validates and stores a new rule; it takes effect on the next observation.
+func (s *AlarmServiceImpl) CreateRule(rule models.AlarmRule) (_ models.AlarmRule, err error) {
+	s, end := s.call("CreateRule")
+	defer end(&err)
+	if err := validateAlarmRule(rule); err != nil {
+		return models.AlarmRule{}, err
+	}
+	if rule.Severity == "" {
+		rule.Severity = "warning"
+	}
+	rule.OrganizationID = owner(s.Tenant)
+	if rule.MachineID != nil {
+		machine, err := s.Machines.ForTenant(s.Tenant).FindByID(*rule.MachineID)
+		if err != nil || !visible(s.Tenant, machine.OrganizationID) {
+			return models.AlarmRule{}, fmt.Errorf("%w: machine %d not found", ErrInvalidAlarmRule, *rule.MachineID)
+		}
+		rule.OrganizationID = machine.OrganizationID
+	}
+	if err := s.Repo.CreateRule(&rule); err != nil {
+		return models.AlarmRule{}, err
+	}
+
+	s.mu.Lock()
+	s.loaded = false
+	s.mu.Unlock()
+	return rule, nil
+}
+
+func validateAlarmRule(rule models.AlarmRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name cannot be empty", ErrInvalidAlarmRule)
+	}
+	switch rule.Kind {
+	case models.AlarmRuleThreshold:
+		if rule.Metric == "" {
+			return fmt.Errorf("%w: threshold rules require a metric", ErrInvalidAlarmRule)
+		}
+		if _, ok := comparisons[rule.Operator]; !ok {
+			return fmt.Errorf("%w: operator must be one of >, >=, <, <=", ErrInvalidAlarmRule)
+		}
+		if rule.ForSeconds < 0 {
+			return fmt.Errorf("%w: for_seconds cannot be negative", ErrInvalidAlarmRule)
+		}
+	case models.AlarmRuleStatusCount:
+		if rule.Status == "" || rule.Count <= 0 || rule.WindowSeconds <= 0 {
+			return fmt.Errorf("%w: status_count rules require a status, a positive count and a positive window_seconds", ErrInvalidAlarmRule)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be %q or %q", ErrInvalidAlarmRule, models.AlarmRuleThreshold, models.AlarmRuleStatusCount)
+	}
+	return nil
+}
+
+func (s *AlarmServiceImpl) GetRules() (_ []models.AlarmRule, err error) {
+	s, end := s.call("GetRules")
+	defer end(&err)
+	rules, err := s.Repo.FindRules()
+	if err != nil {
+		return nil, err
+	}
+	visibleRules := []models.AlarmRule{}
+	for _, rule := range rules {
+		if visible(s.Tenant, rule.OrganizationID) {
+			visibleRules = append(visibleRules, rule)
+		}
+	}
+	return visibleRules, nil
+}
+
+// DeleteRule removes a rule and clears any alarm it still has active.
+func (s *AlarmServiceImpl) DeleteRule(id uint) (err error) {
+	s, end := s.call("DeleteRule")
+	defer end(&err)
+	rule, err := s.Repo.FindRuleByID(id)
+	if err != nil || !visible(s.Tenant, rule.OrganizationID) {
+		return ErrAlarmRuleNotFound
+	}
+	if err := s.Repo.DeleteRule(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	for key, alarm := range s.active {
+		if key.ruleID == id {
+			s.clear(key, alarm, time.Now())
+		}
+	}
+	s.loaded = false
+	return nil
+}
+
+func (s *AlarmServiceImpl) GetAlarms(filter repository.AlarmFilter) (_ []models.Alarm, err error) {
+	s, end := s.call("GetAlarms")
+	defer end(&err)
+	if s.Tenant != 0 {
+		filter.OrganizationID = s.Tenant
+	}
+	return s.Repo.Find(filter)
+}
+
+// AcknowledgeAlarm records that an operator has seen the alarm. Cleared alarms can still be acknowledged.
+func (s *AlarmServiceImpl) AcknowledgeAlarm(id uint, by string) (_ models.Alarm, err error) {
+	s, end := s.call("AcknowledgeAlarm")
+	defer end(&err)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Prefer the cached copy of active alarms so a later clear does not overwrite the acknowledgement
+	var alarm *models.Alarm
+	for _, active := range s.active {
+		if active.ID == id {
+			alarm = active
+			break
+		}
+	}
+	if alarm == nil {
+		found, err := s.Repo.FindByID(id)
+		if err != nil {
+			return models.Alarm{}, ErrAlarmNotFound
+		}
+		alarm = found
+	}
+	if !visible(s.Tenant, alarm.OrganizationID) {
+		return models.Alarm{}, ErrAlarmNotFound
+	}
+
+	if alarm.AcknowledgedAt != nil {
+		return *alarm, ErrAlarmAlreadyAcknowledged
+	}
+	now := time.Now()
+	alarm.AcknowledgedAt = &now
+	alarm.AcknowledgedBy = by
+	if alarm.State == models.AlarmStateRaised {
+		alarm.State = models.AlarmStateAcknowledged
+	}
+	err = s.Repo.Update(alarm)
+	return *alarm, err
+}
+
+// ObserveTelemetry evaluates threshold rules against new samples, and re-evaluates status count
+// rules so their alarms clear once old events leave the window.
+func (s *AlarmServiceImpl) ObserveTelemetry(samples []models.TelemetrySample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		slog.Error("Failed to load alarm rules", "error", err)
+		return
+	}
+
+	evaluated := make(map[uint]models.TelemetrySample) // the latest sample of each machine
+	for _, sample := range samples {
+		for _, rule := range s.rules {
+			if rule.Kind != models.AlarmRuleThreshold || rule.Metric != sample.Metric || !ruleApplies(rule, sample.OrganizationID, sample.MachineID) {
+				continue
+			}
+			s.evaluateThreshold(rule, sample)
+		}
+		if latest, ok := evaluated[sample.MachineID]; !ok || sample.Timestamp.After(latest.Timestamp) {
+			evaluated[sample.MachineID] = sample
+		}
+	}
+	for machineID, latest := range evaluated {
+		s.evaluateStatusCounts(latest.OrganizationID, machineID, latest.Timestamp)
+	}
+}
+
+// ObserveStatus records a machine status change and evaluates status count rules.
+func (s *AlarmServiceImpl) ObserveStatus(machine models.Machine, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		slog.Error("Failed to load alarm rules", "error", err)
+		return
+	}
+
+	s.statusEvents[machine.ID] = append(s.statusEvents[machine.ID], statusEvent{status: machine.Status, at: at})
+	s.evaluateStatusCounts(machine.OrganizationID, machine.ID, at)
+}
+
+func (s *AlarmServiceImpl) ForTenant(organizationID uint) AlarmService {
+	return &AlarmServiceImpl{Repo: s.Repo, Machines: s.Machines, Tenant: organizationID, Ctx: s.Ctx, alarmState: s.alarmState}
+}
+
+func (s *AlarmServiceImpl) WithContext(ctx context.Context) AlarmService {
+	return &AlarmServiceImpl{Repo: s.Repo.WithContext(ctx), Machines: s.Machines.WithContext(ctx), Tenant: s.Tenant, Ctx: ctx, alarmState: s.alarmState}
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *AlarmServiceImpl) call(method string) (*AlarmServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "AlarmService."+method, s, func(ctx context.Context) *AlarmServiceImpl {
+		return s.WithContext(ctx).(*AlarmServiceImpl)
+	})
+}
+
+// load refreshes the cached rules and active alarms after a rule change. Callers must hold s.mu.
+func (s *AlarmServiceImpl) load() error {
+	if s.loaded {
+		return nil
+	}
+	rules, err := s.Repo.FindRules()
+	if err != nil {
+		return err
+	}
+	alarms, err := s.Repo.FindActive()
+	if err != nil {
+		return err
+	}
+
+	s.rules = rules
+	s.active = make(map[alarmKey]*models.Alarm, len(alarms))
+	for i := range alarms {
+		s.active[alarmKey{ruleID: alarms[i].RuleID, machineID: alarms[i].MachineID}] = &alarms[i]
+	}
+	s.loaded = true
+	return nil
+}
+
+var comparisons = map[string]func(value, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+}
+
+func ruleApplies(rule models.AlarmRule, organizationID, machineID uint) bool {
+	return !rule.Disabled && rule.OrganizationID == organizationID && (rule.MachineID == nil || *rule.MachineID == machineID)
+}
+
+func (s *AlarmServiceImpl) evaluateThreshold(rule models.AlarmRule, sample models.TelemetrySample) {
+	key := alarmKey{ruleID: rule.ID, machineID: sample.MachineID}
+	compare := comparisons[rule.Operator]
+	if compare == nil {
+		return
+	}
+
+	if !compare(sample.Value, rule.Threshold) {
+		delete(s.breaches, key)
+		if alarm := s.active[key]; alarm != nil {
+			s.clear(key, alarm, sample.Timestamp)
+		}
+		return
+	}
+
+	since, breaching := s.breaches[key]
+	if !breaching {
+		since = sample.Timestamp
+		s.breaches[key] = since
+	}
+	if s.active[key] == nil && sample.Timestamp.Sub(since) >= time.Duration(rule.ForSeconds)*time.Second {
+		message := fmt.Sprintf("%s %s %g for %ds (value %.2f)", rule.Metric, rule.Operator, rule.Threshold, rule.ForSeconds, sample.Value)
+		s.raise(key, rule, message, sample.Value, sample.Timestamp)
+	}
+}
+
+func (s *AlarmServiceImpl) evaluateStatusCounts(organizationID, machineID uint, now time.Time) {
+	var longestWindow time.Duration
+	for _, rule := range s.rules {
+		if rule.Kind != models.AlarmRuleStatusCount || !ruleApplies(rule, organizationID, machineID) {
+			continue
+		}
+		window := time.Duration(rule.WindowSeconds) * time.Second
+		if window > longestWindow {
+			longestWindow = window
+		}
+
+		count := 0
+		for _, event := range s.statusEvents[machineID] {
+			if event.status == rule.Status && !event.at.Before(now.Add(-window)) {
+				count++
+			}
+		}
+
+		key := alarmKey{ruleID: rule.ID, machineID: machineID}
+		alarm := s.active[key]
+		switch {
+		case count >= rule.Count && alarm == nil:
+			message := fmt.Sprintf("%d %s statuses within %s", count, rule.Status, window)
+			s.raise(key, rule, message, float64(count), now)
+		case count < rule.Count && alarm != nil:
+			s.clear(key, alarm, now)
+		}
+	}
+
+	// Forget events no rule can look back to any more
+	events := s.statusEvents[machineID]
+	kept := events[:0]
+	for _, event := range events {
+		if !event.at.Before(now.Add(-longestWindow)) {
+			kept = append(kept, event)
+		}
+	}
+	s.statusEvents[machineID] = kept
+}
+
+func (s *AlarmServiceImpl) raise(key alarmKey, rule models.AlarmRule, message string, value float64, at time.Time) {
+	alarm := &models.Alarm{
+		OrganizationID: rule.OrganizationID,
+		RuleID:         rule.ID,
+		MachineID:      key.machineID,
+		State:          models.AlarmStateRaised,
+		Severity:       rule.Severity,
+		Message:        message,
+		Value:          value,
+		RaisedAt:       at,
+	}
+	if err := s.Repo.Create(alarm); err != nil {
+		slog.Error("Failed to raise alarm", "rule_id", rule.ID, "machine_id", key.machineID, "error", err)
+		return
+	}
+	s.active[key] = alarm
+	slog.Warn("Alarm raised", "alarm_id", alarm.ID, "machine_id", key.machineID, "rule", rule.Name, "message", message)
+}
+
+func (s *AlarmServiceImpl) clear(key alarmKey, alarm *models.Alarm, at time.Time) {
+	alarm.State = models.AlarmStateCleared
+	alarm.ClearedAt = &at
+	if err := s.Repo.Update(alarm); err != nil {
+		slog.Error("Failed to clear alarm", "alarm_id", alarm.ID, "error", err)
+		return
+	}
+	delete(s.active, key)
+	slog.Info("Alarm cleared", "alarm_id", alarm.ID, "machine_id", key.machineID)
+}
@@ -0,0 +1,217 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockAlarmRepository keeps rules and alarms in memory
+type MockAlarmRepository struct {
+	Rules  []models.AlarmRule
+	Alarms []*models.Alarm
+}
+
+func (m *MockAlarmRepository) WithContext(context.Context) repository.AlarmRepository { return m }
+
+func (m *MockAlarmRepository) CreateRule(rule *models.AlarmRule) error {
+	rule.ID = uint(len(m.Rules) + 1)
+	m.Rules = append(m.Rules, *rule)
+	return nil
+}
+func (m *MockAlarmRepository) FindRules() ([]models.AlarmRule, error) { return m.Rules, nil }
+func (m *MockAlarmRepository) FindRuleByID(id uint) (*models.AlarmRule, error) {
+	for _, rule := range m.Rules {
+		if rule.ID == id {
+			return &rule, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+func (m *MockAlarmRepository) DeleteRule(id uint) error {
+	for i, rule := range m.Rules {
+		if rule.ID == id {
+			m.Rules = append(m.Rules[:i], m.Rules[i+1:]...)
+		}
+	}
+	return nil
+}
+func (m *MockAlarmRepository) Create(alarm *models.Alarm) error {
+	alarm.ID = uint(len(m.Alarms) + 1)
+	stored := *alarm
+	m.Alarms = append(m.Alarms, &stored)
+	return nil
+}
+func (m *MockAlarmRepository) Update(alarm *models.Alarm) error {
+	stored := *alarm
+	m.Alarms[alarm.ID-1] = &stored
+	return nil
+}
+func (m *MockAlarmRepository) FindByID(id uint) (*models.Alarm, error) {
+	if id == 0 || int(id) > len(m.Alarms) {
+		return nil, errors.New("record not found")
+	}
+	alarm := *m.Alarms[id-1]
+	return &alarm, nil
+}
+func (m *MockAlarmRepository) Find(filter repository.AlarmFilter) ([]models.Alarm, error) {
+	var alarms []models.Alarm
+	for _, alarm := range m.Alarms {
+		if filter.State == "" || alarm.State == filter.State {
+			alarms = append(alarms, *alarm)
+		}
+	}
+	return alarms, nil
+}
+func (m *MockAlarmRepository) FindActive() ([]models.Alarm, error) {
+	var alarms []models.Alarm
+	for _, alarm := range m.Alarms {
+		if alarm.State != models.AlarmStateCleared {
+			alarms = append(alarms, *alarm)
+		}
+	}
+	return alarms, nil
+}
+
+func temperature(machineID uint, value float64, at time.Time) []models.TelemetrySample {
+	return []models.TelemetrySample{{OrganizationID: models.DefaultOrganizationID, MachineID: machineID, Metric: "temperature", Value: value, Timestamp: at}}
+}
+
+func machineStatus(machineID uint, status string) models.Machine {
+	return models.Machine{Model: models.Model{ID: machineID}, OrganizationID: models.DefaultOrganizationID, Status: status}
+}
+
+func TestCreateAlarmRuleValidation(t *testing.T) {
+	alarmService := service.NewAlarmService(&MockAlarmRepository{}, &MockMachineRepository{})
+
+	invalid := []models.AlarmRule{
+		{Name: "", Kind: models.AlarmRuleThreshold, Metric: "temperature", Operator: ">"},
+		{Name: "NoMetric", Kind: models.AlarmRuleThreshold, Operator: ">"},
+		{Name: "BadOperator", Kind: models.AlarmRuleThreshold, Metric: "temperature", Operator: "!="},
+		{Name: "NoWindow", Kind: models.AlarmRuleStatusCount, Status: "Error", Count: 3},
+		{Name: "UnknownKind", Kind: "anomaly"},
+	}
+	for _, rule := range invalid {
+		_, err := alarmService.CreateRule(rule)
+		assert.True(t, errors.Is(err, service.ErrInvalidAlarmRule), "Rule %q should be rejected", rule.Name)
+	}
+
+	rule, err := alarmService.CreateRule(models.AlarmRule{Name: "Hot", Kind: models.AlarmRuleThreshold, Metric: "temperature", Operator: ">", Threshold: 80})
+	assert.Nil(t, err)
+	assert.Equal(t, "warning", rule.Severity, "Severity should default to warning")
+}
+
+func TestThresholdAlarmLifecycle(t *testing.T) {
+	repo := &MockAlarmRepository{}
+	alarmService := service.NewAlarmService(repo, &MockMachineRepository{})
+	_, err := alarmService.CreateRule(models.AlarmRule{Name: "Hot", Kind: models.AlarmRuleThreshold, Metric: "temperature", Operator: ">", Threshold: 80, ForSeconds: 30})
+	assert.Nil(t, err)
+
+	start := time.Now()
+
+	// Breaching for less than 30s does not raise anything
+	alarmService.ObserveTelemetry(temperature(1, 85, start))
+	alarmService.ObserveTelemetry(temperature(1, 86, start.Add(20*time.Second)))
+	assert.Len(t, repo.Alarms, 0, "Alarm should wait for the breach duration")
+
+	// A dip resets the breach timer
+	alarmService.ObserveTelemetry(temperature(1, 70, start.Add(25*time.Second)))
+	alarmService.ObserveTelemetry(temperature(1, 85, start.Add(40*time.Second)))
+	assert.Len(t, repo.Alarms, 0, "Breach timer should restart after recovering")
+
+	// Sustained breach raises exactly one alarm
+	alarmService.ObserveTelemetry(temperature(1, 90, start.Add(70*time.Second)))
+	alarmService.ObserveTelemetry(temperature(1, 91, start.Add(80*time.Second)))
+	assert.Len(t, repo.Alarms, 1, "Sustained breach should raise a single alarm")
+	assert.Equal(t, models.AlarmStateRaised, repo.Alarms[0].State)
+	assert.Equal(t, uint(1), repo.Alarms[0].MachineID)
+
+	// Acknowledge, then recover: the acknowledgement survives the clear
+	acked, err := alarmService.AcknowledgeAlarm(1, "operator")
+	assert.Nil(t, err)
+	assert.Equal(t, models.AlarmStateAcknowledged, acked.State)
+
+	_, err = alarmService.AcknowledgeAlarm(1, "operator")
+	assert.True(t, errors.Is(err, service.ErrAlarmAlreadyAcknowledged))
+
+	alarmService.ObserveTelemetry(temperature(1, 60, start.Add(90*time.Second)))
+	assert.Equal(t, models.AlarmStateCleared, repo.Alarms[0].State, "Alarm should clear once the value recovers")
+	assert.Equal(t, "operator", repo.Alarms[0].AcknowledgedBy)
+	assert.NotNil(t, repo.Alarms[0].ClearedAt)
+
+	_, err = alarmService.AcknowledgeAlarm(42, "operator")
+	assert.True(t, errors.Is(err, service.ErrAlarmNotFound))
+}
+
+func TestStatusCountAlarm(t *testing.T) {
+	repo := &MockAlarmRepository{}
+	alarmService := service.NewAlarmService(repo, &MockMachineRepository{})
+	machineID := uint(2)
+	_, err := alarmService.CreateRule(models.AlarmRule{Name: "Flaky", Kind: models.AlarmRuleStatusCount, MachineID: &machineID, Status: "Error", Count: 3, WindowSeconds: 600})
+	assert.Nil(t, err)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		alarmService.ObserveStatus(machineStatus(machineID, "Error"), at)
+		alarmService.ObserveStatus(machineStatus(machineID, "Running"), at.Add(time.Second))
+		// Other machines are not covered by the rule
+		alarmService.ObserveStatus(machineStatus(1, "Error"), at)
+	}
+	assert.Len(t, repo.Alarms, 1, "Third error within the window should raise an alarm")
+	assert.Equal(t, machineID, repo.Alarms[0].MachineID)
+	assert.Equal(t, 3.0, repo.Alarms[0].Value)
+
+	// Once the errors age out of the window, the next cycle clears the alarm
+	alarmService.ObserveTelemetry(temperature(machineID, 60, start.Add(15*time.Minute)))
+	assert.Equal(t, models.AlarmStateCleared, repo.Alarms[0].State)
+}
+
+func TestDeleteAlarmRuleClearsActiveAlarms(t *testing.T) {
+	repo := &MockAlarmRepository{}
+	alarmService := service.NewAlarmService(repo, &MockMachineRepository{})
+	rule, _ := alarmService.CreateRule(models.AlarmRule{Name: "Cold", Kind: models.AlarmRuleThreshold, Metric: "temperature", Operator: "<", Threshold: 10})
+
+	alarmService.ObserveTelemetry(temperature(1, 5, time.Now()))
+	assert.Len(t, repo.Alarms, 1)
+
+	assert.Nil(t, alarmService.DeleteRule(rule.ID))
+	assert.Equal(t, models.AlarmStateCleared, repo.Alarms[0].State)
+	assert.True(t, errors.Is(alarmService.DeleteRule(rule.ID), service.ErrAlarmRuleNotFound))
+}
+
+func TestAlarmRulesForTenant(t *testing.T) {
+	repo := &MockAlarmRepository{}
+	alarms := service.NewAlarmService(repo, &MockMachineRepository{})
+	acme := alarms.ForTenant(2)
+	_, err := acme.CreateRule(models.AlarmRule{Name: "Hot", Kind: models.AlarmRuleThreshold, Metric: "temperature", Operator: ">", Threshold: 80})
+	assert.Nil(t, err)
+	machineID := uint(1)
+	_, err = acme.CreateRule(models.AlarmRule{Name: "Flaky", Kind: models.AlarmRuleStatusCount, MachineID: &machineID, Status: "Error", Count: 1, WindowSeconds: 60})
+	assert.ErrorIs(t, err, service.ErrInvalidAlarmRule, "Machines of other organizations cannot be watched")
+
+	rules, _ := acme.GetRules()
+	assert.Len(t, rules, 1)
+	rules, _ = alarms.ForTenant(models.DefaultOrganizationID).GetRules()
+	assert.Empty(t, rules)
+
+	// The rule watches the machines of its own organization only
+	alarms.ObserveTelemetry(temperature(1, 90, time.Now()))
+	assert.Empty(t, repo.Alarms)
+	sample := temperature(5, 90, time.Now())
+	sample[0].OrganizationID = 2
+	alarms.ObserveTelemetry(sample)
+	if assert.Len(t, repo.Alarms, 1) {
+		assert.Equal(t, uint(2), repo.Alarms[0].OrganizationID)
+	}
+
+	_, err = alarms.ForTenant(3).AcknowledgeAlarm(1, "intruder")
+	assert.ErrorIs(t, err, service.ErrAlarmNotFound)
+	assert.ErrorIs(t, alarms.ForTenant(3).DeleteRule(1), service.ErrAlarmRuleNotFound)
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when an API key ID does not exist
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidAPIKeyRequest is returned when a key cannot be issued as requested
+	ErrInvalidAPIKeyRequest = errors.New("invalid API key request")
+	// ErrInvalidAPIKey is returned when a presented key is unknown, revoked or expired
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+const (
+	// apiKeyMarker starts every key, so leaked keys are easy to spot in logs and by secret scanners
+	apiKeyMarker = "ak_"
+	// apiKeyPrefixLength is how much of a key is stored in the clear to look it up
+	apiKeyPrefixLength = len(apiKeyMarker) + 8
+	// lastUsedResolution limits how often using a key writes its last-used time
+	lastUsedResolution = time.Minute
+)
+
+// IssueKeyRequest is the body of POST /api/v1/api-keys
+type IssueKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" enum:"read,write,admin"`
+	Roles     []string   `json:"roles" enum:"viewer,operator,engineer,admin"`
+	ExpiresAt *time.Time `json:"expires_at"` // nil never expires
+}
+
+// IssuedKey is a newly issued API key. The key is only ever returned here.
+type IssuedKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyService interface {
+	IssueKey(req IssueKeyRequest) (IssuedKey, error)
+	GetKeys() ([]models.APIKey, error)
+	RevokeKey(id uint) error
+
+	// Authenticate returns the key a client presented if it is valid, recording its use
+	Authenticate(key string) (models.APIKey, error)
+
+	// ForTenant returns the service limited to the keys of one organization, which new keys
+	// are issued in; 0 is every organization
+	ForTenant(organizationID uint) APIKeyService
+	WithContext(ctx context.Context) APIKeyService
+}
+
+// APIKeyServiceImpl stores SHA-256 hashes of keys; keys are random enough that a slow hash
+// would only cost time on every request
+type APIKeyServiceImpl struct {
+	Repo   repository.APIKeyRepository
+	Tenant uint // the organization whose keys are visible, 0 for all
+	Ctx    context.Context
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository) APIKeyService {
+	return &APIKeyServiceImpl{Repo: repo}
+}
+
+// --- Implementation of the Interface Methods ---
+
+func (s *APIKeyServiceImpl) IssueKey(req IssueKeyRequest) (_ IssuedKey, err error) {
+	s, end := s.call("IssueKey")
+	defer end(&err)
+	if strings.TrimSpace(req.Name) == "" {
+		return IssuedKey{}, fmt.Errorf("%w: name is required", ErrInvalidAPIKeyRequest)
+	}
+	if len(req.Scopes) == 0 && len(req.Roles) == 0 {
+		return IssuedKey{}, fmt.Errorf("%w: at least one scope or role is required", ErrInvalidAPIKeyRequest)
+	}
+	for _, scope := range req.Scopes {
+		switch scope {
+		case models.APIKeyScopeRead, models.APIKeyScopeWrite, models.APIKeyScopeAdmin:
+		default:
+			return IssuedKey{}, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyRequest, scope)
+		}
+	}
+	for _, role := range req.Roles {
+		if !models.IsRole(role) {
+			return IssuedKey{}, fmt.Errorf("%w: unknown role %q", ErrInvalidAPIKeyRequest, role)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return IssuedKey{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKeyRequest)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return IssuedKey{}, err
+	}
+	raw := apiKeyMarker + base64.RawURLEncoding.EncodeToString(secret)
+	key := models.APIKey{
+		OrganizationID: owner(s.Tenant),
+		Name:           req.Name,
+		Prefix:         raw[:apiKeyPrefixLength],
+		Hash:           hashAPIKey(raw),
+		Scopes:         req.Scopes,
+		Roles:          req.Roles,
+		ExpiresAt:      req.ExpiresAt,
+	}
+	if err := s.Repo.Create(&key); err != nil {
+		return IssuedKey{}, err
+	}
+	slog.InfoContext(s.Ctx, "API key issued", "key_id", key.ID, "name", key.Name, "organization_id", key.OrganizationID, "scopes", key.Scopes, "roles", key.Roles)
+	return IssuedKey{APIKey: key, Key: raw}, nil
+}
+
+func (s *APIKeyServiceImpl) GetKeys() (_ []models.APIKey, err error) {
+	s, end := s.call("GetKeys")
+	defer end(&err)
+	keys, err := s.Repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	visibleKeys := []models.APIKey{}
+	for _, key := range keys {
+		if visible(s.Tenant, key.OrganizationID) {
+			visibleKeys = append(visibleKeys, key)
+		}
+	}
+	return visibleKeys, nil
+}
+
+// RevokeKey stops a key from being accepted; revoking a revoked key does nothing
+func (s *APIKeyServiceImpl) RevokeKey(id uint) (err error) {
+	s, end := s.call("RevokeKey")
+	defer end(&err)
+	key, err := s.Repo.FindByID(id)
+	if err != nil || !visible(s.Tenant, key.OrganizationID) {
+		return ErrAPIKeyNotFound
+	}
+	if err := s.Repo.Revoke(id, time.Now()); err != nil {
+		return err
+	}
+	slog.InfoContext(s.Ctx, "API key revoked", "key_id", id)
+	return nil
+}
+
+func (s *APIKeyServiceImpl) Authenticate(raw string) (_ models.APIKey, err error) {
+	s, end := s.call("Authenticate")
+	defer end(&err)
+	if !strings.HasPrefix(raw, apiKeyMarker) || len(raw) <= apiKeyPrefixLength {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+	key, err := s.Repo.FindByPrefix(raw[:apiKeyPrefixLength])
+	if err != nil || subtle.ConstantTimeCompare([]byte(hashAPIKey(raw)), []byte(key.Hash)) != 1 {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return models.APIKey{}, fmt.Errorf("%w: the key has been revoked", ErrInvalidAPIKey)
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return models.APIKey{}, fmt.Errorf("%w: the key has expired", ErrInvalidAPIKey)
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.Repo.TouchLastUsed(key.ID, now); err != nil {
+			slog.ErrorContext(s.Ctx, "Failed to record use of API key", "key_id", key.ID, "error", err)
+		}
+		key.LastUsedAt = &now
+	}
+	return *key, nil
+}
+
+func (s *APIKeyServiceImpl) ForTenant(organizationID uint) APIKeyService {
+	return &APIKeyServiceImpl{Repo: s.Repo, Tenant: organizationID, Ctx: s.Ctx}
+}
+
+func (s *APIKeyServiceImpl) WithContext(ctx context.Context) APIKeyService {
+	return &APIKeyServiceImpl{Repo: s.Repo.WithContext(ctx), Tenant: s.Tenant, Ctx: ctx}
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *APIKeyServiceImpl) call(method string) (*APIKeyServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "APIKeyService."+method, s, func(ctx context.Context) *APIKeyServiceImpl {
+		return s.WithContext(ctx).(*APIKeyServiceImpl)
+	})
+}
+
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
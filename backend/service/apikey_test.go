@@ -0,0 +1,146 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockAPIKeyRepository keeps keys in memory and counts last-used writes
+type MockAPIKeyRepository struct {
+	keys    []models.APIKey
+	touches int
+}
+
+func (m *MockAPIKeyRepository) WithContext(context.Context) repository.APIKeyRepository { return m }
+
+func (m *MockAPIKeyRepository) Create(key *models.APIKey) error {
+	key.ID = uint(len(m.keys) + 1)
+	m.keys = append(m.keys, *key)
+	return nil
+}
+func (m *MockAPIKeyRepository) FindAll() ([]models.APIKey, error) { return m.keys, nil }
+func (m *MockAPIKeyRepository) FindByID(id uint) (*models.APIKey, error) {
+	if id == 0 || int(id) > len(m.keys) {
+		return nil, errors.New("record not found")
+	}
+	key := m.keys[id-1]
+	return &key, nil
+}
+func (m *MockAPIKeyRepository) FindByPrefix(prefix string) (*models.APIKey, error) {
+	for _, k := range m.keys {
+		if k.Prefix == prefix {
+			return &k, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+func (m *MockAPIKeyRepository) Revoke(id uint, at time.Time) error {
+	m.keys[id-1].RevokedAt = &at
+	return nil
+}
+func (m *MockAPIKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	m.touches++
+	m.keys[id-1].LastUsedAt = &at
+	return nil
+}
+
+func TestIssueAndAuthenticateAPIKey(t *testing.T) {
+	repo := &MockAPIKeyRepository{}
+	keys := service.NewAPIKeyService(repo)
+
+	issued, err := keys.IssueKey(service.IssueKeyRequest{Name: "ci", Scopes: []string{models.APIKeyScopeWrite}})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(issued.Key, "ak_"))
+	assert.True(t, strings.HasPrefix(issued.Key, issued.Prefix))
+	assert.NotContains(t, repo.keys[0].Hash, issued.Key[len(issued.Prefix):], "Only a hash of the key is stored")
+
+	key, err := keys.Authenticate(issued.Key)
+	assert.Nil(t, err)
+	assert.Equal(t, issued.ID, key.ID)
+	assert.NotNil(t, key.LastUsedAt)
+	_, err = keys.Authenticate(issued.Key)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, repo.touches, "Last use is recorded at most once a minute")
+
+	for _, bad := range []string{"", "ak_", "not-a-key", issued.Key + "x", issued.Prefix + "guessed-secret"} {
+		_, err := keys.Authenticate(bad)
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey, bad)
+	}
+
+	assert.Nil(t, keys.RevokeKey(issued.ID))
+	_, err = keys.Authenticate(issued.Key)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey, "Revoked keys are rejected")
+	assert.ErrorIs(t, keys.RevokeKey(42), service.ErrAPIKeyNotFound)
+}
+
+func TestAPIKeyExpiry(t *testing.T) {
+	repo := &MockAPIKeyRepository{}
+	keys := service.NewAPIKeyService(repo)
+
+	soon := time.Now().Add(time.Hour)
+	issued, err := keys.IssueKey(service.IssueKeyRequest{Name: "temp", Scopes: []string{models.APIKeyScopeRead}, ExpiresAt: &soon})
+	assert.Nil(t, err)
+	_, err = keys.Authenticate(issued.Key)
+	assert.Nil(t, err)
+
+	past := time.Now().Add(-time.Second)
+	repo.keys[0].ExpiresAt = &past
+	_, err = keys.Authenticate(issued.Key)
+	if assert.ErrorIs(t, err, service.ErrInvalidAPIKey) {
+		assert.Contains(t, err.Error(), "expired")
+	}
+}
+
+func TestIssueAPIKeyValidation(t *testing.T) {
+	keys := service.NewAPIKeyService(&MockAPIKeyRepository{})
+	past := time.Now().Add(-time.Hour)
+
+	for _, req := range []service.IssueKeyRequest{
+		{Name: " ", Scopes: []string{models.APIKeyScopeRead}},
+		{Name: "ci"},
+		{Name: "ci", Scopes: []string{"root"}},
+		{Name: "ci", Scopes: []string{models.APIKeyScopeRead}, ExpiresAt: &past},
+	} {
+		_, err := keys.IssueKey(req)
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKeyRequest, "%+v", req)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	admin := models.APIKey{Scopes: []string{models.APIKeyScopeAdmin}}
+	reader := models.APIKey{Scopes: []string{models.APIKeyScopeRead}}
+
+	assert.True(t, admin.HasScope(models.APIKeyScopeRead), "Broader scopes include narrower ones")
+	assert.True(t, admin.HasScope(models.APIKeyScopeWrite))
+	assert.True(t, reader.HasScope(models.APIKeyScopeRead))
+	assert.False(t, reader.HasScope(models.APIKeyScopeWrite))
+	assert.False(t, admin.HasScope("root"), "Unknown scopes are never granted")
+}
+
+func TestAPIKeysForTenant(t *testing.T) {
+	keys := service.NewAPIKeyService(&MockAPIKeyRepository{})
+	acme := keys.ForTenant(2)
+
+	platform, err := keys.IssueKey(service.IssueKeyRequest{Name: "ops", Scopes: []string{models.APIKeyScopeAdmin}})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(models.DefaultOrganizationID), platform.OrganizationID)
+	issued, err := acme.IssueKey(service.IssueKeyRequest{Name: "ci", Roles: []string{models.RoleOperator}})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), issued.OrganizationID)
+
+	listed, _ := acme.GetKeys()
+	assert.Len(t, listed, 1, "Organizations only see their own keys")
+	listed, _ = keys.GetKeys()
+	assert.Len(t, listed, 2)
+
+	assert.ErrorIs(t, acme.RevokeKey(platform.ID), service.ErrAPIKeyNotFound)
+	assert.Nil(t, acme.RevokeKey(issued.ID))
+}
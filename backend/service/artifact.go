@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/artifact"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+)
+
+const (
+	// MaxArtifactsPerRun caps how many files are kept of a single run
+	MaxArtifactsPerRun = 100
+	// MaxArtifactSize caps the size of a single artifact
+	MaxArtifactSize = 512 << 20
+	// DefaultMaxArtifactBytesPerRun caps the total size of the artifacts kept of a single run
+	DefaultMaxArtifactBytesPerRun = 1 << 30
+	// artifactPurgeBatch is how many expired artifacts are deleted per query
+	artifactPurgeBatch = 100
+)
+
+// ErrArtifactNotFound is returned when an artifact ID does not exist or belongs to another run
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+type ArtifactService interface {
+	Collect(ctx context.Context, runID uint, dir string) error
+	GetRunArtifacts(runID uint) ([]models.Artifact, error)
+	OpenArtifact(runID, artifactID uint) (models.Artifact, io.ReadCloser, error)
+	PurgeOlderThan(maxAge time.Duration) (int64, error)
+	StartRetention(maxAge, interval time.Duration)
+
+	// ForTenant returns the service limited to the runs of one organization; 0 is every organization
+	ForTenant(organizationID uint) ArtifactService
+	WithContext(ctx context.Context) ArtifactService
+}
+
+type ArtifactServiceImpl struct {
+	Repo        repository.ArtifactRepository
+	Runs        repository.RunRepository
+	Store       artifact.Store
+	MaxRunBytes int64           // total size of the artifacts kept of a run
+	Tenant      uint            // the organization whose runs' artifacts are visible, 0 for all
+	Ctx         context.Context // the request the service works for; nil outside of requests
+}
+
+func NewArtifactService(repo repository.ArtifactRepository, runs repository.RunRepository, store artifact.Store) ArtifactService {
+	return &ArtifactServiceImpl{Repo: repo, Runs: runs, Store: store, MaxRunBytes: DefaultMaxArtifactBytesPerRun}
+}
+
+// --- Implementation of the Interface Methods ---
+
+// Collect uploads every regular file below dir as an artifact of the run, named by its path relative
+// to dir. Files beyond the per-run limits of count and total size are skipped. A file that fails
+// to be read or uploaded does not stop the others from being collected; the failures are returned
+// together once every file was tried.
+func (s *ArtifactServiceImpl) Collect(ctx context.Context, runID uint, dir string) (err error) {
+	ctx, end := startSpan(ctx, "ArtifactService.Collect")
+	defer end(&err)
+	count, budget := 0, s.MaxRunBytes
+	var failed []error
+	fail := func(name string, err error) {
+		slog.Error("Failed to collect artifact", "run_id", runID, "artifact", name, "error", err)
+		failed = append(failed, err)
+	}
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir {
+				return err
+			}
+			fail(p, err)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil // directories are walked, symlinks and devices skipped
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if count == MaxArtifactsPerRun {
+			slog.Warn("Too many artifacts, skipping", "run_id", runID, "max", MaxArtifactsPerRun, "artifact", name)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			fail(name, err)
+			return nil
+		}
+		if info.Size() > MaxArtifactSize {
+			slog.Warn("Artifact too large, skipping", "run_id", runID, "artifact", name, "max_bytes", MaxArtifactSize)
+			return nil
+		}
+		if info.Size() > budget {
+			slog.Warn("Artifacts of run too large, skipping", "run_id", runID, "artifact", name, "max_run_bytes", s.MaxRunBytes)
+			return nil
+		}
+		if err := s.upload(ctx, runID, name, p, info.Size()); err != nil {
+			fail(name, err)
+			return nil
+		}
+		count++
+		budget -= info.Size()
+		return nil
+	})
+	return errors.Join(append(failed, err)...)
+}
+
+// upload stores one file of the given size and records its metadata; the contents are removed
+// again if recording fails. A file that grew since is cut off at size.
+func (s *ArtifactServiceImpl) upload(ctx context.Context, runID uint, name, file string, size int64) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	key := fmt.Sprintf("runs/%d/%s", runID, name)
+	obj, err := s.Store.Put(ctx, key, io.LimitReader(f, size))
+	if err != nil {
+		return fmt.Errorf("failed to store artifact %s: %w", name, err)
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	a := models.Artifact{RunID: runID, Name: name, Key: key, Size: obj.Size, SHA256: obj.SHA256, ContentType: contentType}
+	a.CreatedAt = models.StoredTime(time.Now())
+	if err := s.Repo.Create(&a); err != nil {
+		_ = s.Store.Delete(ctx, key)
+		return fmt.Errorf("failed to record artifact %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *ArtifactServiceImpl) GetRunArtifacts(runID uint) (_ []models.Artifact, err error) {
+	s, end := s.call("GetRunArtifacts")
+	defer end(&err)
+	if !s.runVisible(runID) {
+		return nil, ErrRunNotFound
+	}
+	return s.Repo.FindByRun(runID)
+}
+
+// OpenArtifact returns an artifact of a run with a reader streaming its contents; callers must close it
+func (s *ArtifactServiceImpl) OpenArtifact(runID, artifactID uint) (_ models.Artifact, _ io.ReadCloser, err error) {
+	s, end := s.call("OpenArtifact")
+	defer end(&err)
+	if s.Tenant != 0 && !s.runVisible(runID) {
+		return models.Artifact{}, nil, ErrArtifactNotFound
+	}
+	a, err := s.Repo.FindByID(artifactID)
+	if err != nil || a.RunID != runID {
+		return models.Artifact{}, nil, ErrArtifactNotFound
+	}
+	r, err := s.Store.Open(requestContext(s.Ctx), a.Key)
+	if errors.Is(err, artifact.ErrNotFound) {
+		return models.Artifact{}, nil, ErrArtifactNotFound
+	}
+	if err != nil {
+		return models.Artifact{}, nil, err
+	}
+	return *a, r, nil
+}
+
+// runVisible reports whether a run exists and belongs to the service's organization
+func (s *ArtifactServiceImpl) runVisible(runID uint) bool {
+	run, err := s.Runs.FindByID(runID)
+	return err == nil && visible(s.Tenant, run.OrganizationID)
+}
+
+func (s *ArtifactServiceImpl) ForTenant(organizationID uint) ArtifactService {
+	return &ArtifactServiceImpl{Repo: s.Repo, Runs: s.Runs, Store: s.Store, MaxRunBytes: s.MaxRunBytes, Tenant: organizationID, Ctx: s.Ctx}
+}
+
+func (s *ArtifactServiceImpl) WithContext(ctx context.Context) ArtifactService {
+	return &ArtifactServiceImpl{Repo: s.Repo.WithContext(ctx), Runs: s.Runs.WithContext(ctx), Store: s.Store, MaxRunBytes: s.MaxRunBytes, Tenant: s.Tenant, Ctx: ctx}
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *ArtifactServiceImpl) call(method string) (*ArtifactServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "ArtifactService."+method, s, func(ctx context.Context) *ArtifactServiceImpl {
+		return s.WithContext(ctx).(*ArtifactServiceImpl)
+	})
+}
+
+// PurgeOlderThan deletes artifacts created more than maxAge ago, contents first so none are orphaned
+func (s *ArtifactServiceImpl) PurgeOlderThan(maxAge time.Duration) (_ int64, err error) {
+	s, end := s.call("PurgeOlderThan")
+	defer end(&err)
+	cutoff := time.Now().Add(-maxAge)
+	var deleted int64
+	for {
+		expired, err := s.Repo.FindOlderThan(cutoff, artifactPurgeBatch)
+		if err != nil {
+			return deleted, err
+		}
+		for _, a := range expired {
+			if err := s.Store.Delete(context.Background(), a.Key); err != nil {
+				return deleted, err
+			}
+			if err := s.Repo.Delete(a.ID); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if len(expired) < artifactPurgeBatch {
+			return deleted, nil
+		}
+	}
+}
+
+// StartRetention periodically purges artifacts older than maxAge in the background.
+func (s *ArtifactServiceImpl) StartRetention(maxAge, interval time.Duration) {
+	slog.Info("Starting artifact retention", "max_age", maxAge, "interval", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			deleted, err := s.PurgeOlderThan(maxAge)
+			if err != nil {
+				slog.Error("Failed to purge artifacts", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Info("Purged old artifacts", "deleted", deleted, "max_age", maxAge)
+			}
+		}
+	}()
+}
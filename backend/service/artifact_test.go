@@ -0,0 +1,221 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/artifact"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockArtifactRepository keeps artifact metadata in memory
+type MockArtifactRepository struct {
+	mu        sync.Mutex
+	artifacts map[uint]models.Artifact
+	nextID    uint
+}
+
+func (m *MockArtifactRepository) WithContext(context.Context) repository.ArtifactRepository { return m }
+
+func (m *MockArtifactRepository) Create(a *models.Artifact) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.artifacts == nil {
+		m.artifacts = map[uint]models.Artifact{}
+	}
+	m.nextID++
+	a.ID = m.nextID
+	m.artifacts[a.ID] = *a
+	return nil
+}
+func (m *MockArtifactRepository) FindByID(id uint) (*models.Artifact, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.artifacts[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &a, nil
+}
+func (m *MockArtifactRepository) FindByRun(runID uint) ([]models.Artifact, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var artifacts []models.Artifact
+	for _, a := range m.artifacts {
+		if a.RunID == runID {
+			artifacts = append(artifacts, a)
+		}
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Name < artifacts[j].Name })
+	return artifacts, nil
+}
+func (m *MockArtifactRepository) FindOlderThan(cutoff time.Time, limit int) ([]models.Artifact, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var artifacts []models.Artifact
+	for _, a := range m.artifacts {
+		if a.CreatedAt.Before(cutoff) && len(artifacts) < limit {
+			artifacts = append(artifacts, a)
+		}
+	}
+	return artifacts, nil
+}
+func (m *MockArtifactRepository) Delete(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.artifacts, id)
+	return nil
+}
+
+// MockRunRepository knows runs 1 and 2
+type MockRunRepository struct{}
+
+func (m *MockRunRepository) WithContext(context.Context) repository.RunRepository { return m }
+
+func (m *MockRunRepository) Create(run *models.SimulationRun) error { return nil }
+func (m *MockRunRepository) Update(run *models.SimulationRun) error { return nil }
+func (m *MockRunRepository) FindByID(id uint) (*models.SimulationRun, error) {
+	if id > 2 {
+		return nil, errors.New("record not found")
+	}
+	return &models.SimulationRun{Model: models.Model{ID: id}}, nil
+}
+func (m *MockRunRepository) FindByMachine(machineID uint, limit int) ([]models.SimulationRun, error) {
+	return nil, nil
+}
+
+func writeFile(t *testing.T, path, content string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestArtifactService(t *testing.T) {
+	store, err := artifact.NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+	repo := &MockArtifactRepository{}
+	svc := service.NewArtifactService(repo, &MockRunRepository{}, store)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "results.csv"), "a,b\n1,2\n")
+	writeFile(t, filepath.Join(dir, "plots", "load.png"), "png")
+	assert.Nil(t, svc.Collect(context.Background(), 1, dir))
+
+	artifacts, err := svc.GetRunArtifacts(1)
+	assert.Nil(t, err)
+	if assert.Len(t, artifacts, 2) {
+		assert.Equal(t, "plots/load.png", artifacts[0].Name)
+		assert.Equal(t, "image/png", artifacts[0].ContentType)
+		assert.Equal(t, "results.csv", artifacts[1].Name)
+		assert.Equal(t, int64(8), artifacts[1].Size)
+		assert.Len(t, artifacts[1].SHA256, 64)
+	}
+
+	a, r, err := svc.OpenArtifact(1, artifacts[1].ID)
+	assert.Nil(t, err)
+	data, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "a,b\n1,2\n", string(data))
+	assert.Equal(t, "results.csv", a.Name)
+
+	_, _, err = svc.OpenArtifact(2, artifacts[1].ID)
+	assert.ErrorIs(t, err, service.ErrArtifactNotFound, "Artifacts are only served for their own run")
+	_, err = svc.GetRunArtifacts(3)
+	assert.ErrorIs(t, err, service.ErrRunNotFound)
+
+	// Age one artifact past the retention period
+	repo.mu.Lock()
+	expired := repo.artifacts[artifacts[0].ID]
+	expired.CreatedAt = time.Now().Add(-48 * time.Hour)
+	repo.artifacts[expired.ID] = expired
+	repo.mu.Unlock()
+
+	deleted, err := svc.PurgeOlderThan(24 * time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), deleted)
+	artifacts, _ = svc.GetRunArtifacts(1)
+	assert.Len(t, artifacts, 1)
+	_, err = store.Open(context.Background(), expired.Key)
+	assert.ErrorIs(t, err, artifact.ErrNotFound, "Purging should delete the contents too")
+}
+
+// contextStore records the context artifacts are opened with
+type contextStore struct {
+	artifact.Store
+	opened context.Context
+}
+
+func (s *contextStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.opened = ctx
+	return s.Store.Open(ctx, key)
+}
+
+func TestArtifactServiceLimits(t *testing.T) {
+	local, err := artifact.NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+	store := &contextStore{Store: local}
+	repo := &MockArtifactRepository{}
+	svc := &service.ArtifactServiceImpl{Repo: repo, Runs: &MockRunRepository{}, Store: store, MaxRunBytes: 10}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.csv"), "12345678")
+	writeFile(t, filepath.Join(dir, "b.csv"), "123")
+	writeFile(t, filepath.Join(dir, "c.csv"), "12")
+	assert.Nil(t, svc.Collect(context.Background(), 1, dir))
+
+	artifacts, _ := svc.GetRunArtifacts(1)
+	var names []string
+	for _, a := range artifacts {
+		names = append(names, a.Name)
+	}
+	assert.Equal(t, []string{"a.csv", "c.csv"}, names, "Files beyond the run's byte budget are skipped")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, r, err := svc.WithContext(ctx).OpenArtifact(1, artifacts[0].ID)
+	assert.Nil(t, err)
+	r.Close()
+	assert.Equal(t, ctx, store.opened, "Downloads are tied to the request")
+}
+
+// failingStore fails to store the artifacts with the given keys
+type failingStore struct {
+	artifact.Store
+	fail map[string]bool
+}
+
+func (s *failingStore) Put(ctx context.Context, key string, r io.Reader) (artifact.Object, error) {
+	if s.fail[key] {
+		return artifact.Object{}, errors.New("store unavailable")
+	}
+	return s.Store.Put(ctx, key, r)
+}
+
+func TestArtifactServiceCollectsPastFailures(t *testing.T) {
+	local, err := artifact.NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+	store := &failingStore{Store: local, fail: map[string]bool{"runs/1/a.csv": true}}
+	svc := service.NewArtifactService(&MockArtifactRepository{}, &MockRunRepository{}, store)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.csv"), "1")
+	writeFile(t, filepath.Join(dir, "b.csv"), "2")
+	writeFile(t, filepath.Join(dir, "logs/c.log"), "3")
+	assert.NotNil(t, svc.Collect(context.Background(), 1, dir), "Failed files are reported")
+
+	artifacts, _ := svc.GetRunArtifacts(1)
+	var names []string
+	for _, a := range artifacts {
+		names = append(names, a.Name)
+	}
+	assert.Equal(t, []string{"b.csv", "logs/c.log"}, names, "A failed file does not stop the others from being collected")
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+)
+
+// ErrForbidden is returned when the caller lacks the permission an operation needs
+var ErrForbidden = errors.New("forbidden")
+
+// AuthorizedMachineService checks the permissions of the identity making a request before
+// passing its calls on. Updates need the permission of what they change: machine:command to
+// change the status, machine:config-write to change anything else.
+type AuthorizedMachineService struct {
+	Next     MachineService
+	Identity Identity
+}
+
+// NewAuthorizedMachineService wraps a machine service for one caller
+func NewAuthorizedMachineService(next MachineService, identity Identity) MachineService {
+	return &AuthorizedMachineService{Next: next, Identity: identity}
+}
+
+// --- Implementation of the Interface Methods ---
+
+func (s *AuthorizedMachineService) CreateMachine(machine models.Machine) (models.Machine, error) {
+	if err := s.require(models.PermissionMachineConfigWrite); err != nil {
+		return models.Machine{}, err
+	}
+	return s.Next.CreateMachine(machine)
+}
+
+func (s *AuthorizedMachineService) GetAllMachines() ([]models.Machine, error) {
+	if err := s.require(models.PermissionMachineRead); err != nil {
+		return nil, err
+	}
+	return s.Next.GetAllMachines()
+}
+
+func (s *AuthorizedMachineService) GetMachineByID(id uint) (models.Machine, error) {
+	if err := s.require(models.PermissionMachineRead); err != nil {
+		return models.Machine{}, err
+	}
+	return s.Next.GetMachineByID(id)
+}
+
+func (s *AuthorizedMachineService) UpdateMachine(id uint, updated models.Machine) (models.Machine, error) {
+	existing, err := s.GetMachineByID(id)
+	if err != nil {
+		return models.Machine{}, err
+	}
+	if updated.Name != existing.Name || updated.ConfigJSON != existing.ConfigJSON || !slices.Equal(updated.Tags, existing.Tags) {
+		if err := s.require(models.PermissionMachineConfigWrite); err != nil {
+			return models.Machine{}, err
+		}
+	}
+	// Setting the status is how machines are started and stopped, so every update needs the
+	// command permission
+	if err := s.require(models.PermissionMachineCommand); err != nil {
+		return models.Machine{}, err
+	}
+	return s.Next.UpdateMachine(id, updated)
+}
+
+func (s *AuthorizedMachineService) DeleteMachine(id uint) error {
+	if err := s.require(models.PermissionMachineDelete); err != nil {
+		return err
+	}
+	return s.Next.DeleteMachine(id)
+}
+
+func (s *AuthorizedMachineService) RunCommand(cmd BulkCommand) (BulkCommandResult, error) {
+	if err := s.require(models.PermissionMachineCommand); err != nil {
+		return BulkCommandResult{}, err
+	}
+	return s.Next.RunCommand(cmd)
+}
+
+func (s *AuthorizedMachineService) ForTenant(organizationID uint) MachineService {
+	return &AuthorizedMachineService{Next: s.Next.ForTenant(organizationID), Identity: s.Identity}
+}
+
+func (s *AuthorizedMachineService) WithContext(ctx context.Context) MachineService {
+	return &AuthorizedMachineService{Next: s.Next.WithContext(ctx), Identity: s.Identity}
+}
+
+func (s *AuthorizedMachineService) require(permission string) error {
+	if !s.Identity.Can(permission) {
+		return fmt.Errorf("%w: requires the %s permission", ErrForbidden, permission)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolePermissions(t *testing.T) {
+	machines := service.NewMachineService(&MockMachineRepository{})
+	as := func(roles ...string) service.MachineService {
+		return service.NewAuthorizedMachineService(machines, service.Identity{Subject: "u1", Roles: roles})
+	}
+	stop := service.BulkCommand{Command: "stop", MachineSelector: service.MachineSelector{IDs: []uint{1}}}
+	// MockMachineRepository finds every machine as TestMachine, Idle
+	start := models.Machine{Name: "TestMachine", Status: "Running"}
+	reconfigure := models.Machine{Name: "TestMachine", Status: "Idle", ConfigJSON: `{"speed": 2}`}
+
+	viewer := as(models.RoleViewer)
+	_, err := viewer.GetAllMachines()
+	assert.Nil(t, err)
+	_, err = viewer.GetMachineByID(1)
+	assert.Nil(t, err)
+	_, err = viewer.UpdateMachine(1, start)
+	assert.ErrorIs(t, err, service.ErrForbidden, "Viewers cannot start machines")
+	_, err = viewer.RunCommand(stop)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	operator := as(models.RoleOperator)
+	_, err = operator.UpdateMachine(1, start)
+	assert.Nil(t, err, "Operators start and stop machines")
+	_, err = operator.RunCommand(stop)
+	assert.NotErrorIs(t, err, service.ErrForbidden)
+	_, err = operator.UpdateMachine(1, reconfigure)
+	if assert.ErrorIs(t, err, service.ErrForbidden, "Operators cannot edit the configuration") {
+		assert.Contains(t, err.Error(), models.PermissionMachineConfigWrite)
+	}
+	_, err = operator.UpdateMachine(1, models.Machine{Name: "Renamed", Status: "Idle"})
+	assert.ErrorIs(t, err, service.ErrForbidden, "Operators cannot rename machines")
+	_, err = operator.CreateMachine(models.Machine{Name: "New"})
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	assert.ErrorIs(t, operator.DeleteMachine(1), service.ErrForbidden, "Operators cannot delete machines")
+
+	engineer := as(models.RoleEngineer)
+	_, err = engineer.UpdateMachine(1, reconfigure)
+	assert.Nil(t, err)
+	_, err = engineer.CreateMachine(models.Machine{Name: "New"})
+	assert.Nil(t, err)
+	assert.Nil(t, engineer.DeleteMachine(1))
+
+	_, err = as(models.RoleOperator).UpdateMachine(99, start)
+	assert.NotErrorIs(t, err, service.ErrForbidden, "Missing machines are reported as such")
+	_, err = as().GetAllMachines()
+	assert.ErrorIs(t, err, service.ErrForbidden, "Identities without roles or scopes may do nothing")
+}
+
+func TestScopesActAsRoles(t *testing.T) {
+	reader := service.Identity{Scopes: []string{models.APIKeyScopeRead}}
+	writer := service.Identity{Scopes: []string{models.APIKeyScopeWrite}}
+	adminOperator := service.Identity{Scopes: []string{models.APIKeyScopeAdmin}, Roles: []string{models.RoleOperator}}
+
+	assert.True(t, reader.Can(models.PermissionMachineRead))
+	assert.False(t, reader.Can(models.PermissionMachineCommand))
+	assert.True(t, writer.Can(models.PermissionMachineDelete), "Write keys keep what they could do before roles")
+	assert.True(t, adminOperator.HasScope(models.APIKeyScopeAdmin))
+	assert.False(t, adminOperator.Can(models.PermissionMachineDelete), "Roles, when given, decide machine permissions")
+	assert.False(t, service.Identity{Roles: []string{models.RoleViewer}}.HasScope(models.APIKeyScopeWrite))
+}
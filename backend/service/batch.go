@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/CBYeuler/automation-backend/backend/tracing"
+)
+
+const (
+	// DefaultBatchConcurrency is how many points of a batch run at once when no concurrency is given
+	DefaultBatchConcurrency = 4
+	// MaxBatchConcurrency caps the concurrency a batch may ask for
+	MaxBatchConcurrency = 32
+	// DefaultBatchPollInterval is how often a batch checks on the jobs of its running points
+	DefaultBatchPollInterval = time.Second
+)
+
+var (
+	// ErrBatchNotFound is returned when a batch ID does not exist
+	ErrBatchNotFound = errors.New("batch not found")
+	// ErrInvalidBatch is returned when a batch submission is rejected
+	ErrInvalidBatch = errors.New("invalid batch")
+)
+
+// BatchRequest is the body of POST /api/v1/machines/:id/batches
+type BatchRequest struct {
+	SweepSpec
+	Concurrency int `json:"concurrency"`
+}
+
+// BatchProgress counts the points of a batch per state
+type BatchProgress struct {
+	Pending   int `json:"pending"`
+	Running   int `json:"running"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// BatchResult is a batch with its progress and per-point results
+type BatchResult struct {
+	models.Batch
+	Progress BatchProgress       `json:"progress"`
+	Points   []models.BatchPoint `json:"points"`
+}
+
+type BatchService interface {
+	SubmitBatch(machineID uint, req BatchRequest) (models.Batch, error)
+	GetBatch(id uint) (BatchResult, error)
+	ResumeBatches() error
+
+	// ForTenant returns the service limited to the batches of one organization; 0 is every organization
+	ForTenant(organizationID uint) BatchService
+	WithContext(ctx context.Context) BatchService
+}
+
+// BatchServiceImpl runs the points of batches as jobs of the job queue, so they are executed
+// like any other ad-hoc run, count against the run quota and are cancelled with their machine
+type BatchServiceImpl struct {
+	Repo         repository.BatchRepository
+	Machines     repository.MachineRepository
+	Queue        queue.Queue
+	Quota        int             // how many runs an organization may have pending or leased, 0 for any number; points wait for room
+	PollInterval time.Duration   // how often the jobs of running points are checked; DefaultBatchPollInterval if 0
+	Tenant       uint            // the organization whose batches are visible, 0 for all
+	Ctx          context.Context // the request the service works for; nil outside of requests
+}
+
+func NewBatchService(repo repository.BatchRepository, machines repository.MachineRepository, q queue.Queue, quota int) BatchService {
+	return &BatchServiceImpl{Repo: repo, Machines: machines, Queue: q, Quota: quota}
+}
+
+// --- Implementation of the Interface Methods ---
+
+// SubmitBatch expands the sweep into points, stores them and starts executing them in the background
+func (s *BatchServiceImpl) SubmitBatch(machineID uint, req BatchRequest) (_ models.Batch, err error) {
+	s, end := s.call("SubmitBatch")
+	defer end(&err)
+	machine, err := s.Machines.FindByID(machineID)
+	if err != nil {
+		return models.Batch{}, ErrMachineNotFound
+	}
+	if req.Concurrency == 0 {
+		req.Concurrency = DefaultBatchConcurrency
+	}
+	if req.Concurrency < 0 || req.Concurrency > MaxBatchConcurrency {
+		return models.Batch{}, fmt.Errorf("%w: concurrency must be between 1 and %d", ErrInvalidBatch, MaxBatchConcurrency)
+	}
+
+	params, err := ExpandSweep(&req.SweepSpec)
+	if err != nil {
+		return models.Batch{}, err
+	}
+	for _, p := range params {
+		var overrides map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(p), &overrides); err != nil {
+			return models.Batch{}, fmt.Errorf("%w: %v", ErrInvalidBatch, err)
+		}
+		if err := simulation.CheckParams(overrides); err != nil {
+			return models.Batch{}, fmt.Errorf("%w: %v", ErrInvalidBatch, err)
+		}
+	}
+	if err := checkRunQuota(s.Queue, s.Quota, machine.OrganizationID); err != nil {
+		return models.Batch{}, err
+	}
+	spec, err := json.Marshal(req.SweepSpec)
+	if err != nil {
+		return models.Batch{}, err
+	}
+
+	batch := models.Batch{
+		OrganizationID: machine.OrganizationID,
+		MachineID:      machineID,
+		Mode:           req.Mode,
+		Spec:           string(spec),
+		Concurrency:    req.Concurrency,
+		Status:         models.BatchStatusRunning,
+		Total:          len(params),
+	}
+	points := make([]models.BatchPoint, len(params))
+	for i, p := range params {
+		points[i] = models.BatchPoint{Position: i, Params: p, Status: models.PointStatusPending}
+	}
+	if err := s.Repo.Create(&batch, points); err != nil {
+		return models.Batch{}, err
+	}
+
+	slog.InfoContext(s.Ctx, "Batch submitted", "batch_id", batch.ID, "points", batch.Total, "mode", batch.Mode, "machine_id", machineID)
+	s.start(requestContext(s.Ctx), batch)
+	return batch, nil
+}
+
+func (s *BatchServiceImpl) GetBatch(id uint) (_ BatchResult, err error) {
+	s, end := s.call("GetBatch")
+	defer end(&err)
+	batch, err := s.Repo.FindByID(id)
+	if err != nil || !visible(s.Tenant, batch.OrganizationID) {
+		return BatchResult{}, ErrBatchNotFound
+	}
+	points, err := s.Repo.FindPoints(id)
+	if err != nil {
+		return BatchResult{}, err
+	}
+
+	result := BatchResult{Batch: *batch, Points: points}
+	for _, point := range points {
+		switch point.Status {
+		case models.PointStatusPending:
+			result.Progress.Pending++
+		case models.PointStatusRunning:
+			result.Progress.Running++
+		case models.PointStatusSucceeded:
+			result.Progress.Succeeded++
+		case models.PointStatusFailed:
+			result.Progress.Failed++
+		}
+	}
+	return result, nil
+}
+
+// ResumeBatches restarts batches left unfinished by a restart. Points whose jobs were queued
+// before are followed up on, the others are queued now.
+func (s *BatchServiceImpl) ResumeBatches() (err error) {
+	s, end := s.call("ResumeBatches")
+	defer end(&err)
+	batches, err := s.Repo.FindByStatus(models.BatchStatusRunning)
+	if err != nil {
+		return err
+	}
+	for _, batch := range batches {
+		slog.InfoContext(s.Ctx, "Resuming batch", "batch_id", batch.ID)
+		s.start(requestContext(s.Ctx), batch)
+	}
+	return nil
+}
+
+func (s *BatchServiceImpl) ForTenant(organizationID uint) BatchService {
+	scoped := *s
+	scoped.Machines = s.Machines.ForTenant(organizationID)
+	scoped.Tenant = organizationID
+	return &scoped
+}
+
+func (s *BatchServiceImpl) WithContext(ctx context.Context) BatchService {
+	scoped := *s
+	scoped.Ctx = ctx
+	scoped.Repo = s.Repo.WithContext(ctx)
+	scoped.Machines = s.Machines.WithContext(ctx)
+	return &scoped
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *BatchServiceImpl) call(method string) (*BatchServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "BatchService."+method, s, func(ctx context.Context) *BatchServiceImpl {
+		return s.WithContext(ctx).(*BatchServiceImpl)
+	})
+}
+
+// start executes the unfinished points of a batch in the background. The batch is logged
+// with the request ID of ctx but outlives it.
+func (s *BatchServiceImpl) start(ctx context.Context, batch models.Batch) {
+	background := s.WithContext(context.WithoutCancel(ctx)).(*BatchServiceImpl)
+	go background.execute(batch)
+}
+
+// execute queues the unfinished points of a batch as jobs, keeping at most batch.Concurrency
+// of them queued or running and the organization within its run quota, records their
+// outcomes and then completes the batch
+func (s *BatchServiceImpl) execute(batch models.Batch) {
+	points, err := s.Repo.FindPoints(batch.ID)
+	if err != nil {
+		slog.ErrorContext(s.Ctx, "Failed to load points of batch", "batch_id", batch.ID, "error", err)
+		return
+	}
+
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = DefaultBatchPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var running []*models.BatchPoint
+	next := 0
+	for {
+		running = s.settle(batch, running)
+	fill:
+		for ; len(running) < batch.Concurrency && next < len(points); next++ {
+			point := &points[next]
+			switch {
+			case point.Status == models.PointStatusSucceeded || point.Status == models.PointStatusFailed:
+				continue
+			case point.Status == models.PointStatusRunning && point.JobID != nil:
+				// Queued before a restart, the job is still in the queue
+			default:
+				// Points over the quota wait for runs of the organization to finish
+				if !s.enqueue(batch, point) {
+					break fill
+				}
+			}
+			if point.Status == models.PointStatusRunning {
+				running = append(running, point)
+			}
+		}
+		if len(running) == 0 && next == len(points) {
+			break
+		}
+		<-ticker.C
+	}
+
+	finishedAt := time.Now()
+	batch.Status = models.BatchStatusCompleted
+	batch.FinishedAt = &finishedAt
+	if err := s.Repo.Update(&batch); err != nil {
+		slog.ErrorContext(s.Ctx, "Failed to complete batch", "batch_id", batch.ID, "error", err)
+		return
+	}
+	slog.InfoContext(s.Ctx, "Batch completed", "batch_id", batch.ID)
+}
+
+// enqueue submits the run of a point as a job. Points are not retried, so the job gets one attempt.
+// It reports false, leaving the point as it is, when the organization has no room for the run.
+func (s *BatchServiceImpl) enqueue(batch models.Batch, point *models.BatchPoint) bool {
+	job := models.Job{
+		OrganizationID: batch.OrganizationID,
+		MachineID:      batch.MachineID,
+		Params:         point.Params,
+		MaxAttempts:    1,
+		RequestID:      logging.RequestID(s.Ctx),
+		TraceParent:    tracing.TraceParent(requestContext(s.Ctx)),
+	}
+	startedAt := time.Now()
+	err := enqueueWithinQuota(s.Queue, s.Quota, &job)
+	if errors.Is(err, ErrQuotaExceeded) {
+		return false
+	}
+	point.StartedAt = &startedAt
+	point.Error = ""
+	if err != nil {
+		point.Status, point.Error, point.FinishedAt = models.PointStatusFailed, "failed to queue the run: "+err.Error(), &startedAt
+	} else {
+		point.Status, point.JobID = models.PointStatusRunning, &job.ID
+	}
+	s.savePoint(batch, point)
+	return true
+}
+
+// settle records the outcome of the points whose jobs have finished and returns the others
+func (s *BatchServiceImpl) settle(batch models.Batch, running []*models.BatchPoint) []*models.BatchPoint {
+	var unfinished []*models.BatchPoint
+	for _, point := range running {
+		job, err := s.Queue.Get(*point.JobID)
+		switch {
+		case errors.Is(err, queue.ErrJobNotFound):
+			point.Status, point.Error = models.PointStatusFailed, "the job of the run was not found"
+		case err != nil:
+			slog.ErrorContext(s.Ctx, "Failed to check job of batch point", "batch_id", batch.ID, "point", point.Position, "error", err)
+			unfinished = append(unfinished, point)
+			continue
+		case job.Status == models.JobStatusSucceeded:
+			point.Status, point.RunID = models.PointStatusSucceeded, job.RunID
+		case job.Status == models.JobStatusDead:
+			point.Status, point.Error = models.PointStatusFailed, job.LastError
+		default:
+			unfinished = append(unfinished, point)
+			continue
+		}
+		finishedAt := time.Now()
+		point.FinishedAt = &finishedAt
+		s.savePoint(batch, point)
+	}
+	return unfinished
+}
+
+func (s *BatchServiceImpl) savePoint(batch models.Batch, point *models.BatchPoint) {
+	if err := s.Repo.UpdatePoint(point); err != nil {
+		slog.ErrorContext(s.Ctx, "Failed to update batch point", "batch_id", batch.ID, "point", point.Position, "error", err)
+	}
+}
@@ -0,0 +1,360 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockBatchRepository keeps batches and their points in memory
+type MockBatchRepository struct {
+	mu      sync.Mutex
+	Batches map[uint]models.Batch
+	Points  map[uint][]models.BatchPoint
+}
+
+func NewMockBatchRepository() *MockBatchRepository {
+	return &MockBatchRepository{Batches: map[uint]models.Batch{}, Points: map[uint][]models.BatchPoint{}}
+}
+
+func (m *MockBatchRepository) WithContext(context.Context) repository.BatchRepository { return m }
+
+func (m *MockBatchRepository) Create(batch *models.Batch, points []models.BatchPoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	batch.ID = uint(len(m.Batches) + 1)
+	m.Batches[batch.ID] = *batch
+	for i := range points {
+		points[i].ID = uint(i + 1)
+		points[i].BatchID = batch.ID
+	}
+	m.Points[batch.ID] = append([]models.BatchPoint(nil), points...)
+	return nil
+}
+func (m *MockBatchRepository) Update(batch *models.Batch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Batches[batch.ID] = *batch
+	return nil
+}
+func (m *MockBatchRepository) FindByID(id uint) (*models.Batch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	batch, ok := m.Batches[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &batch, nil
+}
+func (m *MockBatchRepository) FindByStatus(status string) ([]models.Batch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var batches []models.Batch
+	for _, batch := range m.Batches {
+		if batch.Status == status {
+			batches = append(batches, batch)
+		}
+	}
+	return batches, nil
+}
+func (m *MockBatchRepository) FindPoints(batchID uint) ([]models.BatchPoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.BatchPoint(nil), m.Points[batchID]...), nil
+}
+func (m *MockBatchRepository) UpdatePoint(point *models.BatchPoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Points[point.BatchID][point.Position] = *point
+	return nil
+}
+
+// MockJobQueue keeps jobs in memory. Its consumer fails runs whose params set "fail" and
+// tracks how many jobs are unfinished at once.
+type MockJobQueue struct {
+	mu         sync.Mutex
+	Jobs       []models.Job
+	peak, runs int
+}
+
+func (m *MockJobQueue) Enqueue(job *models.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.ID = uint(len(m.Jobs) + 1)
+	job.Status = models.JobStatusPending
+	m.Jobs = append(m.Jobs, *job)
+	unfinished := 0
+	for _, j := range m.Jobs {
+		if j.Status == models.JobStatusPending || j.Status == models.JobStatusLeased {
+			unfinished++
+		}
+	}
+	if unfinished > m.peak {
+		m.peak = unfinished
+	}
+	return nil
+}
+func (m *MockJobQueue) Lease(owner string, visibility time.Duration) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.Jobs {
+		if m.Jobs[i].Status == models.JobStatusPending {
+			m.Jobs[i].Status = models.JobStatusLeased
+			m.Jobs[i].Attempts++
+			m.runs++
+			job := m.Jobs[i]
+			return &job, nil
+		}
+	}
+	return nil, queue.ErrEmpty
+}
+func (m *MockJobQueue) Ack(jobID uint, owner string, runID *uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Jobs[jobID-1].Status, m.Jobs[jobID-1].RunID = models.JobStatusSucceeded, runID
+	return nil
+}
+func (m *MockJobQueue) Nack(jobID uint, owner string, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Jobs[jobID-1].Status, m.Jobs[jobID-1].LastError = models.JobStatusDead, reason
+	return nil
+}
+func (m *MockJobQueue) Get(jobID uint) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if jobID == 0 || int(jobID) > len(m.Jobs) {
+		return nil, queue.ErrJobNotFound
+	}
+	job := m.Jobs[jobID-1]
+	return &job, nil
+}
+func (m *MockJobQueue) List(filter queue.Filter) ([]models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var jobs []models.Job
+	for _, job := range m.Jobs {
+		if job.OrganizationID == filter.OrganizationID && job.Status == filter.Status {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+func (m *MockJobQueue) Depth() (int64, error) { return 0, nil }
+
+// consume runs the queued jobs until the test ends
+func (m *MockJobQueue) consume(t *testing.T) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+			job, err := m.Lease("test", time.Minute)
+			if err != nil {
+				continue
+			}
+			var p map[string]interface{}
+			_ = json.Unmarshal([]byte(job.Params), &p)
+			if p["fail"] == true {
+				_ = m.Nack(job.ID, "test", "injected failure")
+			} else {
+				runID := job.ID + 100
+				_ = m.Ack(job.ID, "test", &runID)
+			}
+		}
+	}()
+}
+
+func (m *MockJobQueue) stats() (peak, runs int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.peak, m.runs
+}
+
+// waitForBatch waits until the batch is completed and returns it
+func waitForBatch(t *testing.T, s service.BatchService, id uint) service.BatchResult {
+	var result service.BatchResult
+	assert.Eventually(t, func() bool {
+		var err error
+		result, err = s.GetBatch(id)
+		return err == nil && result.Status == models.BatchStatusCompleted
+	}, 3*time.Second, 5*time.Millisecond)
+	return result
+}
+
+func TestExpandSweep(t *testing.T) {
+	grid := &service.SweepSpec{Mode: service.SweepGrid, Parameters: map[string][]json.RawMessage{
+		"speed": {json.RawMessage(`1`), json.RawMessage(`2`), json.RawMessage(`3`)},
+		"load":  {json.RawMessage(`0.5`), json.RawMessage(`1.0`)},
+	}}
+	points, err := service.ExpandSweep(grid)
+	assert.Nil(t, err)
+	assert.Len(t, points, 6)
+	assert.JSONEq(t, `{"load": 0.5, "speed": 1}`, points[0])
+	assert.JSONEq(t, `{"load": 1.0, "speed": 3}`, points[5])
+
+	list := &service.SweepSpec{Mode: service.SweepList, Points: []map[string]json.RawMessage{{"load": json.RawMessage(`0.7`)}}}
+	points, err = service.ExpandSweep(list)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`{"load":0.7}`}, points)
+
+	lo, hi := 1.0, 3.0
+	random := &service.SweepSpec{Mode: service.SweepRandom, Samples: 50, Ranges: map[string]service.SweepRange{
+		"speed":    {Min: &lo, Max: &hi, Integer: true},
+		"material": {Values: []json.RawMessage{json.RawMessage(`"steel"`)}},
+	}}
+	points, err = service.ExpandSweep(random)
+	assert.Nil(t, err)
+	assert.Len(t, points, 50)
+	assert.NotZero(t, random.Seed, "A seed should be chosen so the sweep can be reproduced")
+	for _, point := range points {
+		var p struct {
+			Speed    float64 `json:"speed"`
+			Material string  `json:"material"`
+		}
+		assert.Nil(t, json.Unmarshal([]byte(point), &p))
+		assert.Contains(t, []float64{1, 2, 3}, p.Speed)
+		assert.Equal(t, "steel", p.Material)
+	}
+	again, _ := service.ExpandSweep(random)
+	assert.Equal(t, points, again, "The same seed should give the same points")
+
+	// Fractional bounds of integer ranges are rounded inwards
+	fracLo, fracHi := 0.5, 2.5
+	fractional := &service.SweepSpec{Mode: service.SweepRandom, Samples: 50, Ranges: map[string]service.SweepRange{
+		"speed": {Min: &fracLo, Max: &fracHi, Integer: true},
+	}}
+	points, err = service.ExpandSweep(fractional)
+	assert.Nil(t, err)
+	for _, point := range points {
+		var p struct {
+			Speed float64 `json:"speed"`
+		}
+		assert.Nil(t, json.Unmarshal([]byte(point), &p))
+		assert.Contains(t, []float64{1, 2}, p.Speed, "Integer samples should stay within the range")
+	}
+
+	minInt, maxInt := float64(math.MinInt64), float64(math.MaxInt64)
+	noWhole, noWholeMax := 0.2, 0.8
+	invalid := []*service.SweepSpec{
+		{Mode: "spiral"},
+		{Mode: service.SweepGrid},
+		{Mode: service.SweepGrid, Parameters: map[string][]json.RawMessage{"load": {}}},
+		{Mode: service.SweepList},
+		{Mode: service.SweepRandom, Samples: 5},
+		{Mode: service.SweepRandom, Samples: 0, Ranges: map[string]service.SweepRange{"speed": {Min: &lo, Max: &hi}}},
+		{Mode: service.SweepRandom, Samples: 5, Ranges: map[string]service.SweepRange{"speed": {Min: &hi, Max: &lo}}},
+		{Mode: service.SweepRandom, Samples: 5, Ranges: map[string]service.SweepRange{"speed": {Min: &lo}}},
+		{Mode: service.SweepRandom, Samples: 5, Ranges: map[string]service.SweepRange{"speed": {Min: &minInt, Max: &maxInt, Integer: true}}},
+		{Mode: service.SweepRandom, Samples: 5, Ranges: map[string]service.SweepRange{"speed": {Min: &noWhole, Max: &noWholeMax, Integer: true}}},
+	}
+	for _, spec := range invalid {
+		_, err := service.ExpandSweep(spec)
+		assert.ErrorIs(t, err, service.ErrInvalidBatch, "Spec %+v should be rejected", spec)
+	}
+}
+
+func TestBatchRunsPointsWithBoundedConcurrency(t *testing.T) {
+	repo := NewMockBatchRepository()
+	jobs := &MockJobQueue{}
+	jobs.consume(t)
+	s := &service.BatchServiceImpl{Repo: repo, Machines: &MockMachineRepository{}, Queue: jobs, PollInterval: time.Millisecond}
+
+	values := []json.RawMessage{}
+	for i := 0; i < 9; i++ {
+		values = append(values, json.RawMessage(`false`))
+	}
+	values = append(values, json.RawMessage(`true`))
+	batch, err := s.SubmitBatch(1, service.BatchRequest{
+		SweepSpec:   service.SweepSpec{Mode: service.SweepGrid, Parameters: map[string][]json.RawMessage{"fail": values}},
+		Concurrency: 3,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, batch.Total)
+
+	result := waitForBatch(t, s, batch.ID)
+	assert.NotNil(t, result.FinishedAt)
+	assert.Equal(t, service.BatchProgress{Succeeded: 9, Failed: 1}, result.Progress)
+	assert.Equal(t, "injected failure", result.Points[9].Error)
+	assert.NotNil(t, result.Points[0].RunID)
+	assert.NotNil(t, result.Points[9].JobID)
+	peak, _ := jobs.stats()
+	assert.LessOrEqual(t, peak, 3, "At most concurrency points should be queued or running")
+
+	_, err = s.SubmitBatch(99, service.BatchRequest{SweepSpec: service.SweepSpec{Mode: service.SweepList}})
+	assert.ErrorIs(t, err, service.ErrMachineNotFound)
+	_, err = s.SubmitBatch(1, service.BatchRequest{SweepSpec: service.SweepSpec{Mode: service.SweepList}, Concurrency: 100})
+	assert.ErrorIs(t, err, service.ErrInvalidBatch)
+	_, err = s.SubmitBatch(1, service.BatchRequest{SweepSpec: service.SweepSpec{Mode: service.SweepList, Points: []map[string]json.RawMessage{{"runner": json.RawMessage(`"x"`)}}}})
+	assert.ErrorIs(t, err, service.ErrInvalidBatch, "Points cannot override reserved params")
+	_, err = s.GetBatch(42)
+	assert.ErrorIs(t, err, service.ErrBatchNotFound)
+}
+
+func TestResumeBatchesRerunsUnfinishedPoints(t *testing.T) {
+	repo := NewMockBatchRepository()
+	jobs := &MockJobQueue{}
+	jobRunning := uint(1)
+	assert.Nil(t, jobs.Enqueue(&models.Job{MachineID: 1, Params: `{}`, MaxAttempts: 1}))
+	jobs.consume(t)
+	// A batch interrupted by a restart: one point done, one with a queued job, one not started
+	assert.Nil(t, repo.Create(&models.Batch{MachineID: 1, Mode: service.SweepList, Concurrency: 2, Status: models.BatchStatusRunning, Total: 3}, []models.BatchPoint{
+		{Position: 0, Params: `{}`, Status: models.PointStatusSucceeded},
+		{Position: 1, Params: `{}`, Status: models.PointStatusRunning, JobID: &jobRunning},
+		{Position: 2, Params: `{}`, Status: models.PointStatusPending},
+	}))
+
+	s := &service.BatchServiceImpl{Repo: repo, Machines: &MockMachineRepository{}, Queue: jobs, PollInterval: time.Millisecond}
+	assert.Nil(t, s.ResumeBatches())
+
+	result := waitForBatch(t, s, 1)
+	assert.Equal(t, service.BatchProgress{Succeeded: 3}, result.Progress)
+	_, runs := jobs.stats()
+	assert.Equal(t, 2, runs, "Finished points should not run again and queued ones not be queued twice")
+}
+
+func TestBatchRespectsRunQuota(t *testing.T) {
+	repo := NewMockBatchRepository()
+	jobs := &MockJobQueue{}
+	s := &service.BatchServiceImpl{Repo: repo, Machines: &MockMachineRepository{}, Queue: jobs, Quota: 2, PollInterval: time.Millisecond}
+	sweep := service.SweepSpec{Mode: service.SweepGrid, Parameters: map[string][]json.RawMessage{
+		"speed": {json.RawMessage(`1`), json.RawMessage(`2`), json.RawMessage(`3`), json.RawMessage(`4`), json.RawMessage(`5`)},
+	}}
+
+	// Runs the organization has waiting already fill the quota
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, jobs.Enqueue(&models.Job{OrganizationID: models.DefaultOrganizationID, MachineID: 1, MaxAttempts: 1}))
+	}
+	_, err := s.SubmitBatch(1, service.BatchRequest{SweepSpec: sweep, Concurrency: 4})
+	assert.ErrorIs(t, err, service.ErrQuotaExceeded)
+
+	jobs.consume(t)
+	assert.Eventually(t, func() bool {
+		_, runs := jobs.stats()
+		return runs == 2
+	}, time.Second, time.Millisecond)
+	jobs.mu.Lock()
+	jobs.peak = 0
+	jobs.mu.Unlock()
+
+	batch, err := s.SubmitBatch(1, service.BatchRequest{SweepSpec: sweep, Concurrency: 4})
+	assert.Nil(t, err)
+	result := waitForBatch(t, s, batch.ID)
+	assert.Equal(t, service.BatchProgress{Succeeded: 5}, result.Progress)
+	peak, _ := jobs.stats()
+	assert.LessOrEqual(t, peak, 2, "Points should wait for room in the quota rather than exceed it")
+}
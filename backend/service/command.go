@@ -0,0 +1,245 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+)
+
+// Commands that can be run against a selection of machines
+const (
+	CommandStart = "start" // puts Offline machines back into simulation
+	CommandStop  = "stop"  // takes machines Offline
+	CommandReset = "reset" // clears the Error status of machines
+)
+
+// Outcomes of a bulk command for a single machine
+const (
+	CommandUpdated   = "updated"
+	CommandUnchanged = "unchanged" // the machine already was in the requested state
+	CommandSkipped   = "skipped"   // the command does not apply to the machine's status
+	CommandFailed    = "failed"
+)
+
+// ErrInvalidCommand is returned when a bulk command or its machine selection is rejected
+var ErrInvalidCommand = errors.New("invalid command")
+
+// MachineSelector picks the machines a bulk command applies to. All given criteria must match.
+type MachineSelector struct {
+	IDs     []uint   `json:"ids"`
+	GroupID *uint    `json:"group_id"` // includes the machines of its subgroups
+	Tags    []string `json:"tags"`     // machines must carry all of them
+	Status  string   `json:"status" enum:"Offline,Idle,Running,Error"`
+}
+
+// BulkCommand is a command to run against every machine matching the selector
+type BulkCommand struct {
+	Command string `json:"command" binding:"required" enum:"start,stop,reset"`
+	MachineSelector
+}
+
+// CommandResult reports what a bulk command did to a single machine
+type CommandResult struct {
+	MachineID      uint   `json:"machine_id"`
+	Name           string `json:"name,omitempty"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+	Status         string `json:"status,omitempty"`
+	Result         string `json:"result"`
+	Error          string `json:"error,omitempty"`
+}
+
+// BulkCommandResult is the per-machine report of a bulk command
+type BulkCommandResult struct {
+	Command string          `json:"command"`
+	Matched int             `json:"matched"`
+	Updated int             `json:"updated"`
+	Results []CommandResult `json:"results"`
+}
+
+// commandTransition returns the status a command moves a machine in the given status to,
+// or the reason it does not apply
+func commandTransition(command, status string) (string, string) {
+	switch command {
+	case CommandStart:
+		switch status {
+		case "Error":
+			return "", "machine is in Error; reset it first"
+		case "Idle", "Running":
+			return status, ""
+		}
+		return "Running", ""
+	case CommandStop:
+		return "Offline", ""
+	case CommandReset:
+		if status != "Error" {
+			return "", "machine is not in Error"
+		}
+		return "Idle", ""
+	}
+	return "", "unknown command"
+}
+
+// RunCommand starts, stops or resets every selected machine. The status changes are written
+// in a single transaction, so either all applicable machines change or none does.
+func (s *MachineServiceImpl) RunCommand(cmd BulkCommand) (_ BulkCommandResult, err error) {
+	s, end := s.call("RunCommand")
+	defer end(&err)
+	switch cmd.Command {
+	case CommandStart, CommandStop, CommandReset:
+	default:
+		return BulkCommandResult{}, fmt.Errorf("%w: command must be %q, %q or %q", ErrInvalidCommand, CommandStart, CommandStop, CommandReset)
+	}
+
+	// The statuses are read in the transaction writing the new ones, so every transition starts
+	// from the status the machine has when it is written
+	result := BulkCommandResult{Command: cmd.Command, Results: []CommandResult{}}
+	var changed []models.Machine
+	var selectErr error
+	err = s.Repo.Transaction(func(repo repository.MachineRepository) error {
+		machines, missing, err := s.selectMachines(repo, cmd.MachineSelector)
+		if err != nil {
+			selectErr = err
+			return err
+		}
+
+		result.Matched = len(machines)
+		var ids []uint
+		for _, m := range machines {
+			target, reason := commandTransition(cmd.Command, m.Status)
+			r := CommandResult{MachineID: m.ID, Name: m.Name, PreviousStatus: m.Status, Status: m.Status}
+			switch {
+			case reason != "":
+				r.Result, r.Error = CommandSkipped, reason
+			case target == m.Status:
+				r.Result = CommandUnchanged
+			default:
+				r.Result, r.Status = CommandUpdated, target
+				ids = append(ids, m.ID)
+				m.Status = target
+				changed = append(changed, m)
+			}
+			result.Results = append(result.Results, r)
+		}
+		for _, id := range missing {
+			result.Results = append(result.Results, CommandResult{MachineID: id, Result: CommandFailed, Error: ErrMachineNotFound.Error()})
+		}
+
+		// Every command moves the machines it applies to into a single status
+		if len(ids) == 0 {
+			return nil
+		}
+		return repo.UpdateStatus(ids, changed[0].Status)
+	})
+	if selectErr != nil {
+		return BulkCommandResult{}, selectErr
+	}
+	if err != nil {
+		slog.ErrorContext(s.Ctx, "Failed to run bulk command", "command", cmd.Command, "machines", len(changed), "error", err)
+		for i := range result.Results {
+			if r := &result.Results[i]; r.Result == CommandUpdated {
+				r.Result, r.Status, r.Error = CommandFailed, r.PreviousStatus, err.Error()
+			}
+		}
+		return result, nil
+	}
+	result.Updated = len(changed)
+	for _, m := range changed {
+		for _, l := range s.Listeners {
+			l.MachineUpdated(m)
+		}
+	}
+	return result, nil
+}
+
+// selectMachines returns the machines matching the selector, ordered by ID, and the selected IDs
+// that do not exist
+func (s *MachineServiceImpl) selectMachines(repo repository.MachineRepository, sel MachineSelector) ([]models.Machine, []uint, error) {
+	if len(sel.IDs) == 0 && sel.GroupID == nil && len(sel.Tags) == 0 && sel.Status == "" {
+		return nil, nil, fmt.Errorf("%w: select machines by ids, group_id, tags or status", ErrInvalidCommand)
+	}
+
+	var groups map[uint]bool
+	if sel.GroupID != nil {
+		var err error
+		if groups, err = s.groupSubtree(*sel.GroupID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	all, err := repo.FindAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+
+	wanted := make(map[uint]bool)
+	for _, id := range sel.IDs {
+		wanted[id] = true
+	}
+	var machines []models.Machine
+	for _, m := range all {
+		found := wanted[m.ID]
+		delete(wanted, m.ID)
+		switch {
+		case len(sel.IDs) > 0 && !found:
+		case groups != nil && (m.GroupID == nil || !groups[*m.GroupID]):
+		case sel.Status != "" && m.Status != sel.Status:
+		case !hasTags(m, sel.Tags):
+		default:
+			machines = append(machines, m)
+		}
+	}
+
+	var missing []uint
+	for id := range wanted {
+		missing = append(missing, id)
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	return machines, missing, nil
+}
+
+// groupSubtree returns the IDs of a group and of all groups below it
+func (s *MachineServiceImpl) groupSubtree(root uint) (map[uint]bool, error) {
+	if s.Groups == nil {
+		return map[uint]bool{root: true}, nil
+	}
+	if _, err := s.Groups.FindGroupByID(root); err != nil {
+		return nil, ErrGroupNotFound
+	}
+	groups, err := s.Groups.FindGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	subtree := map[uint]bool{root: true}
+	for added := true; added; {
+		added = false
+		for _, g := range groups {
+			if g.ParentID != nil && subtree[*g.ParentID] && !subtree[g.ID] {
+				subtree[g.ID] = true
+				added = true
+			}
+		}
+	}
+	return subtree, nil
+}
+
+func hasTags(machine models.Machine, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range machine.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCommandService returns a machine service over a plant with one line of three machines
+// (one in a cell of the line) and a machine outside of any group
+func newCommandService() (service.MachineService, *MockPlant, *RecordingListener) {
+	site, line, cell := uint(1), uint(2), uint(3)
+	plant := &MockPlant{
+		groups: []models.MachineGroup{
+			{Model: models.Model{ID: site}, Name: "Plant A", Kind: models.GroupKindSite},
+			{Model: models.Model{ID: line}, Name: "Line 3", Kind: models.GroupKindLine, ParentID: &site},
+			{Model: models.Model{ID: cell}, Name: "Cell 1", Kind: models.GroupKindCell, ParentID: &line},
+		},
+		machines: []models.Machine{
+			{Model: models.Model{ID: 1}, Name: "Feeder", Status: "Running", GroupID: &line, Tags: []string{"paint"}},
+			{Model: models.Model{ID: 2}, Name: "Press", Status: "Error", GroupID: &line},
+			{Model: models.Model{ID: 3}, Name: "Welder", Status: "Offline", GroupID: &cell, Tags: []string{"paint", "robot"}},
+			{Model: models.Model{ID: 4}, Name: "Packer", Status: "Error"},
+		},
+	}
+	listener := &RecordingListener{}
+	return &service.MachineServiceImpl{Repo: plantMachines{plant}, Groups: plant, Listeners: []service.MachineListener{listener}}, plant, listener
+}
+
+func TestRunCommandByGroup(t *testing.T) {
+	s, plant, listener := newCommandService()
+	line := uint(2)
+
+	result, err := s.RunCommand(service.BulkCommand{Command: service.CommandStop, MachineSelector: service.MachineSelector{GroupID: &line}})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Matched, "Machines in subgroups should be included")
+	assert.Equal(t, 2, result.Updated)
+	var outcomes []string
+	for _, r := range result.Results {
+		outcomes = append(outcomes, r.Result)
+	}
+	assert.Equal(t, []string{service.CommandUpdated, service.CommandUpdated, service.CommandUnchanged}, outcomes)
+	assert.Equal(t, "Offline", plant.machines[1].Status)
+	assert.Equal(t, "Error", plant.machines[3].Status, "Machines outside the group are left alone")
+	assert.Equal(t, []uint{1, 2}, listener.Updated)
+
+	missing := uint(42)
+	_, err = s.RunCommand(service.BulkCommand{Command: service.CommandStop, MachineSelector: service.MachineSelector{GroupID: &missing}})
+	assert.ErrorIs(t, err, service.ErrGroupNotFound)
+}
+
+func TestRunCommandFilters(t *testing.T) {
+	s, plant, _ := newCommandService()
+
+	result, err := s.RunCommand(service.BulkCommand{Command: service.CommandReset, MachineSelector: service.MachineSelector{Status: "Error"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Updated, "Reset should clear every Error")
+	assert.Equal(t, "Idle", plant.machines[3].Status)
+
+	result, err = s.RunCommand(service.BulkCommand{Command: service.CommandStart, MachineSelector: service.MachineSelector{Tags: []string{"paint", "robot"}}})
+	assert.Nil(t, err)
+	if assert.Len(t, result.Results, 1, "Machines must carry all tags") {
+		assert.Equal(t, "Running", result.Results[0].Status)
+	}
+
+	result, err = s.RunCommand(service.BulkCommand{Command: service.CommandReset, MachineSelector: service.MachineSelector{IDs: []uint{1, 99}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, result.Matched)
+	assert.Equal(t, service.CommandSkipped, result.Results[0].Result, "Only machines in Error can be reset")
+	assert.Equal(t, service.CommandResult{MachineID: 99, Result: service.CommandFailed, Error: "machine not found"}, result.Results[1])
+
+	_, err = s.RunCommand(service.BulkCommand{Command: service.CommandStop})
+	assert.ErrorIs(t, err, service.ErrInvalidCommand, "A selector is required")
+	_, err = s.RunCommand(service.BulkCommand{Command: "explode", MachineSelector: service.MachineSelector{Status: "Error"}})
+	assert.ErrorIs(t, err, service.ErrInvalidCommand)
+}
+
+func TestRunCommandIsAllOrNothing(t *testing.T) {
+	s, plant, listener := newCommandService()
+	plant.updateErr = errors.New("database is locked")
+
+	result, err := s.RunCommand(service.BulkCommand{Command: service.CommandStop, MachineSelector: service.MachineSelector{Tags: []string{"paint"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, result.Updated)
+	assert.Equal(t, service.CommandResult{MachineID: 1, Name: "Feeder", PreviousStatus: "Running", Status: "Running", Result: service.CommandFailed, Error: "database is locked"}, result.Results[0])
+	assert.Equal(t, service.CommandUnchanged, result.Results[1].Result, "Machines needing no change are not failed")
+	assert.Empty(t, listener.Updated, "Failed commands should not notify listeners")
+}
+
+// txPlant only lists machines inside a transaction
+type txPlant struct {
+	plantMachines
+	inTx bool
+}
+
+func (m *txPlant) FindAll() ([]models.Machine, error) {
+	if !m.inTx {
+		return nil, errors.New("read outside of the transaction")
+	}
+	return m.plantMachines.FindAll()
+}
+func (m *txPlant) Transaction(fn func(repository.MachineRepository) error) error {
+	m.inTx = true
+	defer func() { m.inTx = false }()
+	return fn(m)
+}
+
+func TestRunCommandReadsStatusesInTransaction(t *testing.T) {
+	_, plant, _ := newCommandService()
+	s := &service.MachineServiceImpl{Repo: &txPlant{plantMachines: plantMachines{plant}}, Groups: plant}
+
+	result, err := s.RunCommand(service.BulkCommand{Command: service.CommandReset, MachineSelector: service.MachineSelector{Status: "Error"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Updated)
+}
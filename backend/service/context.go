@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"github.com/CBYeuler/automation-backend/backend/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("github.com/CBYeuler/automation-backend/backend/service")
+
+// requestContext is the context of the request a service works for, or the background
+// context for services working outside of requests
+func requestContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// startSpan starts the span of a service call as a child of the span of the request ctx is
+// for, returning the span's context and a function ending the span with the call's error.
+// Calls outside of traced requests, such as housekeeping in the background, are not traced;
+// they get ctx back as it is.
+func startSpan(ctx context.Context, name string) (context.Context, func(err *error)) {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, func(*error) {}
+	}
+	ctx, span := tracer.Start(ctx, name)
+	return ctx, func(err *error) { tracing.End(span, *err) }
+}
+
+// startCall is startSpan for a service bound to its request with WithContext. It returns the
+// service bound to the span by bind instead, so that the queries of the call become children
+// of the span.
+func startCall[S any](ctx context.Context, name string, s S, bind func(ctx context.Context) S) (S, func(err *error)) {
+	spanCtx, end := startSpan(ctx, name)
+	if spanCtx == ctx {
+		return s, end
+	}
+	return bind(spanCtx), end
+}
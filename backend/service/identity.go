@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/token"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidToken is returned when a bearer token fails verification
+var ErrInvalidToken = token.ErrInvalidToken
+
+// How a request was authenticated
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodToken  = "token"
+)
+
+// Identity is who made a request, whichever way they authenticated
+type Identity struct {
+	Subject string   `json:"subject"` // unique per caller, e.g. "api-key:3" or a token's sub claim
+	Name    string   `json:"name"`
+	Email   string   `json:"email,omitempty"`
+	Method  string   `json:"method"`
+	Scopes  []string `json:"scopes"`
+	Roles   []string `json:"roles"`
+
+	OrganizationID uint `json:"organization_id"` // the organization whose machines the caller works with
+}
+
+// HasScope reports whether the identity was granted a scope, directly, through a broader one
+// or through one of its roles
+func (i Identity) HasScope(scope string) bool {
+	scopes := append([]string{}, i.Scopes...)
+	for _, role := range i.Roles {
+		scopes = append(scopes, models.RoleScope(role))
+	}
+	return models.GrantsScope(scopes, scope)
+}
+
+// Can reports whether the identity has a permission. Roles decide; identities without roles
+// act as the role matching each of their scopes, so a write key may do what an engineer may.
+func (i Identity) Can(permission string) bool {
+	roles := i.Roles
+	if len(roles) == 0 {
+		for _, scope := range i.Scopes {
+			roles = append(roles, models.ScopeRole(scope))
+		}
+	}
+	return models.GrantsPermission(roles, permission)
+}
+
+// PlatformAdmin reports whether the identity administers the whole backend rather than one
+// organization: admins of the default organization may create organizations and control
+// the simulator all organizations share
+func (i Identity) PlatformAdmin() bool {
+	return i.OrganizationID == models.DefaultOrganizationID && i.HasScope(models.APIKeyScopeAdmin)
+}
+
+// String names the identity in logs
+func (i Identity) String() string {
+	if i.Name == "" || i.Name == i.Subject {
+		return i.Subject
+	}
+	return fmt.Sprintf("%s (%s)", i.Name, i.Subject)
+}
+
+// APIKeyIdentity is the identity of a client using an API key
+func APIKeyIdentity(key models.APIKey) Identity {
+	return Identity{
+		Subject: fmt.Sprintf("api-key:%d", key.ID),
+		Name:    key.Name,
+		Method:  AuthMethodAPIKey,
+		Scopes:  key.Scopes,
+		Roles:   key.Roles,
+
+		OrganizationID: key.OrganizationID,
+	}
+}
+
+type TokenService interface {
+	// Authenticate verifies a JWT bearer token and returns the identity its claims describe
+	Authenticate(ctx context.Context, raw string) (Identity, error)
+}
+
+// TokenClaims names the claims identities are read from
+type TokenClaims struct {
+	Scope        string // read, write and admin scopes
+	Roles        string // roles, such as operator; "" ignores roles
+	Organization string // name of the caller's organization; tokens without it are rejected
+}
+
+// TokenServiceImpl maps the claims of verified tokens to identities. Scopes and roles are
+// either a space separated string as in OAuth 2.0 or a list; scopes and roles the API does
+// not know, such as openid, are dropped. Tokens naming no or an unknown organization are rejected.
+type TokenServiceImpl struct {
+	Verifier      *token.Verifier
+	Claims        TokenClaims
+	Organizations repository.OrganizationRepository
+}
+
+func NewTokenService(verifier *token.Verifier, claims TokenClaims, organizations repository.OrganizationRepository) TokenService {
+	return &TokenServiceImpl{Verifier: verifier, Claims: claims, Organizations: organizations}
+}
+
+// --- Implementation of the Interface Methods ---
+
+func (s *TokenServiceImpl) Authenticate(ctx context.Context, raw string) (_ Identity, err error) {
+	ctx, end := startSpan(ctx, "TokenService.Authenticate")
+	defer end(&err)
+	claims, err := s.Verifier.Verify(ctx, raw)
+	if err != nil {
+		return Identity{}, err
+	}
+	if claims.Subject == "" {
+		return Identity{}, fmt.Errorf("%w: the token has no subject", ErrInvalidToken)
+	}
+
+	identity := Identity{Subject: claims.Subject, Method: AuthMethodToken, Scopes: []string{}, Roles: []string{}}
+	identity.Name, _ = claims.All["name"].(string)
+	if identity.Name == "" {
+		identity.Name, _ = claims.All["preferred_username"].(string)
+	}
+	identity.Email, _ = claims.All["email"].(string)
+
+	// Tokens must name their organization: falling back to the default one would make the
+	// admins of any organization that forgot the claim administer the whole backend
+	name, _ := claims.All[s.Claims.Organization].(string)
+	if name == "" || s.Claims.Organization == "" {
+		return Identity{}, fmt.Errorf("%w: the token names no organization", ErrInvalidToken)
+	}
+	org, err := s.Organizations.FindByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Identity{}, fmt.Errorf("%w: unknown organization %q", ErrInvalidToken, name)
+	}
+	if err != nil {
+		// The token may well be valid, it is the lookup that failed
+		return Identity{}, fmt.Errorf("failed to look up organization %q: %w", name, err)
+	}
+	identity.OrganizationID = org.ID
+
+	for _, scope := range stringsClaim(claims.All[s.Claims.Scope]) {
+		switch scope {
+		case models.APIKeyScopeRead, models.APIKeyScopeWrite, models.APIKeyScopeAdmin:
+			identity.Scopes = append(identity.Scopes, scope)
+		}
+	}
+	if s.Claims.Roles != "" {
+		for _, role := range stringsClaim(claims.All[s.Claims.Roles]) {
+			if models.IsRole(role) {
+				identity.Roles = append(identity.Roles, role)
+			}
+		}
+	}
+	return identity, nil
+}
+
+// stringsClaim reads a claim holding a space separated string or a list of strings
+func stringsClaim(value any) []string {
+	var values []string
+	switch value := value.(type) {
+	case string:
+		values = strings.Fields(value)
+	case []any:
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,105 @@
+package service_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/CBYeuler/automation-backend/backend/token"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenIdentity(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	data, _ := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: public, KeyID: "k1"}}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, os.WriteFile(path, data, 0o600))
+
+	verifier := &token.Verifier{Keys: token.NewFileKeySet(path), Issuer: "idp", Audience: "backend"}
+	organizations := NewMockOrganizationRepository()
+	acme := models.Organization{Name: "acme"}
+	assert.Nil(t, organizations.Create(&acme))
+	tokens := service.NewTokenService(verifier, service.TokenClaims{Scope: "scope", Roles: "roles", Organization: "org"}, organizations)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.EdDSA, Key: private}, (&jose.SignerOptions{}).WithHeader("kid", "k1"))
+	assert.Nil(t, err)
+	sign := func(subject string, extra map[string]any) string {
+		claims := jwt.Claims{Issuer: "idp", Audience: jwt.Audience{"backend"}, Subject: subject, Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+		raw, err := jwt.Signed(signer).Claims(claims).Claims(extra).Serialize()
+		assert.Nil(t, err)
+		return raw
+	}
+
+	identity, err := tokens.Authenticate(context.Background(), sign("u1", map[string]any{
+		"name": "Alice", "email": "alice@example.com", "scope": "openid profile write", "org": "default",
+	}))
+	if assert.Nil(t, err) {
+		assert.Equal(t, service.Identity{
+			Subject: "u1", Name: "Alice", Email: "alice@example.com", Method: service.AuthMethodToken, Scopes: []string{"write"}, Roles: []string{},
+			OrganizationID: models.DefaultOrganizationID,
+		}, identity, "Scopes the API does not know are dropped")
+		assert.True(t, identity.HasScope(models.APIKeyScopeRead))
+		assert.False(t, identity.HasScope(models.APIKeyScopeAdmin))
+		assert.Equal(t, "Alice (u1)", identity.String())
+	}
+
+	identity, err = tokens.Authenticate(context.Background(), sign("u2", map[string]any{
+		"preferred_username": "bob", "scope": []string{"read", "admin"}, "org": "default",
+	}))
+	if assert.Nil(t, err) {
+		assert.Equal(t, "bob", identity.Name)
+		assert.Equal(t, []string{"read", "admin"}, identity.Scopes, "Scopes may be a list")
+		assert.True(t, identity.PlatformAdmin())
+	}
+
+	identity, err = tokens.Authenticate(context.Background(), sign("u4", map[string]any{
+		"roles": []string{"operator", "superuser"}, "org": "acme",
+	}))
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"operator"}, identity.Roles, "Unknown roles are dropped")
+		assert.True(t, identity.HasScope(models.APIKeyScopeWrite), "Roles imply the scope their permissions need")
+		assert.True(t, identity.Can(models.PermissionMachineCommand))
+		assert.False(t, identity.Can(models.PermissionMachineConfigWrite))
+	}
+
+	identity, err = tokens.Authenticate(context.Background(), sign("u3", map[string]any{"org": "acme"}))
+	if assert.Nil(t, err) {
+		assert.False(t, identity.HasScope(models.APIKeyScopeRead), "Tokens without scopes grant nothing")
+	}
+
+	identity, err = tokens.Authenticate(context.Background(), sign("u5", map[string]any{"org": "acme", "scope": "admin"}))
+	if assert.Nil(t, err) {
+		assert.Equal(t, acme.ID, identity.OrganizationID)
+		assert.False(t, identity.PlatformAdmin(), "Admins of other organizations only administer their own")
+	}
+	_, err = tokens.Authenticate(context.Background(), sign("u6", map[string]any{"org": "globex"}))
+	assert.ErrorIs(t, err, service.ErrInvalidToken, "Tokens of unknown organizations are rejected")
+	_, err = tokens.Authenticate(context.Background(), sign("u7", map[string]any{"scope": "admin"}))
+	assert.ErrorIs(t, err, service.ErrInvalidToken, "Tokens without organization are rejected rather than given the default one")
+
+	_, err = tokens.Authenticate(context.Background(), sign("", nil))
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+
+	organizations.err = errors.New("database is locked")
+	_, err = tokens.Authenticate(context.Background(), sign("u8", map[string]any{"org": "acme"}))
+	assert.ErrorIs(t, err, organizations.err)
+	assert.NotErrorIs(t, err, service.ErrInvalidToken, "Failed lookups are no reason to reject the token")
+}
+
+func TestAPIKeyIdentity(t *testing.T) {
+	identity := service.APIKeyIdentity(models.APIKey{Model: models.Model{ID: 3}, OrganizationID: 2, Name: "ci", Scopes: []string{"read"}})
+	assert.Equal(t, "api-key:3", identity.Subject)
+	assert.Equal(t, uint(2), identity.OrganizationID)
+	assert.Equal(t, service.AuthMethodAPIKey, identity.Method)
+	assert.Equal(t, "ci (api-key:3)", identity.String())
+}
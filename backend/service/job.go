@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/CBYeuler/automation-backend/backend/tracing"
+)
+
+// MaxJobAttempts caps how often a submitted run may be retried
+const MaxJobAttempts = 10
+
+var (
+	// ErrJobNotFound is returned when a job ID does not exist
+	ErrJobNotFound = errors.New("job not found")
+	// ErrInvalidJob is returned when a run submission is rejected
+	ErrInvalidJob = errors.New("invalid job")
+	// ErrQuotaExceeded is returned when an organization has as many runs waiting as it may
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// RunRequest is the body of POST /api/v1/machines/:id/runs
+type RunRequest struct {
+	Params      json.RawMessage `json:"params"`       // JSON object merged over the machine's config_json for this run
+	MaxAttempts int             `json:"max_attempts"` // defaults to queue.DefaultMaxAttempts
+}
+
+type JobService interface {
+	SubmitRun(machineID uint, req RunRequest) (models.Job, error)
+	GetJob(id uint) (models.Job, error)
+	ListJobs(filter queue.Filter) ([]models.Job, error)
+
+	// ForTenant returns the service limited to the jobs of one organization; 0 is every organization
+	ForTenant(organizationID uint) JobService
+	WithContext(ctx context.Context) JobService
+}
+
+type JobServiceImpl struct {
+	Queue    queue.Queue
+	Machines repository.MachineRepository
+	Quota    int             // how many runs an organization may have pending or leased, 0 for any number
+	Tenant   uint            // the organization whose jobs are visible, 0 for all
+	Ctx      context.Context // the request submitting runs, which jobs remember the ID of
+}
+
+func NewJobService(q queue.Queue, machines repository.MachineRepository, quota int) JobService {
+	return &JobServiceImpl{Queue: q, Machines: machines, Quota: quota}
+}
+
+// --- Implementation of the Interface Methods ---
+
+// SubmitRun enqueues an ad-hoc run of a machine. The job is persisted before this returns,
+// so it is picked up after a restart even if no consumer leased it yet.
+func (s *JobServiceImpl) SubmitRun(machineID uint, req RunRequest) (_ models.Job, err error) {
+	s, end := s.call("SubmitRun")
+	defer end(&err)
+	machine, err := s.Machines.FindByID(machineID)
+	if err != nil {
+		return models.Job{}, ErrMachineNotFound
+	}
+	if req.MaxAttempts < 0 || req.MaxAttempts > MaxJobAttempts {
+		return models.Job{}, fmt.Errorf("%w: max_attempts must be between 1 and %d", ErrInvalidJob, MaxJobAttempts)
+	}
+
+	var params string
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		var overrides map[string]json.RawMessage
+		if err := json.Unmarshal(req.Params, &overrides); err != nil {
+			return models.Job{}, fmt.Errorf("%w: params must be a JSON object", ErrInvalidJob)
+		}
+		if err := simulation.CheckParams(overrides); err != nil {
+			return models.Job{}, fmt.Errorf("%w: %v", ErrInvalidJob, err)
+		}
+		params = string(req.Params)
+	}
+
+	job := models.Job{
+		OrganizationID: machine.OrganizationID,
+		MachineID:      machineID,
+		Params:         params,
+		MaxAttempts:    req.MaxAttempts,
+		RequestID:      logging.RequestID(s.Ctx),
+		TraceParent:    tracing.TraceParent(requestContext(s.Ctx)),
+	}
+	if err := enqueueWithinQuota(s.Queue, s.Quota, &job); err != nil {
+		return models.Job{}, err
+	}
+	return job, nil
+}
+
+func (s *JobServiceImpl) GetJob(id uint) (_ models.Job, err error) {
+	s, end := s.call("GetJob")
+	defer end(&err)
+	job, err := s.Queue.Get(id)
+	if errors.Is(err, queue.ErrJobNotFound) {
+		return models.Job{}, ErrJobNotFound
+	}
+	if err != nil {
+		return models.Job{}, err
+	}
+	if !visible(s.Tenant, job.OrganizationID) {
+		return models.Job{}, ErrJobNotFound
+	}
+	return *job, nil
+}
+
+// ListJobs lists jobs, newest first
+func (s *JobServiceImpl) ListJobs(filter queue.Filter) (_ []models.Job, err error) {
+	s, end := s.call("ListJobs")
+	defer end(&err)
+	if s.Tenant != 0 {
+		filter.OrganizationID = s.Tenant
+	}
+	return s.Queue.List(filter)
+}
+
+func (s *JobServiceImpl) ForTenant(organizationID uint) JobService {
+	return &JobServiceImpl{Queue: s.Queue, Machines: s.Machines.ForTenant(organizationID), Quota: s.Quota, Tenant: organizationID, Ctx: s.Ctx}
+}
+
+func (s *JobServiceImpl) WithContext(ctx context.Context) JobService {
+	return &JobServiceImpl{Queue: s.Queue, Machines: s.Machines.WithContext(ctx), Quota: s.Quota, Tenant: s.Tenant, Ctx: ctx}
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *JobServiceImpl) call(method string) (*JobServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "JobService."+method, s, func(ctx context.Context) *JobServiceImpl {
+		return s.WithContext(ctx).(*JobServiceImpl)
+	})
+}
+
+// runQuotaMu serializes checking a run quota with starting the run it admits, so concurrent
+// submissions cannot all pass the check before any of their runs counts
+var runQuotaMu sync.Mutex
+
+// enqueueWithinQuota enqueues a job unless its organization has quota runs waiting or running,
+// see checkRunQuota. Within a backend instance the quota is exact. Instances sharing a Redis
+// queue only serialize their own submissions, so together they may overshoot it by one run
+// per instance.
+func enqueueWithinQuota(q queue.Queue, quota int, job *models.Job) error {
+	if quota > 0 {
+		runQuotaMu.Lock()
+		defer runQuotaMu.Unlock()
+		if err := checkRunQuota(q, quota, job.OrganizationID); err != nil {
+			return err
+		}
+	}
+	return q.Enqueue(job)
+}
+
+// checkRunQuota rejects work of an organization while it has quota runs waiting or running
+// in the job queue; a quota of 0 admits any number. Runs must be started while holding
+// runQuotaMu for the check to hold, see enqueueWithinQuota.
+func checkRunQuota(q queue.Queue, quota int, organizationID uint) error {
+	if quota <= 0 {
+		return nil
+	}
+	active := 0
+	for _, status := range []string{models.JobStatusPending, models.JobStatusLeased} {
+		jobs, err := q.List(queue.Filter{OrganizationID: organizationID, Status: status, Limit: quota})
+		if err != nil {
+			return err
+		}
+		active += len(jobs)
+	}
+	if active >= quota {
+		return fmt.Errorf("%w: at most %d runs may be pending or running at once", ErrQuotaExceeded, quota)
+	}
+	return nil
+}
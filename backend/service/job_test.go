@@ -0,0 +1,52 @@
+package service_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowListQueue takes its time to list jobs, as a database would, so that submissions checking
+// the quota at the same time overlap
+type slowListQueue struct {
+	*MockJobQueue
+}
+
+func (q slowListQueue) List(filter queue.Filter) ([]models.Job, error) {
+	jobs, err := q.MockJobQueue.List(filter)
+	time.Sleep(time.Millisecond)
+	return jobs, err
+}
+
+func TestSubmitRunQuotaHoldsForConcurrentSubmissions(t *testing.T) {
+	jobs := &MockJobQueue{}
+	s := service.NewJobService(slowListQueue{jobs}, &MockMachineRepository{}, 3)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	admitted, rejected := 0, 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.SubmitRun(1, service.RunRequest{})
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				admitted++
+			} else if assert.ErrorIs(t, err, service.ErrQuotaExceeded) {
+				rejected++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, admitted, "Concurrent submissions cannot all pass the quota check")
+	assert.Equal(t, 17, rejected)
+	assert.Len(t, jobs.Jobs, 3)
+}
@@ -1,43 +1,79 @@
 package service
 
 import (
+	"context"
 	"errors"
 
 	"github.com/CBYeuler/automation-backend/backend/models"
 	"github.com/CBYeuler/automation-backend/backend/repository"
+	"gorm.io/gorm"
 )
 
+// ErrMachineNotFound is returned when an operation targets a machine that does not exist
+var ErrMachineNotFound = errors.New("machine not found")
+
 type MachineService interface {
 	CreateMachine(machine models.Machine) (models.Machine, error)
 	GetAllMachines() ([]models.Machine, error)
 	GetMachineByID(id uint) (models.Machine, error)
 	UpdateMachine(id uint, updatedData models.Machine) (models.Machine, error)
 	DeleteMachine(id uint) error
+	RunCommand(cmd BulkCommand) (BulkCommandResult, error)
+
+	// ForTenant returns the service limited to the machines of one organization, which new
+	// machines are created in; 0 is every organization
+	ForTenant(organizationID uint) MachineService
+	// WithContext returns the service working for a request: its records, and the queries of
+	// its repositories, are logged with the request's ID
+	WithContext(ctx context.Context) MachineService
+}
+
+// MachineListener is notified after a machine is changed through the service,
+// e.g. so the simulator can stop a machine's run as soon as it is set Offline or deleted.
+type MachineListener interface {
+	MachineUpdated(machine models.Machine)
+	MachineDeleted(id uint)
 }
 
 type MachineServiceImpl struct {
-	Repo repository.MachineRepository
+	Repo      repository.MachineRepository
+	Groups    repository.TopologyRepository // resolves subgroups for bulk commands; optional
+	Listeners []MachineListener
+	Ctx       context.Context // the request the service works for; nil outside of requests
 }
 
-func NewMachineService(repo repository.MachineRepository) MachineService {
-	return &MachineServiceImpl{Repo: repo}
+func NewMachineService(repo repository.MachineRepository, listeners ...MachineListener) MachineService {
+	return &MachineServiceImpl{Repo: repo, Listeners: listeners}
 }
 
 // --- Implementation of the Interface Methods ---
-func (s *MachineServiceImpl) CreateMachine(machine models.Machine) (models.Machine, error) {
+func (s *MachineServiceImpl) CreateMachine(machine models.Machine) (_ models.Machine, err error) {
+	s, end := s.call("CreateMachine")
+	defer end(&err)
 	if machine.Name == "" {
 		return models.Machine{}, errors.New("machine name cannot be empty")
 	}
-	err := s.Repo.Create(&machine)
+	machine.GroupID = nil // groups are assigned through PUT /api/v1/machines/:id/group, which validates them
+	if machine.Status == "" {
+		machine.Status = "Offline" // the column default, set here so the response carries it too
+	}
+	err = s.Repo.Create(&machine)
 	return machine, err
 }
 
-func (s *MachineServiceImpl) GetAllMachines() ([]models.Machine, error) {
+func (s *MachineServiceImpl) GetAllMachines() (_ []models.Machine, err error) {
+	s, end := s.call("GetAllMachines")
+	defer end(&err)
 	return s.Repo.FindAll()
 }
 
-func (s *MachineServiceImpl) GetMachineByID(id uint) (models.Machine, error) {
+func (s *MachineServiceImpl) GetMachineByID(id uint) (_ models.Machine, err error) {
+	s, end := s.call("GetMachineByID")
+	defer end(&err)
 	machine, err := s.Repo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Machine{}, ErrMachineNotFound
+	}
 	if err != nil {
 		return models.Machine{}, err
 	}
@@ -45,13 +81,15 @@ func (s *MachineServiceImpl) GetMachineByID(id uint) (models.Machine, error) {
 }
 
 // UpdateMachine handles updates, ensuring the ID is correct and exists.
-func (s *MachineServiceImpl) UpdateMachine(id uint, updatedMachine models.Machine) (models.Machine, error) {
+func (s *MachineServiceImpl) UpdateMachine(id uint, updatedMachine models.Machine) (_ models.Machine, err error) {
+	s, end := s.call("UpdateMachine")
+	defer end(&err)
 	//  Check if the machine exists (important for returning 404, not 500)
 
 	existingMachine, err := s.Repo.FindByID(id)
 	if err != nil {
 		// Assume gorm.ErrRecordNotFound translates here
-		return models.Machine{}, errors.New("machine not found")
+		return models.Machine{}, ErrMachineNotFound
 	}
 
 	// Enforce the ID from the path (URL parameter)
@@ -62,11 +100,55 @@ func (s *MachineServiceImpl) UpdateMachine(id uint, updatedMachine models.Machin
 	existingMachine.Name = updatedMachine.Name
 	existingMachine.Status = updatedMachine.Status
 	existingMachine.ConfigJSON = updatedMachine.ConfigJSON
+	existingMachine.Tags = updatedMachine.Tags
 	// Note: LastSimulated and SimulatedRuns should be updated by the Simulator, not the API here
 
 	err = s.Repo.Update(existingMachine) // Use the existingMachine pointer after updating its fields
+	if err == nil {
+		for _, l := range s.Listeners {
+			l.MachineUpdated(*existingMachine)
+		}
+	}
 	return *existingMachine, err
 }
-func (s *MachineServiceImpl) DeleteMachine(id uint) error {
-	return s.Repo.Delete(id)
+func (s *MachineServiceImpl) DeleteMachine(id uint) (err error) {
+	s, end := s.call("DeleteMachine")
+	defer end(&err)
+	// Listeners must not hear of machines of other organizations, which Delete silently skips
+	if _, err := s.Repo.FindByID(id); err != nil {
+		return ErrMachineNotFound
+	}
+	if err := s.Repo.Delete(id); err != nil {
+		return err
+	}
+	for _, l := range s.Listeners {
+		l.MachineDeleted(id)
+	}
+	return nil
+}
+
+func (s *MachineServiceImpl) ForTenant(organizationID uint) MachineService {
+	scoped := *s
+	scoped.Repo = s.Repo.ForTenant(organizationID)
+	if s.Groups != nil {
+		scoped.Groups = s.Groups.ForTenant(organizationID)
+	}
+	return &scoped
+}
+
+func (s *MachineServiceImpl) WithContext(ctx context.Context) MachineService {
+	scoped := *s
+	scoped.Ctx = ctx
+	scoped.Repo = s.Repo.WithContext(ctx)
+	if s.Groups != nil {
+		scoped.Groups = s.Groups.WithContext(ctx)
+	}
+	return &scoped
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *MachineServiceImpl) call(method string) (*MachineServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "MachineService."+method, s, func(ctx context.Context) *MachineServiceImpl {
+		return s.WithContext(ctx).(*MachineServiceImpl)
+	})
 }
@@ -1,11 +1,13 @@
 package service_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
 	"github.com/CBYeuler/automation-backend/backend/service"
 	"github.com/stretchr/testify/assert"
 )
@@ -16,6 +18,10 @@ import (
 type MockMachineRepository struct{}
 
 // Create implements the mock Create method
+// ForTenant implements the mock ForTenant method; every machine belongs to the default organization
+func (m *MockMachineRepository) ForTenant(uint) repository.MachineRepository              { return m }
+func (m *MockMachineRepository) WithContext(context.Context) repository.MachineRepository { return m }
+
 func (m *MockMachineRepository) Create(machine *models.Machine) error {
 	if machine.Name == "ErrorMachine" {
 		return errors.New("mock DB error: duplicate name")
@@ -46,9 +52,10 @@ func (m *MockMachineRepository) FindByID(id uint) (*models.Machine, error) {
 	}
 	// Corrected: Fully qualify the nested struct: models.Model{...}
 	return &models.Machine{
-		Model:  models.Model{ID: id},
-		Name:   "TestMachine",
-		Status: "Idle",
+		Model:          models.Model{ID: id},
+		OrganizationID: models.DefaultOrganizationID,
+		Name:           "TestMachine",
+		Status:         "Idle",
 	}, nil
 }
 
@@ -60,6 +67,26 @@ func (m *MockMachineRepository) Update(machine *models.Machine) error {
 	return nil
 }
 
+// UpdateStatus implements the mock UpdateStatus method
+func (m *MockMachineRepository) UpdateStatus(ids []uint, status string) error {
+	return nil
+}
+
+// Transaction implements the mock Transaction method
+func (m *MockMachineRepository) Transaction(fn func(repository.MachineRepository) error) error {
+	return fn(m)
+}
+
+// SetStatusIf implements the mock SetStatusIf method
+func (m *MockMachineRepository) SetStatusIf(id uint, status string, from ...string) (bool, error) {
+	return true, nil
+}
+
+// CountByStatus implements the mock CountByStatus method
+func (m *MockMachineRepository) CountByStatus() (map[string]int64, error) {
+	return map[string]int64{"Running": 1}, nil
+}
+
 // Delete implements the mock Delete method
 func (m *MockMachineRepository) Delete(id uint) error {
 	if id == 0 {
@@ -82,6 +109,14 @@ func TestCreateMachineSuccess(t *testing.T) {
 	assert.Equal(t, uint(1), createdMachine.ID, "Machine ID should be set by the mock repository")
 }
 
+func TestCreateMachineDefaultsToOffline(t *testing.T) {
+	machineService := service.NewMachineService(&MockMachineRepository{})
+
+	createdMachine, err := machineService.CreateMachine(models.Machine{Name: "NewMachine"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Offline", createdMachine.Status)
+}
+
 func TestCreateMachineValidationFailure(t *testing.T) {
 	mockRepo := &MockMachineRepository{}
 	machineService := service.NewMachineService(mockRepo)
@@ -146,3 +181,30 @@ func TestDeleteMachineSuccess(t *testing.T) {
 
 	assert.Nil(t, err, "Error should be nil for successful delete")
 }
+
+// RecordingListener records the machine changes the service reports
+type RecordingListener struct {
+	Updated []uint
+	Deleted []uint
+}
+
+func (l *RecordingListener) MachineUpdated(machine models.Machine) {
+	l.Updated = append(l.Updated, machine.ID)
+}
+func (l *RecordingListener) MachineDeleted(id uint) { l.Deleted = append(l.Deleted, id) }
+
+func TestMachineListenersNotified(t *testing.T) {
+	listener := &RecordingListener{}
+	machineService := service.NewMachineService(&MockMachineRepository{}, listener)
+
+	_, err := machineService.UpdateMachine(10, models.Machine{Name: "UpdatedName", Status: "Offline"})
+	assert.Nil(t, err)
+	assert.Nil(t, machineService.DeleteMachine(1))
+
+	// Failed operations are not reported
+	_, _ = machineService.UpdateMachine(99, models.Machine{Name: "Missing"})
+	_ = machineService.DeleteMachine(0)
+
+	assert.Equal(t, []uint{10}, listener.Updated)
+	assert.Equal(t, []uint{1}, listener.Deleted)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+)
+
+var (
+	// ErrOrganizationNotFound is returned when an organization name does not exist
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrOrganizationExists is returned when creating an organization with a name already in use
+	ErrOrganizationExists = errors.New("organization already exists")
+	// ErrInvalidOrganization is returned when an organization is rejected
+	ErrInvalidOrganization = errors.New("invalid organization")
+)
+
+type OrganizationService interface {
+	CreateOrganization(org models.Organization) (models.Organization, error)
+	GetOrganizations() ([]models.Organization, error)
+	GetOrganizationByName(name string) (models.Organization, error)
+	WithContext(ctx context.Context) OrganizationService
+}
+
+type OrganizationServiceImpl struct {
+	Repo repository.OrganizationRepository
+	Ctx  context.Context
+}
+
+func NewOrganizationService(repo repository.OrganizationRepository) OrganizationService {
+	return &OrganizationServiceImpl{Repo: repo}
+}
+
+// --- Implementation of the Interface Methods ---
+
+// CreateOrganization adds a tenant. Names are what JWT organization claims carry, so they
+// cannot contain whitespace.
+func (s *OrganizationServiceImpl) CreateOrganization(org models.Organization) (_ models.Organization, err error) {
+	s, end := s.call("CreateOrganization")
+	defer end(&err)
+	if org.Name == "" || strings.ContainsFunc(org.Name, unicode.IsSpace) {
+		return models.Organization{}, fmt.Errorf("%w: name must be non-empty and contain no whitespace", ErrInvalidOrganization)
+	}
+	if _, err := s.Repo.FindByName(org.Name); err == nil {
+		return models.Organization{}, ErrOrganizationExists
+	}
+
+	org.ID = 0
+	if err := s.Repo.Create(&org); err != nil {
+		return models.Organization{}, err
+	}
+	slog.InfoContext(s.Ctx, "Organization created", "organization_id", org.ID, "organization", org.Name)
+	return org, nil
+}
+
+func (s *OrganizationServiceImpl) GetOrganizations() (_ []models.Organization, err error) {
+	s, end := s.call("GetOrganizations")
+	defer end(&err)
+	return s.Repo.FindAll()
+}
+
+func (s *OrganizationServiceImpl) GetOrganizationByName(name string) (_ models.Organization, err error) {
+	s, end := s.call("GetOrganizationByName")
+	defer end(&err)
+	org, err := s.Repo.FindByName(name)
+	if err != nil {
+		return models.Organization{}, ErrOrganizationNotFound
+	}
+	return *org, nil
+}
+
+func (s *OrganizationServiceImpl) WithContext(ctx context.Context) OrganizationService {
+	return &OrganizationServiceImpl{Repo: s.Repo.WithContext(ctx), Ctx: ctx}
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *OrganizationServiceImpl) call(method string) (*OrganizationServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "OrganizationService."+method, s, func(ctx context.Context) *OrganizationServiceImpl {
+		return s.WithContext(ctx).(*OrganizationServiceImpl)
+	})
+}
@@ -0,0 +1,65 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// MockOrganizationRepository keeps organizations in memory, starting with the default one
+type MockOrganizationRepository struct {
+	orgs []models.Organization
+	err  error // returned by lookups when set, e.g. to fail like an unreachable database
+}
+
+func NewMockOrganizationRepository() *MockOrganizationRepository {
+	return &MockOrganizationRepository{orgs: []models.Organization{
+		{Model: models.Model{ID: models.DefaultOrganizationID}, Name: models.DefaultOrganizationName},
+	}}
+}
+
+func (m *MockOrganizationRepository) WithContext(context.Context) repository.OrganizationRepository {
+	return m
+}
+
+func (m *MockOrganizationRepository) Create(org *models.Organization) error {
+	org.ID = uint(len(m.orgs) + 1)
+	m.orgs = append(m.orgs, *org)
+	return nil
+}
+func (m *MockOrganizationRepository) FindAll() ([]models.Organization, error) { return m.orgs, nil }
+func (m *MockOrganizationRepository) FindByName(name string) (*models.Organization, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, org := range m.orgs {
+		if org.Name == name {
+			return &org, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestCreateOrganization(t *testing.T) {
+	orgs := service.NewOrganizationService(NewMockOrganizationRepository())
+
+	org, err := orgs.CreateOrganization(models.Organization{Name: "acme"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), org.ID)
+
+	_, err = orgs.CreateOrganization(models.Organization{Name: "acme"})
+	assert.ErrorIs(t, err, service.ErrOrganizationExists)
+	_, err = orgs.CreateOrganization(models.Organization{Name: "acme corp"})
+	assert.ErrorIs(t, err, service.ErrInvalidOrganization, "Names are JWT claim values, so cannot contain spaces")
+
+	found, err := orgs.GetOrganizationByName("acme")
+	assert.Nil(t, err)
+	assert.Equal(t, org.ID, found.ID)
+	_, err = orgs.GetOrganizationByName("globex")
+	assert.ErrorIs(t, err, service.ErrOrganizationNotFound)
+}
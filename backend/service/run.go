@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+)
+
+// DefaultRunListLimit is how many runs are listed per machine when no limit is given
+const DefaultRunListLimit = 50
+
+// ErrRunNotFound is returned when a simulation run ID does not exist
+var ErrRunNotFound = errors.New("run not found")
+
+type RunService interface {
+	GetRun(id uint) (models.SimulationRun, error)
+	GetMachineRuns(machineID uint, limit int) ([]models.SimulationRun, error)
+
+	// ForTenant returns the service limited to the runs of one organization; 0 is every organization
+	ForTenant(organizationID uint) RunService
+	WithContext(ctx context.Context) RunService
+}
+
+type RunServiceImpl struct {
+	Repo     repository.RunRepository
+	Machines repository.MachineRepository
+	Tenant   uint            // the organization whose runs are visible, 0 for all
+	Ctx      context.Context // the request the service works for; nil outside of requests
+}
+
+func NewRunService(repo repository.RunRepository, machines repository.MachineRepository) RunService {
+	return &RunServiceImpl{Repo: repo, Machines: machines}
+}
+
+// --- Implementation of the Interface Methods ---
+func (s *RunServiceImpl) GetRun(id uint) (_ models.SimulationRun, err error) {
+	s, end := s.call("GetRun")
+	defer end(&err)
+	run, err := s.Repo.FindByID(id)
+	if err != nil || !visible(s.Tenant, run.OrganizationID) {
+		return models.SimulationRun{}, ErrRunNotFound
+	}
+	return *run, nil
+}
+
+// GetMachineRuns lists the most recent runs of a machine, newest first
+func (s *RunServiceImpl) GetMachineRuns(machineID uint, limit int) (_ []models.SimulationRun, err error) {
+	s, end := s.call("GetMachineRuns")
+	defer end(&err)
+	if _, err := s.Machines.FindByID(machineID); err != nil {
+		return nil, ErrMachineNotFound
+	}
+	if limit <= 0 {
+		limit = DefaultRunListLimit
+	}
+	return s.Repo.FindByMachine(machineID, limit)
+}
+
+func (s *RunServiceImpl) ForTenant(organizationID uint) RunService {
+	return &RunServiceImpl{Repo: s.Repo, Machines: s.Machines.ForTenant(organizationID), Tenant: organizationID, Ctx: s.Ctx}
+}
+
+func (s *RunServiceImpl) WithContext(ctx context.Context) RunService {
+	return &RunServiceImpl{Repo: s.Repo.WithContext(ctx), Machines: s.Machines.WithContext(ctx), Tenant: s.Tenant, Ctx: ctx}
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *RunServiceImpl) call(method string) (*RunServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "RunService."+method, s, func(ctx context.Context) *RunServiceImpl {
+		return s.WithContext(ctx).(*RunServiceImpl)
+	})
+}
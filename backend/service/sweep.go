@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Sweep modes
+const (
+	SweepGrid   = "grid"   // every combination of the listed parameter values
+	SweepList   = "list"   // an explicit list of points
+	SweepRandom = "random" // points sampled from parameter ranges
+)
+
+// MaxBatchPoints caps how many runs a single batch may expand into
+const MaxBatchPoints = 10000
+
+// MaxSweepInteger bounds integer ranges. Within it float64 holds every whole number exactly
+// and the width of a range fits an int64.
+const MaxSweepInteger = 1 << 53
+
+// SweepSpec describes how a batch expands into points. Each point is a JSON object whose
+// top-level keys override the same keys of the machine's ConfigJSON for that run.
+type SweepSpec struct {
+	Mode string `json:"mode"`
+
+	// Grid: values per parameter, e.g. {"load": [0.5, 1.0], "speed": [1, 2, 3]} gives 6 points
+	Parameters map[string][]json.RawMessage `json:"parameters,omitempty"`
+	// List: the points themselves
+	Points []map[string]json.RawMessage `json:"points,omitempty"`
+	// Random: Samples points drawn from Ranges; Seed makes the sampling reproducible
+	Ranges  map[string]SweepRange `json:"ranges,omitempty"`
+	Samples int                   `json:"samples,omitempty"`
+	Seed    int64                 `json:"seed,omitempty"`
+}
+
+// SweepRange is the distribution of one parameter in a random sweep: either a uniform
+// numeric range [Min, Max] (whole numbers only when Integer is set) or a choice among Values.
+type SweepRange struct {
+	Min     *float64          `json:"min,omitempty"`
+	Max     *float64          `json:"max,omitempty"`
+	Integer bool              `json:"integer,omitempty"`
+	Values  []json.RawMessage `json:"values,omitempty"`
+}
+
+// ExpandSweep turns a sweep spec into the params of each point, in a deterministic order.
+// A random sweep without a seed gets one, so the stored spec can reproduce the points.
+func ExpandSweep(spec *SweepSpec) ([]string, error) {
+	var points []map[string]json.RawMessage
+	switch spec.Mode {
+	case SweepGrid:
+		if len(spec.Parameters) == 0 {
+			return nil, fmt.Errorf("%w: a grid sweep needs parameters", ErrInvalidBatch)
+		}
+		total := 1
+		for name, values := range spec.Parameters {
+			if len(values) == 0 {
+				return nil, fmt.Errorf("%w: parameter %q has no values", ErrInvalidBatch, name)
+			}
+			total *= len(values)
+			if total > MaxBatchPoints {
+				return nil, fmt.Errorf("%w: the grid has more than %d points", ErrInvalidBatch, MaxBatchPoints)
+			}
+		}
+		points = expandGrid(spec.Parameters)
+
+	case SweepList:
+		if len(spec.Points) == 0 {
+			return nil, fmt.Errorf("%w: a list sweep needs points", ErrInvalidBatch)
+		}
+		points = spec.Points
+
+	case SweepRandom:
+		if len(spec.Ranges) == 0 {
+			return nil, fmt.Errorf("%w: a random sweep needs ranges", ErrInvalidBatch)
+		}
+		if spec.Samples <= 0 || spec.Samples > MaxBatchPoints {
+			return nil, fmt.Errorf("%w: samples must be between 1 and %d", ErrInvalidBatch, MaxBatchPoints)
+		}
+		for name, r := range spec.Ranges {
+			numeric := r.Min != nil && r.Max != nil
+			if numeric == (len(r.Values) > 0) {
+				return nil, fmt.Errorf("%w: range %q needs either min and max or values", ErrInvalidBatch, name)
+			}
+			if numeric && *r.Min > *r.Max {
+				return nil, fmt.Errorf("%w: range %q has min above max", ErrInvalidBatch, name)
+			}
+			if numeric && r.Integer && (*r.Min < -MaxSweepInteger || *r.Max > MaxSweepInteger) {
+				return nil, fmt.Errorf("%w: integer range %q must lie within ±%d", ErrInvalidBatch, name, int64(MaxSweepInteger))
+			}
+			if numeric && r.Integer && math.Ceil(*r.Min) > math.Floor(*r.Max) {
+				return nil, fmt.Errorf("%w: integer range %q holds no whole number", ErrInvalidBatch, name)
+			}
+		}
+		if spec.Seed == 0 {
+			spec.Seed = time.Now().UnixNano()
+		}
+		points = sampleRanges(spec.Ranges, spec.Samples, rand.New(rand.NewSource(spec.Seed)))
+
+	default:
+		return nil, fmt.Errorf("%w: mode must be %q, %q or %q", ErrInvalidBatch, SweepGrid, SweepList, SweepRandom)
+	}
+
+	if len(points) > MaxBatchPoints {
+		return nil, fmt.Errorf("%w: a batch may have at most %d points", ErrInvalidBatch, MaxBatchPoints)
+	}
+
+	params := make([]string, len(points))
+	for i, point := range points {
+		out, err := json.Marshal(point)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBatch, err)
+		}
+		params[i] = string(out)
+	}
+	return params, nil
+}
+
+// sortedKeys returns the parameter names in a stable order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// expandGrid builds the cartesian product of the parameter values; the last parameter varies fastest
+func expandGrid(parameters map[string][]json.RawMessage) []map[string]json.RawMessage {
+	points := []map[string]json.RawMessage{{}}
+	for _, name := range sortedKeys(parameters) {
+		next := make([]map[string]json.RawMessage, 0, len(points)*len(parameters[name]))
+		for _, point := range points {
+			for _, value := range parameters[name] {
+				p := make(map[string]json.RawMessage, len(point)+1)
+				for k, v := range point {
+					p[k] = v
+				}
+				p[name] = value
+				next = append(next, p)
+			}
+		}
+		points = next
+	}
+	return points
+}
+
+// sampleRanges draws n points from the ranges
+func sampleRanges(ranges map[string]SweepRange, n int, rng *rand.Rand) []map[string]json.RawMessage {
+	names := sortedKeys(ranges)
+	points := make([]map[string]json.RawMessage, n)
+	for i := range points {
+		point := make(map[string]json.RawMessage, len(names))
+		for _, name := range names {
+			r := ranges[name]
+			if len(r.Values) > 0 {
+				point[name] = r.Values[rng.Intn(len(r.Values))]
+				continue
+			}
+			if r.Integer {
+				// Fractional bounds are rounded inwards, so samples never leave the range
+				lo, hi := int64(math.Ceil(*r.Min)), int64(math.Floor(*r.Max))
+				point[name], _ = json.Marshal(lo + rng.Int63n(hi-lo+1))
+			} else {
+				point[name], _ = json.Marshal(*r.Min + rng.Float64()*(*r.Max-*r.Min))
+			}
+		}
+		points[i] = point
+	}
+	return points
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/repository"
+)
+
+const (
+	// DefaultTelemetryWindow is the time range returned when a query does not specify one
+	DefaultTelemetryWindow = time.Hour
+	// TargetTelemetryBuckets is the number of buckets per series used when no bucket size is requested
+	TargetTelemetryBuckets = 500
+	// MaxTelemetryBuckets caps the number of points per series a single query may return
+	MaxTelemetryBuckets = 5000
+)
+
+// ErrInvalidTelemetryQuery is returned when a telemetry query has an unusable time range or bucket size
+var ErrInvalidTelemetryQuery = errors.New("invalid telemetry query")
+
+// TelemetryQuery selects and downsamples the telemetry of a single machine.
+// Zero values mean "all metrics", "the last hour" and "pick a bucket size automatically".
+type TelemetryQuery struct {
+	Metrics []string
+	From    time.Time
+	To      time.Time
+	Bucket  time.Duration
+}
+
+// TelemetryPoint aggregates the samples of one metric that fall into a single time bucket
+type TelemetryPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Avg       float64   `json:"avg"`
+	Count     int       `json:"count"`
+}
+
+// TelemetrySeries is the downsampled data of one metric
+type TelemetrySeries struct {
+	Metric string           `json:"metric"`
+	Points []TelemetryPoint `json:"points"`
+}
+
+// TelemetryResult is the response of a telemetry query
+type TelemetryResult struct {
+	MachineID uint              `json:"machine_id"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Bucket    string            `json:"bucket"`
+	Series    []TelemetrySeries `json:"series"`
+}
+
+type TelemetryService interface {
+	GetTelemetry(machineID uint, query TelemetryQuery) (TelemetryResult, error)
+	PurgeOlderThan(maxAge time.Duration) (int64, error)
+	StartRetention(maxAge, interval time.Duration)
+
+	// ForTenant returns the service limited to the machines of one organization; 0 is every organization
+	ForTenant(organizationID uint) TelemetryService
+	WithContext(ctx context.Context) TelemetryService
+}
+
+type TelemetryServiceImpl struct {
+	Repo     repository.TelemetryRepository
+	Machines repository.MachineRepository
+	Ctx      context.Context // the request the service works for; nil outside of requests
+}
+
+func NewTelemetryService(repo repository.TelemetryRepository, machines repository.MachineRepository) TelemetryService {
+	return &TelemetryServiceImpl{Repo: repo, Machines: machines}
+}
+
+// --- Implementation of the Interface Methods ---
+
+// GetTelemetry returns min/max/avg per time bucket for the selected metrics of a machine. The
+// database aggregates the samples, so the bucket count bounds the work of a query, not the range.
+func (s *TelemetryServiceImpl) GetTelemetry(machineID uint, query TelemetryQuery) (_ TelemetryResult, err error) {
+	s, end := s.call("GetTelemetry")
+	defer end(&err)
+	if _, err := s.Machines.FindByID(machineID); err != nil {
+		return TelemetryResult{}, ErrMachineNotFound
+	}
+
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-DefaultTelemetryWindow)
+	}
+	span := query.To.Sub(query.From)
+	if span <= 0 {
+		return TelemetryResult{}, fmt.Errorf("%w: 'from' must be before 'to'", ErrInvalidTelemetryQuery)
+	}
+
+	if query.Bucket == 0 {
+		// Round the automatic bucket up to a whole second so bucket boundaries stay readable
+		query.Bucket = (span/TargetTelemetryBuckets + time.Second - 1).Truncate(time.Second)
+		if query.Bucket < time.Second {
+			query.Bucket = time.Second
+		}
+	}
+	if query.Bucket < time.Millisecond {
+		return TelemetryResult{}, fmt.Errorf("%w: bucket must be at least 1ms", ErrInvalidTelemetryQuery)
+	}
+	if span/query.Bucket > MaxTelemetryBuckets {
+		return TelemetryResult{}, fmt.Errorf("%w: bucket %s yields more than %d points", ErrInvalidTelemetryQuery, query.Bucket, MaxTelemetryBuckets)
+	}
+
+	buckets, err := s.Repo.Aggregate(machineID, query.Metrics, query.From, query.To, query.Bucket)
+	if err != nil {
+		return TelemetryResult{}, err
+	}
+
+	return TelemetryResult{
+		MachineID: machineID,
+		From:      query.From,
+		To:        query.To,
+		Bucket:    query.Bucket.String(),
+		Series:    telemetrySeries(buckets),
+	}, nil
+}
+
+// telemetrySeries splits buckets (ordered by metric, then time) into one series per metric
+func telemetrySeries(buckets []repository.TelemetryBucket) []TelemetrySeries {
+	series := []TelemetrySeries{}
+	for _, b := range buckets {
+		if len(series) == 0 || series[len(series)-1].Metric != b.Metric {
+			series = append(series, TelemetrySeries{Metric: b.Metric, Points: []TelemetryPoint{}})
+		}
+		current := &series[len(series)-1]
+		current.Points = append(current.Points, TelemetryPoint{
+			Timestamp: time.UnixMilli(b.Start).UTC(),
+			Min:       b.Min,
+			Max:       b.Max,
+			Avg:       b.Avg,
+			Count:     b.Count,
+		})
+	}
+	return series
+}
+
+// PurgeOlderThan deletes telemetry older than maxAge
+func (s *TelemetryServiceImpl) PurgeOlderThan(maxAge time.Duration) (_ int64, err error) {
+	s, end := s.call("PurgeOlderThan")
+	defer end(&err)
+	return s.Repo.DeleteOlderThan(time.Now().Add(-maxAge))
+}
+
+func (s *TelemetryServiceImpl) ForTenant(organizationID uint) TelemetryService {
+	return &TelemetryServiceImpl{Repo: s.Repo, Machines: s.Machines.ForTenant(organizationID), Ctx: s.Ctx}
+}
+
+func (s *TelemetryServiceImpl) WithContext(ctx context.Context) TelemetryService {
+	return &TelemetryServiceImpl{Repo: s.Repo.WithContext(ctx), Machines: s.Machines.WithContext(ctx), Ctx: ctx}
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *TelemetryServiceImpl) call(method string) (*TelemetryServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "TelemetryService."+method, s, func(ctx context.Context) *TelemetryServiceImpl {
+		return s.WithContext(ctx).(*TelemetryServiceImpl)
+	})
+}
+
+// StartRetention periodically purges telemetry older than maxAge in the background.
+func (s *TelemetryServiceImpl) StartRetention(maxAge, interval time.Duration) {
+	slog.Info("Starting telemetry retention", "max_age", maxAge, "interval", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			deleted, err := s.PurgeOlderThan(maxAge)
+			if err != nil {
+				slog.Error("Failed to purge telemetry", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Info("Purged old telemetry samples", "deleted", deleted, "max_age", maxAge)
+			}
+		}
+	}()
+}
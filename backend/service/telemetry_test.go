@@ -0,0 +1,105 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockTelemetryRepository returns a fixed set of samples and buckets and records retention calls
+type MockTelemetryRepository struct {
+	Samples []models.TelemetrySample
+	Buckets []repository.TelemetryBucket
+	Bucket  time.Duration
+	Cutoff  time.Time
+}
+
+func (m *MockTelemetryRepository) WithContext(context.Context) repository.TelemetryRepository {
+	return m
+}
+
+func (m *MockTelemetryRepository) Append(samples []models.TelemetrySample) error {
+	m.Samples = append(m.Samples, samples...)
+	return nil
+}
+
+func (m *MockTelemetryRepository) Query(machineID uint, metrics []string, from, to time.Time) ([]models.TelemetrySample, error) {
+	return m.Samples, nil
+}
+
+func (m *MockTelemetryRepository) Aggregate(machineID uint, metrics []string, from, to time.Time, bucket time.Duration) ([]repository.TelemetryBucket, error) {
+	m.Bucket = bucket
+	return m.Buckets, nil
+}
+
+func (m *MockTelemetryRepository) DeleteOlderThan(cutoff time.Time) (int64, error) {
+	m.Cutoff = cutoff
+	return 3, nil
+}
+
+func TestGetTelemetryDownsampling(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := &MockTelemetryRepository{Buckets: []repository.TelemetryBucket{
+		{Metric: "power", Start: base.UnixMilli(), Min: 1, Max: 3, Avg: 2, Count: 2},
+		{Metric: "power", Start: base.Add(time.Minute).UnixMilli(), Min: 8, Max: 8, Avg: 8, Count: 1},
+		{Metric: "temperature", Start: base.UnixMilli(), Min: 60, Max: 60, Avg: 60, Count: 1},
+	}}
+	telemetryService := service.NewTelemetryService(repo, &MockMachineRepository{})
+
+	result, err := telemetryService.GetTelemetry(1, service.TelemetryQuery{From: base, To: base.Add(5 * time.Minute), Bucket: time.Minute})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "1m0s", result.Bucket)
+	assert.Equal(t, time.Minute, repo.Bucket, "The repository aggregates into the requested buckets")
+	assert.Len(t, result.Series, 2, "One series per metric")
+
+	power := result.Series[0]
+	assert.Equal(t, "power", power.Metric)
+	assert.Len(t, power.Points, 2, "Buckets should be grouped per metric")
+	assert.Equal(t, service.TelemetryPoint{Timestamp: base, Min: 1, Max: 3, Avg: 2, Count: 2}, power.Points[0])
+	assert.Equal(t, service.TelemetryPoint{Timestamp: base.Add(time.Minute), Min: 8, Max: 8, Avg: 8, Count: 1}, power.Points[1])
+
+	assert.Equal(t, "temperature", result.Series[1].Metric)
+	assert.Len(t, result.Series[1].Points, 1)
+}
+
+func TestGetTelemetryDefaultsAndValidation(t *testing.T) {
+	telemetryService := service.NewTelemetryService(&MockTelemetryRepository{}, &MockMachineRepository{})
+
+	// Without a range the last hour is returned, split into automatically sized buckets
+	result, err := telemetryService.GetTelemetry(1, service.TelemetryQuery{})
+	assert.Nil(t, err)
+	assert.Equal(t, service.DefaultTelemetryWindow, result.To.Sub(result.From))
+	assert.Equal(t, "8s", result.Bucket, "One hour over 500 buckets rounds up to 8s")
+	assert.NotNil(t, result.Series, "An empty result should still be a list")
+
+	now := time.Now()
+	_, err = telemetryService.GetTelemetry(1, service.TelemetryQuery{From: now, To: now.Add(-time.Minute)})
+	assert.True(t, errors.Is(err, service.ErrInvalidTelemetryQuery), "Inverted ranges should be rejected")
+
+	_, err = telemetryService.GetTelemetry(1, service.TelemetryQuery{From: now.Add(-24 * time.Hour), To: now, Bucket: time.Second})
+	assert.True(t, errors.Is(err, service.ErrInvalidTelemetryQuery), "Too many buckets should be rejected")
+
+	_, err = telemetryService.GetTelemetry(1, service.TelemetryQuery{From: now.Add(-time.Second), To: now, Bucket: time.Microsecond})
+	assert.True(t, errors.Is(err, service.ErrInvalidTelemetryQuery), "Buckets under a millisecond should be rejected")
+
+	_, err = telemetryService.GetTelemetry(99, service.TelemetryQuery{})
+	assert.True(t, errors.Is(err, service.ErrMachineNotFound), "Unknown machines should be reported")
+}
+
+func TestPurgeTelemetry(t *testing.T) {
+	repo := &MockTelemetryRepository{}
+	telemetryService := service.NewTelemetryService(repo, &MockMachineRepository{})
+
+	deleted, err := telemetryService.PurgeOlderThan(24 * time.Hour)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), repo.Cutoff, time.Second)
+}
@@ -0,0 +1,18 @@
+package service
+
+import "github.com/CBYeuler/automation-backend/backend/models"
+
+// visible reports whether a record of organizationID may be seen by a service bound to
+// tenant. Services bound to tenant 0, as used by background work and unauthenticated
+// requests, see every organization.
+func visible(tenant, organizationID uint) bool {
+	return tenant == 0 || tenant == organizationID
+}
+
+// owner is the organization new records created by a service bound to tenant belong to
+func owner(tenant uint) uint {
+	if tenant == 0 {
+		return models.DefaultOrganizationID
+	}
+	return tenant
+}
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+)
+
+var (
+	// ErrGroupNotFound is returned when a machine group ID does not exist
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrGroupNotEmpty is returned when deleting a group that still has subgroups or machines
+	ErrGroupNotEmpty = errors.New("group is not empty")
+	// ErrLinkNotFound is returned when a machine link ID does not exist
+	ErrLinkNotFound = errors.New("link not found")
+	// ErrLinkExists is returned when linking two machines that are already linked
+	ErrLinkExists = errors.New("link already exists")
+	// ErrInvalidTopology is returned when a group or link is rejected
+	ErrInvalidTopology = errors.New("invalid topology")
+)
+
+// parentKinds maps each group kind to the kind of group it must be placed in ("" for none)
+var parentKinds = map[string]string{
+	models.GroupKindSite: "",
+	models.GroupKindLine: models.GroupKindSite,
+	models.GroupKindCell: models.GroupKindLine,
+}
+
+// TopologyListener is notified after groups or links change, e.g. so the simulator can
+// recompute which machines are starved
+type TopologyListener interface {
+	TopologyChanged()
+}
+
+// StarvationReporter reports the machines starved by a machine in Error upstream of them.
+// It is implemented by the simulator.
+type StarvationReporter interface {
+	Starved() map[uint]uint
+}
+
+// GroupNode is a group in the topology graph with its subgroups and machines
+type GroupNode struct {
+	models.MachineGroup
+	Children []GroupNode `json:"children"`
+	Machines []uint      `json:"machines"`
+}
+
+// MachineNode is a machine in the topology graph
+type MachineNode struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	GroupID   *uint  `json:"group_id"`
+	StarvedBy *uint  `json:"starved_by,omitempty"` // the machine in Error cutting off this machine's supply
+}
+
+// TopologyGraph is the plant hierarchy with the machines and the links between them
+type TopologyGraph struct {
+	Groups   []GroupNode          `json:"groups"`
+	Machines []MachineNode        `json:"machines"`
+	Links    []models.MachineLink `json:"links"`
+}
+
+type TopologyService interface {
+	CreateGroup(group models.MachineGroup) (models.MachineGroup, error)
+	GetGroups() ([]models.MachineGroup, error)
+	GetGroupByID(id uint) (models.MachineGroup, error)
+	DeleteGroup(id uint) error
+	SetMachineGroup(machineID uint, groupID *uint) (models.Machine, error)
+	CreateLink(link models.MachineLink) (models.MachineLink, error)
+	GetLinks() ([]models.MachineLink, error)
+	DeleteLink(id uint) error
+	GetTopology(groupID *uint) (TopologyGraph, error)
+	MachineListener // links of deleted machines are removed
+
+	// ForTenant returns the service limited to the groups, links and machines of one
+	// organization; 0 is every organization
+	ForTenant(organizationID uint) TopologyService
+	WithContext(ctx context.Context) TopologyService
+}
+
+type TopologyServiceImpl struct {
+	Repo       repository.TopologyRepository
+	Machines   repository.MachineRepository
+	Starvation StarvationReporter // fills in MachineNode.StarvedBy; may be nil
+	Listeners  []TopologyListener
+	Ctx        context.Context // the request the service works for; nil outside of requests
+}
+
+func NewTopologyService(repo repository.TopologyRepository, machines repository.MachineRepository, starvation StarvationReporter, listeners ...TopologyListener) TopologyService {
+	return &TopologyServiceImpl{Repo: repo, Machines: machines, Starvation: starvation, Listeners: listeners}
+}
+
+// --- Implementation of the Interface Methods ---
+
+// CreateGroup places a site at the top level, a line in a site and a cell in a line.
+// Names are unique among the groups sharing a parent.
+func (s *TopologyServiceImpl) CreateGroup(group models.MachineGroup) (_ models.MachineGroup, err error) {
+	s, end := s.call("CreateGroup")
+	defer end(&err)
+	if group.Name == "" {
+		return models.MachineGroup{}, fmt.Errorf("%w: group name cannot be empty", ErrInvalidTopology)
+	}
+	parentKind, ok := parentKinds[group.Kind]
+	if !ok {
+		return models.MachineGroup{}, fmt.Errorf("%w: group kind must be %q, %q or %q", ErrInvalidTopology, models.GroupKindSite, models.GroupKindLine, models.GroupKindCell)
+	}
+	switch {
+	case parentKind == "" && group.ParentID != nil:
+		return models.MachineGroup{}, fmt.Errorf("%w: a %s cannot have a parent", ErrInvalidTopology, group.Kind)
+	case parentKind != "" && group.ParentID == nil:
+		return models.MachineGroup{}, fmt.Errorf("%w: a %s needs a parent %s", ErrInvalidTopology, group.Kind, parentKind)
+	case parentKind != "":
+		parent, err := s.Repo.FindGroupByID(*group.ParentID)
+		if err != nil {
+			return models.MachineGroup{}, fmt.Errorf("%w: parent group %d not found", ErrInvalidTopology, *group.ParentID)
+		}
+		if parent.Kind != parentKind {
+			return models.MachineGroup{}, fmt.Errorf("%w: a %s must be placed in a %s, not a %s", ErrInvalidTopology, group.Kind, parentKind, parent.Kind)
+		}
+	}
+
+	groups, err := s.Repo.FindGroups()
+	if err != nil {
+		return models.MachineGroup{}, err
+	}
+	for _, g := range groups {
+		if g.Name == group.Name && sameParent(g.ParentID, group.ParentID) {
+			return models.MachineGroup{}, fmt.Errorf("%w: a group named %q already exists there", ErrInvalidTopology, group.Name)
+		}
+	}
+
+	group.ID = 0
+	if err := s.Repo.CreateGroup(&group); err != nil {
+		return models.MachineGroup{}, err
+	}
+	return group, nil
+}
+
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s *TopologyServiceImpl) GetGroups() (_ []models.MachineGroup, err error) {
+	s, end := s.call("GetGroups")
+	defer end(&err)
+	return s.Repo.FindGroups()
+}
+
+func (s *TopologyServiceImpl) GetGroupByID(id uint) (_ models.MachineGroup, err error) {
+	s, end := s.call("GetGroupByID")
+	defer end(&err)
+	group, err := s.Repo.FindGroupByID(id)
+	if err != nil {
+		return models.MachineGroup{}, ErrGroupNotFound
+	}
+	return *group, nil
+}
+
+// DeleteGroup removes an empty group; subgroups and machines must be removed or moved first
+func (s *TopologyServiceImpl) DeleteGroup(id uint) (err error) {
+	s, end := s.call("DeleteGroup")
+	defer end(&err)
+	if _, err := s.Repo.FindGroupByID(id); err != nil {
+		return ErrGroupNotFound
+	}
+	groups, err := s.Repo.FindGroups()
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.ParentID != nil && *g.ParentID == id {
+			return ErrGroupNotEmpty
+		}
+	}
+	machines, err := s.Machines.FindAll()
+	if err != nil {
+		return err
+	}
+	for _, m := range machines {
+		if m.GroupID != nil && *m.GroupID == id {
+			return ErrGroupNotEmpty
+		}
+	}
+	return s.Repo.DeleteGroup(id)
+}
+
+// SetMachineGroup moves a machine into a group, or out of any group for a nil groupID
+func (s *TopologyServiceImpl) SetMachineGroup(machineID uint, groupID *uint) (_ models.Machine, err error) {
+	s, end := s.call("SetMachineGroup")
+	defer end(&err)
+	if _, err := s.Machines.FindByID(machineID); err != nil {
+		return models.Machine{}, ErrMachineNotFound
+	}
+	if groupID != nil {
+		if _, err := s.Repo.FindGroupByID(*groupID); err != nil {
+			return models.Machine{}, ErrGroupNotFound
+		}
+	}
+	if err := s.Repo.SetMachineGroup(machineID, groupID); err != nil {
+		return models.Machine{}, err
+	}
+	machine, err := s.Machines.FindByID(machineID)
+	if err != nil {
+		return models.Machine{}, ErrMachineNotFound
+	}
+	return *machine, nil
+}
+
+// CreateLink records that the output of one machine feeds another
+func (s *TopologyServiceImpl) CreateLink(link models.MachineLink) (_ models.MachineLink, err error) {
+	s, end := s.call("CreateLink")
+	defer end(&err)
+	if link.FromMachineID == link.ToMachineID {
+		return models.MachineLink{}, fmt.Errorf("%w: a machine cannot feed itself", ErrInvalidTopology)
+	}
+	for _, id := range []uint{link.FromMachineID, link.ToMachineID} {
+		if _, err := s.Machines.FindByID(id); err != nil {
+			return models.MachineLink{}, fmt.Errorf("%w: machine %d not found", ErrInvalidTopology, id)
+		}
+	}
+	links, err := s.Repo.FindLinks()
+	if err != nil {
+		return models.MachineLink{}, err
+	}
+	for _, l := range links {
+		if l.FromMachineID == link.FromMachineID && l.ToMachineID == link.ToMachineID {
+			return models.MachineLink{}, ErrLinkExists
+		}
+	}
+
+	link.ID = 0
+	if err := s.Repo.CreateLink(&link); err != nil {
+		return models.MachineLink{}, err
+	}
+	s.notify()
+	return link, nil
+}
+
+func (s *TopologyServiceImpl) GetLinks() (_ []models.MachineLink, err error) {
+	s, end := s.call("GetLinks")
+	defer end(&err)
+	return s.Repo.FindLinks()
+}
+
+func (s *TopologyServiceImpl) DeleteLink(id uint) (err error) {
+	s, end := s.call("DeleteLink")
+	defer end(&err)
+	if _, err := s.Repo.FindLinkByID(id); err != nil {
+		return ErrLinkNotFound
+	}
+	if err := s.Repo.DeleteLink(id); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// GetTopology returns the whole plant, or only the given group with everything below it
+func (s *TopologyServiceImpl) GetTopology(groupID *uint) (_ TopologyGraph, err error) {
+	s, end := s.call("GetTopology")
+	defer end(&err)
+	groups, err := s.Repo.FindGroups()
+	if err != nil {
+		return TopologyGraph{}, err
+	}
+	machines, err := s.Machines.FindAll()
+	if err != nil {
+		return TopologyGraph{}, err
+	}
+	links, err := s.Repo.FindLinks()
+	if err != nil {
+		return TopologyGraph{}, err
+	}
+	starved := map[uint]uint{}
+	if s.Starvation != nil {
+		starved = s.Starvation.Starved()
+	}
+
+	children := make(map[uint][]models.MachineGroup)
+	var roots []models.MachineGroup
+	for _, g := range groups {
+		if g.ParentID == nil {
+			roots = append(roots, g)
+		} else {
+			children[*g.ParentID] = append(children[*g.ParentID], g)
+		}
+	}
+	if groupID != nil {
+		root, err := s.Repo.FindGroupByID(*groupID)
+		if err != nil {
+			return TopologyGraph{}, ErrGroupNotFound
+		}
+		roots = []models.MachineGroup{*root}
+	}
+
+	grouped := make(map[uint][]uint)
+	for _, m := range machines {
+		if m.GroupID != nil {
+			grouped[*m.GroupID] = append(grouped[*m.GroupID], m.ID)
+		}
+	}
+
+	// included collects the groups of the graph, to select the machines in them
+	included := make(map[uint]bool)
+	var build func(g models.MachineGroup) GroupNode
+	build = func(g models.MachineGroup) GroupNode {
+		included[g.ID] = true
+		node := GroupNode{MachineGroup: g, Children: []GroupNode{}, Machines: grouped[g.ID]}
+		if node.Machines == nil {
+			node.Machines = []uint{}
+		}
+		for _, child := range children[g.ID] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+	graph := TopologyGraph{Groups: []GroupNode{}, Machines: []MachineNode{}, Links: []models.MachineLink{}}
+	for _, root := range roots {
+		graph.Groups = append(graph.Groups, build(root))
+	}
+
+	inGraph := make(map[uint]bool)
+	for _, m := range machines {
+		if groupID != nil && (m.GroupID == nil || !included[*m.GroupID]) {
+			continue
+		}
+		inGraph[m.ID] = true
+		node := MachineNode{ID: m.ID, Name: m.Name, Status: m.Status, GroupID: m.GroupID}
+		if source, ok := starved[m.ID]; ok {
+			node.StarvedBy = &source
+		}
+		graph.Machines = append(graph.Machines, node)
+	}
+	for _, l := range links {
+		if inGraph[l.FromMachineID] && inGraph[l.ToMachineID] {
+			graph.Links = append(graph.Links, l)
+		}
+	}
+	return graph, nil
+}
+
+// MachineUpdated implements MachineListener; machine updates do not change the topology
+func (s *TopologyServiceImpl) MachineUpdated(machine models.Machine) {}
+
+// MachineDeleted implements MachineListener by removing the links of a deleted machine
+func (s *TopologyServiceImpl) MachineDeleted(id uint) {
+	if err := s.Repo.DeleteMachineLinks(id); err != nil {
+		slog.Error("Failed to remove links of deleted machine", "machine_id", id, "error", err)
+		return
+	}
+	s.notify()
+}
+
+func (s *TopologyServiceImpl) ForTenant(organizationID uint) TopologyService {
+	scoped := *s
+	scoped.Repo = s.Repo.ForTenant(organizationID)
+	scoped.Machines = s.Machines.ForTenant(organizationID)
+	return &scoped
+}
+
+func (s *TopologyServiceImpl) WithContext(ctx context.Context) TopologyService {
+	scoped := *s
+	scoped.Ctx = ctx
+	scoped.Repo = s.Repo.WithContext(ctx)
+	scoped.Machines = s.Machines.WithContext(ctx)
+	return &scoped
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *TopologyServiceImpl) call(method string) (*TopologyServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "TopologyService."+method, s, func(ctx context.Context) *TopologyServiceImpl {
+		return s.WithContext(ctx).(*TopologyServiceImpl)
+	})
+}
+
+func (s *TopologyServiceImpl) notify() {
+	for _, l := range s.Listeners {
+		l.TopologyChanged()
+	}
+}
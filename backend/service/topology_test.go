@@ -0,0 +1,271 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockPlant keeps groups, links and machines in memory. It serves both as the topology
+// and the machine repository, so moving a machine into a group is visible to both.
+type MockPlant struct {
+	groups   []models.MachineGroup
+	links    []models.MachineLink
+	machines []models.Machine
+
+	updateErr error // returned by UpdateStatus, which then changes nothing
+}
+
+func (m *MockPlant) CreateGroup(group *models.MachineGroup) error {
+	group.ID = uint(len(m.groups) + 1)
+	m.groups = append(m.groups, *group)
+	return nil
+}
+func (m *MockPlant) FindGroups() ([]models.MachineGroup, error) { return m.groups, nil }
+func (m *MockPlant) FindGroupByID(id uint) (*models.MachineGroup, error) {
+	for _, g := range m.groups {
+		if g.ID == id {
+			return &g, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+func (m *MockPlant) DeleteGroup(id uint) error {
+	for i, g := range m.groups {
+		if g.ID == id {
+			m.groups = append(m.groups[:i], m.groups[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+func (m *MockPlant) SetMachineGroup(machineID uint, groupID *uint) error {
+	for i := range m.machines {
+		if m.machines[i].ID == machineID {
+			m.machines[i].GroupID = groupID
+		}
+	}
+	return nil
+}
+func (m *MockPlant) CreateLink(link *models.MachineLink) error {
+	link.ID = uint(len(m.links) + 1)
+	m.links = append(m.links, *link)
+	return nil
+}
+func (m *MockPlant) FindLinks() ([]models.MachineLink, error) { return m.links, nil }
+func (m *MockPlant) FindLinkByID(id uint) (*models.MachineLink, error) {
+	for _, l := range m.links {
+		if l.ID == id {
+			return &l, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+func (m *MockPlant) DeleteLink(id uint) error {
+	var kept []models.MachineLink
+	for _, l := range m.links {
+		if l.ID != id {
+			kept = append(kept, l)
+		}
+	}
+	m.links = kept
+	return nil
+}
+func (m *MockPlant) DeleteMachineLinks(machineID uint) error {
+	var kept []models.MachineLink
+	for _, l := range m.links {
+		if l.FromMachineID != machineID && l.ToMachineID != machineID {
+			kept = append(kept, l)
+		}
+	}
+	m.links = kept
+	return nil
+}
+
+func (m *MockPlant) ForTenant(uint) repository.TopologyRepository              { return m }
+func (m *MockPlant) WithContext(context.Context) repository.TopologyRepository { return m }
+
+// plantMachines is the machine repository view of a MockPlant
+type plantMachines struct{ *MockPlant }
+
+func (m plantMachines) ForTenant(uint) repository.MachineRepository              { return m }
+func (m plantMachines) WithContext(context.Context) repository.MachineRepository { return m }
+func (m plantMachines) Transaction(fn func(repository.MachineRepository) error) error {
+	return fn(m)
+}
+
+func (m *MockPlant) Create(machine *models.Machine) error { return nil }
+func (m *MockPlant) FindAll() ([]models.Machine, error)   { return m.machines, nil }
+func (m *MockPlant) Update(machine *models.Machine) error { return nil }
+func (m *MockPlant) CountByStatus() (map[string]int64, error) {
+	counts := map[string]int64{}
+	for _, machine := range m.machines {
+		counts[machine.Status]++
+	}
+	return counts, nil
+}
+func (m *MockPlant) UpdateStatus(ids []uint, status string) error {
+	if m.updateErr != nil {
+		return m.updateErr
+	}
+	for _, id := range ids {
+		for i := range m.machines {
+			if m.machines[i].ID == id {
+				m.machines[i].Status = status
+			}
+		}
+	}
+	return nil
+}
+func (m *MockPlant) SetStatusIf(id uint, status string, from ...string) (bool, error) {
+	return true, nil
+}
+func (m *MockPlant) Delete(id uint) error { return nil }
+func (m *MockPlant) FindByID(id uint) (*models.Machine, error) {
+	for _, machine := range m.machines {
+		if machine.ID == id {
+			return &machine, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+// MockStarvation reports a fixed starvation set and counts topology changes
+type MockStarvation struct {
+	starved map[uint]uint
+	changes int
+}
+
+func (m *MockStarvation) Starved() map[uint]uint { return m.starved }
+func (m *MockStarvation) TopologyChanged()       { m.changes++ }
+
+func newTopologyService() (service.TopologyService, *MockPlant, *MockStarvation) {
+	plant := &MockPlant{machines: []models.Machine{
+		{Model: models.Model{ID: 1}, Name: "Feeder", Status: "Error"},
+		{Model: models.Model{ID: 2}, Name: "Press", Status: "Idle"},
+		{Model: models.Model{ID: 3}, Name: "Packer", Status: "Running"},
+	}}
+	starvation := &MockStarvation{starved: map[uint]uint{2: 1}}
+	return service.NewTopologyService(plant, plantMachines{plant}, starvation, starvation), plant, starvation
+}
+
+func TestCreateGroupHierarchy(t *testing.T) {
+	s, _, _ := newTopologyService()
+	id := func(g models.MachineGroup) *uint { return &g.ID }
+
+	site, err := s.CreateGroup(models.MachineGroup{Name: "Plant A", Kind: models.GroupKindSite})
+	assert.Nil(t, err)
+	line, err := s.CreateGroup(models.MachineGroup{Name: "Line 1", Kind: models.GroupKindLine, ParentID: id(site)})
+	assert.Nil(t, err)
+	_, err = s.CreateGroup(models.MachineGroup{Name: "Cell 1", Kind: models.GroupKindCell, ParentID: id(line)})
+	assert.Nil(t, err)
+
+	missing := uint(42)
+	invalid := []models.MachineGroup{
+		{Name: "", Kind: models.GroupKindSite},
+		{Name: "Area", Kind: "area"},
+		{Name: "Plant B", Kind: models.GroupKindSite, ParentID: id(site)},
+		{Name: "Line 2", Kind: models.GroupKindLine},
+		{Name: "Line 2", Kind: models.GroupKindLine, ParentID: &missing},
+		{Name: "Cell 2", Kind: models.GroupKindCell, ParentID: id(site)},
+		{Name: "Line 1", Kind: models.GroupKindLine, ParentID: id(site)},
+	}
+	for _, g := range invalid {
+		_, err := s.CreateGroup(g)
+		assert.ErrorIs(t, err, service.ErrInvalidTopology, "Group %q (%s) should be rejected", g.Name, g.Kind)
+	}
+
+	// The same name is fine in another parent
+	other, _ := s.CreateGroup(models.MachineGroup{Name: "Plant B", Kind: models.GroupKindSite})
+	_, err = s.CreateGroup(models.MachineGroup{Name: "Line 1", Kind: models.GroupKindLine, ParentID: id(other)})
+	assert.Nil(t, err)
+}
+
+func TestDeleteGroupMustBeEmpty(t *testing.T) {
+	s, _, _ := newTopologyService()
+	site, _ := s.CreateGroup(models.MachineGroup{Name: "Plant A", Kind: models.GroupKindSite})
+	line, _ := s.CreateGroup(models.MachineGroup{Name: "Line 1", Kind: models.GroupKindLine, ParentID: &site.ID})
+
+	_, err := s.SetMachineGroup(2, &line.ID)
+	assert.Nil(t, err)
+	assert.ErrorIs(t, s.DeleteGroup(site.ID), service.ErrGroupNotEmpty)
+	assert.ErrorIs(t, s.DeleteGroup(line.ID), service.ErrGroupNotEmpty)
+
+	machine, err := s.SetMachineGroup(2, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, machine.GroupID)
+	assert.Nil(t, s.DeleteGroup(line.ID))
+	assert.Nil(t, s.DeleteGroup(site.ID))
+	assert.ErrorIs(t, s.DeleteGroup(site.ID), service.ErrGroupNotFound)
+
+	_, err = s.SetMachineGroup(99, nil)
+	assert.ErrorIs(t, err, service.ErrMachineNotFound)
+	_, err = s.SetMachineGroup(2, &line.ID)
+	assert.ErrorIs(t, err, service.ErrGroupNotFound)
+}
+
+func TestLinksNotifyListeners(t *testing.T) {
+	s, _, starvation := newTopologyService()
+
+	link, err := s.CreateLink(models.MachineLink{FromMachineID: 1, ToMachineID: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, starvation.changes)
+
+	_, err = s.CreateLink(models.MachineLink{FromMachineID: 1, ToMachineID: 2})
+	assert.ErrorIs(t, err, service.ErrLinkExists)
+	_, err = s.CreateLink(models.MachineLink{FromMachineID: 2, ToMachineID: 2})
+	assert.ErrorIs(t, err, service.ErrInvalidTopology, "A machine cannot feed itself")
+	_, err = s.CreateLink(models.MachineLink{FromMachineID: 2, ToMachineID: 99})
+	assert.ErrorIs(t, err, service.ErrInvalidTopology, "Both machines must exist")
+	assert.Equal(t, 1, starvation.changes, "Rejected links should not notify")
+
+	assert.Nil(t, s.DeleteLink(link.ID))
+	assert.ErrorIs(t, s.DeleteLink(link.ID), service.ErrLinkNotFound)
+	assert.Equal(t, 2, starvation.changes)
+
+	_, _ = s.CreateLink(models.MachineLink{FromMachineID: 1, ToMachineID: 2})
+	_, _ = s.CreateLink(models.MachineLink{FromMachineID: 2, ToMachineID: 3})
+	s.MachineDeleted(1)
+	links, _ := s.GetLinks()
+	if assert.Len(t, links, 1, "Links of deleted machines should be removed") {
+		assert.Equal(t, uint(2), links[0].FromMachineID)
+	}
+}
+
+func TestGetTopology(t *testing.T) {
+	s, _, _ := newTopologyService()
+	siteA, _ := s.CreateGroup(models.MachineGroup{Name: "Plant A", Kind: models.GroupKindSite})
+	line, _ := s.CreateGroup(models.MachineGroup{Name: "Line 1", Kind: models.GroupKindLine, ParentID: &siteA.ID})
+	siteB, _ := s.CreateGroup(models.MachineGroup{Name: "Plant B", Kind: models.GroupKindSite})
+	_, _ = s.SetMachineGroup(1, &line.ID)
+	_, _ = s.SetMachineGroup(2, &line.ID)
+	_, _ = s.SetMachineGroup(3, &siteB.ID)
+	_, _ = s.CreateLink(models.MachineLink{FromMachineID: 1, ToMachineID: 2})
+	_, _ = s.CreateLink(models.MachineLink{FromMachineID: 2, ToMachineID: 3})
+
+	graph, err := s.GetTopology(nil)
+	assert.Nil(t, err)
+	assert.Len(t, graph.Groups, 2)
+	assert.Equal(t, "Line 1", graph.Groups[0].Children[0].Name)
+	assert.Equal(t, []uint{1, 2}, graph.Groups[0].Children[0].Machines)
+	assert.Len(t, graph.Machines, 3)
+	assert.Len(t, graph.Links, 2)
+	if assert.NotNil(t, graph.Machines[1].StarvedBy) {
+		assert.Equal(t, uint(1), *graph.Machines[1].StarvedBy)
+	}
+
+	graph, err = s.GetTopology(&siteA.ID)
+	assert.Nil(t, err)
+	assert.Len(t, graph.Groups, 1)
+	assert.Len(t, graph.Machines, 2, "Only machines below the group are included")
+	assert.Len(t, graph.Links, 1, "Only links between included machines are kept")
+
+	missing := uint(42)
+	_, err = s.GetTopology(&missing)
+	assert.ErrorIs(t, err, service.ErrGroupNotFound)
+}
@@ -0,0 +1,41 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestServiceSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	machines := service.NewMachineService(&MockMachineRepository{})
+
+	// Calls outside of traced requests are not
+	_, err := machines.GetMachineByID(1)
+	assert.Nil(t, err)
+	_, err = machines.WithContext(context.Background()).GetMachineByID(1)
+	assert.Nil(t, err)
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "GET /api/v1/machines/:id")
+	_, err = machines.WithContext(ctx).GetMachineByID(1)
+	assert.Nil(t, err)
+	_, err = machines.ForTenant(2).WithContext(ctx).GetMachineByID(99)
+	assert.NotNil(t, err)
+	request.End()
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 3) {
+		assert.Equal(t, "MachineService.GetMachineByID", spans[0].Name())
+		assert.Equal(t, request.SpanContext().SpanID(), spans[0].Parent().SpanID(), "Calls are children of the request's span")
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+		assert.Equal(t, request.SpanContext().SpanID(), spans[1].Parent().SpanID(), "Tenants keep the request's span")
+		assert.Equal(t, codes.Error, spans[1].Status().Code, "Failed calls are marked")
+	}
+}
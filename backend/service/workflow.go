@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/workflow"
+)
+
+var (
+	// ErrWorkflowNotFound is returned when a workflow ID does not exist
+	ErrWorkflowNotFound = errors.New("workflow not found")
+	// ErrWorkflowRunNotFound is returned when a workflow run ID does not exist
+	ErrWorkflowRunNotFound = errors.New("workflow run not found")
+	// ErrWorkflowExists is returned when creating a workflow with a name already in use
+	ErrWorkflowExists = errors.New("workflow already exists")
+	// ErrInvalidWorkflow is returned when a workflow definition or run request is rejected
+	ErrInvalidWorkflow = workflow.ErrInvalidWorkflow
+)
+
+// WorkflowRunRequest is the optional body of POST /api/v1/workflows/:id/runs
+type WorkflowRunRequest struct {
+	Inputs map[string]interface{} `json:"inputs"` // referenced by steps as ${inputs.<key>}
+}
+
+// WorkflowRunResult is a workflow run with the state of each of its steps
+type WorkflowRunResult struct {
+	models.WorkflowRun
+	Steps []models.WorkflowStepRun `json:"steps"`
+}
+
+type WorkflowService interface {
+	CreateWorkflow(workflow models.Workflow) (models.Workflow, error)
+	GetWorkflows() ([]models.Workflow, error)
+	GetWorkflowByID(id uint) (models.Workflow, error)
+	DeleteWorkflow(id uint) error
+	StartRun(workflowID uint, req WorkflowRunRequest) (models.WorkflowRun, error)
+	GetRun(id uint) (WorkflowRunResult, error)
+
+	// ForTenant returns the service limited to the workflows of one organization, which new
+	// workflows are created in; 0 is every organization
+	ForTenant(organizationID uint) WorkflowService
+	WithContext(ctx context.Context) WorkflowService
+}
+
+type WorkflowServiceImpl struct {
+	Repo     repository.WorkflowRepository
+	Machines repository.MachineRepository
+	Engine   *workflow.Engine
+	Quota    int             // how many runs of workflows an organization may have running, 0 for any number
+	Tenant   uint            // the organization whose workflows are visible, 0 for all
+	Ctx      context.Context // the request starting runs, which they are logged with
+}
+
+func NewWorkflowService(repo repository.WorkflowRepository, machines repository.MachineRepository, engine *workflow.Engine, quota int) WorkflowService {
+	return &WorkflowServiceImpl{Repo: repo, Machines: machines, Engine: engine, Quota: quota}
+}
+
+// --- Implementation of the Interface Methods ---
+
+// CreateWorkflow validates the DAG and that every step's machine exists before storing it
+func (s *WorkflowServiceImpl) CreateWorkflow(wf models.Workflow) (_ models.Workflow, err error) {
+	s, end := s.call("CreateWorkflow")
+	defer end(&err)
+	if wf.Name == "" {
+		return models.Workflow{}, fmt.Errorf("%w: workflow name cannot be empty", ErrInvalidWorkflow)
+	}
+	if err := workflow.Validate(wf.Steps); err != nil {
+		return models.Workflow{}, err
+	}
+	for _, step := range wf.Steps {
+		if _, err := s.Machines.FindByID(step.MachineID); err != nil {
+			return models.Workflow{}, fmt.Errorf("%w: step %q: machine %d not found", ErrInvalidWorkflow, step.Name, step.MachineID)
+		}
+	}
+	wf.OrganizationID = owner(s.Tenant)
+	if _, err := s.Repo.FindByName(wf.OrganizationID, wf.Name); err == nil {
+		return models.Workflow{}, ErrWorkflowExists
+	}
+
+	wf.ID = 0
+	if err := s.Repo.Create(&wf); err != nil {
+		return models.Workflow{}, err
+	}
+	return wf, nil
+}
+
+func (s *WorkflowServiceImpl) GetWorkflows() (_ []models.Workflow, err error) {
+	s, end := s.call("GetWorkflows")
+	defer end(&err)
+	workflows, err := s.Repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	visibleWorkflows := []models.Workflow{}
+	for _, wf := range workflows {
+		if visible(s.Tenant, wf.OrganizationID) {
+			visibleWorkflows = append(visibleWorkflows, wf)
+		}
+	}
+	return visibleWorkflows, nil
+}
+
+func (s *WorkflowServiceImpl) GetWorkflowByID(id uint) (_ models.Workflow, err error) {
+	s, end := s.call("GetWorkflowByID")
+	defer end(&err)
+	wf, err := s.Repo.FindByID(id)
+	if err != nil || !visible(s.Tenant, wf.OrganizationID) {
+		return models.Workflow{}, ErrWorkflowNotFound
+	}
+	return *wf, nil
+}
+
+// DeleteWorkflow removes a workflow; runs already started carry on with their copy of the steps
+func (s *WorkflowServiceImpl) DeleteWorkflow(id uint) (err error) {
+	s, end := s.call("DeleteWorkflow")
+	defer end(&err)
+	if _, err := s.GetWorkflowByID(id); err != nil {
+		return err
+	}
+	return s.Repo.Delete(id)
+}
+
+// StartRun creates a run of the workflow and starts executing it in the background
+func (s *WorkflowServiceImpl) StartRun(workflowID uint, req WorkflowRunRequest) (_ models.WorkflowRun, err error) {
+	s, end := s.call("StartRun")
+	defer end(&err)
+	wf, err := s.GetWorkflowByID(workflowID)
+	if err != nil {
+		return models.WorkflowRun{}, err
+	}
+	inputs := ""
+	if len(req.Inputs) > 0 {
+		data, err := json.Marshal(req.Inputs)
+		if err != nil {
+			return models.WorkflowRun{}, fmt.Errorf("%w: %v", ErrInvalidWorkflow, err)
+		}
+		inputs = string(data)
+	}
+
+	run := models.WorkflowRun{OrganizationID: wf.OrganizationID, WorkflowID: wf.ID, Status: models.WorkflowStatusRunning, Inputs: inputs, Steps: wf.Steps}
+	steps := make([]models.WorkflowStepRun, len(wf.Steps))
+	for i, step := range wf.Steps {
+		steps[i] = models.WorkflowStepRun{Step: step.Name, Status: models.StepStatusPending}
+	}
+	if err := s.createRunWithinQuota(&run, steps); err != nil {
+		return models.WorkflowRun{}, err
+	}
+
+	slog.InfoContext(s.Ctx, "Workflow started", "workflow_id", wf.ID, "workflow", wf.Name, "workflow_run_id", run.ID)
+	s.Engine.Start(requestContext(s.Ctx), run)
+	return run, nil
+}
+
+func (s *WorkflowServiceImpl) GetRun(id uint) (_ WorkflowRunResult, err error) {
+	s, end := s.call("GetRun")
+	defer end(&err)
+	run, err := s.Repo.FindRunByID(id)
+	if err != nil || !visible(s.Tenant, run.OrganizationID) {
+		return WorkflowRunResult{}, ErrWorkflowRunNotFound
+	}
+	steps, err := s.Repo.FindStepRuns(id)
+	if err != nil {
+		return WorkflowRunResult{}, err
+	}
+	return WorkflowRunResult{WorkflowRun: *run, Steps: steps}, nil
+}
+
+func (s *WorkflowServiceImpl) ForTenant(organizationID uint) WorkflowService {
+	return &WorkflowServiceImpl{Repo: s.Repo, Machines: s.Machines.ForTenant(organizationID), Engine: s.Engine, Quota: s.Quota, Tenant: organizationID, Ctx: s.Ctx}
+}
+
+func (s *WorkflowServiceImpl) WithContext(ctx context.Context) WorkflowService {
+	return &WorkflowServiceImpl{Repo: s.Repo.WithContext(ctx), Machines: s.Machines.WithContext(ctx), Engine: s.Engine, Quota: s.Quota, Tenant: s.Tenant, Ctx: ctx}
+}
+
+// call starts the span of a call of the service, see startCall
+func (s *WorkflowServiceImpl) call(method string) (*WorkflowServiceImpl, func(*error)) {
+	return startCall(s.Ctx, "WorkflowService."+method, s, func(ctx context.Context) *WorkflowServiceImpl {
+		return s.WithContext(ctx).(*WorkflowServiceImpl)
+	})
+}
+
+// createRunWithinQuota records a run unless its organization has no room for it. The check
+// and the insert hold runQuotaMu, so concurrent starts cannot overshoot the quota together.
+func (s *WorkflowServiceImpl) createRunWithinQuota(run *models.WorkflowRun, steps []models.WorkflowStepRun) error {
+	if s.Quota > 0 {
+		runQuotaMu.Lock()
+		defer runQuotaMu.Unlock()
+		if err := s.checkQuota(run.OrganizationID); err != nil {
+			return err
+		}
+	}
+	return s.Repo.CreateRun(run, steps)
+}
+
+// checkQuota rejects a run while the organization has Quota runs of workflows running. Their
+// steps run on the simulator directly rather than through the job queue, so they are counted
+// on their own.
+func (s *WorkflowServiceImpl) checkQuota(organizationID uint) error {
+	if s.Quota <= 0 {
+		return nil
+	}
+	runs, err := s.Repo.FindRunsByStatus(models.WorkflowStatusRunning)
+	if err != nil {
+		return err
+	}
+	active := 0
+	for _, run := range runs {
+		if run.OrganizationID == organizationID {
+			active++
+		}
+	}
+	if active >= s.Quota {
+		return fmt.Errorf("%w: at most %d workflow runs may be running at once", ErrQuotaExceeded, s.Quota)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/service"
+	"github.com/CBYeuler/automation-backend/backend/workflow"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockWorkflowRepository keeps workflows and their runs in memory
+type MockWorkflowRepository struct {
+	mu        sync.Mutex
+	Workflows []models.Workflow
+	Runs      []models.WorkflowRun
+}
+
+func (m *MockWorkflowRepository) WithContext(context.Context) repository.WorkflowRepository { return m }
+
+func (m *MockWorkflowRepository) Create(wf *models.Workflow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wf.ID = uint(len(m.Workflows) + 1)
+	m.Workflows = append(m.Workflows, *wf)
+	return nil
+}
+func (m *MockWorkflowRepository) FindAll() ([]models.Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.Workflow(nil), m.Workflows...), nil
+}
+func (m *MockWorkflowRepository) FindByID(id uint) (*models.Workflow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id == 0 || int(id) > len(m.Workflows) {
+		return nil, errors.New("record not found")
+	}
+	wf := m.Workflows[id-1]
+	return &wf, nil
+}
+func (m *MockWorkflowRepository) FindByName(organizationID uint, name string) (*models.Workflow, error) {
+	return nil, errors.New("record not found")
+}
+func (m *MockWorkflowRepository) Delete(id uint) error { return nil }
+
+func (m *MockWorkflowRepository) CreateRun(run *models.WorkflowRun, steps []models.WorkflowStepRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run.ID = uint(len(m.Runs) + 1)
+	m.Runs = append(m.Runs, *run)
+	return nil
+}
+func (m *MockWorkflowRepository) UpdateRun(run *models.WorkflowRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Runs[run.ID-1] = *run
+	return nil
+}
+func (m *MockWorkflowRepository) FindRunByID(id uint) (*models.WorkflowRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := m.Runs[id-1]
+	return &run, nil
+}
+func (m *MockWorkflowRepository) FindRunsByStatus(status string) ([]models.WorkflowRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var runs []models.WorkflowRun
+	for _, run := range m.Runs {
+		if run.Status == status {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+func (m *MockWorkflowRepository) FindStepRuns(runID uint) ([]models.WorkflowStepRun, error) {
+	return nil, nil
+}
+func (m *MockWorkflowRepository) UpdateStepRun(step *models.WorkflowStepRun) error { return nil }
+
+func TestStartRunRespectsRunQuota(t *testing.T) {
+	repo := &MockWorkflowRepository{}
+	assert.Nil(t, repo.Create(&models.Workflow{OrganizationID: models.DefaultOrganizationID, Name: "empty"}))
+	engine := workflow.NewEngine(context.Background(), repo, nil)
+	s := service.NewWorkflowService(repo, &MockMachineRepository{}, engine, 1)
+
+	// Runs of other organizations do not count
+	assert.Nil(t, repo.CreateRun(&models.WorkflowRun{OrganizationID: 2, WorkflowID: 1, Status: models.WorkflowStatusRunning}, nil))
+	_, err := s.StartRun(1, service.WorkflowRunRequest{})
+	assert.Nil(t, err)
+	engine.Wait()
+
+	assert.Nil(t, repo.CreateRun(&models.WorkflowRun{OrganizationID: models.DefaultOrganizationID, WorkflowID: 1, Status: models.WorkflowStatusRunning}, nil))
+	_, err = s.StartRun(1, service.WorkflowRunRequest{})
+	assert.ErrorIs(t, err, service.ErrQuotaExceeded)
+}
+
+// stuckWorkflowRepository takes its time to find runs, as a database would, so that starts
+// checking the quota at the same time overlap. It cannot load the steps of runs, so the engine
+// leaves its runs Running.
+type stuckWorkflowRepository struct {
+	*MockWorkflowRepository
+}
+
+func (r stuckWorkflowRepository) FindRunsByStatus(status string) ([]models.WorkflowRun, error) {
+	runs, err := r.MockWorkflowRepository.FindRunsByStatus(status)
+	time.Sleep(time.Millisecond)
+	return runs, err
+}
+
+func (r stuckWorkflowRepository) FindStepRuns(runID uint) ([]models.WorkflowStepRun, error) {
+	return nil, errors.New("steps unavailable")
+}
+
+func TestStartRunQuotaHoldsForConcurrentStarts(t *testing.T) {
+	repo := &MockWorkflowRepository{}
+	assert.Nil(t, repo.Create(&models.Workflow{OrganizationID: models.DefaultOrganizationID, Name: "stuck"}))
+	engine := workflow.NewEngine(context.Background(), stuckWorkflowRepository{repo}, nil)
+	s := service.NewWorkflowService(stuckWorkflowRepository{repo}, &MockMachineRepository{}, engine, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = s.StartRun(1, service.WorkflowRunRequest{})
+		}()
+	}
+	wg.Wait()
+	engine.Wait()
+
+	runs, _ := repo.FindRunsByStatus(models.WorkflowStatusRunning)
+	assert.Len(t, runs, 2, "Concurrent starts cannot all pass the quota check")
+}
@@ -0,0 +1,159 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Simulator states reported by Status
+const (
+	SimulatorStopped = "stopped" // StartGlobalSimulation has not been called
+	SimulatorRunning = "running"
+	SimulatorPaused  = "paused"
+)
+
+// DefaultTickInterval is how often the monitor reconciles machines with their simulations
+const DefaultTickInterval = 5 * time.Second
+
+// ErrInvalidConfig is returned when a runtime configuration change is rejected
+var ErrInvalidConfig = errors.New("invalid simulator config")
+
+// Status is a snapshot of the simulator, as exposed by GET /api/v1/simulator
+type Status struct {
+	State         string       `json:"state"`
+	TickInterval  string       `json:"tick_interval"`
+	ErrorRate     float64      `json:"error_rate"`
+	ActiveWorkers int          `json:"active_workers"`
+	LastReconcile *time.Time   `json:"last_reconcile,omitempty"`
+	Workers       []WorkerInfo `json:"workers,omitempty"` // only shown to platform admins, the workers run every organization's machines
+}
+
+// ConfigUpdate changes simulator settings at runtime; nil/empty fields are left unchanged
+type ConfigUpdate struct {
+	TickInterval string   `json:"tick_interval"`
+	ErrorRate    *float64 `json:"error_rate"`
+}
+
+// Status reports whether the simulator is running, its settings and per-machine run stats
+func (s *MachineSimulator) Status() Status {
+	workers := s.Workers()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{
+		State:         SimulatorRunning,
+		TickInterval:  s.tickInterval.String(),
+		ErrorRate:     s.Faults.Chaos().ErrorRate,
+		ActiveWorkers: len(s.workers),
+		Workers:       workers,
+	}
+	switch {
+	case s.paused:
+		status.State = SimulatorPaused
+	case s.ticker == nil:
+		status.State = SimulatorStopped
+	}
+	if !s.lastReconcile.IsZero() {
+		lastReconcile := s.lastReconcile
+		status.LastReconcile = &lastReconcile
+	}
+	return status
+}
+
+// ActiveWorkers counts the machines being simulated, as reported in Status
+func (s *MachineSimulator) ActiveWorkers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.workers)
+}
+
+// Pause stops all machines from starting new runs until Resume is called.
+// Runs already in progress finish normally.
+func (s *MachineSimulator) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused {
+		return
+	}
+	s.paused = true
+	s.resumeCh = make(chan struct{})
+	slog.Info("Simulator paused")
+}
+
+// Resume lets paused machines continue their simulation
+func (s *MachineSimulator) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paused {
+		return
+	}
+	s.paused = false
+	close(s.resumeCh)
+
+	// Paused workers sent no heartbeats; give them a fresh deadline instead of flagging them as hung
+	now := time.Now()
+	for _, w := range s.workers {
+		w.deadline = now.Add(s.HangTimeout)
+	}
+	slog.Info("Simulator resumed")
+}
+
+// Configure applies runtime changes to the tick interval and the chaos error rate
+func (s *MachineSimulator) Configure(update ConfigUpdate) error {
+	var tickInterval time.Duration
+	if update.TickInterval != "" {
+		d, err := time.ParseDuration(update.TickInterval)
+		if err != nil || d < 100*time.Millisecond {
+			return fmt.Errorf("%w: tick_interval must be a duration of at least 100ms", ErrInvalidConfig)
+		}
+		tickInterval = d
+	}
+	if update.ErrorRate != nil {
+		profile := s.Faults.Chaos()
+		profile.ErrorRate = *update.ErrorRate
+		if err := s.Faults.SetChaos(profile); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	}
+
+	if tickInterval > 0 {
+		s.mu.Lock()
+		s.tickInterval = tickInterval
+		if s.ticker != nil {
+			s.ticker.Reset(tickInterval)
+		}
+		s.mu.Unlock()
+	}
+	slog.Info("Simulator config updated", "tick_interval", s.TickInterval(), "error_rate", s.Faults.Chaos().ErrorRate)
+	return nil
+}
+
+// TickInterval returns how often the monitor currently reconciles machines
+func (s *MachineSimulator) TickInterval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tickInterval
+}
+
+// waitWhilePaused blocks while the simulator is paused.
+// It returns ctx.Err() if the worker is stopped while waiting.
+func (s *MachineSimulator) waitWhilePaused(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		paused, resumeCh := s.paused, s.resumeCh
+		s.mu.Unlock()
+		if !paused {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-resumeCh:
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Supported fault types for FaultSpec.Type
+const (
+	FaultError      = "error"       // force the machine into Error now (and keep it there for the duration)
+	FaultSlow       = "slow"        // make every run take Factor times longer
+	FaultFailRuns   = "fail_runs"   // fail the next Count runs
+	FaultDropWrites = "drop_writes" // silently discard the run's DB writes
+	FaultHang       = "hang"        // block runs until the fault expires or is removed
+)
+
+// ErrInvalidFault is returned when a fault specification cannot be applied
+var ErrInvalidFault = errors.New("invalid fault")
+
+// FaultSpec is a request to inject a fault into a machine's simulation
+type FaultSpec struct {
+	Type     string  `json:"type" binding:"required"`
+	Duration string  `json:"duration"` // Go duration; empty keeps the fault until removed (or its Count is used up)
+	Factor   float64 `json:"factor"`   // slow
+	Count    int     `json:"count"`    // fail_runs
+}
+
+// Fault is an active injected fault
+type Fault struct {
+	ID         uint       `json:"id"`
+	MachineID  uint       `json:"machine_id"`
+	Type       string     `json:"type"`
+	Factor     float64    `json:"factor,omitempty"`
+	Remaining  int        `json:"remaining,omitempty"`
+	InjectedAt time.Time  `json:"injected_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+func (f *Fault) expired(now time.Time) bool {
+	return f.ExpiresAt != nil && !now.Before(*f.ExpiresAt)
+}
+
+// ChaosProfile applies random faults to every simulated machine.
+// Rates are per-run probabilities between 0 and 1.
+type ChaosProfile struct {
+	ErrorRate     float64 `json:"error_rate"`
+	SlowRate      float64 `json:"slow_rate"`
+	SlowFactor    float64 `json:"slow_factor"`
+	DropWriteRate float64 `json:"drop_write_rate"`
+}
+
+// DefaultChaosProfile keeps the historical behaviour of a 2% chance of error per run
+func DefaultChaosProfile() ChaosProfile {
+	return ChaosProfile{ErrorRate: 0.02, SlowFactor: 2}
+}
+
+func (p ChaosProfile) validate() error {
+	for name, rate := range map[string]float64{"error_rate": p.ErrorRate, "slow_rate": p.SlowRate, "drop_write_rate": p.DropWriteRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%w: %s must be between 0 and 1", ErrInvalidFault, name)
+		}
+	}
+	if p.SlowRate > 0 && p.SlowFactor < 1 {
+		return fmt.Errorf("%w: slow_factor must be at least 1", ErrInvalidFault)
+	}
+	return nil
+}
+
+// RunPlan is the combined effect of every active fault on a single simulation run
+type RunPlan struct {
+	Fail       bool
+	SlowFactor float64
+	DropWrites bool
+	Hang       bool
+}
+
+// FaultInjector keeps track of injected faults and the global chaos profile.
+// It is safe for concurrent use by the simulation goroutines and the API.
+type FaultInjector struct {
+	mu     sync.Mutex
+	nextID uint
+	faults map[uint][]*Fault
+	chaos  ChaosProfile
+	rng    *rand.Rand
+}
+
+// NewFaultInjector creates an injector with no faults and the default chaos profile
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{
+		faults: make(map[uint][]*Fault),
+		chaos:  DefaultChaosProfile(),
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Inject validates and registers a fault for a machine
+func (f *FaultInjector) Inject(machineID uint, spec FaultSpec) (Fault, error) {
+	now := time.Now()
+	fault := &Fault{MachineID: machineID, Type: spec.Type, InjectedAt: now}
+
+	if spec.Duration != "" {
+		duration, err := time.ParseDuration(spec.Duration)
+		if err != nil || duration <= 0 {
+			return Fault{}, fmt.Errorf("%w: duration must be a positive Go duration such as 30s", ErrInvalidFault)
+		}
+		expiresAt := now.Add(duration)
+		fault.ExpiresAt = &expiresAt
+	}
+
+	switch spec.Type {
+	case FaultError, FaultDropWrites, FaultHang:
+	case FaultSlow:
+		if spec.Factor <= 1 {
+			return Fault{}, fmt.Errorf("%w: slow faults require a factor greater than 1", ErrInvalidFault)
+		}
+		fault.Factor = spec.Factor
+	case FaultFailRuns:
+		if spec.Count <= 0 {
+			return Fault{}, fmt.Errorf("%w: fail_runs faults require a positive count", ErrInvalidFault)
+		}
+		fault.Remaining = spec.Count
+	default:
+		return Fault{}, fmt.Errorf("%w: unknown fault type %q", ErrInvalidFault, spec.Type)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	fault.ID = f.nextID
+	// An error fault without a duration only forces the Error state once; there is nothing to keep
+	if fault.Type != FaultError || fault.ExpiresAt != nil {
+		f.faults[machineID] = append(f.faults[machineID], fault)
+	}
+	return *fault, nil
+}
+
+// Faults returns the active faults of a machine
+func (f *FaultInjector) Faults(machineID uint) []Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prune(machineID, time.Now())
+
+	faults := make([]Fault, 0, len(f.faults[machineID]))
+	for _, fault := range f.faults[machineID] {
+		faults = append(faults, *fault)
+	}
+	sort.Slice(faults, func(i, j int) bool { return faults[i].ID < faults[j].ID })
+	return faults
+}
+
+// Remove deletes a single fault, reporting whether it existed
+func (f *FaultInjector) Remove(machineID, faultID uint) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, fault := range f.faults[machineID] {
+		if fault.ID == faultID {
+			f.faults[machineID] = append(f.faults[machineID][:i], f.faults[machineID][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear removes every fault of a machine
+func (f *FaultInjector) Clear(machineID uint) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.faults, machineID)
+}
+
+// Chaos returns the current global chaos profile
+func (f *FaultInjector) Chaos() ChaosProfile {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.chaos
+}
+
+// SetChaos replaces the global chaos profile
+func (f *FaultInjector) SetChaos(profile ChaosProfile) error {
+	if err := profile.validate(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chaos = profile
+	return nil
+}
+
+// PlanRun combines the machine's faults and the chaos profile into the plan for its next run.
+// It consumes one run from any fail_runs fault.
+func (f *FaultInjector) PlanRun(machineID uint) RunPlan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prune(machineID, time.Now())
+
+	plan := RunPlan{SlowFactor: 1}
+	for _, fault := range f.faults[machineID] {
+		switch fault.Type {
+		case FaultError:
+			plan.Fail = true
+		case FaultSlow:
+			plan.SlowFactor *= fault.Factor
+		case FaultFailRuns:
+			plan.Fail = true
+			fault.Remaining--
+		case FaultDropWrites:
+			plan.DropWrites = true
+		case FaultHang:
+			plan.Hang = true
+		}
+	}
+
+	if f.rng.Float64() < f.chaos.ErrorRate {
+		plan.Fail = true
+	}
+	if f.rng.Float64() < f.chaos.SlowRate {
+		plan.SlowFactor *= f.chaos.SlowFactor
+	}
+	if f.rng.Float64() < f.chaos.DropWriteRate {
+		plan.DropWrites = true
+	}
+	return plan
+}
+
+// Hanging reports whether a hang fault is still active for the machine
+func (f *FaultInjector) Hanging(machineID uint) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prune(machineID, time.Now())
+	for _, fault := range f.faults[machineID] {
+		if fault.Type == FaultHang {
+			return true
+		}
+	}
+	return false
+}
+
+// prune drops expired and used-up faults. Callers must hold f.mu.
+func (f *FaultInjector) prune(machineID uint, now time.Time) {
+	kept := f.faults[machineID][:0]
+	for _, fault := range f.faults[machineID] {
+		if fault.expired(now) || (fault.Type == FaultFailRuns && fault.Remaining <= 0) {
+			continue
+		}
+		kept = append(kept, fault)
+	}
+	if len(kept) == 0 {
+		delete(f.faults, machineID)
+		return
+	}
+	f.faults[machineID] = kept
+}
@@ -0,0 +1,94 @@
+package simulation_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+// quietInjector returns an injector whose chaos profile never fires, so only injected faults matter
+func quietInjector(t *testing.T) *simulation.FaultInjector {
+	injector := simulation.NewFaultInjector()
+	assert.Nil(t, injector.SetChaos(simulation.ChaosProfile{}))
+	return injector
+}
+
+func TestFaultInjectorValidation(t *testing.T) {
+	injector := quietInjector(t)
+
+	invalid := []simulation.FaultSpec{
+		{Type: "meltdown"},
+		{Type: simulation.FaultSlow, Factor: 0.5},
+		{Type: simulation.FaultFailRuns},
+		{Type: simulation.FaultHang, Duration: "soon"},
+		{Type: simulation.FaultHang, Duration: "-5s"},
+	}
+	for _, spec := range invalid {
+		_, err := injector.Inject(1, spec)
+		assert.True(t, errors.Is(err, simulation.ErrInvalidFault), "Spec %+v should be rejected", spec)
+	}
+
+	assert.NotNil(t, injector.SetChaos(simulation.ChaosProfile{ErrorRate: 1.5}), "Rates above 1 should be rejected")
+}
+
+func TestFaultInjectorRunPlan(t *testing.T) {
+	injector := quietInjector(t)
+
+	assert.Equal(t, simulation.RunPlan{SlowFactor: 1}, injector.PlanRun(1), "No faults means a normal run")
+
+	_, err := injector.Inject(1, simulation.FaultSpec{Type: simulation.FaultFailRuns, Count: 2})
+	assert.Nil(t, err)
+	_, err = injector.Inject(1, simulation.FaultSpec{Type: simulation.FaultSlow, Factor: 3})
+	assert.Nil(t, err)
+	_, err = injector.Inject(1, simulation.FaultSpec{Type: simulation.FaultDropWrites})
+	assert.Nil(t, err)
+
+	plan := injector.PlanRun(1)
+	assert.True(t, plan.Fail)
+	assert.True(t, plan.DropWrites)
+	assert.Equal(t, 3.0, plan.SlowFactor)
+
+	assert.True(t, injector.PlanRun(1).Fail, "Second run should still fail")
+	assert.False(t, injector.PlanRun(1).Fail, "fail_runs should be used up after two runs")
+	assert.Len(t, injector.Faults(1), 2)
+
+	assert.Equal(t, simulation.RunPlan{SlowFactor: 1}, injector.PlanRun(2), "Faults are per machine")
+
+	injector.Clear(1)
+	assert.Len(t, injector.Faults(1), 0)
+}
+
+func TestFaultInjectorExpiryAndRemoval(t *testing.T) {
+	injector := quietInjector(t)
+
+	hang, err := injector.Inject(1, simulation.FaultSpec{Type: simulation.FaultHang})
+	assert.Nil(t, err)
+	assert.True(t, injector.Hanging(1))
+	assert.True(t, injector.Remove(1, hang.ID))
+	assert.False(t, injector.Hanging(1))
+	assert.False(t, injector.Remove(1, hang.ID), "Removing twice should report a missing fault")
+
+	_, err = injector.Inject(1, simulation.FaultSpec{Type: simulation.FaultError, Duration: "20ms"})
+	assert.Nil(t, err)
+	assert.True(t, injector.PlanRun(1).Fail, "Timed error faults keep failing runs")
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, injector.PlanRun(1).Fail, "Faults expire after their duration")
+
+	// One-shot error faults are applied by the simulator and not kept around
+	_, err = injector.Inject(1, simulation.FaultSpec{Type: simulation.FaultError})
+	assert.Nil(t, err)
+	assert.Len(t, injector.Faults(1), 0)
+}
+
+func TestChaosProfile(t *testing.T) {
+	injector := simulation.NewFaultInjector()
+	assert.Equal(t, 0.02, injector.Chaos().ErrorRate, "Default profile keeps the 2% error rate")
+
+	assert.Nil(t, injector.SetChaos(simulation.ChaosProfile{ErrorRate: 1, DropWriteRate: 1}))
+	plan := injector.PlanRun(1)
+	assert.True(t, plan.Fail)
+	assert.True(t, plan.DropWrites)
+}
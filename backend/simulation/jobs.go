@@ -0,0 +1,236 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/logging"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/CBYeuler/automation-backend/backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// jobPollInterval is how long an idle consumer waits before asking the queue for work again
+const jobPollInterval = time.Second
+
+// ReservedParams are the keys of config_json that choose what a run executes. Runs may override
+// the machine's parameters, but not these.
+var ReservedParams = []string{"runner", "timeout", "command"}
+
+// adHocRun tracks an ad-hoc run in flight so it can be cancelled with its machine. Guarded by MachineSimulator.mu.
+type adHocRun struct {
+	machineID uint
+	cancel    context.CancelFunc
+}
+
+// CheckParams rejects parameter overrides of reserved keys
+func CheckParams(overrides map[string]json.RawMessage) error {
+	for _, key := range ReservedParams {
+		if _, ok := overrides[key]; ok {
+			return fmt.Errorf("params cannot override %q", key)
+		}
+	}
+	return nil
+}
+
+// MergeParams applies a JSON object of parameter overrides on top of a machine's ConfigJSON
+func MergeParams(configJSON, params string) (string, error) {
+	if params == "" {
+		return configJSON, nil
+	}
+	overrides := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(params), &overrides); err != nil {
+		return "", fmt.Errorf("params must be a JSON object: %w", err)
+	}
+
+	merged := map[string]json.RawMessage{}
+	if configJSON != "" {
+		if err := json.Unmarshal([]byte(configJSON), &merged); err != nil {
+			return "", fmt.Errorf("invalid config_json: %w", err)
+		}
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+
+	out, err := json.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// StartJobConsumers starts n goroutines that lease ad-hoc run jobs from q and execute them.
+// A job is acknowledged when its run succeeds and rejected (retried or dead-lettered) otherwise.
+func (s *MachineSimulator) StartJobConsumers(q queue.Queue, n int, visibility time.Duration) {
+	host, _ := os.Hostname()
+	for i := 0; i < n; i++ {
+		owner := fmt.Sprintf("%s-%d-%d", host, os.Getpid(), i)
+		go s.consumeJobs(q, owner, visibility)
+	}
+	slog.Info("Started job consumers", "consumers", n)
+}
+
+// consumeJobs is a long-lived goroutine leasing and running jobs one at a time
+func (s *MachineSimulator) consumeJobs(q queue.Queue, owner string, visibility time.Duration) {
+	for {
+		if err := s.waitWhilePaused(s.ctx); err != nil {
+			return
+		}
+
+		job, err := q.Lease(owner, visibility)
+		if err != nil {
+			if !errors.Is(err, queue.ErrEmpty) {
+				slog.Error("Failed to lease job", "error", err)
+			}
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(jobPollInterval):
+			}
+			continue
+		}
+
+		s.processJob(q, owner, job, visibility)
+	}
+}
+
+// processJob runs a leased job and reports the outcome back to the queue
+func (s *MachineSimulator) processJob(q queue.Queue, owner string, job *models.Job, visibility time.Duration) {
+	// Records of the job are tagged with the request that submitted it, and its run continues
+	// the request's trace
+	ctx := logging.WithRequestID(tracing.WithTraceParent(s.ctx, job.TraceParent), job.RequestID)
+	ctx, span := tracer.Start(ctx, "simulation.job", trace.WithAttributes(
+		attribute.Int("job.id", int(job.ID)),
+		attribute.Int("job.attempt", job.Attempts),
+	))
+	var err error
+	defer func() { tracing.End(span, err) }()
+	logger := slog.With("job_id", job.ID, "machine_id", job.MachineID)
+	logger.InfoContext(ctx, "Running job", "attempt", job.Attempts, "max_attempts", job.MaxAttempts)
+
+	// The run must not outlive its lease, otherwise another consumer could start the same job
+	ctx, cancel := context.WithTimeout(ctx, visibility)
+	defer cancel()
+
+	var run models.SimulationRun
+	run, err = s.RunOnce(ctx, job.MachineID, job.Params, &job.ID)
+	if err != nil {
+		logger.WarnContext(ctx, "Job failed", "error", err)
+		if err := q.Nack(job.ID, owner, err.Error()); err != nil {
+			logger.ErrorContext(ctx, "Failed to reject job", "error", err)
+		}
+		return
+	}
+
+	var runID *uint
+	if run.ID != 0 {
+		runID = &run.ID
+	}
+	if err := q.Ack(job.ID, owner, runID); err != nil {
+		logger.ErrorContext(ctx, "Failed to acknowledge job", "error", err)
+		return
+	}
+	logger.InfoContext(ctx, "Job succeeded", "run_id", run.ID)
+}
+
+// RunOnce executes a single ad-hoc run of a machine with params merged over its ConfigJSON.
+// Like a regular cycle it honours injected faults, bumps the run counters and records telemetry,
+// but it leaves the machine's status alone, and it is cancelled when the machine is deleted or set
+// Offline. The run's outputs are the sampled telemetry values, overridden by any outputs of the
+// command. The recorded run is returned with the run's error, if any.
+func (s *MachineSimulator) RunOnce(ctx context.Context, machineID uint, params string, jobID *uint) (models.SimulationRun, error) {
+	run := models.SimulationRun{MachineID: machineID, JobID: jobID, Params: params}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	tracked := &adHocRun{machineID: machineID, cancel: cancel}
+	s.mu.Lock()
+	s.adHocRuns[tracked] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.adHocRuns, tracked)
+		s.mu.Unlock()
+	}()
+
+	machine, err := s.Repo.FindByID(machineID)
+	if err != nil {
+		return run, fmt.Errorf("machine %d not found", machineID)
+	}
+	config, err := MergeParams(machine.ConfigJSON, params)
+	if err != nil {
+		return run, err
+	}
+	runMachine := *machine
+	runMachine.ConfigJSON = config
+	logger := machineLog(*machine)
+
+	plan := s.Faults.PlanRun(machineID)
+	work := s.runDuration(plan.SlowFactor)
+	if err := s.executeRun(ctx, &run, runMachine, plan, work); err != nil {
+		return run, err
+	}
+
+	// Reload the machine so changes made during the run are not overwritten
+	machine, err = s.Repo.FindByID(machineID)
+	if err != nil {
+		return run, fmt.Errorf("machine %d not found", machineID)
+	}
+	machine.SimulatedRuns++
+	machine.LastSimulated = time.Now()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(machineID)))
+	telemetry, err := NewTelemetryGenerator(config, machine.LastSimulated, rng)
+	if err != nil {
+		logger.WarnContext(ctx, "Invalid telemetry config for ad-hoc run, using defaults", "error", err)
+		telemetry, _ = NewTelemetryGenerator("", machine.LastSimulated, rng)
+	}
+	samples := telemetry.Sample(*machine, machine.LastSimulated)
+
+	if plan.DropWrites {
+		logger.InfoContext(ctx, "Fault: dropping database writes of ad-hoc run")
+	} else {
+		if err := s.Repo.Update(machine); err != nil {
+			logger.ErrorContext(ctx, "Failed to update machine", "error", err)
+		}
+		s.storeTelemetry(samples)
+	}
+	for _, o := range s.Observers {
+		o.ObserveTelemetry(samples)
+	}
+
+	// The sampled telemetry doubles as outputs, so workflows can use simulated readings;
+	// outputs of the command take precedence
+	outputs := Outputs{}
+	for _, sample := range samples {
+		outputs[sample.Metric] = sample.Value
+	}
+	if run.Outputs != "" {
+		_ = json.Unmarshal([]byte(run.Outputs), &outputs)
+	}
+	setRunOutputs(&run, outputs)
+	if run.ID != 0 && !plan.DropWrites {
+		if err := s.Runs.Update(&run); err != nil {
+			logger.ErrorContext(ctx, "Failed to update run", "run_id", run.ID, "error", err)
+		}
+	}
+	return run, nil
+}
+
+// cancelAdHocRuns aborts the ad-hoc runs of a machine. Callers must hold s.mu.
+func (s *MachineSimulator) cancelAdHocRuns(machineID uint) {
+	for r := range s.adHocRuns {
+		if r.machineID == machineID {
+			r.cancel()
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package simulation_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/queue"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockQueue hands out its jobs once each and records how consumers settled them
+type MockQueue struct {
+	mu     sync.Mutex
+	jobs   []*models.Job
+	acked  map[uint]*uint
+	nacked map[uint]string
+}
+
+func NewMockQueue(jobs ...*models.Job) *MockQueue {
+	return &MockQueue{jobs: jobs, acked: map[uint]*uint{}, nacked: map[uint]string{}}
+}
+
+func (m *MockQueue) Enqueue(job *models.Job) error { return nil }
+func (m *MockQueue) Lease(owner string, visibility time.Duration) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.jobs) == 0 {
+		return nil, queue.ErrEmpty
+	}
+	job := m.jobs[0]
+	m.jobs = m.jobs[1:]
+	job.Attempts++
+	return job, nil
+}
+func (m *MockQueue) Ack(jobID uint, owner string, runID *uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.acked[jobID] = runID
+	return nil
+}
+func (m *MockQueue) Nack(jobID uint, owner string, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nacked[jobID] = reason
+	return nil
+}
+func (m *MockQueue) Get(jobID uint) (*models.Job, error)            { return nil, queue.ErrJobNotFound }
+func (m *MockQueue) List(filter queue.Filter) ([]models.Job, error) { return nil, nil }
+func (m *MockQueue) Depth() (int64, error)                          { return 0, nil }
+
+func (m *MockQueue) settled() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.acked) + len(m.nacked)
+}
+
+func TestMergeParams(t *testing.T) {
+	merged, err := simulation.MergeParams(`{"runner": "a", "timeout": "1s"}`, `{"timeout": "5s", "load": 0.8}`)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"runner": "a", "timeout": "5s", "load": 0.8}`, merged)
+
+	merged, err = simulation.MergeParams(`{"timeout": "1s"}`, "")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"timeout": "1s"}`, merged)
+
+	_, err = simulation.MergeParams("", `[1, 2]`)
+	assert.NotNil(t, err)
+}
+
+func TestJobConsumersAckAndNack(t *testing.T) {
+	repo := NewMockMachineRepository(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Offline"})
+	runs := &MockRunRepository{}
+	sim := newTestSimulator(t, repo)
+	sim.Runs = runs
+
+	q := NewMockQueue(
+		&models.Job{Model: models.Model{ID: 1}, MachineID: 1, Params: `{"timeout": "1s"}`, MaxAttempts: 3},
+		&models.Job{Model: models.Model{ID: 2}, MachineID: 42, MaxAttempts: 3},
+	)
+	sim.StartJobConsumers(q, 2, time.Minute)
+
+	assert.Eventually(t, func() bool { return q.settled() == 2 }, 3*time.Second, 5*time.Millisecond)
+	assert.Contains(t, q.nacked[2], "not found", "Jobs for unknown machines should be rejected")
+	if assert.NotNil(t, q.acked[1]) {
+		run, _ := runs.FindByID(*q.acked[1])
+		assert.Equal(t, models.RunStatusSucceeded, run.Status)
+		assert.Equal(t, uint(1), *run.JobID)
+		assert.Equal(t, `{"timeout": "1s"}`, run.Params)
+		assert.Contains(t, run.Outputs, `"temperature"`, "Sampled telemetry should be recorded as outputs")
+	}
+
+	machine, _ := repo.FindByID(1)
+	assert.Equal(t, 1, machine.SimulatedRuns)
+	assert.Equal(t, "Offline", machine.Status, "Ad-hoc runs should not change the machine status")
+}
+
+func TestMachineOfflineCancelsJobRuns(t *testing.T) {
+	repo := NewMockMachineRepository(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Idle"})
+	runs := &MockRunRepository{}
+	sim := newTestSimulator(t, repo)
+	sim.Runs = runs
+	sim.MinRunTime, sim.MaxRunTime = time.Hour, time.Hour
+
+	q := NewMockQueue(&models.Job{Model: models.Model{ID: 1}, MachineID: 1, MaxAttempts: 3})
+	sim.StartJobConsumers(q, 1, time.Minute)
+	assert.Eventually(t, func() bool { return len(runs.statuses()) == 1 }, 3*time.Second, 5*time.Millisecond)
+
+	sim.MachineUpdated(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Offline"})
+	assert.Eventually(t, func() bool { return q.settled() == 1 }, 3*time.Second, 5*time.Millisecond, "Runs of machines set Offline are cancelled")
+	assert.Contains(t, q.nacked[1], "canceled")
+	assert.Equal(t, []string{models.RunStatusCancelled}, runs.statuses())
+}
+
+// MockArtifactCollector records the files collected per run
+type MockArtifactCollector struct {
+	mu    sync.Mutex
+	files map[uint][]string
+}
+
+func (m *MockArtifactCollector) Collect(ctx context.Context, runID uint, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range entries {
+		m.files[runID] = append(m.files[runID], e.Name())
+	}
+	return nil
+}
+
+func TestRunOnceCollectsArtifacts(t *testing.T) {
+	repo := NewMockMachineRepository(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Idle"})
+	sim := newTestSimulator(t, repo)
+	sim.Runs = &MockRunRepository{}
+	collector := &MockArtifactCollector{files: map[uint][]string{}}
+	sim.Artifacts = collector
+	sim.Runner = &simulation.DefaultRunner{Commands: map[string][]string{
+		"results": {"sh", "-c", "echo 1,2 > \"$RUN_ARTIFACTS/results.csv\"; exit 1"},
+	}}
+
+	params := `{"runner": "results"}`
+	run, err := sim.RunOnce(context.Background(), 1, params, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"results.csv"}, collector.files[run.ID], "Artifacts of failed runs should be kept")
+}
+
+func TestMachineDeletedCancelsRunOnce(t *testing.T) {
+	repo := NewMockMachineRepository(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Idle"})
+	sim := newTestSimulator(t, repo)
+	runs := &MockRunRepository{}
+	sim.Runs = runs
+	sim.MinRunTime, sim.MaxRunTime = time.Hour, time.Hour
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := sim.RunOnce(context.Background(), 1, "", nil)
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return len(runs.statuses()) == 1 }, 3*time.Second, 5*time.Millisecond)
+	sim.MachineDeleted(1)
+	select {
+	case err := <-errs:
+		assert.ErrorContains(t, err, "canceled", "Runs not started by a job, such as workflow steps, are cancelled too")
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run was not cancelled with its machine")
+	}
+}
@@ -0,0 +1,182 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+)
+
+// DefaultRunLogLimit caps how many bytes of output are kept of a single run
+const DefaultRunLogLimit = 1 << 20
+
+// truncationMarker ends a log that hit its limit
+const truncationMarker = "\n[log truncated: output exceeded %d bytes]\n"
+
+// ErrRunLogNotFound is returned for runs that were recorded without log capture
+var ErrRunLogNotFound = errors.New("run log not found")
+
+// RunLog captures the output of a run in memory while it runs. Writes beyond the limit
+// are dropped, and a truncation marker is appended once.
+type RunLog struct {
+	mu        sync.Mutex
+	buf       []byte
+	limit     int
+	truncated bool
+	done      bool
+	changed   chan struct{} // closed and replaced on every change, to wake up followers
+}
+
+func newRunLog(limit int) *RunLog {
+	return &RunLog{limit: limit, changed: make(chan struct{})}
+}
+
+// Write appends p to the log. It never fails, so a chatty subprocess is not killed by a full log.
+func (l *RunLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done || l.truncated {
+		return len(p), nil
+	}
+	if room := l.limit - len(l.buf); len(p) > room {
+		l.buf = append(l.buf, p[:room]...)
+		l.buf = append(l.buf, fmt.Sprintf(truncationMarker, l.limit)...)
+		l.truncated = true
+	} else {
+		l.buf = append(l.buf, p...)
+	}
+	l.notify()
+	return len(p), nil
+}
+
+// Printf writes a timestamped simulator message to the log; it is a no-op on a nil log
+func (l *RunLog) Printf(format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	fmt.Fprintf(l, "%s %s\n", time.Now().UTC().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+// notify wakes up followers. Callers must hold l.mu.
+func (l *RunLog) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// next returns the output from offset on, whether the log is complete, and a channel
+// that is closed on the next change
+func (l *RunLog) next(offset int) ([]byte, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]byte(nil), l.buf[offset:]...), l.done, l.changed
+}
+
+func (l *RunLog) snapshot(runID uint) models.RunLog {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return models.RunLog{RunID: runID, Content: string(l.buf), Truncated: l.truncated}
+}
+
+// LogHub captures the logs of running runs and stores them once the runs finish
+type LogHub struct {
+	Repo  repository.RunLogRepository
+	Limit int
+
+	mu   sync.Mutex
+	live map[uint]*RunLog // Key: Run ID
+}
+
+// NewLogHub creates a hub storing finished logs in repo
+func NewLogHub(repo repository.RunLogRepository) *LogHub {
+	return &LogHub{Repo: repo, Limit: DefaultRunLogLimit, live: make(map[uint]*RunLog)}
+}
+
+// open starts capturing the log of a run
+func (h *LogHub) open(runID uint) *RunLog {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l := newRunLog(h.Limit)
+	h.live[runID] = l
+	return l
+}
+
+// close stores the log of a finished run. The log stays live until it is stored,
+// so readers always find it in one place or the other.
+func (h *LogHub) close(runID uint) {
+	h.mu.Lock()
+	l := h.live[runID]
+	h.mu.Unlock()
+	if l == nil {
+		return
+	}
+
+	stored := l.snapshot(runID)
+	if err := h.Repo.Create(&stored); err != nil {
+		slog.Error("Failed to store run log", "run_id", runID, "error", err)
+	}
+
+	l.mu.Lock()
+	l.done = true
+	l.notify()
+	l.mu.Unlock()
+
+	h.mu.Lock()
+	delete(h.live, runID)
+	h.mu.Unlock()
+}
+
+func (h *LogHub) liveLog(runID uint) *RunLog {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.live[runID]
+}
+
+// Get returns the log of a run captured so far
+func (h *LogHub) Get(runID uint) (models.RunLog, error) {
+	if l := h.liveLog(runID); l != nil {
+		return l.snapshot(runID), nil
+	}
+	stored, err := h.Repo.FindByRun(runID)
+	if err != nil {
+		return models.RunLog{}, ErrRunLogNotFound
+	}
+	return *stored, nil
+}
+
+// Follow passes the log of a run to fn as it is written, until the run finishes or ctx is cancelled.
+// The log of a finished run is passed in one go.
+func (h *LogHub) Follow(ctx context.Context, runID uint, fn func(chunk []byte)) error {
+	l := h.liveLog(runID)
+	if l == nil {
+		stored, err := h.Get(runID)
+		if err != nil {
+			return err
+		}
+		if stored.Content != "" {
+			fn([]byte(stored.Content))
+		}
+		return nil
+	}
+
+	offset := 0
+	for {
+		chunk, done, changed := l.next(offset)
+		if len(chunk) > 0 {
+			fn(chunk)
+			offset += len(chunk)
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
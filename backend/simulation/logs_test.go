@@ -0,0 +1,121 @@
+package simulation_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockRunLogRepository keeps stored run logs in memory
+type MockRunLogRepository struct {
+	mu   sync.Mutex
+	logs map[uint]models.RunLog
+}
+
+func (m *MockRunLogRepository) WithContext(context.Context) repository.RunLogRepository { return m }
+
+func (m *MockRunLogRepository) Create(log *models.RunLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logs[log.RunID] = *log
+	return nil
+}
+func (m *MockRunLogRepository) FindByRun(runID uint) (*models.RunLog, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	log, ok := m.logs[runID]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &log, nil
+}
+
+func newLogSimulator(t *testing.T) (*simulation.MachineSimulator, *MockRunLogRepository) {
+	repo := NewMockMachineRepository(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Idle"})
+	sim := newTestSimulator(t, repo)
+	sim.Runs = &MockRunRepository{}
+	logs := &MockRunLogRepository{logs: map[uint]models.RunLog{}}
+	sim.Logs = simulation.NewLogHub(logs)
+	sim.Runner = &simulation.DefaultRunner{Commands: map[string][]string{
+		"streams": {"sh", "-c", "echo to-stdout; echo to-stderr >&2; exit 2"},
+		"verbose": {"sh", "-c", "for i in $(seq 100); do echo line $i; done"},
+		"slow":    {"sh", "-c", "echo first; sleep 0.3; echo second"},
+	}}
+	return sim, logs
+}
+
+func TestRunLogCapture(t *testing.T) {
+	sim, logs := newLogSimulator(t)
+	sim.Logs.Limit = 256
+
+	params := `{"runner": "streams"}`
+	run, err := sim.RunOnce(context.Background(), 1, params, nil)
+	assert.NotNil(t, err)
+
+	stored, err := logs.FindByRun(run.ID)
+	if assert.Nil(t, err, "The log should be stored once the run finishes") {
+		assert.Contains(t, stored.Content, "Run 1 of machine 1 (Press) started.")
+		assert.Contains(t, stored.Content, "to-stdout\n")
+		assert.Contains(t, stored.Content, "to-stderr\n")
+		assert.Contains(t, stored.Content, "Run Failed: command failed: exit status 2")
+		assert.False(t, stored.Truncated)
+	}
+
+	params = `{"runner": "verbose"}`
+	run, err = sim.RunOnce(context.Background(), 1, params, nil)
+	assert.Nil(t, err)
+	truncated, _ := sim.Logs.Get(run.ID)
+	assert.True(t, truncated.Truncated)
+	assert.True(t, strings.HasSuffix(truncated.Content, "[log truncated: output exceeded 256 bytes]\n"), truncated.Content)
+	assert.NotContains(t, truncated.Content, "line 100")
+
+	_, err = sim.Logs.Get(run.ID + 1)
+	assert.ErrorIs(t, err, simulation.ErrRunLogNotFound)
+}
+
+func TestRunLogFollow(t *testing.T) {
+	sim, _ := newLogSimulator(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		params := `{"runner": "slow"}`
+		_, _ = sim.RunOnce(context.Background(), 1, params, nil)
+	}()
+
+	// Attach while the run is still going
+	assert.Eventually(t, func() bool {
+		l, err := sim.Logs.Get(1)
+		return err == nil && strings.Contains(l.Content, "first")
+	}, 3*time.Second, 5*time.Millisecond)
+
+	var chunks []string
+	assert.Nil(t, sim.Logs.Follow(context.Background(), 1, func(chunk []byte) {
+		chunks = append(chunks, string(chunk))
+	}))
+	<-done
+	assert.Greater(t, len(chunks), 1, "A live log should be streamed as it is written")
+	followed := strings.Join(chunks, "")
+	assert.Contains(t, followed, "first\n")
+	assert.Contains(t, followed, "second\n")
+	assert.Contains(t, followed, "Run Succeeded.")
+
+	// Once the run is over the stored log is passed in one go
+	chunks = nil
+	assert.Nil(t, sim.Logs.Follow(context.Background(), 1, func(chunk []byte) {
+		chunks = append(chunks, string(chunk))
+	}))
+	assert.Equal(t, []string{followed}, chunks)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sim.Logs.Follow(ctx, 2, func([]byte) {}), simulation.ErrRunLogNotFound)
+}
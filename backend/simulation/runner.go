@@ -0,0 +1,183 @@
+package simulation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+)
+
+// DefaultKillGrace is how long a cancelled subprocess may take to exit after being killed
+// before its output pipes are forcibly closed.
+const DefaultKillGrace = 5 * time.Second
+
+// runnerEnv names the variables of the server's environment commands inherit. Nothing else is
+// passed on, so commands never see the server's secrets, e.g. its database or storage credentials.
+var runnerEnv = []string{"PATH", "HOME", "TMPDIR", "LANG", "TZ"}
+
+// errInjectedFailure marks runs failed by an injected fault or the chaos profile
+var errInjectedFailure = errors.New("injected failure")
+
+// ErrUnknownRunner is returned for runs of machines naming a runner the server does not have
+var ErrUnknownRunner = errors.New("unknown runner")
+
+// Outputs are the named results of a run, e.g. for a workflow to pass on to its next step
+type Outputs map[string]interface{}
+
+// RunSpec describes a single simulation run for a Runner
+type RunSpec struct {
+	Machine     models.Machine
+	Duration    time.Duration // planned duration of in-process work
+	ArtifactDir string        // files the run leaves here are kept as its artifacts; empty when not collected
+	Log         io.Writer     // receives the run's output, e.g. a subprocess's stdout and stderr; nil to discard it
+}
+
+// Runner executes the work of a single simulation run and returns its outputs, if any.
+// Implementations must return promptly once ctx is cancelled, killing any subprocess they started,
+// and report ctx.Err() in that case.
+type Runner interface {
+	Run(ctx context.Context, spec RunSpec) (Outputs, error)
+}
+
+// RunConfig is the part of Machine.ConfigJSON that controls how runs are executed
+type RunConfig struct {
+	Runner  string `json:"runner"`  // name of a command registered on the server, e.g. "warmup"; empty simulates work in-process
+	Timeout string `json:"timeout"` // optional Go duration after which the command is killed and the run fails
+}
+
+// ParseRunConfig extracts the run settings from a machine's ConfigJSON. Commands cannot be
+// given there, anyone allowed to edit machines could run anything on the server otherwise.
+func ParseRunConfig(configJSON string) (RunConfig, error) {
+	var cfg struct {
+		RunConfig
+		Command json.RawMessage `json:"command"`
+	}
+	if configJSON == "" {
+		return cfg.RunConfig, nil
+	}
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return cfg.RunConfig, fmt.Errorf("invalid config_json: %w", err)
+	}
+	if cfg.Command != nil {
+		return cfg.RunConfig, errors.New("config_json cannot set a command, name a runner instead")
+	}
+	return cfg.RunConfig, nil
+}
+
+// DefaultRunner simulates work in-process for the planned duration, or executes the command
+// registered for the machine's runner with the context so cancellation kills the process.
+// A command reports outputs by writing a JSON object to the file named by $RUN_OUTPUT,
+// and keeps files as artifacts by writing them to the directory named by $RUN_ARTIFACTS.
+// Commands run in a process group of their own, which is killed as a whole when the run ends
+// early, and only see the variables named by runnerEnv besides those describing the run.
+type DefaultRunner struct {
+	Commands  map[string][]string // Key: runner name, Value: the command it executes; nothing else is ever executed
+	KillGrace time.Duration
+}
+
+func (r *DefaultRunner) Run(ctx context.Context, spec RunSpec) (Outputs, error) {
+	machine := spec.Machine
+	cfg, err := ParseRunConfig(machine.ConfigJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Runner == "" {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(spec.Duration):
+			return nil, nil
+		}
+	}
+	command, ok := r.Commands[cfg.Runner]
+	if !ok || len(command) == 0 {
+		return nil, fmt.Errorf("%w %q", ErrUnknownRunner, cfg.Runner)
+	}
+
+	runCtx := ctx
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid run timeout %q", cfg.Timeout)
+		}
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	outputFile, err := os.CreateTemp("", "run-output-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	outputFile.Close()
+	defer os.Remove(outputFile.Name())
+
+	cmd := exec.CommandContext(runCtx, command[0], command[1:]...)
+	cmd.Env = commandEnv(
+		"MACHINE_ID="+strconv.FormatUint(uint64(machine.ID), 10),
+		"MACHINE_NAME="+machine.Name,
+		"MACHINE_CONFIG="+machine.ConfigJSON,
+		"RUN_OUTPUT="+outputFile.Name(),
+	)
+	if spec.ArtifactDir != "" {
+		cmd.Env = append(cmd.Env, "RUN_ARTIFACTS="+spec.ArtifactDir)
+	}
+	cmd.Stdout = spec.Log
+	if cmd.Stdout == nil {
+		cmd.Stdout = io.Discard
+	}
+	cmd.Stderr = cmd.Stdout
+	setProcessGroup(cmd)
+	cmd.WaitDelay = r.KillGrace
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = DefaultKillGrace
+	}
+
+	err = cmd.Run()
+	switch {
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case runCtx.Err() != nil:
+		return nil, fmt.Errorf("run timed out after %s", cfg.Timeout)
+	case err != nil:
+		return nil, fmt.Errorf("command failed: %w", err)
+	}
+	return readOutputs(outputFile.Name())
+}
+
+// commandEnv is the environment of a command: the variables of the server's named by runnerEnv,
+// if set, followed by vars
+func commandEnv(vars ...string) []string {
+	var env []string
+	for _, name := range runnerEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return append(env, vars...)
+}
+
+// readOutputs parses the outputs a command wrote to its $RUN_OUTPUT file; an empty file means no outputs
+func readOutputs(path string) (Outputs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run outputs: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var outputs Outputs
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("run outputs must be a JSON object: %w", err)
+	}
+	return outputs, nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package simulation
+
+import "os/exec"
+
+// setProcessGroup leaves cmd as it is; without process groups cancelling it only kills the
+// command itself
+func setProcessGroup(cmd *exec.Cmd) {}
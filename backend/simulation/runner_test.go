@@ -0,0 +1,117 @@
+package simulation_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRunnerInProcess(t *testing.T) {
+	runner := &simulation.DefaultRunner{}
+
+	_, err := runner.Run(context.Background(), simulation.RunSpec{Duration: 10 * time.Millisecond})
+	assert.Nil(t, err, "In-process runs succeed after their duration")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = runner.Run(ctx, simulation.RunSpec{Duration: time.Hour})
+	assert.True(t, errors.Is(err, context.Canceled), "Cancelled runs return immediately")
+}
+
+func TestDefaultRunnerCommand(t *testing.T) {
+	runner := &simulation.DefaultRunner{KillGrace: 100 * time.Millisecond, Commands: map[string][]string{
+		"press":     {"sh", "-c", "test \"$MACHINE_NAME\" = Press"},
+		"fail":      {"sh", "-c", "exit 3"},
+		"outputs":   {"sh", "-c", "echo '{\"yield\": 0.93}' > \"$RUN_OUTPUT\""},
+		"malformed": {"sh", "-c", "echo nope > \"$RUN_OUTPUT\""},
+		"artifacts": {"sh", "-c", "echo 1,2 > \"$RUN_ARTIFACTS/results.csv\""},
+		"sleep":     {"sleep", "10"},
+		"spawn":     {"sh", "-c", "sleep 10 & wait"},
+		"env":       {"sh", "-c", "test -z \"$RUNNER_TEST_SECRET\" && test -n \"$PATH\""},
+	}}
+
+	t.Run("Success", func(t *testing.T) {
+		machine := models.Machine{ConfigJSON: `{"runner": "press"}`, Name: "Press"}
+		_, err := runner.Run(context.Background(), simulation.RunSpec{Machine: machine})
+		assert.Nil(t, err)
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		machine := models.Machine{ConfigJSON: `{"runner": "fail"}`}
+		_, err := runner.Run(context.Background(), simulation.RunSpec{Machine: machine})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Outputs", func(t *testing.T) {
+		machine := models.Machine{ConfigJSON: `{"runner": "outputs"}`}
+		outputs, err := runner.Run(context.Background(), simulation.RunSpec{Machine: machine})
+		assert.Nil(t, err)
+		assert.Equal(t, simulation.Outputs{"yield": 0.93}, outputs)
+
+		machine.ConfigJSON = `{"runner": "malformed"}`
+		_, err = runner.Run(context.Background(), simulation.RunSpec{Machine: machine})
+		assert.NotNil(t, err, "Malformed outputs fail the run")
+	})
+
+	t.Run("Artifacts", func(t *testing.T) {
+		dir := t.TempDir()
+		machine := models.Machine{ConfigJSON: `{"runner": "artifacts"}`}
+		_, err := runner.Run(context.Background(), simulation.RunSpec{Machine: machine, ArtifactDir: dir})
+		assert.Nil(t, err)
+		assert.FileExists(t, filepath.Join(dir, "results.csv"))
+	})
+
+	t.Run("CancelKillsProcess", func(t *testing.T) {
+		machine := models.Machine{ConfigJSON: `{"runner": "sleep"}`}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := runner.Run(ctx, simulation.RunSpec{Machine: machine})
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Less(t, time.Since(start), 2*time.Second, "The subprocess should be killed promptly")
+	})
+
+	t.Run("CancelKillsProcessGroup", func(t *testing.T) {
+		// The grandchild would keep the log open until the kill grace ran out if it survived
+		runner := &simulation.DefaultRunner{KillGrace: 10 * time.Second, Commands: runner.Commands}
+		machine := models.Machine{ConfigJSON: `{"runner": "spawn"}`}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := runner.Run(ctx, simulation.RunSpec{Machine: machine, Log: &bytes.Buffer{}})
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Less(t, time.Since(start), 2*time.Second, "Processes the command started should be killed too")
+	})
+
+	t.Run("Environment", func(t *testing.T) {
+		t.Setenv("RUNNER_TEST_SECRET", "hunter2")
+		machine := models.Machine{ConfigJSON: `{"runner": "env"}`}
+		_, err := runner.Run(context.Background(), simulation.RunSpec{Machine: machine})
+		assert.Nil(t, err, "Commands see PATH but not the rest of the server's environment")
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		machine := models.Machine{ConfigJSON: `{"runner": "sleep", "timeout": "50ms"}`}
+		_, err := runner.Run(context.Background(), simulation.RunSpec{Machine: machine})
+		assert.NotNil(t, err)
+		assert.False(t, errors.Is(err, context.Canceled), "A timeout is a failure, not a cancellation")
+	})
+	t.Run("OnlyRegisteredCommands", func(t *testing.T) {
+		machine := models.Machine{ConfigJSON: `{"runner": "rm"}`}
+		_, err := runner.Run(context.Background(), simulation.RunSpec{Machine: machine})
+		assert.ErrorIs(t, err, simulation.ErrUnknownRunner)
+
+		machine.ConfigJSON = `{"command": ["sh", "-c", "touch pwned"]}`
+		_, err = runner.Run(context.Background(), simulation.RunSpec{Machine: machine})
+		assert.NotNil(t, err, "Commands from config_json are never executed")
+	})
+}
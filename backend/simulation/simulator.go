@@ -1,124 +1,517 @@
 package simulation
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"log/slog"
 	"math/rand"
+	"os"
+	"sync"
 	"time"
 
+	"github.com/CBYeuler/automation-backend/backend/metrics"
+	"github.com/CBYeuler/automation-backend/backend/models"
 	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Observer is notified of everything the simulator produces, e.g. for alarm evaluation
+type Observer interface {
+	ObserveTelemetry(samples []models.TelemetrySample)
+	ObserveStatus(machine models.Machine, at time.Time) // after machine.Status changed
+}
+
+// ArtifactCollector keeps the files a run left in its artifact directory, e.g. in an artifact store
+type ArtifactCollector interface {
+	Collect(ctx context.Context, runID uint, dir string) error
+}
+
+var tracer = tracing.Tracer("github.com/CBYeuler/automation-backend/backend/simulation")
+
+// machineLog returns a logger tagging records with a machine
+func machineLog(machine models.Machine) *slog.Logger {
+	return slog.With("machine_id", machine.ID, "machine", machine.Name, "organization_id", machine.OrganizationID)
+}
+
+// hangPollInterval is how often a hanging run checks whether its hang fault was lifted
+const hangPollInterval = time.Second
+
 // MachineSimulator defines the structure to hold dependencies
 type MachineSimulator struct {
-	Repo repository.MachineRepository
+	Repo      repository.MachineRepository
+	Telemetry repository.TelemetryRepository
+	Runs      repository.RunRepository
+	Runner    Runner
+	Faults    *FaultInjector
+	Observers []Observer
+	Artifacts ArtifactCollector             // nil disables artifact collection
+	Logs      *LogHub                       // nil disables per-run log capture
+	Topology  repository.TopologyRepository // nil when machines are independent of each other
+
+	// MinRunTime and MaxRunTime bound the simulated duration of a single run
+	MinRunTime time.Duration
+	MaxRunTime time.Duration
+	// HangTimeout is how long a run may overrun before the watchdog cancels it
+	HangTimeout time.Duration
+
+	mu            sync.Mutex
+	ctx           context.Context        // parent of every worker context
+	workers       map[uint]*worker       // Key: Machine ID, Value: the active simulation goroutine
+	cancelled     map[*worker]struct{}   // workers that were told to stop but have not exited yet
+	adHocRuns     map[*adHocRun]struct{} // the ad-hoc runs in flight
+	tickInterval  time.Duration
+	ticker        *time.Ticker // nil until StartGlobalSimulation is called
+	lastReconcile time.Time
+	paused        bool
+	resumeCh      chan struct{} // closed when a paused simulator resumes
+	starved       map[uint]uint // Key: Machine ID, Value: the machine in Error upstream of it
+	topologyCh    chan struct{} // closed when the set of starved machines changes
 }
 
 // NewMachineSimulator creates a new instance
-func NewMachineSimulator(repo repository.MachineRepository) *MachineSimulator {
-	return &MachineSimulator{Repo: repo}
+func NewMachineSimulator(repo repository.MachineRepository, telemetry repository.TelemetryRepository, runs repository.RunRepository) *MachineSimulator {
+	return &MachineSimulator{
+		Repo:        repo,
+		Telemetry:   telemetry,
+		Runs:        runs,
+		Runner:      &DefaultRunner{},
+		Faults:      NewFaultInjector(),
+		MinRunTime:  1 * time.Second,
+		MaxRunTime:  5 * time.Second,
+		HangTimeout: DefaultHangTimeout,
+
+		ctx:          context.Background(),
+		workers:      make(map[uint]*worker),
+		cancelled:    make(map[*worker]struct{}),
+		adHocRuns:    make(map[*adHocRun]struct{}),
+		starved:      make(map[uint]uint),
+		topologyCh:   make(chan struct{}),
+		tickInterval: DefaultTickInterval,
+	}
+}
+
+// AddObserver registers an observer for simulator output. It must be called before StartGlobalSimulation.
+func (s *MachineSimulator) AddObserver(o Observer) {
+	s.Observers = append(s.Observers, o)
 }
 
 // StartGlobalSimulation continuously checks for machines and starts/manages simulation goroutines.
 func (s *MachineSimulator) StartGlobalSimulation() {
-	log.Println("Starting global machine simulation monitor...")
+	slog.Info("Starting global machine simulation monitor")
+
+	// Check machines every tick interval (5 seconds by default, adjustable at runtime via Configure)
+	s.mu.Lock()
+	s.ticker = time.NewTicker(s.tickInterval)
+	ticker := s.ticker
+	s.mu.Unlock()
 
 	// This goroutine runs indefinitely to keep the simulation alive and monitor machines
 	go func() {
-		ticker := time.NewTicker(5 * time.Second) // Check machines every 5 seconds
-		defer ticker.Stop()
+		for range ticker.C {
+			s.Reconcile()
+		}
+	}()
+}
 
-		// A channel map to track which machine simulation goroutines are running
-		// Key: Machine ID, Value: A channel to signal stopping the goroutine
-		runningSims := make(map[uint]chan struct{})
+// Reconcile runs one monitor pass: it starts simulations for Idle/Running machines, stops Offline ones,
+// and lets the watchdog cancel hung workers and workers whose machine has been deleted.
+func (s *MachineSimulator) Reconcile() {
+	machines, err := s.Repo.FindAll()
+	if err != nil {
+		slog.Error("Failed to fetch machines for simulation", "error", err)
+		return
+	}
+	s.refreshStarvation(machines)
 
-		for range ticker.C {
-			machines, err := s.Repo.FindAll()
-			if err != nil {
-				log.Printf("Error fetching machines for simulation: %v", err)
-				continue
-			}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastReconcile = time.Now()
 
-			// Check for new machines or machines that should be running
-			for _, machine := range machines {
-				// Only simulate machines with status "Idle" or "Running"
-				if (machine.Status == "Idle" || machine.Status == "Running") && runningSims[machine.ID] == nil {
-					// Start a new simulation goroutine for this machine
-					stopCh := make(chan struct{})
-					runningSims[machine.ID] = stopCh
-					go s.runMachineSimulation(machine.ID, stopCh)
-				}
-
-				// Handle status changes (e.g., if a dashboard command set it to 'Offline')
-				if machine.Status == "Offline" && runningSims[machine.ID] != nil {
-					// Signal the running goroutine to stop
-					close(runningSims[machine.ID])
-					delete(runningSims, machine.ID)
-					log.Printf("Machine %d (%s) simulation stopped.", machine.ID, machine.Name)
-				}
-			}
+	existing := make(map[uint]bool, len(machines))
+	for _, machine := range machines {
+		existing[machine.ID] = true
+
+		// Only simulate machines with status "Idle" or "Running"
+		if machine.Status == "Idle" || machine.Status == "Running" {
+			s.startWorker(machine.ID)
 		}
-	}()
+
+		// Handle status changes (e.g., if a dashboard command set it to 'Offline')
+		if w := s.workers[machine.ID]; machine.Status == "Offline" && w != nil {
+			// Signal the running goroutine to stop
+			s.cancelWorker(w, WorkerStopping)
+			w.log.Info("Machine simulation stopped", "machine", machine.Name)
+		}
+	}
+
+	s.checkWorkers(existing, time.Now())
+}
+
+// MachineUpdated reacts to a machine changed through the API: an Offline machine has its
+// simulation (and any run in flight) cancelled immediately rather than at the next tick.
+func (s *MachineSimulator) MachineUpdated(machine models.Machine) {
+	s.mu.Lock()
+	if machine.Status == "Offline" {
+		s.cancelAdHocRuns(machine.ID)
+		if w := s.workers[machine.ID]; w != nil {
+			s.cancelWorker(w, WorkerStopping)
+			w.log.Info("Machine simulation stopped", "machine", machine.Name)
+		}
+	}
+	s.mu.Unlock()
+	// The machine may have been put into or out of Error, affecting the machines downstream
+	s.TopologyChanged()
+}
+
+// MachineDeleted cancels the simulation and any ad-hoc runs of a machine deleted through the API
+func (s *MachineSimulator) MachineDeleted(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelAdHocRuns(id)
+	if w := s.workers[id]; w != nil {
+		s.cancelWorker(w, WorkerOrphaned)
+		w.log.Info("Machine deleted, simulation cancelled")
+	}
+	metrics.ForgetMachine(id)
+}
+
+// runDuration picks how long the next run takes, stretched by any slow fault
+func (s *MachineSimulator) runDuration(slowFactor float64) time.Duration {
+	base := s.MinRunTime
+	if spread := s.MaxRunTime - s.MinRunTime; spread > 0 {
+		base += time.Duration(rand.Int63n(int64(spread)))
+	}
+	return time.Duration(float64(base) * slowFactor)
 }
 
 // runMachineSimulation is a long-lived goroutine for a single machine's simulation cycle.
-func (s *MachineSimulator) runMachineSimulation(machineID uint, stopCh <-chan struct{}) {
-	log.Printf("Machine %d simulation started.", machineID)
+// Every run executes under the worker's context, so stopping or deleting the machine cancels it mid-flight.
+func (s *MachineSimulator) runMachineSimulation(w *worker) {
+	defer s.finishWorker(w)
+	machineID, ctx, logger := w.machineID, w.ctx, w.log
+	logger.Info("Machine simulation started")
 
 	// Update status to Running initially
 	s.updateMachineStatus(machineID, "Running")
 
+	// Telemetry generators are rebuilt whenever the machine's ConfigJSON changes
+	var telemetry *TelemetryGenerator
+	var telemetryConfig string
+	rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(machineID)))
+
 	// Simulate work cycles
 	for {
-		select {
-		case <-stopCh:
-			// Received stop signal
-			s.updateMachineStatus(machineID, "Idle") // Set to Idle/Offline upon stopping
+		if err := s.waitWhilePaused(ctx); err != nil {
+			s.stopped(w)
 			return
+		}
+		if err := s.waitWhileStarved(w); err != nil {
+			s.stopped(w)
+			return
+		}
 
-		case <-time.After(time.Duration(rand.Intn(4)+1) * time.Second): // Simulate work taking 1-5 seconds
-			// Simulation Step
-			// machine is a *models.Machine (pointer) because s.Repo.FindByID returns a pointer
-			machine, err := s.Repo.FindByID(machineID)
-			if err != nil {
-				log.Printf("Sim Error: Machine %d not found, stopping simulation.", machineID)
-				return // Stop if machine is deleted
+		// machine is a *models.Machine (pointer) because s.Repo.FindByID returns a pointer
+		machine, err := s.Repo.FindByID(machineID)
+		if err != nil {
+			logger.Error("Machine not found, stopping simulation")
+			return // Stop if machine is deleted
+		}
+
+		// Injected faults and the chaos profile decide how this run goes wrong, if at all
+		plan := s.Faults.PlanRun(machineID)
+		work := s.runDuration(plan.SlowFactor) // Simulate work taking 1-5 seconds (longer under a slow fault)
+		s.heartbeat(w, work)
+
+		run := &models.SimulationRun{MachineID: machineID}
+		runErr := s.executeRun(ctx, run, *machine, plan, work)
+		if ctx.Err() != nil {
+			// Received stop signal while the run was in flight
+			s.stopped(w)
+			return
+		}
+
+		// Simulation Step: reload the machine so API changes made during the run are not overwritten
+		machine, err = s.Repo.FindByID(machineID)
+		if err != nil {
+			logger.Error("Machine not found, stopping simulation")
+			return
+		}
+
+		// Core simulation logic: increment runs and update timestamp
+		machine.SimulatedRuns++
+		machine.LastSimulated = time.Now()
+
+		previousStatus := machine.Status
+		if runErr != nil {
+			machine.Status = "Error"
+			logger.Warn("Machine entered the Error state", "machine", machine.Name, "error", runErr)
+			if previousStatus != "Error" {
+				metrics.MachineErrors.WithLabelValues(metrics.MachineLabel(machineID)).Inc()
 			}
+			// Don't return, let the next loop check the status again (e.g., for recovery command)
+		} else if machine.Status == "Error" {
+			// Return to Running if it was in error
+			machine.Status = "Running"
+		}
 
-			// Core simulation logic: increment runs and update timestamp
-			machine.SimulatedRuns++
-			machine.LastSimulated = time.Now()
-
-			// Introduce a small chance of error (2%)
-			if rand.Intn(100) < 2 {
-				s.updateMachineStatus(machineID, "Error")
-				log.Printf("Machine %d (%s) has ERROR state!", machineID, machine.Name)
-				// Don't return, let the next loop check the status again (e.g., for recovery command)
-			} else {
-				// Return to Running/Idle if it was in error, or keep Running
-				if machine.Status == "Error" {
-					s.updateMachineStatus(machineID, "Running")
-				}
+		if telemetry == nil || machine.ConfigJSON != telemetryConfig {
+			telemetryConfig = machine.ConfigJSON
+			telemetry, err = NewTelemetryGenerator(telemetryConfig, machine.LastSimulated, rng)
+			if err != nil {
+				logger.Warn("Invalid telemetry config, using defaults", "error", err)
+				telemetry, _ = NewTelemetryGenerator("", machine.LastSimulated, rng)
 			}
+		}
+		samples := telemetry.Sample(*machine, machine.LastSimulated)
 
+		if plan.DropWrites {
+			logger.Info("Fault: dropping database writes of run", "run_number", machine.SimulatedRuns)
+		} else {
 			if err := s.Repo.Update(machine); err != nil {
-				log.Printf("Sim Error: Failed to update machine %d: %v", machineID, err)
+				logger.Error("Failed to update machine", "error", err)
 			}
-			log.Printf("Machine %d (%s) completed run #%d.", machineID, machine.Name, machine.SimulatedRuns)
+			s.storeTelemetry(samples)
+		}
+
+		for _, o := range s.Observers {
+			if machine.Status != previousStatus {
+				o.ObserveStatus(*machine, machine.LastSimulated)
+			}
+			o.ObserveTelemetry(samples)
+		}
+
+		// Entering or leaving Error starves or restores the machines downstream
+		if (machine.Status == "Error") != (previousStatus == "Error") && !plan.DropWrites {
+			s.TopologyChanged()
+		}
+
+		s.completeRun(w, runErr != nil)
+		logger.Debug("Machine completed run", "machine", machine.Name, "run_number", machine.SimulatedRuns)
+	}
+}
+
+// executeRun performs a single run under ctx and records it in run (Succeeded, Failed or Cancelled,
+// with the runner's outputs). It returns the run's error, if any.
+func (s *MachineSimulator) executeRun(ctx context.Context, run *models.SimulationRun, machine models.Machine, plan RunPlan, work time.Duration) (err error) {
+	ctx, span := tracer.Start(ctx, "simulation.run", trace.WithAttributes(
+		attribute.Int("machine.id", int(machine.ID)),
+		attribute.String("machine.name", machine.Name),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("run.id", int(run.ID)), attribute.String("run.status", run.Status))
+		tracing.End(span, err)
+	}()
+
+	logger := machineLog(machine)
+	run.OrganizationID = machine.OrganizationID
+	run.Status = models.RunStatusRunning
+	run.StartedAt = time.Now()
+	// The run is recorded even when it is cancelled
+	runs := s.Runs
+	record := runs != nil && !plan.DropWrites
+	if record {
+		runs = runs.WithContext(context.WithoutCancel(ctx))
+		if err := runs.Create(run); err != nil {
+			logger.ErrorContext(ctx, "Failed to record run", "error", err)
+			record = false
+		}
+	}
+
+	spec := RunSpec{Machine: machine, Duration: work}
+
+	// Logs and artifacts can only be linked to recorded runs
+	var runLog *RunLog
+	if record && s.Logs != nil {
+		runLog = s.Logs.open(run.ID)
+		defer s.Logs.close(run.ID)
+		spec.Log = runLog
+	}
+	if record && s.Artifacts != nil {
+		dir, err := os.MkdirTemp("", "run-artifacts-*")
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to create artifact directory", "run_id", run.ID, "error", err)
+		} else {
+			spec.ArtifactDir = dir
+			defer os.RemoveAll(dir)
+		}
+	}
+	runLog.Printf("Run %d of machine %d (%s) started.", run.ID, machine.ID, machine.Name)
+
+	outputs, err := s.runWork(ctx, plan, spec, runLog)
+	if err == nil {
+		setRunOutputs(run, outputs)
+	}
+	// Artifacts of failed and cancelled runs are kept too, they are often what explains the failure
+	if spec.ArtifactDir != "" {
+		if err := s.Artifacts.Collect(context.WithoutCancel(ctx), run.ID, spec.ArtifactDir); err != nil {
+			logger.ErrorContext(ctx, "Failed to collect artifacts", "run_id", run.ID, "error", err)
+			runLog.Printf("Failed to collect artifacts: %v", err)
+		}
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	switch {
+	case ctx.Err() != nil:
+		run.Status = models.RunStatusCancelled
+		run.Error = ctx.Err().Error()
+		logger.InfoContext(ctx, "Run cancelled", "run_id", run.ID)
+	case err != nil:
+		run.Status = models.RunStatusFailed
+		run.Error = err.Error()
+	default:
+		run.Status = models.RunStatusSucceeded
+	}
+	metrics.RunsCompleted.WithLabelValues(metrics.MachineLabel(machine.ID), run.Status).Inc()
+	if run.Error != "" {
+		runLog.Printf("Run %s: %s", run.Status, run.Error)
+	} else {
+		runLog.Printf("Run %s.", run.Status)
+	}
+	if record {
+		if err := runs.Update(run); err != nil {
+			logger.ErrorContext(ctx, "Failed to update run", "run_id", run.ID, "error", err)
 		}
 	}
+	return err
+}
+
+// runWork applies the planned faults around the runner
+func (s *MachineSimulator) runWork(ctx context.Context, plan RunPlan, spec RunSpec, runLog *RunLog) (Outputs, error) {
+	// A hanging run deliberately stops sending heartbeats so the watchdog can catch it
+	if plan.Hang {
+		runLog.Printf("Fault: run hangs until the hang fault is cleared.")
+		if err := s.waitWhileHanging(ctx, spec.Machine.ID); err != nil {
+			return nil, err
+		}
+	}
+	if plan.SlowFactor > 1 {
+		runLog.Printf("Fault: run slowed down %.1fx.", plan.SlowFactor)
+	}
+	outputs, err := s.Runner.Run(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Fail {
+		runLog.Printf("Fault: run fails after completing its work.")
+		return nil, errInjectedFailure
+	}
+	return outputs, nil
+}
+
+// setRunOutputs stores outputs on a run as a JSON object, keeping outputs already recorded unless overridden
+func setRunOutputs(run *models.SimulationRun, outputs Outputs) {
+	if len(outputs) == 0 {
+		return
+	}
+	merged := Outputs{}
+	if run.Outputs != "" {
+		_ = json.Unmarshal([]byte(run.Outputs), &merged)
+	}
+	for key, value := range outputs {
+		merged[key] = value
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		slog.Error("Failed to encode run outputs", "run_id", run.ID, "error", err)
+		return
+	}
+	run.Outputs = string(data)
 }
 
-// updateMachineStatus is a helper function to set machine status in DB
+// stopped handles a worker's stop signal. Machines stopped for going Offline stay Offline;
+// a hung machine is set back to Idle so the next reconciliation restarts its simulation.
+func (s *MachineSimulator) stopped(w *worker) {
+	s.mu.Lock()
+	state := w.state
+	s.mu.Unlock()
+
+	if state == WorkerHung {
+		s.updateMachineStatus(w.machineID, "Idle")
+	}
+	w.log.Info("Machine simulation exited", "state", state)
+}
+
+// waitWhileHanging blocks while a hang fault is active for the machine.
+// It returns ctx.Err() if the run is cancelled while hanging.
+func (s *MachineSimulator) waitWhileHanging(ctx context.Context, machineID uint) error {
+	slog.InfoContext(ctx, "Fault: run is hanging", "machine_id", machineID)
+	for s.Faults.Hanging(machineID) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(hangPollInterval):
+		}
+	}
+	return nil
+}
+
+// storeTelemetry persists the samples produced by one simulation cycle
+func (s *MachineSimulator) storeTelemetry(samples []models.TelemetrySample) {
+	if s.Telemetry == nil {
+		return
+	}
+	if err := s.Telemetry.Append(samples); err != nil {
+		slog.Error("Failed to store telemetry", "error", err)
+	}
+}
+
+// InjectFault registers a fault for a machine. Error faults take effect immediately.
+func (s *MachineSimulator) InjectFault(machineID uint, spec FaultSpec) (Fault, error) {
+	fault, err := s.Faults.Inject(machineID, spec)
+	if err != nil {
+		return Fault{}, err
+	}
+	if fault.Type == FaultError {
+		s.updateMachineStatus(machineID, "Error")
+	}
+	slog.Info("Fault injected", "machine_id", machineID, "fault_id", fault.ID, "fault", fault.Type)
+	return fault, nil
+}
+
+// swapMachineStatus is a compare-and-set of a machine's status: the status is only written
+// while the machine is still in one of the from statuses, checked in the same statement. A
+// starved machine is thus only restored to Running while it is still Idle, and statuses set
+// meanwhile, e.g. Offline or Error through the API, are kept. Observers only hear of changes.
+func (s *MachineSimulator) swapMachineStatus(machineID uint, status string, from ...string) {
+	changed, err := s.Repo.SetStatusIf(machineID, status, from...)
+	if err != nil {
+		slog.Error("Failed to update machine status", "machine_id", machineID, "status", status, "error", err)
+		return
+	}
+	if !changed {
+		return
+	}
+	machine, err := s.Repo.FindByID(machineID)
+	if err != nil {
+		return
+	}
+	for _, o := range s.Observers {
+		o.ObserveStatus(*machine, time.Now())
+	}
+}
+
+// updateMachineStatus sets the status of a machine in the DB whatever it is, telling observers
+// if it changed
 func (s *MachineSimulator) updateMachineStatus(machineID uint, status string) {
 	// machine is a *models.Machine (pointer)
 	machine, err := s.Repo.FindByID(machineID)
 	if err != nil {
-		log.Printf("Update Status Error: Machine %d not found.", machineID)
+		slog.Error("Failed to update machine status: machine not found", "machine_id", machineID)
 		return
 	}
+	changed := machine.Status != status
 	machine.Status = status
 	// FIX: Removed '&' since 'machine' is already a pointer
 	if err := s.Repo.Update(machine); err != nil {
-		log.Printf("Update Status Error: Failed to update status for machine %d: %v", machineID, err)
+		slog.Error("Failed to update machine status", "machine_id", machineID, "status", status, "error", err)
+		return
+	}
+	if changed {
+		for _, o := range s.Observers {
+			o.ObserveStatus(*machine, time.Now())
+		}
 	}
 }
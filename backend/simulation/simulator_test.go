@@ -0,0 +1,304 @@
+package simulation_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/metrics"
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockMachineRepository is a concurrency-safe in-memory repository shared by the simulation goroutines
+type MockMachineRepository struct {
+	mu       sync.Mutex
+	machines map[uint]models.Machine
+}
+
+func NewMockMachineRepository(machines ...models.Machine) *MockMachineRepository {
+	repo := &MockMachineRepository{machines: make(map[uint]models.Machine)}
+	for _, machine := range machines {
+		repo.machines[machine.ID] = machine
+	}
+	return repo
+}
+
+func (m *MockMachineRepository) ForTenant(uint) repository.MachineRepository              { return m }
+func (m *MockMachineRepository) WithContext(context.Context) repository.MachineRepository { return m }
+
+func (m *MockMachineRepository) Create(machine *models.Machine) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.machines[machine.ID] = *machine
+	return nil
+}
+func (m *MockMachineRepository) CountByStatus() (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := map[string]int64{}
+	for _, machine := range m.machines {
+		counts[machine.Status]++
+	}
+	return counts, nil
+}
+func (m *MockMachineRepository) FindAll() ([]models.Machine, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var machines []models.Machine
+	for _, machine := range m.machines {
+		machines = append(machines, machine)
+	}
+	return machines, nil
+}
+func (m *MockMachineRepository) FindByID(id uint) (*models.Machine, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	machine, ok := m.machines[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &machine, nil
+}
+func (m *MockMachineRepository) Update(machine *models.Machine) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.machines[machine.ID]; !ok {
+		return errors.New("record not found")
+	}
+	m.machines[machine.ID] = *machine
+	return nil
+}
+func (m *MockMachineRepository) Transaction(fn func(repository.MachineRepository) error) error {
+	return fn(m)
+}
+func (m *MockMachineRepository) SetStatusIf(id uint, status string, from ...string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	machine, ok := m.machines[id]
+	if !ok || !slices.Contains(from, machine.Status) {
+		return false, nil
+	}
+	machine.Status = status
+	m.machines[id] = machine
+	return true, nil
+}
+func (m *MockMachineRepository) UpdateStatus(ids []uint, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		if machine, ok := m.machines[id]; ok {
+			machine.Status = status
+			m.machines[id] = machine
+		}
+	}
+	return nil
+}
+func (m *MockMachineRepository) Delete(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.machines, id)
+	return nil
+}
+
+// MockRunRepository keeps simulation runs in memory
+type MockRunRepository struct {
+	mu   sync.Mutex
+	runs []models.SimulationRun
+}
+
+func (m *MockRunRepository) WithContext(context.Context) repository.RunRepository { return m }
+
+func (m *MockRunRepository) Create(run *models.SimulationRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run.ID = uint(len(m.runs) + 1)
+	m.runs = append(m.runs, *run)
+	return nil
+}
+func (m *MockRunRepository) Update(run *models.SimulationRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs[run.ID-1] = *run
+	return nil
+}
+func (m *MockRunRepository) FindByID(id uint) (*models.SimulationRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := m.runs[id-1]
+	return &run, nil
+}
+func (m *MockRunRepository) FindByMachine(machineID uint, limit int) ([]models.SimulationRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.SimulationRun(nil), m.runs...), nil
+}
+
+func (m *MockRunRepository) statuses() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var statuses []string
+	for _, run := range m.runs {
+		statuses = append(statuses, run.Status)
+	}
+	return statuses
+}
+
+// newTestSimulator returns a simulator with millisecond runs and no random chaos
+func newTestSimulator(t *testing.T, repo *MockMachineRepository) *simulation.MachineSimulator {
+	sim := simulation.NewMachineSimulator(repo, nil, nil)
+	sim.MinRunTime = 5 * time.Millisecond
+	sim.MaxRunTime = 10 * time.Millisecond
+	sim.HangTimeout = 50 * time.Millisecond
+	assert.Nil(t, sim.Faults.SetChaos(simulation.ChaosProfile{}))
+	return sim
+}
+
+func workerStates(sim *simulation.MachineSimulator) []string {
+	var states []string
+	for _, w := range sim.Workers() {
+		states = append(states, w.State)
+	}
+	return states
+}
+
+func TestReconcileStartsAndStopsWorkers(t *testing.T) {
+	repo := NewMockMachineRepository(
+		models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Idle"},
+		models.Machine{Model: models.Model{ID: 2}, Name: "Lathe", Status: "Offline"},
+	)
+	sim := newTestSimulator(t, repo)
+
+	sim.Reconcile()
+	workers := sim.Workers()
+	assert.Len(t, workers, 1, "Only Idle/Running machines are simulated")
+	assert.Equal(t, uint(1), workers[0].MachineID)
+	assert.Equal(t, 1, sim.ActiveWorkers())
+
+	assert.Eventually(t, func() bool {
+		return len(sim.Workers()) == 1 && sim.Workers()[0].Runs > 0
+	}, time.Second, 5*time.Millisecond, "Worker should complete runs")
+
+	machine, _ := repo.FindByID(1)
+	machine.Status = "Offline"
+	assert.Nil(t, repo.Update(machine))
+	sim.Reconcile()
+
+	assert.Eventually(t, func() bool { return len(sim.Workers()) == 0 }, time.Second, 5*time.Millisecond, "Worker should exit")
+	machine, _ = repo.FindByID(1)
+	assert.Equal(t, "Offline", machine.Status, "Stopping must not flip the machine back to Idle")
+}
+
+func TestWatchdogCancelsOrphanedWorkers(t *testing.T) {
+	repo := NewMockMachineRepository(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Idle"})
+	sim := newTestSimulator(t, repo)
+
+	// Hang the worker so it cannot notice the deletion on its own
+	_, err := sim.Faults.Inject(1, simulation.FaultSpec{Type: simulation.FaultHang})
+	assert.Nil(t, err)
+	sim.Reconcile()
+
+	assert.Nil(t, repo.Delete(1))
+	sim.Reconcile()
+
+	assert.Contains(t, workerStates(sim), simulation.WorkerOrphaned)
+	assert.Eventually(t, func() bool { return len(sim.Workers()) == 0 }, 2*time.Second, 10*time.Millisecond, "Orphaned worker should exit")
+}
+
+func TestWatchdogCancelsHungWorkers(t *testing.T) {
+	repo := NewMockMachineRepository(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Idle"})
+	sim := newTestSimulator(t, repo)
+
+	_, err := sim.Faults.Inject(1, simulation.FaultSpec{Type: simulation.FaultHang, Duration: "150ms"})
+	assert.Nil(t, err)
+	sim.Reconcile()
+
+	// Wait past the run time plus the hang timeout, then let the watchdog look
+	time.Sleep(100 * time.Millisecond)
+	sim.Reconcile()
+
+	states := workerStates(sim)
+	assert.Contains(t, states, simulation.WorkerHung, "Hung worker should be cancelled")
+
+	// Once the hang is over, reconciliation restarts a healthy worker
+	assert.Eventually(t, func() bool {
+		sim.Reconcile()
+		workers := sim.Workers()
+		return len(workers) == 1 && workers[0].State == simulation.WorkerRunning && workers[0].Runs > 0
+	}, 2*time.Second, 20*time.Millisecond, "Simulation should recover after the hang")
+}
+
+func TestPauseAndResume(t *testing.T) {
+	repo := NewMockMachineRepository(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Idle"})
+	sim := newTestSimulator(t, repo)
+
+	sim.Pause()
+	sim.Reconcile()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, sim.Workers()[0].Runs, "Paused workers should not run")
+	assert.Equal(t, simulation.SimulatorPaused, sim.Status().State)
+
+	// Paused workers are not mistaken for hung ones
+	time.Sleep(60 * time.Millisecond)
+	sim.Reconcile()
+	assert.Equal(t, []string{simulation.WorkerRunning}, workerStates(sim))
+
+	sim.Resume()
+	assert.Eventually(t, func() bool { return sim.Workers()[0].Runs > 0 }, time.Second, 5*time.Millisecond, "Resumed workers should run again")
+}
+
+func TestStopCancelsRunInFlight(t *testing.T) {
+	repo := NewMockMachineRepository(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Idle"})
+	runs := &MockRunRepository{}
+	sim := newTestSimulator(t, repo)
+	sim.Runs = runs
+	// Runs would take a minute; only cancellation can end them
+	sim.MinRunTime, sim.MaxRunTime = time.Minute, time.Minute
+	sim.HangTimeout = time.Minute
+
+	sim.Reconcile()
+	assert.Eventually(t, func() bool {
+		return len(runs.statuses()) == 1
+	}, time.Second, 5*time.Millisecond, "A run should be recorded as soon as it starts")
+	assert.Equal(t, []string{models.RunStatusRunning}, runs.statuses())
+
+	machine, _ := repo.FindByID(1)
+	machine.Status = "Offline"
+	assert.Nil(t, repo.Update(machine))
+	sim.MachineUpdated(*machine)
+
+	assert.Eventually(t, func() bool {
+		return len(sim.Workers()) == 0
+	}, time.Second, 5*time.Millisecond, "The worker should exit without waiting for the run")
+	assert.Equal(t, []string{models.RunStatusCancelled}, runs.statuses())
+}
+
+func TestRunsRecordFailures(t *testing.T) {
+	repo := NewMockMachineRepository(models.Machine{Model: models.Model{ID: 1}, Name: "Press", Status: "Idle"})
+	runs := &MockRunRepository{}
+	sim := newTestSimulator(t, repo)
+	sim.Runs = runs
+
+	_, err := sim.Faults.Inject(1, simulation.FaultSpec{Type: simulation.FaultFailRuns, Count: 1})
+	assert.Nil(t, err)
+	sim.Reconcile()
+
+	assert.Eventually(t, func() bool {
+		statuses := runs.statuses()
+		return len(statuses) >= 3 && statuses[1] == models.RunStatusSucceeded
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, models.RunStatusFailed, runs.statuses()[0], "The injected failure should be recorded")
+	label := metrics.MachineLabel(1)
+	assert.GreaterOrEqual(t, testutil.ToFloat64(metrics.RunsCompleted.WithLabelValues(label, models.RunStatusFailed)), float64(1))
+	assert.GreaterOrEqual(t, testutil.ToFloat64(metrics.RunsCompleted.WithLabelValues(label, models.RunStatusSucceeded)), float64(1))
+	assert.GreaterOrEqual(t, testutil.ToFloat64(metrics.MachineErrors.WithLabelValues(label)), float64(1))
+
+	sim.MachineDeleted(1)
+	assert.Eventually(t, func() bool { return len(sim.Workers()) == 0 }, time.Second, 5*time.Millisecond)
+}
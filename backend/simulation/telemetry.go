@@ -0,0 +1,253 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+)
+
+// Supported signal generator types for the "generator" field of a SignalConfig
+const (
+	GeneratorSine       = "sine"
+	GeneratorRandomWalk = "random_walk"
+	GeneratorStep       = "step"
+	GeneratorNoise      = "noise"
+	GeneratorDrift      = "drift"
+)
+
+// SignalConfig describes how a single telemetry metric is generated.
+// Only the fields relevant to the selected generator are used; durations are Go duration strings ("30s", "5m").
+type SignalConfig struct {
+	Generator string    `json:"generator"`
+	Base      float64   `json:"base"`
+	Amplitude float64   `json:"amplitude,omitempty"` // sine
+	Period    string    `json:"period,omitempty"`    // sine
+	Step      float64   `json:"step,omitempty"`      // random_walk: maximum change per sample
+	Min       *float64  `json:"min,omitempty"`       // random_walk, drift: lower clamp
+	Max       *float64  `json:"max,omitempty"`       // random_walk, drift: upper clamp
+	StdDev    float64   `json:"stddev,omitempty"`    // noise
+	Levels    []float64 `json:"levels,omitempty"`    // step
+	Interval  string    `json:"interval,omitempty"`  // step: time spent on each level
+	Target    float64   `json:"target,omitempty"`    // drift: value the signal degrades towards (the failure level)
+	Rate      float64   `json:"rate,omitempty"`      // drift: units per second towards Target
+	Noise     float64   `json:"noise,omitempty"`     // optional gaussian noise added on top of any generator
+}
+
+// telemetryConfig is the part of Machine.ConfigJSON the simulator cares about
+type telemetryConfig struct {
+	Telemetry map[string]SignalConfig `json:"telemetry"`
+}
+
+// DefaultTelemetryConfig returns the signals emitted for machines without a "telemetry" section in their ConfigJSON.
+func DefaultTelemetryConfig() map[string]SignalConfig {
+	throughputMin, throughputMax := 0.0, 200.0
+	return map[string]SignalConfig{
+		"temperature": {Generator: GeneratorSine, Base: 65, Amplitude: 5, Period: "5m", Noise: 0.5},
+		"vibration":   {Generator: GeneratorNoise, Base: 2.5, StdDev: 0.2},
+		"throughput":  {Generator: GeneratorRandomWalk, Base: 120, Step: 3, Min: &throughputMin, Max: &throughputMax},
+		"power":       {Generator: GeneratorStep, Base: 4.5, Levels: []float64{4.5, 5.5}, Interval: "1m", Noise: 0.1},
+	}
+}
+
+// ParseTelemetryConfig extracts the signal definitions from a machine's ConfigJSON.
+// Machines without a "telemetry" section fall back to DefaultTelemetryConfig.
+func ParseTelemetryConfig(configJSON string) (map[string]SignalConfig, error) {
+	if configJSON == "" {
+		return DefaultTelemetryConfig(), nil
+	}
+	var cfg telemetryConfig
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config_json: %w", err)
+	}
+	if len(cfg.Telemetry) == 0 {
+		return DefaultTelemetryConfig(), nil
+	}
+	return cfg.Telemetry, nil
+}
+
+// SignalGenerator produces the next value of a signal at a given point in time
+type SignalGenerator interface {
+	Next(t time.Time) float64
+}
+
+// NewSignalGenerator builds a generator from its configuration, using rng for any randomness.
+func NewSignalGenerator(cfg SignalConfig, start time.Time, rng *rand.Rand) (SignalGenerator, error) {
+	var gen SignalGenerator
+	switch cfg.Generator {
+	case GeneratorSine:
+		period, err := parsePositiveDuration("period", cfg.Period)
+		if err != nil {
+			return nil, err
+		}
+		gen = &sineGenerator{base: cfg.Base, amplitude: cfg.Amplitude, period: period, start: start}
+	case GeneratorRandomWalk:
+		gen = &randomWalkGenerator{value: cfg.Base, step: cfg.Step, min: cfg.Min, max: cfg.Max, rng: rng}
+	case GeneratorStep:
+		if len(cfg.Levels) == 0 {
+			return nil, fmt.Errorf("step generator requires at least one level")
+		}
+		interval, err := parsePositiveDuration("interval", cfg.Interval)
+		if err != nil {
+			return nil, err
+		}
+		gen = &stepGenerator{levels: cfg.Levels, interval: interval, start: start}
+	case GeneratorNoise:
+		gen = &noiseGenerator{base: cfg.Base, stddev: cfg.StdDev, rng: rng}
+	case GeneratorDrift:
+		gen = &driftGenerator{base: cfg.Base, target: cfg.Target, rate: cfg.Rate, min: cfg.Min, max: cfg.Max, start: start}
+	default:
+		return nil, fmt.Errorf("unknown signal generator %q", cfg.Generator)
+	}
+
+	if cfg.Noise > 0 {
+		gen = &noisyGenerator{inner: gen, stddev: cfg.Noise, rng: rng}
+	}
+	return gen, nil
+}
+
+func parsePositiveDuration(field, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", field, value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got %q", field, value)
+	}
+	return d, nil
+}
+
+func clamp(v float64, min, max *float64) float64 {
+	if min != nil && v < *min {
+		return *min
+	}
+	if max != nil && v > *max {
+		return *max
+	}
+	return v
+}
+
+// sineGenerator oscillates around base with the given amplitude and period
+type sineGenerator struct {
+	base, amplitude float64
+	period          time.Duration
+	start           time.Time
+}
+
+func (g *sineGenerator) Next(t time.Time) float64 {
+	phase := 2 * math.Pi * float64(t.Sub(g.start)) / float64(g.period)
+	return g.base + g.amplitude*math.Sin(phase)
+}
+
+// randomWalkGenerator moves by at most step (in either direction) per sample
+type randomWalkGenerator struct {
+	value, step float64
+	min, max    *float64
+	rng         *rand.Rand
+}
+
+func (g *randomWalkGenerator) Next(time.Time) float64 {
+	g.value = clamp(g.value+(g.rng.Float64()*2-1)*g.step, g.min, g.max)
+	return g.value
+}
+
+// stepGenerator cycles through fixed levels, spending interval on each
+type stepGenerator struct {
+	levels   []float64
+	interval time.Duration
+	start    time.Time
+}
+
+func (g *stepGenerator) Next(t time.Time) float64 {
+	elapsed := t.Sub(g.start)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return g.levels[int(elapsed/g.interval)%len(g.levels)]
+}
+
+// noiseGenerator returns gaussian noise around base
+type noiseGenerator struct {
+	base, stddev float64
+	rng          *rand.Rand
+}
+
+func (g *noiseGenerator) Next(time.Time) float64 {
+	return g.base + g.rng.NormFloat64()*g.stddev
+}
+
+// driftGenerator degrades linearly from base towards target (e.g. a bearing heating up before failure)
+type driftGenerator struct {
+	base, target, rate float64
+	min, max           *float64
+	start              time.Time
+}
+
+func (g *driftGenerator) Next(t time.Time) float64 {
+	delta := g.rate * t.Sub(g.start).Seconds()
+	value := g.base + delta
+	if g.target < g.base {
+		value = g.base - delta
+		value = math.Max(value, g.target)
+	} else {
+		value = math.Min(value, g.target)
+	}
+	return clamp(value, g.min, g.max)
+}
+
+// noisyGenerator adds gaussian noise on top of another generator
+type noisyGenerator struct {
+	inner  SignalGenerator
+	stddev float64
+	rng    *rand.Rand
+}
+
+func (g *noisyGenerator) Next(t time.Time) float64 {
+	return g.inner.Next(t) + g.rng.NormFloat64()*g.stddev
+}
+
+// TelemetryGenerator holds the signal generators for every metric of one machine.
+type TelemetryGenerator struct {
+	metrics    []string
+	generators map[string]SignalGenerator
+}
+
+// NewTelemetryGenerator builds the generators described by a machine's ConfigJSON.
+func NewTelemetryGenerator(configJSON string, start time.Time, rng *rand.Rand) (*TelemetryGenerator, error) {
+	configs, err := ParseTelemetryConfig(configJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &TelemetryGenerator{generators: make(map[string]SignalGenerator, len(configs))}
+	for metric, cfg := range configs {
+		gen, err := NewSignalGenerator(cfg, start, rng)
+		if err != nil {
+			return nil, fmt.Errorf("metric %q: %w", metric, err)
+		}
+		g.generators[metric] = gen
+		g.metrics = append(g.metrics, metric)
+	}
+	// Keep the output order stable so samples are easy to compare in tests and logs
+	sort.Strings(g.metrics)
+	return g, nil
+}
+
+// Sample produces one reading per metric for the given machine at time t.
+func (g *TelemetryGenerator) Sample(machine models.Machine, t time.Time) []models.TelemetrySample {
+	samples := make([]models.TelemetrySample, 0, len(g.metrics))
+	for _, metric := range g.metrics {
+		samples = append(samples, models.TelemetrySample{
+			OrganizationID: machine.OrganizationID,
+			MachineID:      machine.ID,
+			Metric:         metric,
+			Value:          g.generators[metric].Next(t),
+			Timestamp:      t,
+		})
+	}
+	return samples
+}
@@ -0,0 +1,86 @@
+package simulation_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTelemetryConfigDefaults(t *testing.T) {
+	// Machines without a telemetry section emit the default signals
+	cfg, err := simulation.ParseTelemetryConfig(`{"temp": 50}`)
+	assert.Nil(t, err)
+	assert.Contains(t, cfg, "temperature")
+	assert.Contains(t, cfg, "vibration")
+	assert.Contains(t, cfg, "throughput")
+	assert.Contains(t, cfg, "power")
+
+	_, err = simulation.ParseTelemetryConfig(`{not json`)
+	assert.NotNil(t, err, "Invalid JSON should be reported")
+}
+
+func TestSignalGenerators(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rng := rand.New(rand.NewSource(1))
+
+	t.Run("Sine", func(t *testing.T) {
+		gen, err := simulation.NewSignalGenerator(simulation.SignalConfig{Generator: "sine", Base: 50, Amplitude: 10, Period: "4s"}, start, rng)
+		assert.Nil(t, err)
+		assert.InDelta(t, 50, gen.Next(start), 1e-9)
+		assert.InDelta(t, 60, gen.Next(start.Add(time.Second)), 1e-9)
+		assert.InDelta(t, 40, gen.Next(start.Add(3*time.Second)), 1e-9)
+	})
+
+	t.Run("Step", func(t *testing.T) {
+		gen, err := simulation.NewSignalGenerator(simulation.SignalConfig{Generator: "step", Levels: []float64{1, 2, 3}, Interval: "10s"}, start, rng)
+		assert.Nil(t, err)
+		assert.Equal(t, 1.0, gen.Next(start.Add(5*time.Second)))
+		assert.Equal(t, 2.0, gen.Next(start.Add(15*time.Second)))
+		assert.Equal(t, 1.0, gen.Next(start.Add(35*time.Second)), "Levels should wrap around")
+	})
+
+	t.Run("RandomWalkClamped", func(t *testing.T) {
+		min, max := 0.0, 1.0
+		gen, err := simulation.NewSignalGenerator(simulation.SignalConfig{Generator: "random_walk", Base: 0.5, Step: 5, Min: &min, Max: &max}, start, rng)
+		assert.Nil(t, err)
+		for i := 0; i < 100; i++ {
+			v := gen.Next(start)
+			assert.GreaterOrEqual(t, v, min)
+			assert.LessOrEqual(t, v, max)
+		}
+	})
+
+	t.Run("DriftTowardsFailure", func(t *testing.T) {
+		gen, err := simulation.NewSignalGenerator(simulation.SignalConfig{Generator: "drift", Base: 60, Target: 90, Rate: 1}, start, rng)
+		assert.Nil(t, err)
+		assert.Equal(t, 70.0, gen.Next(start.Add(10*time.Second)))
+		assert.Equal(t, 90.0, gen.Next(start.Add(time.Hour)), "Drift should stop at the target")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := simulation.NewSignalGenerator(simulation.SignalConfig{Generator: "square"}, start, rng)
+		assert.NotNil(t, err, "Unknown generators should be rejected")
+
+		_, err = simulation.NewSignalGenerator(simulation.SignalConfig{Generator: "sine", Period: "0s"}, start, rng)
+		assert.NotNil(t, err, "Non-positive periods should be rejected")
+	})
+}
+
+func TestTelemetryGeneratorSample(t *testing.T) {
+	start := time.Now()
+	config := `{"telemetry": {"temperature": {"generator": "drift", "base": 60, "target": 95, "rate": 0.5}, "vibration": {"generator": "noise", "base": 2, "stddev": 0.1}}}`
+
+	gen, err := simulation.NewTelemetryGenerator(config, start, rand.New(rand.NewSource(1)))
+	assert.Nil(t, err)
+
+	samples := gen.Sample(models.Machine{Model: models.Model{ID: 7}}, start)
+	assert.Len(t, samples, 2, "Only the configured metrics should be sampled")
+	assert.Equal(t, "temperature", samples[0].Metric, "Samples should be ordered by metric name")
+	assert.Equal(t, "vibration", samples[1].Metric)
+	assert.Equal(t, uint(7), samples[0].MachineID)
+	assert.Equal(t, 60.0, samples[0].Value)
+}
@@ -0,0 +1,144 @@
+package simulation
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+)
+
+// starvedMachines returns the machines cut off from their supply: every machine downstream of a
+// machine in Error, directly or through other starved machines. Each is mapped to the Error machine
+// starving it. Machines in Error are never starved themselves; they keep their own state.
+func starvedMachines(machines []models.Machine, links []models.MachineLink) map[uint]uint {
+	downstream := make(map[uint][]uint)
+	for _, link := range links {
+		downstream[link.FromMachineID] = append(downstream[link.FromMachineID], link.ToMachineID)
+	}
+	status := make(map[uint]string, len(machines))
+	var failed []uint
+	for _, m := range machines {
+		status[m.ID] = m.Status
+		if m.Status == "Error" {
+			failed = append(failed, m.ID)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i] < failed[j] })
+
+	starved := make(map[uint]uint)
+	for _, source := range failed {
+		queue := append([]uint(nil), downstream[source]...)
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			if _, seen := starved[id]; seen || status[id] == "Error" || status[id] == "" {
+				continue
+			}
+			starved[id] = source
+			queue = append(queue, downstream[id]...)
+		}
+	}
+	return starved
+}
+
+// TopologyChanged recomputes which machines are starved, e.g. after links were added or removed.
+// It is a no-op without a topology.
+func (s *MachineSimulator) TopologyChanged() {
+	if s.Topology == nil {
+		return
+	}
+	machines, err := s.Repo.FindAll()
+	if err != nil {
+		slog.Error("Failed to load machines for topology", "error", err)
+		return
+	}
+	s.refreshStarvation(machines)
+}
+
+// refreshStarvation recomputes the starved machines from the current machine states and wakes up
+// starved workers if anything changed
+func (s *MachineSimulator) refreshStarvation(machines []models.Machine) {
+	if s.Topology == nil {
+		return
+	}
+	links, err := s.Topology.FindLinks()
+	if err != nil {
+		slog.Error("Failed to load machine links", "error", err)
+		return
+	}
+	starved := starvedMachines(machines, links)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sameStarvation(s.starved, starved) {
+		return
+	}
+	s.starved = starved
+	close(s.topologyCh)
+	s.topologyCh = make(chan struct{})
+}
+
+func sameStarvation(a, b map[uint]uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id, source := range a {
+		if other, ok := b[id]; !ok || other != source {
+			return false
+		}
+	}
+	return true
+}
+
+// Starved returns the starved machines, each mapped to the machine in Error upstream of it
+func (s *MachineSimulator) Starved() map[uint]uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	starved := make(map[uint]uint, len(s.starved))
+	for id, source := range s.starved {
+		starved[id] = source
+	}
+	return starved
+}
+
+// waitWhileStarved parks a worker while a machine upstream of it is in Error. The machine is Idle
+// for as long as it is starved and Running again once its supply is restored, unless its status
+// was changed meanwhile, e.g. to Offline or Error.
+func (s *MachineSimulator) waitWhileStarved(w *worker) error {
+	wasStarved := false
+	for {
+		s.mu.Lock()
+		source, starved := s.starved[w.machineID]
+		changed := s.topologyCh
+		switch {
+		case starved && w.state == WorkerRunning:
+			w.state = WorkerStarved
+		case !starved && w.state == WorkerStarved:
+			// Starved workers send no heartbeats; start a fresh deadline
+			w.state = WorkerRunning
+			w.lastHeartbeat = time.Now()
+			w.deadline = w.lastHeartbeat.Add(s.HangTimeout)
+		}
+		s.mu.Unlock()
+
+		if !starved {
+			if wasStarved {
+				w.log.Info("Machine supply restored, resuming simulation")
+				s.swapMachineStatus(w.machineID, "Running", "Idle")
+			}
+			return nil
+		}
+		if !wasStarved {
+			wasStarved = true
+			w.log.Info("Machine starved: an upstream machine is in Error", "upstream_machine_id", source)
+			s.swapMachineStatus(w.machineID, "Idle", "Running")
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		case <-changed:
+		}
+	}
+}
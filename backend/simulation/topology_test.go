@@ -0,0 +1,149 @@
+package simulation_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockTopologyRepository serves a fixed set of machine links
+type MockTopologyRepository struct {
+	mu    sync.Mutex
+	links []models.MachineLink
+}
+
+func (m *MockTopologyRepository) ForTenant(uint) repository.TopologyRepository              { return m }
+func (m *MockTopologyRepository) WithContext(context.Context) repository.TopologyRepository { return m }
+
+func (m *MockTopologyRepository) CreateGroup(group *models.MachineGroup) error        { return nil }
+func (m *MockTopologyRepository) FindGroups() ([]models.MachineGroup, error)          { return nil, nil }
+func (m *MockTopologyRepository) DeleteGroup(id uint) error                           { return nil }
+func (m *MockTopologyRepository) SetMachineGroup(machineID uint, groupID *uint) error { return nil }
+func (m *MockTopologyRepository) FindGroupByID(id uint) (*models.MachineGroup, error) {
+	return nil, errors.New("record not found")
+}
+func (m *MockTopologyRepository) CreateLink(link *models.MachineLink) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.links = append(m.links, *link)
+	return nil
+}
+func (m *MockTopologyRepository) FindLinks() ([]models.MachineLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.MachineLink(nil), m.links...), nil
+}
+func (m *MockTopologyRepository) FindLinkByID(id uint) (*models.MachineLink, error) {
+	return nil, errors.New("record not found")
+}
+func (m *MockTopologyRepository) DeleteLink(id uint) error                { return nil }
+func (m *MockTopologyRepository) DeleteMachineLinks(machineID uint) error { return nil }
+
+func link(from, to uint) models.MachineLink {
+	return models.MachineLink{FromMachineID: from, ToMachineID: to}
+}
+
+func TestStarvedMachines(t *testing.T) {
+	repo := NewMockMachineRepository(
+		models.Machine{Model: models.Model{ID: 1}, Status: "Error"},
+		models.Machine{Model: models.Model{ID: 2}, Status: "Running"},
+		models.Machine{Model: models.Model{ID: 3}, Status: "Idle"},
+		models.Machine{Model: models.Model{ID: 4}, Status: "Error"},
+		models.Machine{Model: models.Model{ID: 5}, Status: "Running"},
+		models.Machine{Model: models.Model{ID: 6}, Status: "Running"},
+	)
+	sim := newTestSimulator(t, repo)
+	// 1 -> 2 -> 3 -> 4 -> 5, and 6 feeds 2 but is not starved by it
+	sim.Topology = &MockTopologyRepository{links: []models.MachineLink{link(1, 2), link(2, 3), link(3, 4), link(4, 5), link(6, 2)}}
+
+	sim.TopologyChanged()
+	assert.Equal(t, map[uint]uint{2: 1, 3: 1, 5: 4}, sim.Starved(), "Machines in Error keep their state and starve their own downstream")
+}
+
+func TestUpstreamErrorStarvesDownstream(t *testing.T) {
+	repo := NewMockMachineRepository(
+		models.Machine{Model: models.Model{ID: 1}, Name: "Feeder", Status: "Idle"},
+		models.Machine{Model: models.Model{ID: 2}, Name: "Press", Status: "Idle"},
+		models.Machine{Model: models.Model{ID: 3}, Name: "Packer", Status: "Idle"},
+	)
+	sim := newTestSimulator(t, repo)
+	sim.Topology = &MockTopologyRepository{links: []models.MachineLink{link(1, 2), link(2, 3)}}
+	_, err := sim.Faults.Inject(1, simulation.FaultSpec{Type: simulation.FaultFailRuns, Count: 1000})
+	assert.Nil(t, err)
+
+	status := func(id uint) string {
+		machine, _ := repo.FindByID(id)
+		return machine.Status
+	}
+	starvedWorkers := func() int {
+		n := 0
+		for _, w := range sim.Workers() {
+			if w.State == simulation.WorkerStarved {
+				n++
+			}
+		}
+		return n
+	}
+
+	sim.Reconcile()
+	assert.Eventually(t, func() bool {
+		return status(1) == "Error" && status(2) == "Idle" && status(3) == "Idle" && starvedWorkers() == 2
+	}, 3*time.Second, 5*time.Millisecond, "Machines downstream of an Error should starve to Idle")
+
+	// Starved workers send no heartbeats but must not be taken for hung
+	time.Sleep(2 * sim.HangTimeout)
+	sim.Reconcile()
+	assert.Equal(t, 2, starvedWorkers())
+
+	sim.Faults.Clear(1)
+	assert.Eventually(t, func() bool {
+		return status(1) == "Running" && status(2) == "Running" && status(3) == "Running" && starvedWorkers() == 0
+	}, 3*time.Second, 5*time.Millisecond, "Machines should resume once the upstream machine recovers")
+	assert.Empty(t, sim.Starved())
+
+	for _, m := range []uint{1, 2, 3} {
+		machine, _ := repo.FindByID(m)
+		machine.Status = "Offline"
+		_ = repo.Update(machine)
+		sim.MachineUpdated(*machine)
+	}
+}
+
+func TestStarvationKeepsStatusChangedMeanwhile(t *testing.T) {
+	repo := NewMockMachineRepository(
+		models.Machine{Model: models.Model{ID: 1}, Name: "Feeder", Status: "Idle"},
+		models.Machine{Model: models.Model{ID: 2}, Name: "Press", Status: "Idle"},
+	)
+	sim := newTestSimulator(t, repo)
+	sim.Topology = &MockTopologyRepository{links: []models.MachineLink{link(1, 2)}}
+	_, err := sim.Faults.Inject(1, simulation.FaultSpec{Type: simulation.FaultFailRuns, Count: 1000})
+	assert.Nil(t, err)
+
+	status := func(id uint) string {
+		machine, _ := repo.FindByID(id)
+		return machine.Status
+	}
+	sim.Reconcile()
+	assert.Eventually(t, func() bool { return status(1) == "Error" && status(2) == "Idle" }, 3*time.Second, 5*time.Millisecond)
+
+	// Set Offline while starved, before the simulator is told
+	_, _ = repo.SetStatusIf(2, "Offline", "Idle")
+	sim.Faults.Clear(1)
+	assert.Eventually(t, func() bool { return status(1) == "Running" && len(sim.Starved()) == 0 }, 3*time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "Offline", status(2), "Restoring the supply should not overwrite a status set meanwhile")
+
+	for _, m := range []uint{1, 2} {
+		machine, _ := repo.FindByID(m)
+		machine.Status = "Offline"
+		_ = repo.Update(machine)
+		sim.MachineUpdated(*machine)
+	}
+}
@@ -0,0 +1,160 @@
+package simulation
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// Worker states reported by Workers
+const (
+	WorkerRunning  = "running"
+	WorkerStopping = "stopping" // asked to stop because the machine went Offline
+	WorkerHung     = "hung"     // missed its heartbeat deadline and was cancelled
+	WorkerOrphaned = "orphaned" // its machine was deleted and it was cancelled
+	WorkerStarved  = "starved"  // waiting for a machine upstream of it to recover from Error
+)
+
+// DefaultHangTimeout is how long a worker may overrun its expected heartbeat before it is considered hung
+const DefaultHangTimeout = 30 * time.Second
+
+// worker tracks one machine simulation goroutine. Fields are guarded by MachineSimulator.mu.
+type worker struct {
+	machineID     uint
+	log           *slog.Logger    // tags the worker's records with its machine
+	ctx           context.Context // cancelled when the machine is stopped, deleted or hung
+	cancel        context.CancelFunc
+	state         string
+	startedAt     time.Time
+	lastHeartbeat time.Time
+	deadline      time.Time // the worker is hung if it has not reported again by then
+	runs          int
+	failures      int
+	lastRunAt     time.Time
+	cancelledAt   time.Time
+}
+
+// WorkerInfo is a snapshot of a simulation goroutine, as exposed by GET /api/v1/simulator/workers
+type WorkerInfo struct {
+	MachineID     uint       `json:"machine_id"`
+	State         string     `json:"state"`
+	StartedAt     time.Time  `json:"started_at"`
+	LastHeartbeat time.Time  `json:"last_heartbeat"`
+	Runs          int        `json:"runs"`
+	Failures      int        `json:"failures"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	CancelledAt   *time.Time `json:"cancelled_at,omitempty"`
+}
+
+// Workers returns the state of every simulation goroutine, including cancelled ones that have not exited yet
+func (s *MachineSimulator) Workers() []WorkerInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]WorkerInfo, 0, len(s.workers)+len(s.cancelled))
+	add := func(w *worker) {
+		info := WorkerInfo{MachineID: w.machineID, State: w.state, StartedAt: w.startedAt, LastHeartbeat: w.lastHeartbeat, Runs: w.runs, Failures: w.failures}
+		if !w.lastRunAt.IsZero() {
+			lastRunAt := w.lastRunAt
+			info.LastRunAt = &lastRunAt
+		}
+		if !w.cancelledAt.IsZero() {
+			cancelledAt := w.cancelledAt
+			info.CancelledAt = &cancelledAt
+		}
+		infos = append(infos, info)
+	}
+	for _, w := range s.workers {
+		add(w)
+	}
+	for w := range s.cancelled {
+		add(w)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].MachineID != infos[j].MachineID {
+			return infos[i].MachineID < infos[j].MachineID
+		}
+		return infos[i].StartedAt.Before(infos[j].StartedAt)
+	})
+	return infos
+}
+
+// startWorker launches a simulation goroutine for the machine unless one is already running. Callers must hold s.mu.
+func (s *MachineSimulator) startWorker(machineID uint) {
+	if s.workers[machineID] != nil {
+		return
+	}
+	now := time.Now()
+	ctx, cancel := context.WithCancel(s.ctx)
+	w := &worker{
+		machineID:     machineID,
+		log:           slog.With("machine_id", machineID),
+		ctx:           ctx,
+		cancel:        cancel,
+		state:         WorkerRunning,
+		startedAt:     now,
+		lastHeartbeat: now,
+		deadline:      now.Add(s.HangTimeout),
+	}
+	s.workers[machineID] = w
+	go s.runMachineSimulation(w)
+}
+
+// cancelWorker cancels a worker's context, aborting any run in flight, and keeps it visible until it exits.
+// Callers must hold s.mu.
+func (s *MachineSimulator) cancelWorker(w *worker, state string) {
+	w.cancel()
+	w.state = state
+	w.cancelledAt = time.Now()
+	delete(s.workers, w.machineID)
+	s.cancelled[w] = struct{}{}
+}
+
+// heartbeat records that a worker is alive and expects to report again within expected
+func (s *MachineSimulator) heartbeat(w *worker, expected time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	w.lastHeartbeat = now
+	w.deadline = now.Add(expected + s.HangTimeout)
+}
+
+// completeRun counts a finished simulation run
+func (s *MachineSimulator) completeRun(w *worker, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.runs++
+	w.lastRunAt = time.Now()
+	if failed {
+		w.failures++
+	}
+}
+
+// finishWorker forgets a worker once its goroutine has exited
+func (s *MachineSimulator) finishWorker(w *worker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.workers[w.machineID] == w {
+		delete(s.workers, w.machineID)
+	}
+	delete(s.cancelled, w)
+	w.cancel()
+}
+
+// checkWorkers is the watchdog pass: it cancels workers whose machine no longer exists
+// (deleted or soft-deleted) and workers that missed their heartbeat deadline.
+// Paused and starved workers send no heartbeats, so hangs are only detected while running. Callers must hold s.mu.
+func (s *MachineSimulator) checkWorkers(existing map[uint]bool, now time.Time) {
+	for machineID, w := range s.workers {
+		switch {
+		case !existing[machineID]:
+			w.log.Warn("Watchdog: machine no longer exists, cancelling orphaned simulation")
+			s.cancelWorker(w, WorkerOrphaned)
+		case !s.paused && w.state != WorkerStarved && now.After(w.deadline):
+			w.log.Warn("Watchdog: simulation missed its heartbeat, cancelling hung run", "last_heartbeat", now.Sub(w.lastHeartbeat).Round(time.Second))
+			s.cancelWorker(w, WorkerHung)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package token verifies JWT bearer tokens issued by an OpenID Connect provider against its
+// JSON Web Key Set (JWKS).
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"golang.org/x/sync/singleflight"
+)
+
+// minReload limits how often an unknown key ID makes the key set reload, so tokens signed
+// with made-up key IDs cannot hammer the provider
+const minReload = 30 * time.Second
+
+// KeySet holds the public keys tokens are signed with. Keys are loaded on first use and
+// reloaded when they are older than MaxAge or a token names a key ID the set lacks, which is
+// how providers roll their keys.
+type KeySet struct {
+	Source string        // where the keys come from, for errors
+	MaxAge time.Duration // 0 keeps loaded keys until an unknown key ID is seen
+	load   func(ctx context.Context) ([]byte, error)
+
+	loads    singleflight.Group // loads the keys once for all callers waiting for them
+	mu       sync.Mutex         // guards keys and loadedAt, never held while loading
+	keys     *jose.JSONWebKeySet
+	loadedAt time.Time
+}
+
+// NewRemoteKeySet fetches keys from a JWKS URL, such as the jwks_uri of an OIDC provider
+func NewRemoteKeySet(url string, client *http.Client, maxAge time.Duration) *KeySet {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &KeySet{Source: url, MaxAge: maxAge, load: func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}}
+}
+
+// NewFileKeySet reads keys from a JWKS file, e.g. for testing without a provider
+func NewFileKeySet(path string) *KeySet {
+	return &KeySet{Source: path, load: func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}}
+}
+
+// Key returns the key with an ID, or the only key of the set if the ID is empty
+func (s *KeySet) Key(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	s.mu.Lock()
+	keys, loadedAt := s.keys, s.loadedAt
+	s.mu.Unlock()
+
+	stale := keys == nil || (s.MaxAge > 0 && time.Since(loadedAt) > s.MaxAge)
+	if stale {
+		reloaded, err := s.reload(ctx)
+		if err != nil && keys == nil {
+			return nil, err
+		}
+		if err == nil {
+			keys = reloaded
+		}
+	}
+	if key := find(keys, kid); key != nil {
+		return key, nil
+	}
+	if !stale && time.Since(loadedAt) > minReload {
+		reloaded, err := s.reload(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if key := find(reloaded, kid); key != nil {
+			return key, nil
+		}
+	}
+	if kid == "" {
+		return nil, fmt.Errorf("the token names no key and %s has more than one", s.Source)
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// reload loads the keys and swaps them in, sharing one load among concurrent callers. On
+// failure the previous keys, if any, are kept. A caller whose ctx ends stops waiting, but the
+// load goes on for the others.
+func (s *KeySet) reload(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	loaded := s.loads.DoChan("keys", func() (interface{}, error) {
+		data, err := s.load(context.WithoutCancel(ctx))
+		var keys jose.JSONWebKeySet
+		if err == nil {
+			err = json.Unmarshal(data, &keys)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.loadedAt = time.Now()
+		if err != nil {
+			return nil, err
+		}
+		s.keys = &keys
+		return &keys, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-loaded:
+		if result.Err != nil {
+			err := fmt.Errorf("loading keys from %s: %w", s.Source, result.Err)
+			slog.ErrorContext(ctx, "Failed to load signing keys", "source", s.Source, "error", err)
+			return nil, err
+		}
+		return result.Val.(*jose.JSONWebKeySet), nil
+	}
+}
+
+// find returns the signing key with an ID, or the only one if the ID is empty
+func find(keys *jose.JSONWebKeySet, kid string) *jose.JSONWebKey {
+	var signing []jose.JSONWebKey
+	for _, key := range keys.Keys {
+		if key.Use == "" || key.Use == "sig" {
+			signing = append(signing, key)
+		}
+	}
+	if kid == "" {
+		if len(signing) == 1 {
+			return &signing[0]
+		}
+		return nil
+	}
+	for i := range signing {
+		if signing[i].KeyID == kid {
+			return &signing[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, badly signed, expired or meant
+// for another issuer or audience
+var ErrInvalidToken = errors.New("invalid token")
+
+// algorithms are the signature algorithms accepted; symmetric ones are not, since every
+// holder of the key set could forge tokens with them
+var algorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// Claims are the verified claims of a token: the registered ones, and all of them by name
+type Claims struct {
+	jwt.Claims
+	All map[string]any
+}
+
+// Verifier checks tokens against a key set and the expected issuer and audience
+type Verifier struct {
+	Keys     *KeySet
+	Issuer   string
+	Audience string
+	Leeway   time.Duration // allowed clock skew between the provider and the backend
+	Now      func() time.Time
+}
+
+// Verify checks a token's signature and its iss, aud, exp and nbf claims, and returns its claims
+func (v *Verifier) Verify(ctx context.Context, raw string) (Claims, error) {
+	tok, err := jwt.ParseSigned(raw, algorithms)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	header := tok.Headers[0]
+	key, err := v.Keys.Key(ctx, header.KeyID)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if key.Algorithm != "" && key.Algorithm != header.Algorithm {
+		return Claims{}, fmt.Errorf("%w: key %q is for %s, not %s", ErrInvalidToken, key.KeyID, key.Algorithm, header.Algorithm)
+	}
+
+	var claims Claims
+	if err := tok.Claims(key.Key, &claims.Claims, &claims.All); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Expiry == nil {
+		return Claims{}, fmt.Errorf("%w: the token does not expire", ErrInvalidToken)
+	}
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	expected := jwt.Expected{Issuer: v.Issuer, Time: now()}
+	if v.Audience != "" {
+		expected.AnyAudience = jwt.Audience{v.Audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, v.Leeway); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
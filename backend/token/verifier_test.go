@@ -0,0 +1,172 @@
+package token_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/token"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+type signingKey struct {
+	private *ecdsa.PrivateKey
+	kid     string
+}
+
+func newSigningKey(t *testing.T, kid string) signingKey {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	return signingKey{private: private, kid: kid}
+}
+
+func (k signingKey) public() jose.JSONWebKey {
+	return jose.JSONWebKey{Key: &k.private.PublicKey, KeyID: k.kid, Algorithm: string(jose.ES256), Use: "sig"}
+}
+
+func (k signingKey) sign(t *testing.T, claims jwt.Claims, extra map[string]any) string {
+	opts := (&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", k.kid)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: k.private}, opts)
+	assert.Nil(t, err)
+	raw, err := jwt.Signed(signer).Claims(claims).Claims(extra).Serialize()
+	assert.Nil(t, err)
+	return raw
+}
+
+func writeKeySet(t *testing.T, keys ...jose.JSONWebKey) string {
+	data, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func validClaims() jwt.Claims {
+	return jwt.Claims{
+		Issuer: "https://idp.example.com", Audience: jwt.Audience{"automation-backend"}, Subject: "alice",
+		Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key := newSigningKey(t, "k1")
+	verifier := &token.Verifier{
+		Keys:   token.NewFileKeySet(writeKeySet(t, key.public())),
+		Issuer: "https://idp.example.com", Audience: "automation-backend",
+	}
+
+	claims, err := verifier.Verify(context.Background(), key.sign(t, validClaims(), map[string]any{"name": "Alice"}))
+	if assert.Nil(t, err) {
+		assert.Equal(t, "alice", claims.Subject)
+		assert.Equal(t, "Alice", claims.All["name"])
+	}
+
+	other := newSigningKey(t, "k1")
+	expired, noExpiry, wrongIssuer, wrongAudience := validClaims(), validClaims(), validClaims(), validClaims()
+	expired.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	noExpiry.Expiry = nil
+	wrongIssuer.Issuer = "https://evil.example.com"
+	wrongAudience.Audience = jwt.Audience{"another-service"}
+	unsigned := "eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9."
+
+	for name, raw := range map[string]string{
+		"Malformed":     "not.a.jwt",
+		"Unsigned":      unsigned,
+		"ForeignKey":    other.sign(t, validClaims(), nil),
+		"UnknownKeyID":  newSigningKey(t, "k2").sign(t, validClaims(), nil),
+		"Expired":       key.sign(t, expired, nil),
+		"NoExpiry":      key.sign(t, noExpiry, nil),
+		"WrongIssuer":   key.sign(t, wrongIssuer, nil),
+		"WrongAudience": key.sign(t, wrongAudience, nil),
+	} {
+		_, err := verifier.Verify(context.Background(), raw)
+		assert.ErrorIs(t, err, token.ErrInvalidToken, name)
+	}
+}
+
+func TestVerifyLeeway(t *testing.T) {
+	key := newSigningKey(t, "k1")
+	claims := validClaims()
+	claims.Expiry = jwt.NewNumericDate(time.Now().Add(-30 * time.Second))
+	verifier := &token.Verifier{
+		Keys:   token.NewFileKeySet(writeKeySet(t, key.public())),
+		Issuer: claims.Issuer, Audience: "automation-backend", Leeway: time.Minute,
+	}
+	_, err := verifier.Verify(context.Background(), key.sign(t, claims, nil))
+	assert.Nil(t, err, "Clock skew within the leeway is tolerated")
+}
+
+func TestRemoteKeySetRotation(t *testing.T) {
+	old, rotated := newSigningKey(t, "old"), newSigningKey(t, "new")
+	var published atomic.Value
+	published.Store(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{old.public()}})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(published.Load())
+	}))
+	defer server.Close()
+
+	keys := token.NewRemoteKeySet(server.URL, server.Client(), time.Hour)
+	verifier := &token.Verifier{Keys: keys, Issuer: "https://idp.example.com", Audience: "automation-backend"}
+	ctx := context.Background()
+
+	_, err := verifier.Verify(ctx, old.sign(t, validClaims(), nil))
+	assert.Nil(t, err)
+	_, err = verifier.Verify(ctx, old.sign(t, validClaims(), nil))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), fetches.Load(), "Keys are cached")
+
+	published.Store(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{old.public(), rotated.public()}})
+	_, err = verifier.Verify(ctx, rotated.sign(t, validClaims(), nil))
+	assert.ErrorIs(t, err, token.ErrInvalidToken, "Unknown key IDs reload the keys at most every 30s")
+	assert.Equal(t, int32(1), fetches.Load())
+
+	keys.MaxAge = time.Nanosecond
+	_, err = verifier.Verify(ctx, rotated.sign(t, validClaims(), nil))
+	assert.Nil(t, err, "Stale keys are fetched again")
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestRemoteKeySetSharesLoads(t *testing.T) {
+	key := newSigningKey(t, "k1")
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.public()}})
+	}))
+	defer server.Close()
+	keys := token.NewRemoteKeySet(server.URL, server.Client(), time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := keys.Key(ctx, "k1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "Callers stop waiting for a slow provider when their context ends")
+
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			_, err := keys.Key(context.Background(), "k1")
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, <-errs)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "Concurrent callers share one load")
+}
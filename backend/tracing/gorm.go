@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"errors"
+
+	"github.com/CBYeuler/automation-backend/backend/database/callbacks"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey is where GormPlugin keeps the span of a query on its statement
+const spanKey = "tracing:span"
+
+var gormTracer = Tracer("github.com/CBYeuler/automation-backend/backend/tracing/gorm")
+
+// GormPlugin records a span for every query GORM runs, as a child of the span in the
+// context the query runs with, which repositories take from requests with WithContext
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize traces the create, query, update, delete, row and raw callbacks of db
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	return callbacks.Around(db, "tracing", startQuery, endQuery)
+}
+
+func startQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if !trace.SpanContextFromContext(db.Statement.Context).IsValid() {
+			return // queries outside of traced requests and runs would each start a trace of their own
+		}
+		name := "db." + operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		_, span := gormTracer.Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(db.Dialector.Name()),
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+			))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endQuery(string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+		span := v.(trace.Span)
+		span.SetAttributes(
+			semconv.DBQueryText(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+		err := db.Statement.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Records that were not found are answered with 404, they are no failure of the query
+			err = nil
+		}
+		End(span, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Package tracing sets up OpenTelemetry tracing: spans of API requests, service calls,
+// database queries and simulation runs, linked into one trace per request and exported over
+// OTLP or to a file. Trace context arrives and leaves in W3C traceparent headers.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName identifies the backend's spans
+const ServiceName = "automation-backend"
+
+// Exporters selectable with TRACE_EXPORTER
+const (
+	ExporterNone   = "none"   // spans are not recorded (default)
+	ExporterOTLP   = "otlp"   // spans are sent over OTLP/HTTP, configured with the OTEL_EXPORTER_OTLP_* variables
+	ExporterStdout = "stdout" // spans are written as JSON to stdout or TRACE_FILE, for local testing
+)
+
+// traceParentHeader is the W3C header carrying trace context
+const traceParentHeader = "traceparent"
+
+func init() {
+	// Trace context is propagated even when spans are not recorded, so traces of callers
+	// continue through the backend's outgoing calls
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Setup installs the global tracer provider, which samples a ratio of new traces and exports
+// their spans with exporter. Traces started by callers are sampled if they were. The returned
+// function flushes the spans still buffered and stops exporting.
+func Setup(ctx context.Context, exporter, file string, sampleRatio float64) (func(context.Context) error, error) {
+	var (
+		spans  sdktrace.SpanExporter
+		closer io.Closer
+		err    error
+	)
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		spans, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		var w io.Writer = os.Stdout
+		if file != "" {
+			f, openErr := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if openErr != nil {
+				return nil, openErr
+			}
+			w, closer = f, f
+		}
+		spans, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("trace exporter must be %q, %q or %q, got %q", ExporterNone, ExporterOTLP, ExporterStdout, exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spans),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// Tracer returns the tracer of an instrumented package, backed by the provider Setup installed
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// End ends a span, marking it failed if err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceParent returns the W3C traceparent of the span in ctx, or "" if there is none. It lets
+// work picked up later, such as queued jobs, continue the trace that submitted it.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier[traceParentHeader]
+}
+
+// WithTraceParent returns a context continuing the trace of a W3C traceparent
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{traceParentHeader: traceParent})
+}
@@ -0,0 +1,78 @@
+package tracing_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// recorder keeps the spans of all tests. It is installed once, since tracers taken before the
+// first provider was set stay bound to it.
+var recorder = tracetest.NewSpanRecorder()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	os.Exit(m.Run())
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := tracing.Setup(context.Background(), tracing.ExporterNone, "", 1)
+	assert.Nil(t, err)
+	assert.Nil(t, shutdown(context.Background()))
+
+	_, err = tracing.Setup(context.Background(), "jaeger", "", 1)
+	assert.NotNil(t, err)
+}
+
+func TestTraceParent(t *testing.T) {
+	assert.Equal(t, "", tracing.TraceParent(context.Background()))
+
+	ctx, span := tracing.Tracer("test").Start(context.Background(), "submit")
+	defer span.End()
+	traceParent := tracing.TraceParent(ctx)
+	assert.Contains(t, traceParent, span.SpanContext().TraceID().String())
+
+	_, child := tracing.Tracer("test").Start(tracing.WithTraceParent(context.Background(), traceParent), "process")
+	defer child.End()
+	assert.Equal(t, span.SpanContext().TraceID(), child.SpanContext().TraceID(), "Work picked up later continues the trace")
+}
+
+func TestGormPlugin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:TestTracingGormPlugin?mode=memory&cache=shared"), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Use(tracing.GormPlugin{}))
+	assert.Nil(t, db.AutoMigrate(&models.Organization{}))
+
+	ended := len(recorder.Ended())
+	var count int64
+	assert.Nil(t, db.WithContext(context.Background()).Model(&models.Organization{}).Count(&count).Error)
+	assert.Len(t, recorder.Ended(), ended, "Queries without a span to be children of are not traced")
+
+	ctx, span := tracing.Tracer("test").Start(context.Background(), "request")
+	assert.Nil(t, db.WithContext(ctx).Create(&models.Organization{Name: "acme"}).Error)
+	var org models.Organization
+	assert.NotNil(t, db.WithContext(ctx).First(&org, 99).Error)
+	span.End()
+
+	var queries []sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Parent().SpanID() == span.SpanContext().SpanID() {
+			queries = append(queries, s)
+		}
+	}
+	if assert.Len(t, queries, 2, "Queries are children of the span of their context") {
+		assert.Equal(t, "db.create organizations", queries[0].Name())
+		assert.Equal(t, "db.query organizations", queries[1].Name())
+		assert.Equal(t, codes.Unset, queries[1].Status().Code, "Records that were not found are no failure")
+	}
+}
@@ -0,0 +1,143 @@
+// Package workflow validates workflow DAGs and executes their steps as simulation runs.
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/simulation"
+)
+
+// MaxStepRetries caps how often a single step may be retried
+const MaxStepRetries = 10
+
+// ErrInvalidWorkflow is returned when a workflow definition is rejected
+var ErrInvalidWorkflow = errors.New("invalid workflow")
+
+// stepNamePattern keeps step names usable inside ${steps.<name>...} references
+var stepNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Validate checks that steps form a well-defined DAG: unique names, known dependencies,
+// valid conditions and retries, no cycles, and references only to outputs of upstream steps.
+func Validate(steps []models.WorkflowStep) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("%w: a workflow needs at least one step", ErrInvalidWorkflow)
+	}
+
+	byName := make(map[string]models.WorkflowStep, len(steps))
+	for _, step := range steps {
+		if !stepNamePattern.MatchString(step.Name) {
+			return fmt.Errorf("%w: step name %q must only contain letters, digits, '-' and '_'", ErrInvalidWorkflow, step.Name)
+		}
+		if _, ok := byName[step.Name]; ok {
+			return fmt.Errorf("%w: duplicate step %q", ErrInvalidWorkflow, step.Name)
+		}
+		byName[step.Name] = step
+
+		if step.MachineID == 0 {
+			return fmt.Errorf("%w: step %q needs a machine_id", ErrInvalidWorkflow, step.Name)
+		}
+		switch step.When {
+		case "", models.StepWhenSuccess, models.StepWhenFailure, models.StepWhenAlways:
+		default:
+			return fmt.Errorf("%w: step %q has unknown condition %q", ErrInvalidWorkflow, step.Name, step.When)
+		}
+		if step.When != "" && step.When != models.StepWhenSuccess && len(step.DependsOn) == 0 {
+			return fmt.Errorf("%w: step %q has a condition but no dependencies", ErrInvalidWorkflow, step.Name)
+		}
+		if step.Retries < 0 || step.Retries > MaxStepRetries {
+			return fmt.Errorf("%w: step %q retries must be between 0 and %d", ErrInvalidWorkflow, step.Name, MaxStepRetries)
+		}
+		if err := simulation.CheckParams(step.Params); err != nil {
+			return fmt.Errorf("%w: step %q: %v", ErrInvalidWorkflow, step.Name, err)
+		}
+	}
+
+	for _, step := range steps {
+		for _, dep := range step.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("%w: step %q depends on unknown step %q", ErrInvalidWorkflow, step.Name, dep)
+			}
+		}
+	}
+	if cycle := findCycle(steps, byName); cycle != nil {
+		return fmt.Errorf("%w: dependency cycle %s", ErrInvalidWorkflow, strings.Join(cycle, " -> "))
+	}
+
+	for _, step := range steps {
+		upstream := ancestors(step.Name, byName)
+		refs, err := stepReferences(step)
+		if err != nil {
+			return fmt.Errorf("%w: step %q: %v", ErrInvalidWorkflow, step.Name, err)
+		}
+		for _, ref := range refs {
+			if !upstream[ref] {
+				return fmt.Errorf("%w: step %q references step %q, which it does not depend on", ErrInvalidWorkflow, step.Name, ref)
+			}
+		}
+	}
+	return nil
+}
+
+// findCycle returns the steps of a dependency cycle, starting and ending with the same step, or nil
+func findCycle(steps []models.WorkflowStep, byName map[string]models.WorkflowStep) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(steps))
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range byName[name].DependsOn {
+			switch state[dep] {
+			case visiting:
+				// The cycle is the part of the path from dep onwards
+				for i, n := range path {
+					if n == dep {
+						return append(append([]string(nil), path[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, step := range steps {
+		if state[step.Name] == unvisited {
+			if cycle := visit(step.Name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// ancestors returns every step the named step depends on, directly or transitively
+func ancestors(name string, byName map[string]models.WorkflowStep) map[string]bool {
+	seen := map[string]bool{}
+	stack := append([]string(nil), byName[name].DependsOn...)
+	for len(stack) > 0 {
+		dep := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[dep] {
+			continue
+		}
+		seen[dep] = true
+		stack = append(stack, byName[dep].DependsOn...)
+	}
+	return seen
+}
@@ -0,0 +1,64 @@
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func params(t *testing.T, v map[string]interface{}) map[string]json.RawMessage {
+	out := make(map[string]json.RawMessage, len(v))
+	for key, value := range v {
+		data, err := json.Marshal(value)
+		assert.Nil(t, err)
+		out[key] = data
+	}
+	return out
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		steps []models.WorkflowStep
+		err   string
+	}{
+		{"Valid", []models.WorkflowStep{
+			{Name: "warmup", MachineID: 1},
+			{Name: "run", MachineID: 2, DependsOn: []string{"warmup"}, Params: params(t, map[string]interface{}{"temp": "${steps.warmup.outputs.temperature}"})},
+			{Name: "cleanup", MachineID: 1, DependsOn: []string{"run"}, When: models.StepWhenAlways},
+		}, ""},
+		{"Empty", nil, "at least one step"},
+		{"Bad name", []models.WorkflowStep{{Name: "a b", MachineID: 1}}, "must only contain"},
+		{"Duplicate", []models.WorkflowStep{{Name: "a", MachineID: 1}, {Name: "a", MachineID: 1}}, "duplicate step"},
+		{"No machine", []models.WorkflowStep{{Name: "a"}}, "needs a machine_id"},
+		{"Unknown condition", []models.WorkflowStep{{Name: "a", MachineID: 1}, {Name: "b", MachineID: 1, DependsOn: []string{"a"}, When: "sometimes"}}, "unknown condition"},
+		{"Condition without deps", []models.WorkflowStep{{Name: "a", MachineID: 1, When: models.StepWhenFailure}}, "no dependencies"},
+		{"Retries", []models.WorkflowStep{{Name: "a", MachineID: 1, Retries: MaxStepRetries + 1}}, "retries"},
+		{"Unknown dependency", []models.WorkflowStep{{Name: "a", MachineID: 1, DependsOn: []string{"b"}}}, "unknown step"},
+		{"Cycle", []models.WorkflowStep{
+			{Name: "a", MachineID: 1, DependsOn: []string{"c"}},
+			{Name: "b", MachineID: 1, DependsOn: []string{"a"}},
+			{Name: "c", MachineID: 1, DependsOn: []string{"b"}},
+		}, "dependency cycle a -> c -> b -> a"},
+		{"Reference to non-ancestor", []models.WorkflowStep{
+			{Name: "a", MachineID: 1},
+			{Name: "b", MachineID: 1, Params: params(t, map[string]interface{}{"x": "${steps.a.outputs.x}"})},
+		}, "does not depend on"},
+		{"Invalid reference", []models.WorkflowStep{{Name: "a", MachineID: 1, Params: params(t, map[string]interface{}{"x": "${outputs.x}"})}}, "invalid reference"},
+		{"Reserved param", []models.WorkflowStep{{Name: "a", MachineID: 1, Params: params(t, map[string]interface{}{"command": []string{"sh", "-c", "${inputs.x}"}})}}, `cannot override "command"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.steps)
+			if tc.err == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, ErrInvalidWorkflow))
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+)
+
+// DefaultRetryDelay is how long a failed step waits before its next attempt
+const DefaultRetryDelay = time.Second
+
+// Executor executes a single ad-hoc run of a machine with params merged over its ConfigJSON.
+// It is implemented by the simulator.
+type Executor interface {
+	RunOnce(ctx context.Context, machineID uint, params string, jobID *uint) (models.SimulationRun, error)
+}
+
+// Engine executes workflow runs: every step starts as soon as all of its dependencies have
+// finished, so independent branches run in parallel. State is persisted after every change,
+// so a run interrupted by a restart can be resumed.
+type Engine struct {
+	Repo       repository.WorkflowRepository
+	Executor   Executor
+	RetryDelay time.Duration
+
+	ctx context.Context // ends every run, e.g. at shutdown
+	wg  sync.WaitGroup  // tracks running workflows
+}
+
+// NewEngine creates a new workflow engine whose runs are interrupted when ctx ends. Interrupted
+// runs are left as they are, to be resumed on the next start.
+func NewEngine(ctx context.Context, repo repository.WorkflowRepository, executor Executor) *Engine {
+	return &Engine{Repo: repo, Executor: executor, RetryDelay: DefaultRetryDelay, ctx: ctx}
+}
+
+// Start executes the unfinished steps of a run in the background. The run is logged with the
+// request ID of ctx but outlives it; it ends with the engine's context instead.
+func (e *Engine) Start(ctx context.Context, run models.WorkflowRun) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(e.ctx, cancel)
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer stop()
+		defer cancel()
+		e.execute(ctx, run)
+	}()
+}
+
+// Resume restarts workflow runs left unfinished by a restart. Steps that were running
+// when the backend stopped are run again.
+func (e *Engine) Resume() error {
+	runs, err := e.Repo.FindRunsByStatus(models.WorkflowStatusRunning)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		runLog(run).InfoContext(e.ctx, "Resuming workflow run")
+		e.Start(e.ctx, run)
+	}
+	return nil
+}
+
+// Wait blocks until every workflow run started by the engine has finished or, once the
+// engine's context has ended, has been interrupted
+func (e *Engine) Wait() {
+	e.wg.Wait()
+}
+
+// finished reports whether a step run has reached a final state
+func finished(status string) bool {
+	return status == models.StepStatusSucceeded || status == models.StepStatusFailed || status == models.StepStatusSkipped
+}
+
+// shouldRun evaluates a step's condition once all of its dependencies have finished
+func shouldRun(step models.WorkflowStep, states map[string]*models.WorkflowStepRun) bool {
+	allSucceeded, anyFailed := true, false
+	for _, dep := range step.DependsOn {
+		switch states[dep].Status {
+		case models.StepStatusSucceeded:
+		case models.StepStatusFailed:
+			allSucceeded, anyFailed = false, true
+		default:
+			allSucceeded = false
+		}
+	}
+	switch step.When {
+	case models.StepWhenFailure:
+		return anyFailed
+	case models.StepWhenAlways:
+		return true
+	}
+	return allSucceeded
+}
+
+// execute schedules the steps of a run until every step has finished, then completes the run
+func (e *Engine) execute(ctx context.Context, run models.WorkflowRun) {
+	stepRuns, err := e.Repo.FindStepRuns(run.ID)
+	if err != nil {
+		runLog(run).ErrorContext(ctx, "Failed to load steps of workflow run", "error", err)
+		return
+	}
+	states := make(map[string]*models.WorkflowStepRun, len(stepRuns))
+	for i := range stepRuns {
+		states[stepRuns[i].Step] = &stepRuns[i]
+	}
+
+	scope := Scope{Inputs: map[string]interface{}{}, Steps: map[string]StepState{}}
+	if run.Inputs != "" {
+		if err := json.Unmarshal([]byte(run.Inputs), &scope.Inputs); err != nil {
+			runLog(run).WarnContext(ctx, "Invalid inputs of workflow run", "error", err)
+		}
+	}
+	for _, state := range states {
+		if state.Status == models.StepStatusRunning {
+			state.Status = models.StepStatusPending // interrupted by a restart
+		}
+		if finished(state.Status) {
+			scope.Steps[state.Step] = stepState(state)
+		}
+	}
+
+	results := make(chan models.WorkflowStepRun)
+	running := 0
+	for {
+		// Keep scheduling until nothing changes: skipping a step can unblock its dependents
+		for changed := true; changed; {
+			changed = false
+			for _, step := range run.Steps {
+				state := states[step.Name]
+				if ctx.Err() != nil || state.Status != models.StepStatusPending || !e.ready(step, states) {
+					continue
+				}
+				changed = true
+
+				if !shouldRun(step, states) {
+					state.Status = models.StepStatusSkipped
+					e.saveStep(ctx, run, state)
+					scope.Steps[step.Name] = stepState(state)
+					continue
+				}
+
+				params, err := ResolveParams(step, scope)
+				if err != nil {
+					now := time.Now()
+					state.Status, state.Error, state.FinishedAt = models.StepStatusFailed, err.Error(), &now
+					e.saveStep(ctx, run, state)
+					scope.Steps[step.Name] = stepState(state)
+					continue
+				}
+
+				now := time.Now()
+				state.Status, state.Params, state.StartedAt = models.StepStatusRunning, params, &now
+				e.saveStep(ctx, run, state)
+				running++
+				go func(step models.WorkflowStep, state models.WorkflowStepRun) {
+					results <- e.runStep(ctx, run, step, state)
+				}(step, *state)
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+		result := <-results
+		running--
+		*states[result.Step] = result
+		scope.Steps[result.Step] = stepState(&result)
+	}
+
+	if ctx.Err() != nil {
+		runLog(run).InfoContext(ctx, "Workflow run interrupted, it resumes on the next start")
+		return
+	}
+	e.complete(ctx, &run, states)
+}
+
+// ready reports whether every dependency of a step has finished
+func (e *Engine) ready(step models.WorkflowStep, states map[string]*models.WorkflowStepRun) bool {
+	for _, dep := range step.DependsOn {
+		if !finished(states[dep].Status) {
+			return false
+		}
+	}
+	return true
+}
+
+// runStep executes a step, retrying failed attempts, and returns its final state. A step
+// interrupted by the end of ctx is returned still Running, so it is run again on resume.
+func (e *Engine) runStep(ctx context.Context, run models.WorkflowRun, step models.WorkflowStep, state models.WorkflowStepRun) models.WorkflowStepRun {
+	for {
+		state.Attempts++
+		e.saveStep(ctx, run, &state)
+
+		simRun, err := e.Executor.RunOnce(ctx, step.MachineID, state.Params, nil)
+		if simRun.ID != 0 {
+			runID := simRun.ID
+			state.RunID = &runID
+		}
+		if err == nil {
+			state.Status, state.Outputs, state.Error = models.StepStatusSucceeded, simRun.Outputs, ""
+			break
+		}
+		if ctx.Err() != nil {
+			return state
+		}
+
+		state.Error = err.Error()
+		if state.Attempts > step.Retries {
+			state.Status = models.StepStatusFailed
+			break
+		}
+		runLog(run).WarnContext(ctx, "Workflow step failed, retrying", "step", step.Name, "attempt", state.Attempts, "max_attempts", step.Retries+1, "error", err)
+		timer := time.NewTimer(e.RetryDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return state
+		case <-timer.C:
+		}
+	}
+
+	finishedAt := time.Now()
+	state.FinishedAt = &finishedAt
+	e.saveStep(ctx, run, &state)
+	runLog(run).InfoContext(ctx, "Workflow step finished", "step", step.Name, "status", state.Status)
+	return state
+}
+
+// complete records the outcome of a run: it fails if any of its steps failed
+func (e *Engine) complete(ctx context.Context, run *models.WorkflowRun, states map[string]*models.WorkflowStepRun) {
+	run.Status = models.WorkflowStatusSucceeded
+	run.Error = ""
+	for _, step := range run.Steps {
+		if states[step.Name].Status == models.StepStatusFailed {
+			run.Status = models.WorkflowStatusFailed
+			run.Error = fmt.Sprintf("step %q failed: %s", step.Name, states[step.Name].Error)
+			break
+		}
+	}
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	if err := e.Repo.UpdateRun(run); err != nil {
+		runLog(*run).ErrorContext(ctx, "Failed to complete workflow run", "error", err)
+		return
+	}
+	runLog(*run).InfoContext(ctx, "Workflow run finished", "status", run.Status)
+}
+
+func (e *Engine) saveStep(ctx context.Context, run models.WorkflowRun, state *models.WorkflowStepRun) {
+	if err := e.Repo.UpdateStepRun(state); err != nil {
+		runLog(run).ErrorContext(ctx, "Failed to update workflow step", "step", state.Step, "error", err)
+	}
+}
+
+// runLog returns a logger tagging records with a workflow run
+func runLog(run models.WorkflowRun) *slog.Logger {
+	return slog.With("workflow_run_id", run.ID, "workflow_id", run.WorkflowID)
+}
+
+// stepState is what later steps can reference of a finished step run
+func stepState(state *models.WorkflowStepRun) StepState {
+	outputs := map[string]interface{}{}
+	if state.Outputs != "" {
+		_ = json.Unmarshal([]byte(state.Outputs), &outputs)
+	}
+	return StepState{Status: state.Status, Outputs: outputs}
+}
@@ -0,0 +1,256 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/CBYeuler/automation-backend/backend/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockRepository keeps workflow runs and their step runs in memory
+type mockRepository struct {
+	mu    sync.Mutex
+	runs  map[uint]models.WorkflowRun
+	steps map[uint]models.WorkflowStepRun
+}
+
+func newMockRepository() *mockRepository {
+	return &mockRepository{runs: map[uint]models.WorkflowRun{}, steps: map[uint]models.WorkflowStepRun{}}
+}
+
+func (m *mockRepository) WithContext(context.Context) repository.WorkflowRepository { return m }
+
+func (m *mockRepository) Create(*models.Workflow) error       { return nil }
+func (m *mockRepository) FindAll() ([]models.Workflow, error) { return nil, nil }
+func (m *mockRepository) FindByID(uint) (*models.Workflow, error) {
+	return nil, errors.New("record not found")
+}
+func (m *mockRepository) FindByName(uint, string) (*models.Workflow, error) {
+	return nil, errors.New("record not found")
+}
+func (m *mockRepository) Delete(uint) error { return nil }
+func (m *mockRepository) FindRunByID(id uint) (*models.WorkflowRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := m.runs[id]
+	return &run, nil
+}
+
+func (m *mockRepository) CreateRun(run *models.WorkflowRun, steps []models.WorkflowStepRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run.ID = uint(len(m.runs) + 1)
+	m.runs[run.ID] = *run
+	for i := range steps {
+		steps[i].ID = uint(len(m.steps) + 1)
+		steps[i].WorkflowRunID = run.ID
+		m.steps[steps[i].ID] = steps[i]
+	}
+	return nil
+}
+func (m *mockRepository) UpdateRun(run *models.WorkflowRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs[run.ID] = *run
+	return nil
+}
+func (m *mockRepository) FindRunsByStatus(status string) ([]models.WorkflowRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var runs []models.WorkflowRun
+	for _, run := range m.runs {
+		if run.Status == status {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+func (m *mockRepository) FindStepRuns(runID uint) ([]models.WorkflowStepRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var steps []models.WorkflowStepRun
+	for _, step := range m.steps {
+		if step.WorkflowRunID == runID {
+			steps = append(steps, step)
+		}
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i].ID < steps[j].ID })
+	return steps, nil
+}
+func (m *mockRepository) UpdateStepRun(step *models.WorkflowStepRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.steps[step.ID] = *step
+	return nil
+}
+
+// stepStatuses returns the status of every step of a run by name
+func (m *mockRepository) stepStatuses(runID uint) map[string]string {
+	steps, _ := m.FindStepRuns(runID)
+	statuses := make(map[string]string, len(steps))
+	for _, step := range steps {
+		statuses[step.Step] = step.Status
+	}
+	return statuses
+}
+
+// mockExecutor fails runs whose params set "fail" until they have been attempted that many
+// times, and otherwise returns the params as outputs
+type mockExecutor struct {
+	mu       sync.Mutex
+	attempts map[string]int
+	params   []string
+}
+
+func (m *mockExecutor) RunOnce(ctx context.Context, machineID uint, params string, jobID *uint) (models.SimulationRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.attempts == nil {
+		m.attempts = map[string]int{}
+	}
+	m.params = append(m.params, params)
+	m.attempts[params]++
+
+	var p struct {
+		Fail int `json:"fail"`
+	}
+	_ = json.Unmarshal([]byte(params), &p)
+	run := models.SimulationRun{Model: models.Model{ID: uint(len(m.params))}, MachineID: machineID, Outputs: params}
+	if m.attempts[params] <= p.Fail {
+		return run, errors.New("exit status 1")
+	}
+	return run, nil
+}
+
+func startRun(t *testing.T, engine *Engine, inputs string, steps []models.WorkflowStep) models.WorkflowRun {
+	assert.Nil(t, Validate(steps))
+	run := models.WorkflowRun{WorkflowID: 1, Status: models.WorkflowStatusRunning, Inputs: inputs, Steps: steps}
+	stepRuns := make([]models.WorkflowStepRun, len(steps))
+	for i, step := range steps {
+		stepRuns[i] = models.WorkflowStepRun{Step: step.Name, Status: models.StepStatusPending}
+	}
+	assert.Nil(t, engine.Repo.CreateRun(&run, stepRuns))
+	engine.Start(context.Background(), run)
+	engine.Wait()
+	return run
+}
+
+func TestEngine(t *testing.T) {
+	t.Run("Outputs flow downstream", func(t *testing.T) {
+		repo, executor := newMockRepository(), &mockExecutor{}
+		engine := NewEngine(context.Background(), repo, executor)
+		run := startRun(t, engine, `{"load": 0.8}`, []models.WorkflowStep{
+			{Name: "a", MachineID: 1, Params: params(t, map[string]interface{}{"load": "${inputs.load}"})},
+			{Name: "b", MachineID: 2, DependsOn: []string{"a"}, Params: params(t, map[string]interface{}{"from_a": "${steps.a.outputs.load}"})},
+		})
+
+		final, _ := repo.FindRunByID(run.ID)
+		assert.Equal(t, models.WorkflowStatusSucceeded, final.Status)
+		assert.NotNil(t, final.FinishedAt)
+		assert.Equal(t, []string{`{"load":0.8}`, `{"from_a":0.8}`}, executor.params)
+	})
+
+	t.Run("Failure branch and skips", func(t *testing.T) {
+		repo := newMockRepository()
+		engine := NewEngine(context.Background(), repo, &mockExecutor{})
+		run := startRun(t, engine, "", []models.WorkflowStep{
+			{Name: "a", MachineID: 1, Params: params(t, map[string]interface{}{"fail": 1})},
+			{Name: "next", MachineID: 1, DependsOn: []string{"a"}},
+			{Name: "after-next", MachineID: 1, DependsOn: []string{"next"}},
+			{Name: "recover", MachineID: 1, DependsOn: []string{"a"}, When: models.StepWhenFailure},
+			{Name: "cleanup", MachineID: 1, DependsOn: []string{"after-next"}, When: models.StepWhenAlways},
+		})
+
+		assert.Equal(t, map[string]string{
+			"a":          models.StepStatusFailed,
+			"next":       models.StepStatusSkipped,
+			"after-next": models.StepStatusSkipped,
+			"recover":    models.StepStatusSucceeded,
+			"cleanup":    models.StepStatusSucceeded,
+		}, repo.stepStatuses(run.ID))
+		final, _ := repo.FindRunByID(run.ID)
+		assert.Equal(t, models.WorkflowStatusFailed, final.Status)
+		assert.Contains(t, final.Error, `step "a" failed`)
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		repo := newMockRepository()
+		engine := NewEngine(context.Background(), repo, &mockExecutor{})
+		engine.RetryDelay = 0
+		run := startRun(t, engine, "", []models.WorkflowStep{
+			{Name: "flaky", MachineID: 1, Retries: 2, Params: params(t, map[string]interface{}{"fail": 2})},
+		})
+
+		steps, _ := repo.FindStepRuns(run.ID)
+		assert.Equal(t, models.StepStatusSucceeded, steps[0].Status)
+		assert.Equal(t, 3, steps[0].Attempts)
+		assert.Empty(t, steps[0].Error)
+	})
+
+	t.Run("Unresolvable reference fails the step", func(t *testing.T) {
+		repo := newMockRepository()
+		engine := NewEngine(context.Background(), repo, &mockExecutor{})
+		run := startRun(t, engine, "", []models.WorkflowStep{
+			{Name: "a", MachineID: 1, Params: params(t, map[string]interface{}{"x": "${inputs.missing}"})},
+		})
+
+		steps, _ := repo.FindStepRuns(run.ID)
+		assert.Equal(t, models.StepStatusFailed, steps[0].Status)
+		assert.Contains(t, steps[0].Error, "unknown input")
+		assert.Zero(t, steps[0].Attempts)
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		repo, executor := newMockRepository(), &mockExecutor{}
+		engine := NewEngine(context.Background(), repo, executor)
+		steps := []models.WorkflowStep{
+			{Name: "a", MachineID: 1},
+			{Name: "b", MachineID: 1, DependsOn: []string{"a"}, Params: params(t, map[string]interface{}{"step": "b"})},
+		}
+		run := models.WorkflowRun{WorkflowID: 1, Status: models.WorkflowStatusRunning, Steps: steps}
+		assert.Nil(t, repo.CreateRun(&run, []models.WorkflowStepRun{
+			{Step: "a", Status: models.StepStatusSucceeded, Outputs: `{}`},
+			{Step: "b", Status: models.StepStatusRunning}, // interrupted by a restart
+		}))
+
+		assert.Nil(t, engine.Resume())
+		engine.Wait()
+
+		assert.Equal(t, []string{`{"step":"b"}`}, executor.params, "Only the interrupted step should run again")
+		final, _ := repo.FindRunByID(run.ID)
+		assert.Equal(t, models.WorkflowStatusSucceeded, final.Status)
+	})
+	t.Run("Interrupted", func(t *testing.T) {
+		repo := newMockRepository()
+		ctx, cancel := context.WithCancel(context.Background())
+		engine := NewEngine(ctx, repo, &mockExecutor{})
+		engine.RetryDelay = time.Hour
+		steps := []models.WorkflowStep{
+			{Name: "flaky", MachineID: 1, Retries: 1, Params: params(t, map[string]interface{}{"fail": 1})},
+			{Name: "next", MachineID: 1, DependsOn: []string{"flaky"}},
+		}
+		run := models.WorkflowRun{WorkflowID: 1, Status: models.WorkflowStatusRunning, Steps: steps}
+		assert.Nil(t, repo.CreateRun(&run, []models.WorkflowStepRun{
+			{Step: "flaky", Status: models.StepStatusPending},
+			{Step: "next", Status: models.StepStatusPending},
+		}))
+		engine.Start(context.Background(), run)
+		assert.Eventually(t, func() bool {
+			steps, _ := repo.FindStepRuns(run.ID)
+			return steps[0].Attempts == 1
+		}, time.Second, time.Millisecond)
+		cancel()
+		engine.Wait()
+
+		final, _ := repo.FindRunByID(run.ID)
+		assert.Equal(t, models.WorkflowStatusRunning, final.Status, "Interrupted runs should be left to resume")
+		assert.Equal(t, map[string]string{"flaky": models.StepStatusRunning, "next": models.StepStatusPending}, repo.stepStatuses(run.ID))
+	})
+}
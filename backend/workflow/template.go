@@ -0,0 +1,195 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+)
+
+// referencePattern matches ${...} references in step params
+var referencePattern = regexp.MustCompile(`\$\{\s*([^}\s]+)\s*\}`)
+
+// StepState is what later steps can reference of a finished step
+type StepState struct {
+	Status  string
+	Outputs map[string]interface{}
+}
+
+// Scope holds the values references resolve against while a workflow runs
+type Scope struct {
+	Inputs map[string]interface{}
+	Steps  map[string]StepState
+}
+
+// ResolveParams builds the params of a step run: its params with every reference replaced,
+// plus its runner, if any. A string that is a single reference takes the referenced
+// value as is (keeping numbers numbers); references inside longer strings are interpolated.
+func ResolveParams(step models.WorkflowStep, scope Scope) (string, error) {
+	params, err := stepParams(step)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := walkStrings(params, func(s string) (interface{}, error) {
+		return scope.substitute(s)
+	})
+	if err != nil {
+		return "", err
+	}
+	// The runner is a name taken as is, so inputs can never choose what is executed
+	if step.Runner != "" {
+		resolved.(map[string]interface{})["runner"] = step.Runner
+	}
+	out, err := json.Marshal(resolved)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// stepReferences returns the names of the steps referenced by a step
+func stepReferences(step models.WorkflowStep) ([]string, error) {
+	params, err := stepParams(step)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	_, err = walkStrings(params, func(s string) (interface{}, error) {
+		for _, match := range referencePattern.FindAllStringSubmatch(s, -1) {
+			ref, err := parseReference(match[1])
+			if err != nil {
+				return nil, err
+			}
+			if ref.step != "" {
+				names = append(names, ref.step)
+			}
+		}
+		return s, nil
+	})
+	return names, err
+}
+
+// stepParams decodes a step's params
+func stepParams(step models.WorkflowStep) (map[string]interface{}, error) {
+	params := make(map[string]interface{}, len(step.Params)+1)
+	for key, raw := range step.Params {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid param %q: %w", key, err)
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+// walkStrings returns a copy of v with every string replaced by fn's result
+func walkStrings(v interface{}, fn func(string) (interface{}, error)) (interface{}, error) {
+	switch value := v.(type) {
+	case string:
+		return fn(value)
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			resolved, err := walkStrings(item, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			resolved, err := walkStrings(item, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// reference is a parsed ${...} expression: an input, or the status or an output of a step
+type reference struct {
+	input  string
+	step   string
+	output string // empty for the step's status
+}
+
+func parseReference(expr string) (reference, error) {
+	parts := strings.SplitN(expr, ".", 4)
+	switch {
+	case len(parts) >= 2 && parts[0] == "inputs":
+		return reference{input: strings.TrimPrefix(expr, "inputs.")}, nil
+	case len(parts) == 3 && parts[0] == "steps" && parts[2] == "status":
+		return reference{step: parts[1]}, nil
+	case len(parts) == 4 && parts[0] == "steps" && parts[2] == "outputs":
+		return reference{step: parts[1], output: parts[3]}, nil
+	}
+	return reference{}, fmt.Errorf("invalid reference ${%s}: expected inputs.<key>, steps.<name>.status or steps.<name>.outputs.<key>", expr)
+}
+
+// lookup returns the value of a reference
+func (s Scope) lookup(expr string) (interface{}, error) {
+	ref, err := parseReference(expr)
+	if err != nil {
+		return nil, err
+	}
+	if ref.step == "" {
+		value, ok := s.Inputs[ref.input]
+		if !ok {
+			return nil, fmt.Errorf("unknown input %q", ref.input)
+		}
+		return value, nil
+	}
+
+	state, ok := s.Steps[ref.step]
+	if !ok {
+		return nil, fmt.Errorf("step %q has not finished", ref.step)
+	}
+	if ref.output == "" {
+		return state.Status, nil
+	}
+	value, ok := state.Outputs[ref.output]
+	if !ok {
+		return nil, fmt.Errorf("step %q has no output %q", ref.step, ref.output)
+	}
+	return value, nil
+}
+
+// substitute resolves the references in a string
+func (s Scope) substitute(str string) (interface{}, error) {
+	matches := referencePattern.FindAllStringSubmatchIndex(str, -1)
+	if len(matches) == 0 {
+		return str, nil
+	}
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(str) {
+		return s.lookup(str[matches[0][2]:matches[0][3]])
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, m := range matches {
+		out.WriteString(str[last:m[0]])
+		value, err := s.lookup(str[m[2]:m[3]])
+		if err != nil {
+			return nil, err
+		}
+		if text, ok := value.(string); ok {
+			out.WriteString(text)
+		} else {
+			encoded, _ := json.Marshal(value)
+			out.Write(encoded)
+		}
+		last = m[1]
+	}
+	out.WriteString(str[last:])
+	return out.String(), nil
+}
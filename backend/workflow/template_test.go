@@ -0,0 +1,46 @@
+package workflow
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/CBYeuler/automation-backend/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveParams(t *testing.T) {
+	scope := Scope{
+		Inputs: map[string]interface{}{"batch": "B-7"},
+		Steps: map[string]StepState{
+			"warmup": {Status: models.StepStatusSucceeded, Outputs: map[string]interface{}{"temperature": 71.5}},
+		},
+	}
+	step := models.WorkflowStep{
+		Name:   "run",
+		Runner: "qualify",
+		Params: params(t, map[string]interface{}{
+			"temp":   "${steps.warmup.outputs.temperature}",
+			"label":  "after ${steps.warmup.status} at ${steps.warmup.outputs.temperature}",
+			"nested": map[string]interface{}{"batch": "${inputs.batch}"},
+			"load":   0.5,
+		}),
+	}
+
+	resolved, err := ResolveParams(step, scope)
+	assert.Nil(t, err)
+	var out map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(resolved), &out))
+	assert.Equal(t, 71.5, out["temp"], "A whole-string reference should keep the value's type")
+	assert.Equal(t, "after Succeeded at 71.5", out["label"])
+	assert.Equal(t, map[string]interface{}{"batch": "B-7"}, out["nested"])
+	assert.Equal(t, 0.5, out["load"])
+	assert.Equal(t, "qualify", out["runner"], "The runner should be set as is")
+
+	step.Params = params(t, map[string]interface{}{"x": "${inputs.missing}"})
+	_, err = ResolveParams(step, scope)
+	assert.ErrorContains(t, err, `unknown input "missing"`)
+
+	step.Params = params(t, map[string]interface{}{"x": "${steps.warmup.outputs.pressure}"})
+	_, err = ResolveParams(step, scope)
+	assert.ErrorContains(t, err, `has no output "pressure"`)
+}